@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var logger service.Logger
+
+const usage = `Usage:
+  api-keys create -name NAME [-rate N] [-burst N] [-daily-quota N]
+  api-keys list
+  api-keys revoke -id ID`
+
+// Defaults of newly issued keys
+const (
+	DefaultKeyRequestsPerMinute = 600
+	DefaultKeyBurst             = 100
+	DefaultKeyDailyQuota        = 0
+)
+
+func main() {
+	// Load environment variables from .env file only in development
+	env := os.Getenv("ENV")
+	if env == "" || strings.ToLower(env) == "development" {
+		if err := godotenv.Load("../.env"); err != nil {
+			// Use fmt.Println since logger isn't initialized yet
+			fmt.Printf("Warning: No .env file found: %v\n", err)
+		}
+	}
+
+	// initialize default logger implementation
+	logger = service.NewDefaultLogger()
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	switch os.Args[1] {
+	case "create":
+		err = runCreate(ctx, os.Args[2:])
+	case "list":
+		err = runList(ctx, os.Args[2:])
+	case "revoke":
+		err = runRevoke(ctx, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logger.Fatalf("api-keys %s failed: %v", os.Args[1], err)
+	}
+}
+
+func openKeys() (*repository.APIKeyRepository, func(), error) {
+	repo, err := repository.NewRideDataHistoryRepository()
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo.APIKeys(), func() { repo.Close() }, nil
+}
+
+func runCreate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	name := fs.String("name", "", "who the key is issued to")
+	rate := fs.Int("rate", DefaultKeyRequestsPerMinute, "sustained requests per minute; 0 is unlimited")
+	burst := fs.Int("burst", DefaultKeyBurst, "requests that may be made at once")
+	dailyQuota := fs.Int("daily-quota", DefaultKeyDailyQuota, "requests per UTC day; 0 is unlimited")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*name) == "" {
+		return errors.New("-name is required")
+	}
+	if *rate < 0 || *burst < 0 || *dailyQuota < 0 {
+		return errors.New("limits must not be negative")
+	}
+
+	keys, closeRepo, err := openKeys()
+	if err != nil {
+		return err
+	}
+	defer closeRepo()
+
+	created, key, err := keys.CreateAPIKey(ctx, *name, repository.APIKeyLimits{
+		RequestsPerMinute: *rate, Burst: *burst, DailyQuota: *dailyQuota,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The key is printed once on stdout; only its hash is stored
+	fmt.Println(key)
+	logger.Infof("Created API key %d (%s) for %q", created.ID, created.Prefix, created.Name)
+	return nil
+}
+
+func runList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+
+	keys, closeRepo, err := openKeys()
+	if err != nil {
+		return err
+	}
+	defer closeRepo()
+
+	list, err := keys.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPREFIX\tNAME\tRATE\tBURST\tDAILY QUOTA\tCREATED\tREVOKED")
+	for _, k := range list {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n", k.ID, k.Prefix, k.Name,
+			k.RequestsPerMinute, k.Burst, k.DailyQuota, k.CreatedAt.UTC().Format(time.RFC3339), revoked)
+	}
+	return tw.Flush()
+}
+
+func runRevoke(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := fs.Int64("id", 0, "ID of the key to revoke, as shown by list")
+	fs.Parse(args)
+
+	if *id <= 0 {
+		return errors.New("-id is required")
+	}
+
+	keys, closeRepo, err := openKeys()
+	if err != nil {
+		return err
+	}
+	defer closeRepo()
+
+	if err := keys.RevokeAPIKey(ctx, *id); err != nil {
+		return err
+	}
+	logger.Infof("Revoked API key %d; cached lookups expire within a minute", *id)
+	return nil
+}
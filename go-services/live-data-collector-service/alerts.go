@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"go-services/shared/alerts"
+	"go-services/shared/notify"
+	"go-services/shared/repository"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// newAlertEvaluator configures delivery of the users' wait time alerts. Email goes through the
+// SMTP_* server and Web Push is signed with the VAPID_* keys; rules of a channel that is not
+// configured log failed deliveries. Webhooks and push messages only reach public addresses unless
+// ALERT_ALLOW_PRIVATE_TARGETS is set for local development.
+func newAlertEvaluator(repo *repository.RideDataHistoryRepository) (*alerts.Evaluator, error) {
+	var channels alerts.Channels
+	if strings.TrimSpace(os.Getenv("SMTP_ADDR")) != "" {
+		smtp, err := notify.SMTPFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		channels.SMTP = smtp
+	}
+
+	vapid, err := notify.VAPIDFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	channels.VAPID = vapid
+
+	allowPrivate, err := allowPrivateTargets()
+	if err != nil {
+		return nil, err
+	}
+	channels.Client = notify.PublicClient(AlertDeliveryTimeout, allowPrivate)
+
+	return alerts.NewEvaluator(repo.AlertRules(), channels), nil
+}
+
+// allowPrivateTargets reads ALERT_ALLOW_PRIVATE_TARGETS, which lets alerts and webhook subscriptions
+// reach private addresses for local development
+func allowPrivateTargets() (bool, error) {
+	v := strings.TrimSpace(os.Getenv("ALERT_ALLOW_PRIVATE_TARGETS"))
+	if v == "" {
+		return false, nil
+	}
+	allow, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("ALERT_ALLOW_PRIVATE_TARGETS must be a boolean, got %q", v)
+	}
+	return allow, nil
+}
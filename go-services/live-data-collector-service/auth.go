@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"go-services/shared/auth"
+	"os"
+	"strings"
+	"time"
+)
+
+// signatureMaxSkew is how far the timestamp of an HMAC signed request may be from the server clock
+const signatureMaxSkew = 5 * time.Minute
+
+// collectAuthenticators configures who may trigger /collect from the environment:
+//   - COLLECT_HMAC_SECRET accepts requests signed with the shared secret in the X-Signature header
+//   - OIDC_AUDIENCE accepts Bearer ID tokens for that audience, e.g. from Cloud Scheduler. OIDC_ISSUER
+//     and OIDC_JWKS_URL default to Google; OIDC_JWKS_FILE reads a local key set instead, and
+//     OIDC_ALLOWED_EMAILS limits the service accounts that may call.
+//
+// With neither set, /collect rejects every request.
+func collectAuthenticators() ([]auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+
+	if secret := os.Getenv("COLLECT_HMAC_SECRET"); secret != "" {
+		authenticators = append(authenticators, auth.NewHMAC([]byte(secret), signatureMaxSkew))
+	}
+
+	if audience := os.Getenv("OIDC_AUDIENCE"); audience != "" {
+		cfg := auth.OIDCConfig{
+			Issuer:        envOr("OIDC_ISSUER", auth.GoogleIssuer),
+			Audience:      audience,
+			AllowedEmails: splitList(os.Getenv("OIDC_ALLOWED_EMAILS")),
+		}
+		if path := os.Getenv("OIDC_JWKS_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read OIDC_JWKS_FILE: %w", err)
+			}
+			keys, err := auth.ParseJWKS(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse OIDC_JWKS_FILE: %w", err)
+			}
+			cfg.Keys = keys
+		} else {
+			cfg.Keys = auth.NewRemoteKeySet(envOr("OIDC_JWKS_URL", auth.GoogleJWKSURL))
+		}
+		authenticators = append(authenticators, auth.NewOIDC(cfg))
+	}
+
+	return authenticators, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
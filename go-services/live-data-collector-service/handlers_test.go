@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"go-services/shared/auth"
+	"go-services/shared/circuit"
+	"go-services/shared/health"
+	"go-services/shared/middleware"
+	"go-services/shared/response"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -40,7 +49,7 @@ func TestHealthHandler(t *testing.T) {
 			req := httptest.NewRequest(tt.method, "/health", nil)
 			w := httptest.NewRecorder()
 
-			healthHandler(w, req)
+			newRouter(nil, nil, nil).ServeHTTP(w, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -112,23 +121,27 @@ func TestCollectHandler_MethodNotAllowed(t *testing.T) {
 	req := httptest.NewRequest("GET", "/collect", nil)
 	w := httptest.NewRecorder()
 
-	collectHandler(w, req)
+	collectHandler(nil)(w, req)
 
 	if w.Code != http.StatusMethodNotAllowed {
 		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
 	}
 
-	var response LiveDataCollectorResponse
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-		t.Errorf("Failed to unmarshal response: %v", err)
+	if contentType := w.Header().Get("Content-Type"); contentType != response.ProblemContentType {
+		t.Errorf("Expected content type %s, got %s", response.ProblemContentType, contentType)
 	}
 
-	if response.Success {
-		t.Error("Expected request to fail")
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "POST") {
+		t.Errorf("Expected Allow header to list POST, got: %s", allow)
 	}
 
-	if !strings.Contains(response.Message, "Only POST method is allowed") {
-		t.Errorf("Expected error message about POST method, got: %s", response.Message)
+	var problem response.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Errorf("Failed to unmarshal response: %v", err)
+	}
+
+	if problem.Code != response.CodeMethodNotAllowed {
+		t.Errorf("Expected code %s, got: %s", response.CodeMethodNotAllowed, problem.Code)
 	}
 }
 
@@ -157,33 +170,28 @@ func TestDefaultParkIDs(t *testing.T) {
 }
 
 func TestCORSHeaders(t *testing.T) {
+	defer func(policy middleware.CORSConfig) { CORSPolicy = policy }(CORSPolicy)
+
 	tests := []struct {
 		name     string
-		method   string
+		origins  []string
 		endpoint string
-		handler  func(http.ResponseWriter, *http.Request)
+		want     string
 	}{
-		{
-			name:     "health endpoint",
-			method:   "OPTIONS",
-			endpoint: "/health",
-			handler:  healthHandler,
-		},
-		{
-			name:     "collect endpoint",
-			method:   "OPTIONS",
-			endpoint: "/collect",
-			handler:  collectHandler,
-		},
+		{"no origins by default", nil, "/collect", ""},
+		{"configured origin on health", []string{"https://*.example.com"}, "/health", "https://admin.example.com"},
+		{"configured origin before auth", []string{"https://*.example.com"}, "/collect", "https://admin.example.com"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, tt.endpoint, nil)
-			req.Header.Set("Origin", "https://example.com")
+			CORSPolicy.AllowedOrigins = tt.origins
+			req := httptest.NewRequest("OPTIONS", tt.endpoint, nil)
+			req.Header.Set("Origin", "https://admin.example.com")
+			req.Header.Set("Access-Control-Request-Method", "POST")
 			w := httptest.NewRecorder()
 
-			tt.handler(w, req)
+			newRouter(nil, nil, nil).ServeHTTP(w, req)
 
 			if w.Code != http.StatusOK {
 				t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
@@ -191,8 +199,11 @@ func TestCORSHeaders(t *testing.T) {
 
 			// Check CORS headers
 			allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
-			if allowOrigin != "*" {
-				t.Errorf("Expected CORS Allow-Origin '*', got '%s'", allowOrigin)
+			if allowOrigin != tt.want {
+				t.Errorf("Expected CORS Allow-Origin '%s', got '%s'", tt.want, allowOrigin)
+			}
+			if tt.want == "" {
+				return
 			}
 
 			allowMethods := w.Header().Get("Access-Control-Allow-Methods")
@@ -201,34 +212,144 @@ func TestCORSHeaders(t *testing.T) {
 			}
 
 			allowHeaders := w.Header().Get("Access-Control-Allow-Headers")
-			if allowHeaders == "" {
-				t.Error("Expected CORS Allow-Headers header to be set")
+			if !strings.Contains(allowHeaders, "X-Signature") {
+				t.Errorf("Expected X-Signature to be an allowed header, got '%s'", allowHeaders)
 			}
 		})
 	}
 }
 
-func TestWriteErrorResponse(t *testing.T) {
+func TestRouterProblems(t *testing.T) {
+	req := httptest.NewRequest("DELETE", "/health", nil)
+	req.Header.Set(response.RequestIDHeader, "scheduler-run-42")
 	w := httptest.NewRecorder()
-	message := "Test error message"
-	statusCode := http.StatusBadRequest
 
-	writeErrorResponse(w, statusCode, message)
+	newRouter(nil, nil, nil).ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
 
-	if w.Code != statusCode {
-		t.Errorf("Expected status %d, got %d", statusCode, w.Code)
+	if requestID := w.Header().Get(response.RequestIDHeader); requestID != "scheduler-run-42" {
+		t.Errorf("Expected request ID to be echoed, got '%s'", requestID)
 	}
 
-	var response LiveDataCollectorResponse
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+	var problem response.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
 	}
 
-	if response.Success {
-		t.Error("Expected error response to have Success=false")
+	if problem.Status != http.StatusMethodNotAllowed || problem.RequestID != "scheduler-run-42" {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+
+	if problem.Instance != "/health" {
+		t.Errorf("Expected instance '/health', got '%s'", problem.Instance)
+	}
+}
+
+func TestRouterRequiresCollectAuth(t *testing.T) {
+	secret := []byte("collect-secret")
+	router := newRouter(nil, []auth.Authenticator{auth.NewHMAC(secret, time.Minute)}, nil)
+
+	// Unauthenticated collection is rejected before the handler runs
+	req := httptest.NewRequest("POST", "/collect", strings.NewReader("{}"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != response.ProblemContentType {
+		t.Errorf("Expected a problem response, got %s", contentType)
+	}
+
+	// A signed request reaches the handler, which only accepts POST
+	req = httptest.NewRequest("GET", "/collect", nil)
+	req.Header.Set(auth.SignatureHeader, auth.Sign(secret, time.Now(), "GET", "/collect", nil))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected the signed request to reach the handler, got %d", w.Code)
+	}
+
+	// Health checks stay public
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected /health without credentials to succeed, got %d", w.Code)
+	}
+}
+
+func TestCollectAuthenticators(t *testing.T) {
+	t.Setenv("COLLECT_HMAC_SECRET", "")
+	t.Setenv("OIDC_AUDIENCE", "")
+	if authenticators, err := collectAuthenticators(); err != nil || len(authenticators) != 0 {
+		t.Errorf("Expected no authenticators, got %d (%v)", len(authenticators), err)
+	}
+
+	t.Setenv("COLLECT_HMAC_SECRET", "secret")
+	t.Setenv("OIDC_AUDIENCE", "https://collector.example.com")
+	t.Setenv("OIDC_JWKS_FILE", "does-not-exist.json")
+	if _, err := collectAuthenticators(); err == nil {
+		t.Error("Expected a missing OIDC_JWKS_FILE to fail")
+	}
+
+	path := t.TempDir() + "/jwks.json"
+	if err := os.WriteFile(path, []byte(`{"keys":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OIDC_JWKS_FILE", path)
+	if authenticators, err := collectAuthenticators(); err != nil || len(authenticators) != 2 {
+		t.Errorf("Expected HMAC and OIDC authenticators, got %d (%v)", len(authenticators), err)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := newRouter(nil, nil, nil)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if want := `route="/health",status="200"`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected the health check to be counted, missing %s", want)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	breaker := circuit.New(1, time.Minute)
+	breaker.Allow()
+	breaker.Failure(errors.New("API returned status 503"))
+
+	ready := health.New(ServiceName, "", time.Second)
+	ready.Add("upstream", health.Upstream(func(context.Context) error { return nil }, breaker, time.Minute))
+	ready.Add("migrations", health.Migrations(func(context.Context) (string, error) {
+		return "20260508105435_optimize_ride_history_and_remove_legacy", nil
+	}, "20261019110000_add_api_keys"))
+	router := newRouter(nil, nil, ready)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a schema behind the required migration to be unready, got %d", w.Code)
+	}
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	upstream := report.Checks["upstream"]
+	if upstream.Status != health.Warn || upstream.Message != "circuit breaker is open" {
+		t.Errorf("Expected an open circuit to warn, got %+v", upstream)
+	}
+	if report.Checks["migrations"].Status != health.Fail {
+		t.Errorf("Expected the migration check to fail, got %+v", report.Checks["migrations"])
 	}
 
-	if response.Message != message {
-		t.Errorf("Expected message '%s', got '%s'", message, response.Message)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected /livez to answer 200, got %d", w.Code)
 	}
 }
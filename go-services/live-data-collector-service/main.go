@@ -3,7 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"go-services/shared/metrics"
+	"go-services/shared/middleware"
+	"go-services/shared/notify"
+	"go-services/shared/repository"
 	"go-services/shared/service"
+	"go-services/shared/tracing"
+	"go-services/shared/webhooks"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,7 +20,7 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var logger service.Logger
+var logger *service.StructuredLogger
 
 // Main function to start the HTTP server
 func main() {
@@ -31,21 +37,94 @@ func main() {
 	// initialize default logger implementation
 	logger = service.NewDefaultLogger()
 
+	// OTEL_TRACES_EXPORTER enables tracing; spans are flushed on shutdown
+	shutdownTracing, err := tracing.Setup(context.Background(), ServiceName)
+	if err != nil {
+		logger.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Errorf("Failed to flush traces: %v", err)
+		}
+	}()
+
 	// Determine port for HTTP server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Register HTTP handlers
-	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/collect", collectHandler)
-	http.HandleFunc("/", rootHandler)
+	// CORS_* override the default cross-origin policy
+	cors, err := middleware.CORSFromEnv(CORSPolicy)
+	if err != nil {
+		logger.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	CORSPolicy = cors
+
+	// Initialize repository once so its latest-state cache survives across collections
+	repo, err := repository.NewRideDataHistoryRepository()
+	if err != nil {
+		logger.Fatalf("Failed to initialize repository: %v", err)
+	}
+	defer repo.Close()
+	metrics.RegisterPool(repo.PoolStat)
+
+	// Wait time alerts are evaluated and queued after every insert
+	evaluator, err := newAlertEvaluator(repo)
+	if err != nil {
+		logger.Fatalf("Invalid alert configuration: %v", err)
+	}
+	repo.OnInsert(evaluator.Evaluate)
+
+	// Ride state changes are queued for the webhook subscriptions after every insert
+	allowPrivate, err := allowPrivateTargets()
+	if err != nil {
+		logger.Fatalf("Invalid webhook configuration: %v", err)
+	}
+	dispatcher := webhooks.NewDispatcher(repo.Webhooks(), notify.PublicClient(WebhookDeliveryTimeout, allowPrivate))
+	repo.OnChange(dispatcher.Enqueue)
+
+	authenticators, err := collectAuthenticators()
+	if err != nil {
+		logger.Fatalf("Failed to configure /collect authentication: %v", err)
+	}
+	if len(authenticators) == 0 {
+		logger.Warnf("Neither COLLECT_HMAC_SECRET nor OIDC_AUDIENCE is set; /collect will reject every request")
+	}
+
+	ready, err := newReadiness(repo)
+	if err != nil {
+		logger.Fatalf("Invalid readiness configuration: %v", err)
+	}
+
+	// The alert and webhook deliveries and the watchdog run until shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go evaluator.Run(backgroundCtx, AlertPollInterval)
+	go dispatcher.Run(backgroundCtx, WebhookPollInterval)
+
+	// The watchdog runs when an alert destination is configured
+	dog, err := newWatchdog(repositoryIncidentStore{repo, repo.StalenessIncidents()})
+	if err != nil {
+		logger.Fatalf("Invalid watchdog configuration: %v", err)
+	}
+	if dog == nil {
+		logger.Warnf("No ALERT_* destination is set; the staleness watchdog is disabled")
+	} else {
+		interval, err := watchdogIntervalFromEnv()
+		if err != nil {
+			logger.Fatalf("Invalid watchdog configuration: %v", err)
+		}
+		logger.InfoContext(backgroundCtx, "Staleness watchdog started", "interval", interval.String(), "notifiers", dog.notifier.Name())
+		go dog.run(backgroundCtx, interval)
+	}
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: nil, // Uses DefaultServeMux
+		Handler: newRouter(repo, authenticators, ready),
 	}
 
 	// Set up graceful shutdown
@@ -55,6 +134,7 @@ func main() {
 	go func() {
 		<-sigChan
 		logger.Infof("Shutdown signal received, initiating graceful shutdown...")
+		stopBackground()
 		
 		// Create a context with a timeout for the shutdown process
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
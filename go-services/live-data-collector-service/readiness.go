@@ -0,0 +1,35 @@
+package main
+
+import (
+	"go-services/shared"
+	"go-services/shared/health"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+)
+
+// newReadiness builds the /readyz checks: the database and its pool, the schema migration, the
+// freshness of the collected parks and the upstream API with its circuit breaker.
+// STALE_AFTER_MINUTES overrides how old a park's newest ride data may get.
+func newReadiness(repo *repository.RideDataHistoryRepository) (*health.Checker, error) {
+	staleAfter, err := staleAfterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ready := health.New(ServiceName, "", ReadinessTimeout)
+	ready.Add("database", health.Database(repo.HealthCheck, repo.PoolStat, PoolSaturationWarning))
+	ready.Add("migrations", health.Migrations(repo.LatestMigration, repository.RequiredMigration))
+	ready.Add("freshness", health.Freshness(repo.LatestUpdateByPark, collectedParks(), staleAfter))
+	ready.Add("upstream", health.Upstream(service.ProbeUpstream, service.UpstreamBreaker, UpstreamProbeInterval))
+	return ready, nil
+}
+
+// collectedParks maps the IDs of the parks collected by default to their names
+func collectedParks() map[string]string {
+	parks := make(map[string]string, len(defaultParkIDs))
+	for _, id := range defaultParkIDs {
+		park, _ := shared.GetParkInfo(id)
+		parks[id] = park.Name
+	}
+	return parks
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"go-services/shared/auth"
+	"go-services/shared/health"
+	"go-services/shared/metrics"
+	"go-services/shared/middleware"
+	"go-services/shared/repository"
+	"go-services/shared/tracing"
+	"net/http"
+)
+
+// newRouter registers the collector's routes behind the same middleware as the wait times API. A nil
+// ready reports no dependencies.
+func newRouter(repo *repository.RideDataHistoryRepository, authenticators []auth.Authenticator, ready *health.Checker) http.Handler {
+	if ready == nil {
+		ready = health.New(ServiceName, "", ReadinessTimeout)
+	}
+	cors := middleware.NewCORS(CORSPolicy)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("GET /livez", health.LiveHandler(ServiceName))
+	mux.HandleFunc("GET /readyz", ready.ReadyHandler())
+	// /collect triggers upstream fetches and database writes, so only authenticated callers may use it
+	mux.Handle("/collect", auth.Require(authenticators...)(collectHandler(repo)))
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("/", rootHandler)
+	// CORS answers preflights before authentication; /collect requests are traced through the upstream
+	// fetches and inserts they trigger
+	handler := middleware.RequestID(cors.Middleware(middleware.Problems(tracing.Route(metrics.Instrument(mux)))))
+	return tracing.Middleware(ServiceName, handler)
+}
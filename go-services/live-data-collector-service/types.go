@@ -1,11 +1,74 @@
 package main
 
+import (
+	"go-services/shared/middleware"
+	"net/http"
+	"time"
+)
+
 // Default park IDs for Disney parks (you can customize these)
 var defaultParkIDs = []string{
 	"7340550b-c14d-4def-80bb-acdb51d49a66", // Disneyland
 	"832fcd51-ea19-4e77-85c7-75d5843b127c", // Disney California Adventure
 }
 
+// ServiceName identifies the collector in health responses and traces
+const ServiceName = "live-data-collector"
+
+// CORSPolicy is the default cross-origin policy. /collect is called by schedulers rather than browsers,
+// so no origin is allowed unless CORS_ALLOWED_ORIGINS names one.
+var CORSPolicy = middleware.CORSConfig{
+	AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+	AllowedHeaders: []string{"Content-Type", "Authorization", "X-Signature"},
+	ExposedHeaders: []string{"X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+// partitionMonthsAhead is how many months of ride_data_history partitions are created ahead of the current month
+const partitionMonthsAhead = 3
+
+// Settings for the /readyz checks
+const (
+	// DefaultStaleAfterMinutes is how old the newest ride data of a park may get before /readyz
+	// fails. STALE_AFTER_MINUTES overrides it.
+	DefaultStaleAfterMinutes = 30
+	// PoolSaturationWarning is the share of pool connections in use at which the database check warns
+	PoolSaturationWarning = 0.9
+	// ReadinessTimeout bounds each readiness check
+	ReadinessTimeout = 5 * time.Second
+	// UpstreamProbeInterval is how long a probe of the upstream API is reused by /readyz
+	UpstreamProbeInterval = time.Minute
+)
+
+// Settings for the staleness watchdog
+const (
+	// DefaultWatchdogIntervalMinutes is how often the watchdog checks freshness.
+	// WATCHDOG_INTERVAL_MINUTES overrides it.
+	DefaultWatchdogIntervalMinutes = 5
+	// DefaultWatchdogHours are the daily hours during which ride data must stay fresh. WATCHDOG_HOURS
+	// overrides them.
+	DefaultWatchdogHours = "08:00-23:00"
+	// DefaultWatchdogTimezone is the zone of the watchdog hours, that of the Disneyland Resort.
+	// WATCHDOG_TIMEZONE overrides it.
+	DefaultWatchdogTimezone = "America/Los_Angeles"
+)
+
+// Settings for wait time alerts
+const (
+	// AlertDeliveryTimeout bounds each webhook and push request of a wait time alert
+	AlertDeliveryTimeout = 10 * time.Second
+	// AlertPollInterval is how often the alert queue is checked for retries that came due
+	AlertPollInterval = 15 * time.Second
+)
+
+// Settings for outgoing webhooks
+const (
+	// WebhookDeliveryTimeout bounds each webhook request
+	WebhookDeliveryTimeout = 10 * time.Second
+	// WebhookPollInterval is how often the queue is checked for retries that came due
+	WebhookPollInterval = 15 * time.Second
+)
+
 // LiveDataCollectorRequest represents the request payload for the function
 type LiveDataCollectorRequest struct {
 	ParkIDs []string `json:"parkIds"`
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-services/shared/models"
+	"go-services/shared/notify"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incidentStore is the part of the repository the watchdog uses
+type incidentStore interface {
+	LatestUpdateByPark(ctx context.Context) (map[string]time.Time, error)
+	OpenStalenessIncident(ctx context.Context, parkID string, lastDataAt *time.Time) (*models.StalenessIncident, error)
+	ClaimStalenessNotification(ctx context.Context, id int64) (bool, error)
+	ReleaseStalenessNotification(ctx context.Context, id int64) error
+	ResolveStalenessIncident(ctx context.Context, parkID string) (*models.StalenessIncident, error)
+}
+
+// repositoryIncidentStore reads freshness from the ride data history and keeps incidents in
+// staleness_incidents
+type repositoryIncidentStore struct {
+	*repository.RideDataHistoryRepository
+	*repository.StalenessIncidentRepository
+}
+
+// dailyWindow is a time of day range in a park's time zone. A window whose end is before its start
+// runs past midnight.
+type dailyWindow struct {
+	start, end time.Duration
+	loc        *time.Location
+}
+
+// parseDailyWindow parses "HH:MM-HH:MM"
+func parseDailyWindow(s string, loc *time.Location) (dailyWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return dailyWindow{}, fmt.Errorf("expected HH:MM-HH:MM, got %q", s)
+	}
+	w := dailyWindow{loc: loc}
+	for _, part := range []struct {
+		s string
+		d *time.Duration
+	}{{from, &w.start}, {to, &w.end}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part.s))
+		if err != nil {
+			return dailyWindow{}, fmt.Errorf("expected HH:MM-HH:MM, got %q", s)
+		}
+		*part.d = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return w, nil
+}
+
+// openedAt returns when the window containing t opened, and false if t is outside the window
+func (w dailyWindow) openedAt(t time.Time) (time.Time, bool) {
+	t = t.In(w.loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.loc)
+	offset := t.Sub(midnight)
+	switch {
+	case w.start <= w.end && offset >= w.start && offset < w.end:
+		return midnight.Add(w.start), true
+	case w.start > w.end && offset >= w.start:
+		return midnight.Add(w.start), true
+	case w.start > w.end && offset < w.end:
+		return midnight.AddDate(0, 0, -1).Add(w.start), true
+	}
+	return time.Time{}, false
+}
+
+// watchdog alerts when collected parks stop receiving data during operating hours. Incidents are
+// stored, so each is alerted once across restarts and collector instances, and a recovery notice
+// follows once data is fresh again.
+type watchdog struct {
+	store      incidentStore
+	notifier   notify.Notifier
+	parks      map[string]string
+	staleAfter time.Duration
+	hours      dailyWindow
+	now        func() time.Time
+}
+
+// check evaluates every park once
+func (w *watchdog) check(ctx context.Context) error {
+	latest, err := w.store.LatestUpdateByPark(ctx)
+	if err != nil {
+		return err
+	}
+	now := w.now()
+	for parkID, name := range w.parks {
+		ctx := service.WithPark(ctx, parkID)
+		var lastData *time.Time
+		if t, ok := latest[parkID]; ok {
+			lastData = &t
+		}
+
+		if lastData != nil && now.Sub(*lastData) <= w.staleAfter {
+			if err := w.recover(ctx, parkID, name, *lastData); err != nil {
+				logger.ErrorContext(ctx, "Failed to resolve staleness incident", "error", err)
+			}
+			continue
+		}
+
+		// Data only has to be fresh during operating hours, and only from staleAfter after opening
+		opened, open := w.hours.openedAt(now)
+		if !open || now.Sub(opened) <= w.staleAfter {
+			continue
+		}
+		if err := w.alert(ctx, parkID, name, lastData, now); err != nil {
+			logger.ErrorContext(ctx, "Failed to alert on stale ride data", "error", err)
+		}
+	}
+	return nil
+}
+
+func (w *watchdog) alert(ctx context.Context, parkID, name string, lastData *time.Time, now time.Time) error {
+	incident, err := w.store.OpenStalenessIncident(ctx, parkID, lastData)
+	if err != nil {
+		return err
+	}
+	if incident.NotifiedAt != nil {
+		return nil
+	}
+	claimed, err := w.store.ClaimStalenessNotification(ctx, incident.ID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	since := "no ride data in the past week"
+	data := map[string]any{"park_id": parkID, "park_name": name, "incident_id": incident.ID, "stale_after_seconds": int64(w.staleAfter.Seconds())}
+	if lastData != nil {
+		since = fmt.Sprintf("the newest ride data is from %s (%s ago)", lastData.UTC().Format(time.RFC3339), now.Sub(*lastData).Round(time.Minute))
+		data["last_data_at"] = lastData.UTC()
+	}
+	msg := notify.Message{
+		Event: "staleness.alert",
+		Title: fmt.Sprintf("Ride data for %s is stale", name),
+		Text:  fmt.Sprintf("%s is open but %s. Check the collector and its Cloud Scheduler job.", name, since),
+		Data:  data,
+		Time:  now,
+	}
+	if err := w.notifier.Notify(ctx, msg); err != nil {
+		if releaseErr := w.store.ReleaseStalenessNotification(ctx, incident.ID); releaseErr != nil {
+			logger.ErrorContext(ctx, "Failed to release staleness incident", "error", releaseErr)
+		}
+		return err
+	}
+	logger.WarnContext(ctx, "Alerted on stale ride data", "incident_id", incident.ID, "notifiers", w.notifier.Name())
+	return nil
+}
+
+func (w *watchdog) recover(ctx context.Context, parkID, name string, lastData time.Time) error {
+	incident, err := w.store.ResolveStalenessIncident(ctx, parkID)
+	if err != nil || incident == nil || incident.NotifiedAt == nil {
+		return err
+	}
+	now := w.now()
+	msg := notify.Message{
+		Event: "staleness.recovered",
+		Title: fmt.Sprintf("Ride data for %s is fresh again", name),
+		Text:  fmt.Sprintf("Ride data for %s is being collected again after %s.", name, now.Sub(incident.OpenedAt).Round(time.Minute)),
+		Data:  map[string]any{"park_id": parkID, "park_name": name, "incident_id": incident.ID, "last_data_at": lastData.UTC(), "opened_at": incident.OpenedAt.UTC()},
+		Time:  now,
+	}
+	if err := w.notifier.Notify(ctx, msg); err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "Sent staleness recovery notice", "incident_id", incident.ID)
+	return nil
+}
+
+// run checks every interval until ctx is done
+func (w *watchdog) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.check(ctx); err != nil {
+			logger.ErrorContext(ctx, "Staleness watchdog check failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// staleAfterFromEnv reads STALE_AFTER_MINUTES, shared by /readyz and the watchdog
+func staleAfterFromEnv() (time.Duration, error) {
+	minutes := DefaultStaleAfterMinutes
+	if v := strings.TrimSpace(os.Getenv("STALE_AFTER_MINUTES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("STALE_AFTER_MINUTES must be a positive integer, got %q", v)
+		}
+		minutes = n
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// watchdogIntervalFromEnv reads WATCHDOG_INTERVAL_MINUTES
+func watchdogIntervalFromEnv() (time.Duration, error) {
+	minutes := DefaultWatchdogIntervalMinutes
+	if v := strings.TrimSpace(os.Getenv("WATCHDOG_INTERVAL_MINUTES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("WATCHDOG_INTERVAL_MINUTES must be a positive integer, got %q", v)
+		}
+		minutes = n
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// newWatchdog configures the watchdog from the environment. It returns nil when no notifier is
+// configured. WATCHDOG_HOURS ("HH:MM-HH:MM", default DefaultWatchdogHours) in WATCHDOG_TIMEZONE
+// (default DefaultWatchdogTimezone) are the hours data must stay fresh.
+func newWatchdog(store incidentStore) (*watchdog, error) {
+	notifiers, err := notify.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+
+	staleAfter, err := staleAfterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	tz := DefaultWatchdogTimezone
+	if v := strings.TrimSpace(os.Getenv("WATCHDOG_TIMEZONE")); v != "" {
+		tz = v
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WATCHDOG_TIMEZONE: %w", err)
+	}
+	window := DefaultWatchdogHours
+	if v := strings.TrimSpace(os.Getenv("WATCHDOG_HOURS")); v != "" {
+		window = v
+	}
+	hours, err := parseDailyWindow(window, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WATCHDOG_HOURS: %w", err)
+	}
+
+	return &watchdog{
+		store:      store,
+		notifier:   notifiers,
+		parks:      collectedParks(),
+		staleAfter: staleAfter,
+		hours:      hours,
+		now:        time.Now,
+	}, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go-services/shared/models"
+	"go-services/shared/notify"
+	"testing"
+	"time"
+)
+
+// fakeIncidentStore keeps incidents in memory like the staleness_incidents table
+type fakeIncidentStore struct {
+	latest    map[string]time.Time
+	open      map[string]*models.StalenessIncident
+	nextID    int64
+	resolved  int
+	claimFail bool
+}
+
+func newFakeIncidentStore() *fakeIncidentStore {
+	return &fakeIncidentStore{latest: map[string]time.Time{}, open: map[string]*models.StalenessIncident{}}
+}
+
+func (s *fakeIncidentStore) LatestUpdateByPark(context.Context) (map[string]time.Time, error) {
+	return s.latest, nil
+}
+
+func (s *fakeIncidentStore) OpenStalenessIncident(_ context.Context, parkID string, lastDataAt *time.Time) (*models.StalenessIncident, error) {
+	if i, ok := s.open[parkID]; ok {
+		return i, nil
+	}
+	s.nextID++
+	i := &models.StalenessIncident{ID: s.nextID, ParkID: parkID, LastDataAt: lastDataAt, OpenedAt: time.Now()}
+	s.open[parkID] = i
+	return i, nil
+}
+
+func (s *fakeIncidentStore) find(id int64) *models.StalenessIncident {
+	for _, i := range s.open {
+		if i.ID == id {
+			return i
+		}
+	}
+	return nil
+}
+
+func (s *fakeIncidentStore) ClaimStalenessNotification(_ context.Context, id int64) (bool, error) {
+	i := s.find(id)
+	if s.claimFail || i == nil || i.NotifiedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	i.NotifiedAt = &now
+	return true, nil
+}
+
+func (s *fakeIncidentStore) ReleaseStalenessNotification(_ context.Context, id int64) error {
+	if i := s.find(id); i != nil {
+		i.NotifiedAt = nil
+	}
+	return nil
+}
+
+func (s *fakeIncidentStore) ResolveStalenessIncident(_ context.Context, parkID string) (*models.StalenessIncident, error) {
+	i, ok := s.open[parkID]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.open, parkID)
+	s.resolved++
+	return i, nil
+}
+
+type recordingNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Message) error {
+	n.sent = append(n.sent, msg)
+	return n.err
+}
+
+func newTestWatchdog(t *testing.T, store *fakeIncidentStore, notifier notify.Notifier, now *time.Time) *watchdog {
+	t.Helper()
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hours, err := parseDailyWindow("08:00-23:00", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &watchdog{
+		store:      store,
+		notifier:   notifier,
+		parks:      map[string]string{"dl": "Disneyland"},
+		staleAfter: 30 * time.Minute,
+		hours:      hours,
+		now:        func() time.Time { return *now },
+	}
+}
+
+func TestWatchdogAlertsOnceAndRecovers(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	now := time.Date(2026, 10, 19, 14, 0, 0, 0, loc)
+	store := newFakeIncidentStore()
+	notifier := &recordingNotifier{}
+	w := newTestWatchdog(t, store, notifier, &now)
+	ctx := context.Background()
+
+	store.latest["dl"] = now.Add(-10 * time.Minute)
+	w.check(ctx)
+	if len(notifier.sent) != 0 || len(store.open) != 0 {
+		t.Fatalf("Expected fresh data not to alert, sent %v", notifier.sent)
+	}
+
+	now = now.Add(time.Hour)
+	w.check(ctx)
+	w.check(ctx)
+	if len(notifier.sent) != 1 || notifier.sent[0].Event != "staleness.alert" {
+		t.Fatalf("Expected one alert for stale data, sent %v", notifier.sent)
+	}
+	if notifier.sent[0].Data["park_id"] != "dl" || notifier.sent[0].Data["last_data_at"] == nil {
+		t.Errorf("Unexpected alert data %v", notifier.sent[0].Data)
+	}
+
+	store.latest["dl"] = now.Add(-time.Minute)
+	w.check(ctx)
+	if len(notifier.sent) != 2 || notifier.sent[1].Event != "staleness.recovered" || len(store.open) != 0 {
+		t.Fatalf("Expected a recovery notice, sent %v", notifier.sent)
+	}
+	w.check(ctx)
+	if len(notifier.sent) != 2 {
+		t.Errorf("Expected one recovery notice, sent %d messages", len(notifier.sent))
+	}
+}
+
+func TestWatchdogOperatingHours(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	store := newFakeIncidentStore()
+	notifier := &recordingNotifier{}
+	now := time.Date(2026, 10, 19, 2, 0, 0, 0, loc)
+	w := newTestWatchdog(t, store, notifier, &now)
+	ctx := context.Background()
+	store.latest["dl"] = time.Date(2026, 10, 18, 23, 0, 0, 0, loc)
+
+	w.check(ctx)
+	if len(notifier.sent) != 0 {
+		t.Error("Expected no alert while the park is closed")
+	}
+
+	// Collection gets staleAfter after opening to catch up
+	now = time.Date(2026, 10, 19, 8, 20, 0, 0, loc)
+	w.check(ctx)
+	if len(notifier.sent) != 0 {
+		t.Error("Expected no alert right after opening")
+	}
+
+	now = time.Date(2026, 10, 19, 8, 40, 0, 0, loc)
+	w.check(ctx)
+	if len(notifier.sent) != 1 {
+		t.Errorf("Expected an alert once the grace period ended, sent %d messages", len(notifier.sent))
+	}
+}
+
+func TestWatchdogParkWithoutData(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, loc)
+	store := newFakeIncidentStore()
+	notifier := &recordingNotifier{}
+	newTestWatchdog(t, store, notifier, &now).check(context.Background())
+
+	if len(notifier.sent) != 1 || store.open["dl"].LastDataAt != nil {
+		t.Fatalf("Expected a park without data to alert, sent %v", notifier.sent)
+	}
+	if _, ok := notifier.sent[0].Data["last_data_at"]; ok {
+		t.Error("Expected no last_data_at for a park without data")
+	}
+}
+
+func TestWatchdogRetriesFailedAlerts(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, loc)
+	store := newFakeIncidentStore()
+	notifier := &recordingNotifier{err: errors.New("endpoint returned status 502")}
+	w := newTestWatchdog(t, store, notifier, &now)
+	store.latest["dl"] = now.Add(-time.Hour)
+
+	w.check(context.Background())
+	if store.open["dl"].NotifiedAt != nil {
+		t.Fatal("Expected a failed alert to release its claim")
+	}
+	notifier.err = nil
+	w.check(context.Background())
+	if len(notifier.sent) != 2 || store.open["dl"].NotifiedAt == nil {
+		t.Errorf("Expected the alert to be retried, sent %d messages", len(notifier.sent))
+	}
+
+	// Another instance already alerted
+	store.open["dl"].NotifiedAt = nil
+	store.claimFail = true
+	w.check(context.Background())
+	if len(notifier.sent) != 2 {
+		t.Error("Expected no alert without the claim")
+	}
+
+	// Incidents nobody was alerted about resolve silently
+	store.latest["dl"] = now
+	w.check(context.Background())
+	if len(notifier.sent) != 2 || store.resolved != 1 {
+		t.Errorf("Expected a silent resolve, sent %d messages", len(notifier.sent))
+	}
+}
+
+func TestDailyWindow(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	overnight, err := parseDailyWindow("22:00-02:00", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		at     time.Time
+		open   bool
+		opened time.Time
+	}{
+		{time.Date(2026, 10, 19, 23, 0, 0, 0, loc), true, time.Date(2026, 10, 19, 22, 0, 0, 0, loc)},
+		{time.Date(2026, 10, 20, 1, 0, 0, 0, loc), true, time.Date(2026, 10, 19, 22, 0, 0, 0, loc)},
+		{time.Date(2026, 10, 20, 2, 0, 0, 0, loc), false, time.Time{}},
+		{time.Date(2026, 10, 20, 12, 0, 0, 0, loc), false, time.Time{}},
+	} {
+		opened, open := overnight.openedAt(tc.at.UTC())
+		if open != tc.open || !opened.Equal(tc.opened) {
+			t.Errorf("At %s expected %v %s, got %v %s", tc.at, tc.open, tc.opened, open, opened)
+		}
+	}
+
+	for _, bad := range []string{"", "08:00", "8-23", "08:00-24:30"} {
+		if _, err := parseDailyWindow(bad, loc); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package waittimesv1 contains the generated protobuf messages and gRPC stubs of the wait times service.
+package waittimesv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative waittimes/v1/wait_times.proto
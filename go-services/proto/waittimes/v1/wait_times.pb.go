@@ -0,0 +1,931 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: waittimes/v1/wait_times.proto
+
+package waittimesv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Ride struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RideId        string                 `protobuf:"bytes,1,opt,name=ride_id,json=rideId,proto3" json:"ride_id,omitempty"`
+	RideName      string                 `protobuf:"bytes,2,opt,name=ride_name,json=rideName,proto3" json:"ride_name,omitempty"`
+	ParkId        string                 `protobuf:"bytes,3,opt,name=park_id,json=parkId,proto3" json:"park_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ride) Reset() {
+	*x = Ride{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ride) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ride) ProtoMessage() {}
+
+func (x *Ride) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ride.ProtoReflect.Descriptor instead.
+func (*Ride) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Ride) GetRideId() string {
+	if x != nil {
+		return x.RideId
+	}
+	return ""
+}
+
+func (x *Ride) GetRideName() string {
+	if x != nil {
+		return x.RideName
+	}
+	return ""
+}
+
+func (x *Ride) GetParkId() string {
+	if x != nil {
+		return x.ParkId
+	}
+	return ""
+}
+
+type Park struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ParkId        string                 `protobuf:"bytes,1,opt,name=park_id,json=parkId,proto3" json:"park_id,omitempty"`
+	ParkName      string                 `protobuf:"bytes,2,opt,name=park_name,json=parkName,proto3" json:"park_name,omitempty"`
+	Rides         []*Ride                `protobuf:"bytes,3,rep,name=rides,proto3" json:"rides,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Park) Reset() {
+	*x = Park{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Park) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Park) ProtoMessage() {}
+
+func (x *Park) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Park.ProtoReflect.Descriptor instead.
+func (*Park) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Park) GetParkId() string {
+	if x != nil {
+		return x.ParkId
+	}
+	return ""
+}
+
+func (x *Park) GetParkName() string {
+	if x != nil {
+		return x.ParkName
+	}
+	return ""
+}
+
+func (x *Park) GetRides() []*Ride {
+	if x != nil {
+		return x.Rides
+	}
+	return nil
+}
+
+type ForecastPoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	WaitTime      int32                  `protobuf:"varint,2,opt,name=wait_time,json=waitTime,proto3" json:"wait_time,omitempty"`
+	Percentage    float64                `protobuf:"fixed64,3,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForecastPoint) Reset() {
+	*x = ForecastPoint{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForecastPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForecastPoint) ProtoMessage() {}
+
+func (x *ForecastPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForecastPoint.ProtoReflect.Descriptor instead.
+func (*ForecastPoint) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ForecastPoint) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *ForecastPoint) GetWaitTime() int32 {
+	if x != nil {
+		return x.WaitTime
+	}
+	return 0
+}
+
+func (x *ForecastPoint) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+type LiveStatus struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	RideId   string                 `protobuf:"bytes,1,opt,name=ride_id,json=rideId,proto3" json:"ride_id,omitempty"`
+	RideName string                 `protobuf:"bytes,2,opt,name=ride_name,json=rideName,proto3" json:"ride_name,omitempty"`
+	ParkId   string                 `protobuf:"bytes,3,opt,name=park_id,json=parkId,proto3" json:"park_id,omitempty"`
+	// Standby wait in minutes, unset when the ride has no standby queue.
+	WaitTime    *int32                 `protobuf:"varint,4,opt,name=wait_time,json=waitTime,proto3,oneof" json:"wait_time,omitempty"`
+	Status      string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	LastUpdated *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	// Only populated by GetLive.
+	Forecast      []*ForecastPoint `protobuf:"bytes,7,rep,name=forecast,proto3" json:"forecast,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LiveStatus) Reset() {
+	*x = LiveStatus{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LiveStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LiveStatus) ProtoMessage() {}
+
+func (x *LiveStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LiveStatus.ProtoReflect.Descriptor instead.
+func (*LiveStatus) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LiveStatus) GetRideId() string {
+	if x != nil {
+		return x.RideId
+	}
+	return ""
+}
+
+func (x *LiveStatus) GetRideName() string {
+	if x != nil {
+		return x.RideName
+	}
+	return ""
+}
+
+func (x *LiveStatus) GetParkId() string {
+	if x != nil {
+		return x.ParkId
+	}
+	return ""
+}
+
+func (x *LiveStatus) GetWaitTime() int32 {
+	if x != nil && x.WaitTime != nil {
+		return *x.WaitTime
+	}
+	return 0
+}
+
+func (x *LiveStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *LiveStatus) GetLastUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return nil
+}
+
+func (x *LiveStatus) GetForecast() []*ForecastPoint {
+	if x != nil {
+		return x.Forecast
+	}
+	return nil
+}
+
+type HistoryPoint struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Standby wait in minutes, unset for a closed or no-standby snapshot.
+	WaitTime      *int32                 `protobuf:"varint,1,opt,name=wait_time,json=waitTime,proto3,oneof" json:"wait_time,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	SnapshotTime  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=snapshot_time,json=snapshotTime,proto3" json:"snapshot_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryPoint) Reset() {
+	*x = HistoryPoint{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryPoint) ProtoMessage() {}
+
+func (x *HistoryPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryPoint.ProtoReflect.Descriptor instead.
+func (*HistoryPoint) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HistoryPoint) GetWaitTime() int32 {
+	if x != nil && x.WaitTime != nil {
+		return *x.WaitTime
+	}
+	return 0
+}
+
+func (x *HistoryPoint) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HistoryPoint) GetSnapshotTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SnapshotTime
+	}
+	return nil
+}
+
+type ListParksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListParksRequest) Reset() {
+	*x = ListParksRequest{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListParksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListParksRequest) ProtoMessage() {}
+
+func (x *ListParksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListParksRequest.ProtoReflect.Descriptor instead.
+func (*ListParksRequest) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{5}
+}
+
+type ListParksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Parks         []*Park                `protobuf:"bytes,1,rep,name=parks,proto3" json:"parks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListParksResponse) Reset() {
+	*x = ListParksResponse{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListParksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListParksResponse) ProtoMessage() {}
+
+func (x *ListParksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListParksResponse.ProtoReflect.Descriptor instead.
+func (*ListParksResponse) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListParksResponse) GetParks() []*Park {
+	if x != nil {
+		return x.Parks
+	}
+	return nil
+}
+
+// Rides are selected by ID or by park; an empty selection means every tracked ride.
+type GetLiveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RideIds       []string               `protobuf:"bytes,1,rep,name=ride_ids,json=rideIds,proto3" json:"ride_ids,omitempty"`
+	ParkIds       []string               `protobuf:"bytes,2,rep,name=park_ids,json=parkIds,proto3" json:"park_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLiveRequest) Reset() {
+	*x = GetLiveRequest{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLiveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLiveRequest) ProtoMessage() {}
+
+func (x *GetLiveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLiveRequest.ProtoReflect.Descriptor instead.
+func (*GetLiveRequest) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetLiveRequest) GetRideIds() []string {
+	if x != nil {
+		return x.RideIds
+	}
+	return nil
+}
+
+func (x *GetLiveRequest) GetParkIds() []string {
+	if x != nil {
+		return x.ParkIds
+	}
+	return nil
+}
+
+type GetLiveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rides         []*LiveStatus          `protobuf:"bytes,1,rep,name=rides,proto3" json:"rides,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLiveResponse) Reset() {
+	*x = GetLiveResponse{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLiveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLiveResponse) ProtoMessage() {}
+
+func (x *GetLiveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLiveResponse.ProtoReflect.Descriptor instead.
+func (*GetLiveResponse) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetLiveResponse) GetRides() []*LiveStatus {
+	if x != nil {
+		return x.Rides
+	}
+	return nil
+}
+
+type GetHistoryRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	RideId string                 `protobuf:"bytes,1,opt,name=ride_id,json=rideId,proto3" json:"ride_id,omitempty"`
+	// Hours of history to include, 24 when unset and clamped to MAX_WINDOW_HOURS.
+	WindowHours uint32 `protobuf:"varint,2,opt,name=window_hours,json=windowHours,proto3" json:"window_hours,omitempty"`
+	// Page size, capped at 1000 and 200 when unset.
+	PageSize uint32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous response.
+	PageToken     string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetHistoryRequest) GetRideId() string {
+	if x != nil {
+		return x.RideId
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetWindowHours() uint32 {
+	if x != nil {
+		return x.WindowHours
+	}
+	return 0
+}
+
+func (x *GetHistoryRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetHistoryRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type GetHistoryResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	RideId  string                 `protobuf:"bytes,1,opt,name=ride_id,json=rideId,proto3" json:"ride_id,omitempty"`
+	History []*HistoryPoint        `protobuf:"bytes,2,rep,name=history,proto3" json:"history,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,3,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetHistoryResponse) GetRideId() string {
+	if x != nil {
+		return x.RideId
+	}
+	return ""
+}
+
+func (x *GetHistoryResponse) GetHistory() []*HistoryPoint {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+func (x *GetHistoryResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type StreamLiveRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	RideIds []string               `protobuf:"bytes,1,rep,name=ride_ids,json=rideIds,proto3" json:"ride_ids,omitempty"`
+	ParkIds []string               `protobuf:"bytes,2,rep,name=park_ids,json=parkIds,proto3" json:"park_ids,omitempty"`
+	// Replays recent updates with a larger id, for resuming an interrupted stream.
+	AfterId       int64 `protobuf:"varint,3,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamLiveRequest) Reset() {
+	*x = StreamLiveRequest{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamLiveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamLiveRequest) ProtoMessage() {}
+
+func (x *StreamLiveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamLiveRequest.ProtoReflect.Descriptor instead.
+func (*StreamLiveRequest) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamLiveRequest) GetRideIds() []string {
+	if x != nil {
+		return x.RideIds
+	}
+	return nil
+}
+
+func (x *StreamLiveRequest) GetParkIds() []string {
+	if x != nil {
+		return x.ParkIds
+	}
+	return nil
+}
+
+func (x *StreamLiveRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+type LiveUpdate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Increasing update id, usable as after_id.
+	Id            int64       `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        *LiveStatus `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LiveUpdate) Reset() {
+	*x = LiveUpdate{}
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LiveUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LiveUpdate) ProtoMessage() {}
+
+func (x *LiveUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_waittimes_v1_wait_times_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LiveUpdate.ProtoReflect.Descriptor instead.
+func (*LiveUpdate) Descriptor() ([]byte, []int) {
+	return file_waittimes_v1_wait_times_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LiveUpdate) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LiveUpdate) GetStatus() *LiveStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+var File_waittimes_v1_wait_times_proto protoreflect.FileDescriptor
+
+const file_waittimes_v1_wait_times_proto_rawDesc = "" +
+	"\n" +
+	"\x1dwaittimes/v1/wait_times.proto\x12\fwaittimes.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"U\n" +
+	"\x04Ride\x12\x17\n" +
+	"\aride_id\x18\x01 \x01(\tR\x06rideId\x12\x1b\n" +
+	"\tride_name\x18\x02 \x01(\tR\brideName\x12\x17\n" +
+	"\apark_id\x18\x03 \x01(\tR\x06parkId\"f\n" +
+	"\x04Park\x12\x17\n" +
+	"\apark_id\x18\x01 \x01(\tR\x06parkId\x12\x1b\n" +
+	"\tpark_name\x18\x02 \x01(\tR\bparkName\x12(\n" +
+	"\x05rides\x18\x03 \x03(\v2\x12.waittimes.v1.RideR\x05rides\"|\n" +
+	"\rForecastPoint\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1b\n" +
+	"\twait_time\x18\x02 \x01(\x05R\bwaitTime\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x03 \x01(\x01R\n" +
+	"percentage\"\x9b\x02\n" +
+	"\n" +
+	"LiveStatus\x12\x17\n" +
+	"\aride_id\x18\x01 \x01(\tR\x06rideId\x12\x1b\n" +
+	"\tride_name\x18\x02 \x01(\tR\brideName\x12\x17\n" +
+	"\apark_id\x18\x03 \x01(\tR\x06parkId\x12 \n" +
+	"\twait_time\x18\x04 \x01(\x05H\x00R\bwaitTime\x88\x01\x01\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12=\n" +
+	"\flast_updated\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated\x127\n" +
+	"\bforecast\x18\a \x03(\v2\x1b.waittimes.v1.ForecastPointR\bforecastB\f\n" +
+	"\n" +
+	"_wait_time\"\x97\x01\n" +
+	"\fHistoryPoint\x12 \n" +
+	"\twait_time\x18\x01 \x01(\x05H\x00R\bwaitTime\x88\x01\x01\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12?\n" +
+	"\rsnapshot_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\fsnapshotTimeB\f\n" +
+	"\n" +
+	"_wait_time\"\x12\n" +
+	"\x10ListParksRequest\"=\n" +
+	"\x11ListParksResponse\x12(\n" +
+	"\x05parks\x18\x01 \x03(\v2\x12.waittimes.v1.ParkR\x05parks\"F\n" +
+	"\x0eGetLiveRequest\x12\x19\n" +
+	"\bride_ids\x18\x01 \x03(\tR\arideIds\x12\x19\n" +
+	"\bpark_ids\x18\x02 \x03(\tR\aparkIds\"A\n" +
+	"\x0fGetLiveResponse\x12.\n" +
+	"\x05rides\x18\x01 \x03(\v2\x18.waittimes.v1.LiveStatusR\x05rides\"\x8b\x01\n" +
+	"\x11GetHistoryRequest\x12\x17\n" +
+	"\aride_id\x18\x01 \x01(\tR\x06rideId\x12!\n" +
+	"\fwindow_hours\x18\x02 \x01(\rR\vwindowHours\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\rR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"\x8b\x01\n" +
+	"\x12GetHistoryResponse\x12\x17\n" +
+	"\aride_id\x18\x01 \x01(\tR\x06rideId\x124\n" +
+	"\ahistory\x18\x02 \x03(\v2\x1a.waittimes.v1.HistoryPointR\ahistory\x12&\n" +
+	"\x0fnext_page_token\x18\x03 \x01(\tR\rnextPageToken\"d\n" +
+	"\x11StreamLiveRequest\x12\x19\n" +
+	"\bride_ids\x18\x01 \x03(\tR\arideIds\x12\x19\n" +
+	"\bpark_ids\x18\x02 \x03(\tR\aparkIds\x12\x19\n" +
+	"\bafter_id\x18\x03 \x01(\x03R\aafterId\"N\n" +
+	"\n" +
+	"LiveUpdate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x120\n" +
+	"\x06status\x18\x02 \x01(\v2\x18.waittimes.v1.LiveStatusR\x06status2\xc4\x02\n" +
+	"\x10WaitTimesService\x12L\n" +
+	"\tListParks\x12\x1e.waittimes.v1.ListParksRequest\x1a\x1f.waittimes.v1.ListParksResponse\x12F\n" +
+	"\aGetLive\x12\x1c.waittimes.v1.GetLiveRequest\x1a\x1d.waittimes.v1.GetLiveResponse\x12O\n" +
+	"\n" +
+	"GetHistory\x12\x1f.waittimes.v1.GetHistoryRequest\x1a .waittimes.v1.GetHistoryResponse\x12I\n" +
+	"\n" +
+	"StreamLive\x12\x1f.waittimes.v1.StreamLiveRequest\x1a\x18.waittimes.v1.LiveUpdate0\x01B,Z*go-services/proto/waittimes/v1;waittimesv1b\x06proto3"
+
+var (
+	file_waittimes_v1_wait_times_proto_rawDescOnce sync.Once
+	file_waittimes_v1_wait_times_proto_rawDescData []byte
+)
+
+func file_waittimes_v1_wait_times_proto_rawDescGZIP() []byte {
+	file_waittimes_v1_wait_times_proto_rawDescOnce.Do(func() {
+		file_waittimes_v1_wait_times_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_waittimes_v1_wait_times_proto_rawDesc), len(file_waittimes_v1_wait_times_proto_rawDesc)))
+	})
+	return file_waittimes_v1_wait_times_proto_rawDescData
+}
+
+var file_waittimes_v1_wait_times_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_waittimes_v1_wait_times_proto_goTypes = []any{
+	(*Ride)(nil),                  // 0: waittimes.v1.Ride
+	(*Park)(nil),                  // 1: waittimes.v1.Park
+	(*ForecastPoint)(nil),         // 2: waittimes.v1.ForecastPoint
+	(*LiveStatus)(nil),            // 3: waittimes.v1.LiveStatus
+	(*HistoryPoint)(nil),          // 4: waittimes.v1.HistoryPoint
+	(*ListParksRequest)(nil),      // 5: waittimes.v1.ListParksRequest
+	(*ListParksResponse)(nil),     // 6: waittimes.v1.ListParksResponse
+	(*GetLiveRequest)(nil),        // 7: waittimes.v1.GetLiveRequest
+	(*GetLiveResponse)(nil),       // 8: waittimes.v1.GetLiveResponse
+	(*GetHistoryRequest)(nil),     // 9: waittimes.v1.GetHistoryRequest
+	(*GetHistoryResponse)(nil),    // 10: waittimes.v1.GetHistoryResponse
+	(*StreamLiveRequest)(nil),     // 11: waittimes.v1.StreamLiveRequest
+	(*LiveUpdate)(nil),            // 12: waittimes.v1.LiveUpdate
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_waittimes_v1_wait_times_proto_depIdxs = []int32{
+	0,  // 0: waittimes.v1.Park.rides:type_name -> waittimes.v1.Ride
+	13, // 1: waittimes.v1.ForecastPoint.time:type_name -> google.protobuf.Timestamp
+	13, // 2: waittimes.v1.LiveStatus.last_updated:type_name -> google.protobuf.Timestamp
+	2,  // 3: waittimes.v1.LiveStatus.forecast:type_name -> waittimes.v1.ForecastPoint
+	13, // 4: waittimes.v1.HistoryPoint.snapshot_time:type_name -> google.protobuf.Timestamp
+	1,  // 5: waittimes.v1.ListParksResponse.parks:type_name -> waittimes.v1.Park
+	3,  // 6: waittimes.v1.GetLiveResponse.rides:type_name -> waittimes.v1.LiveStatus
+	4,  // 7: waittimes.v1.GetHistoryResponse.history:type_name -> waittimes.v1.HistoryPoint
+	3,  // 8: waittimes.v1.LiveUpdate.status:type_name -> waittimes.v1.LiveStatus
+	5,  // 9: waittimes.v1.WaitTimesService.ListParks:input_type -> waittimes.v1.ListParksRequest
+	7,  // 10: waittimes.v1.WaitTimesService.GetLive:input_type -> waittimes.v1.GetLiveRequest
+	9,  // 11: waittimes.v1.WaitTimesService.GetHistory:input_type -> waittimes.v1.GetHistoryRequest
+	11, // 12: waittimes.v1.WaitTimesService.StreamLive:input_type -> waittimes.v1.StreamLiveRequest
+	6,  // 13: waittimes.v1.WaitTimesService.ListParks:output_type -> waittimes.v1.ListParksResponse
+	8,  // 14: waittimes.v1.WaitTimesService.GetLive:output_type -> waittimes.v1.GetLiveResponse
+	10, // 15: waittimes.v1.WaitTimesService.GetHistory:output_type -> waittimes.v1.GetHistoryResponse
+	12, // 16: waittimes.v1.WaitTimesService.StreamLive:output_type -> waittimes.v1.LiveUpdate
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_waittimes_v1_wait_times_proto_init() }
+func file_waittimes_v1_wait_times_proto_init() {
+	if File_waittimes_v1_wait_times_proto != nil {
+		return
+	}
+	file_waittimes_v1_wait_times_proto_msgTypes[3].OneofWrappers = []any{}
+	file_waittimes_v1_wait_times_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_waittimes_v1_wait_times_proto_rawDesc), len(file_waittimes_v1_wait_times_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_waittimes_v1_wait_times_proto_goTypes,
+		DependencyIndexes: file_waittimes_v1_wait_times_proto_depIdxs,
+		MessageInfos:      file_waittimes_v1_wait_times_proto_msgTypes,
+	}.Build()
+	File_waittimes_v1_wait_times_proto = out.File
+	file_waittimes_v1_wait_times_proto_goTypes = nil
+	file_waittimes_v1_wait_times_proto_depIdxs = nil
+}
@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: waittimes/v1/wait_times.proto
+
+package waittimesv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WaitTimesService_ListParks_FullMethodName  = "/waittimes.v1.WaitTimesService/ListParks"
+	WaitTimesService_GetLive_FullMethodName    = "/waittimes.v1.WaitTimesService/GetLive"
+	WaitTimesService_GetHistory_FullMethodName = "/waittimes.v1.WaitTimesService/GetHistory"
+	WaitTimesService_StreamLive_FullMethodName = "/waittimes.v1.WaitTimesService/StreamLive"
+)
+
+// WaitTimesServiceClient is the client API for WaitTimesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WaitTimesService serves the same tracked-ride data as the wait-times-api HTTP endpoints.
+type WaitTimesServiceClient interface {
+	// ListParks returns every tracked park with its tracked rides.
+	ListParks(ctx context.Context, in *ListParksRequest, opts ...grpc.CallOption) (*ListParksResponse, error)
+	// GetLive returns the latest state of the selected rides, including the upstream forecast.
+	GetLive(ctx context.Context, in *GetLiveRequest, opts ...grpc.CallOption) (*GetLiveResponse, error)
+	// GetHistory pages through the history of one ride, newest first.
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// StreamLive sends an update whenever a selected ride changes wait time, status or return time.
+	StreamLive(ctx context.Context, in *StreamLiveRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LiveUpdate], error)
+}
+
+type waitTimesServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWaitTimesServiceClient(cc grpc.ClientConnInterface) WaitTimesServiceClient {
+	return &waitTimesServiceClient{cc}
+}
+
+func (c *waitTimesServiceClient) ListParks(ctx context.Context, in *ListParksRequest, opts ...grpc.CallOption) (*ListParksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListParksResponse)
+	err := c.cc.Invoke(ctx, WaitTimesService_ListParks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *waitTimesServiceClient) GetLive(ctx context.Context, in *GetLiveRequest, opts ...grpc.CallOption) (*GetLiveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLiveResponse)
+	err := c.cc.Invoke(ctx, WaitTimesService_GetLive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *waitTimesServiceClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, WaitTimesService_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *waitTimesServiceClient) StreamLive(ctx context.Context, in *StreamLiveRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LiveUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WaitTimesService_ServiceDesc.Streams[0], WaitTimesService_StreamLive_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamLiveRequest, LiveUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WaitTimesService_StreamLiveClient = grpc.ServerStreamingClient[LiveUpdate]
+
+// WaitTimesServiceServer is the server API for WaitTimesService service.
+// All implementations must embed UnimplementedWaitTimesServiceServer
+// for forward compatibility.
+//
+// WaitTimesService serves the same tracked-ride data as the wait-times-api HTTP endpoints.
+type WaitTimesServiceServer interface {
+	// ListParks returns every tracked park with its tracked rides.
+	ListParks(context.Context, *ListParksRequest) (*ListParksResponse, error)
+	// GetLive returns the latest state of the selected rides, including the upstream forecast.
+	GetLive(context.Context, *GetLiveRequest) (*GetLiveResponse, error)
+	// GetHistory pages through the history of one ride, newest first.
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// StreamLive sends an update whenever a selected ride changes wait time, status or return time.
+	StreamLive(*StreamLiveRequest, grpc.ServerStreamingServer[LiveUpdate]) error
+	mustEmbedUnimplementedWaitTimesServiceServer()
+}
+
+// UnimplementedWaitTimesServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWaitTimesServiceServer struct{}
+
+func (UnimplementedWaitTimesServiceServer) ListParks(context.Context, *ListParksRequest) (*ListParksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListParks not implemented")
+}
+func (UnimplementedWaitTimesServiceServer) GetLive(context.Context, *GetLiveRequest) (*GetLiveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLive not implemented")
+}
+func (UnimplementedWaitTimesServiceServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedWaitTimesServiceServer) StreamLive(*StreamLiveRequest, grpc.ServerStreamingServer[LiveUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLive not implemented")
+}
+func (UnimplementedWaitTimesServiceServer) mustEmbedUnimplementedWaitTimesServiceServer() {}
+func (UnimplementedWaitTimesServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeWaitTimesServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WaitTimesServiceServer will
+// result in compilation errors.
+type UnsafeWaitTimesServiceServer interface {
+	mustEmbedUnimplementedWaitTimesServiceServer()
+}
+
+func RegisterWaitTimesServiceServer(s grpc.ServiceRegistrar, srv WaitTimesServiceServer) {
+	// If the following call pancis, it indicates UnimplementedWaitTimesServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WaitTimesService_ServiceDesc, srv)
+}
+
+func _WaitTimesService_ListParks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListParksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WaitTimesServiceServer).ListParks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WaitTimesService_ListParks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WaitTimesServiceServer).ListParks(ctx, req.(*ListParksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WaitTimesService_GetLive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WaitTimesServiceServer).GetLive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WaitTimesService_GetLive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WaitTimesServiceServer).GetLive(ctx, req.(*GetLiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WaitTimesService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WaitTimesServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WaitTimesService_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WaitTimesServiceServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WaitTimesService_StreamLive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLiveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WaitTimesServiceServer).StreamLive(m, &grpc.GenericServerStream[StreamLiveRequest, LiveUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WaitTimesService_StreamLiveServer = grpc.ServerStreamingServer[LiveUpdate]
+
+// WaitTimesService_ServiceDesc is the grpc.ServiceDesc for WaitTimesService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WaitTimesService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "waittimes.v1.WaitTimesService",
+	HandlerType: (*WaitTimesServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListParks",
+			Handler:    _WaitTimesService_ListParks_Handler,
+		},
+		{
+			MethodName: "GetLive",
+			Handler:    _WaitTimesService_GetLive_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _WaitTimesService_GetHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLive",
+			Handler:       _WaitTimesService_StreamLive_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "waittimes/v1/wait_times.proto",
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-services/shared/archive"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveCutoff returns the start of the month containing now-retention.
+// Only whole months strictly before the cutoff are archived, so every park/month is exported exactly once.
+func archiveCutoff(now time.Time, retention time.Duration) time.Time {
+	t := now.UTC().Add(-retention)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// archiveJob exports old ride data history to compressed files and then removes it from the database
+type archiveJob struct {
+	store     archiveStore
+	logger    service.Logger
+	dir       string
+	format    archive.Format
+	batchSize int
+	dryRun    bool
+	now       func() time.Time
+}
+
+// Run archives every park/month partition older than the cutoff, then drops the monthly table
+// partitions of ride_data_history that the archive emptied
+func (j *archiveJob) Run(ctx context.Context, cutoff time.Time) (ArchiveSummary, error) {
+	var summary ArchiveSummary
+
+	partitions, err := j.store.ListArchivePartitions(ctx, cutoff)
+	if err != nil {
+		return summary, err
+	}
+
+	j.logger.Infof("Found %d park/month partitions older than %s (dry_run=%v)",
+		len(partitions), cutoff.Format("2006-01-02"), j.dryRun)
+
+	runAt := j.now()
+	for _, p := range partitions {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		path := filepath.Join(j.dir, archive.FileName(p.ParkID, p.Month, runAt, j.format))
+		if j.dryRun {
+			j.logger.Infof("[dry-run] Would archive %d rows for park %s month %s to %s",
+				p.Rows, p.ParkID, p.Month.Format("2006-01"), path)
+			summary.Partitions++
+			summary.Archived += p.Rows
+			continue
+		}
+
+		archived, deleted, err := j.archivePartition(ctx, p, path)
+		if err != nil {
+			return summary, fmt.Errorf("park %s month %s: %w", p.ParkID, p.Month.Format("2006-01"), err)
+		}
+
+		summary.Partitions++
+		summary.Archived += archived
+		summary.Deleted += deleted
+		summary.Files = append(summary.Files, path)
+	}
+
+	if j.dryRun {
+		return summary, nil
+	}
+	for i, p := range partitions {
+		if i > 0 && p.Month.Equal(partitions[i-1].Month) {
+			continue
+		}
+		dropped, err := j.store.DropEmptyMonthlyPartition(ctx, p.Month)
+		if err != nil {
+			return summary, fmt.Errorf("month %s: %w", p.Month.Format("2006-01"), err)
+		}
+		if dropped {
+			j.logger.Infof("Dropped the emptied ride data history partition of %s", p.Month.Format("2006-01"))
+			summary.DroppedMonths = append(summary.DroppedMonths, p.Month)
+		}
+	}
+
+	return summary, nil
+}
+
+// archivePartition writes a partition to path, verifies the file, then deletes the archived rows in batches
+func (j *archiveJob) archivePartition(ctx context.Context, p repository.ArchivePartition, path string) (archived int64, deleted int64, err error) {
+	from := p.Month
+	to := p.Month.AddDate(0, 1, 0)
+
+	expected, err := j.store.CountRideDataHistoryInRange(ctx, p.ParkID, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	ids, err := j.writeArchive(ctx, p.ParkID, from, to, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, 0, err
+	}
+
+	written := int64(len(ids))
+
+	// Verify both what we wrote and what is readable back from disk before touching the database
+	if written != expected {
+		os.Remove(tmpPath)
+		return 0, 0, fmt.Errorf("row count mismatch: database has %d rows, wrote %d", expected, written)
+	}
+	onDisk, err := countArchiveFile(tmpPath, j.format)
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, 0, fmt.Errorf("failed to verify archive: %w", err)
+	}
+	if onDisk != expected {
+		os.Remove(tmpPath)
+		return 0, 0, fmt.Errorf("row count mismatch: database has %d rows, archive has %d", expected, onDisk)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, 0, fmt.Errorf("failed to finalise archive: %w", err)
+	}
+
+	j.logger.Infof("Archived %d rows for park %s month %s to %s", written, p.ParkID, p.Month.Format("2006-01"), path)
+
+	// Only the rows in the file are deleted; a row committed into the range after it was read stays
+	for len(ids) > 0 {
+		batch := ids[:min(j.batchSize, len(ids))]
+		ids = ids[len(batch):]
+		n, err := j.store.DeleteRideDataHistoryByIDs(ctx, batch)
+		if err != nil {
+			return written, deleted, err
+		}
+		deleted += n
+		j.logger.Debugf("Deleted %d/%d rows for park %s month %s", deleted, expected, p.ParkID, p.Month.Format("2006-01"))
+	}
+
+	if deleted != expected {
+		j.logger.Warnf("Deleted %d rows for park %s month %s but archived %d", deleted, p.ParkID, p.Month.Format("2006-01"), expected)
+	}
+
+	return written, deleted, nil
+}
+
+// writeArchive writes the rows of a park and month to path and returns the ids it wrote
+func (j *archiveJob) writeArchive(ctx context.Context, parkID string, from, to time.Time, path string) ([]int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer f.Close()
+
+	w, err := archive.NewWriter(f, j.format)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	err = j.store.ForEachRideDataHistoryInRange(ctx, parkID, from, to, func(record *models.RideDataHistoryRecord) error {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		ids = append(ids, record.ID)
+		return nil
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	return ids, nil
+}
+
+func countArchiveFile(path string, format archive.Format) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return archive.CountRecords(f, format)
+}
+
+// restoreJob reimports an archive file into ride_data_history
+type restoreJob struct {
+	store     archiveStore
+	logger    service.Logger
+	batchSize int
+	dryRun    bool
+}
+
+// Run reads the archive at path and restores its rows in batches
+func (j *restoreJob) Run(ctx context.Context, path string) (RestoreSummary, error) {
+	var summary RestoreSummary
+
+	format, err := archive.FormatFromPath(path)
+	if err != nil {
+		return summary, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return summary, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	r, err := archive.NewReader(f, format)
+	if err != nil {
+		return summary, err
+	}
+	defer r.Close()
+
+	batch := make([]*models.RideDataHistoryRecord, 0, j.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if !j.dryRun {
+			n, err := j.store.RestoreRideDataHistory(ctx, batch)
+			if err != nil {
+				return err
+			}
+			summary.Restored += n
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+
+		summary.Read++
+		batch = append(batch, record)
+		if len(batch) >= j.batchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"go-services/shared/archive"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockLogger for testing
+type MockLogger struct{}
+
+func (m *MockLogger) Infof(format string, args ...interface{})  {}
+func (m *MockLogger) Debugf(format string, args ...interface{}) {}
+func (m *MockLogger) Warnf(format string, args ...interface{})  {}
+func (m *MockLogger) Errorf(format string, args ...interface{}) {}
+func (m *MockLogger) Fatalf(format string, args ...interface{}) {}
+func (m *MockLogger) Fatal(args ...interface{})                 {}
+
+// fakeArchiveStore keeps ride data history in memory and implements archiveStore
+type fakeArchiveStore struct {
+	records []*models.RideDataHistoryRecord
+	// afterRead runs once a range has been read, as a concurrent writer would
+	afterRead func()
+	// dropped lists the months whose partition was dropped
+	dropped []time.Time
+}
+
+func (s *fakeArchiveStore) inRange(r *models.RideDataHistoryRecord, parkID string, from, to time.Time) bool {
+	return r.ParkID == parkID && !r.LastUpdated.Before(from) && r.LastUpdated.Before(to)
+}
+
+func (s *fakeArchiveStore) ListArchivePartitions(ctx context.Context, before time.Time) ([]repository.ArchivePartition, error) {
+	counts := make(map[string]*repository.ArchivePartition)
+	for _, r := range s.records {
+		if !r.LastUpdated.Before(before) {
+			continue
+		}
+		month := time.Date(r.LastUpdated.Year(), r.LastUpdated.Month(), 1, 0, 0, 0, 0, time.UTC)
+		key := r.ParkID + month.String()
+		if counts[key] == nil {
+			counts[key] = &repository.ArchivePartition{ParkID: r.ParkID, Month: month}
+		}
+		counts[key].Rows++
+	}
+
+	var partitions []repository.ArchivePartition
+	for _, p := range counts {
+		partitions = append(partitions, *p)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if !partitions[i].Month.Equal(partitions[j].Month) {
+			return partitions[i].Month.Before(partitions[j].Month)
+		}
+		return partitions[i].ParkID < partitions[j].ParkID
+	})
+	return partitions, nil
+}
+
+func (s *fakeArchiveStore) CountRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time) (int64, error) {
+	var n int64
+	for _, r := range s.records {
+		if s.inRange(r, parkID, from, to) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeArchiveStore) ForEachRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time, fn func(*models.RideDataHistoryRecord) error) error {
+	for _, r := range s.records {
+		if s.inRange(r, parkID, from, to) {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+	}
+	if s.afterRead != nil {
+		s.afterRead()
+	}
+	return nil
+}
+
+func (s *fakeArchiveStore) DeleteRideDataHistoryByIDs(ctx context.Context, ids []int64) (int64, error) {
+	var kept []*models.RideDataHistoryRecord
+	var deleted int64
+	for _, r := range s.records {
+		if slices.Contains(ids, r.ID) {
+			deleted++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	s.records = kept
+	return deleted, nil
+}
+
+func (s *fakeArchiveStore) DropEmptyMonthlyPartition(ctx context.Context, month time.Time) (bool, error) {
+	for _, r := range s.records {
+		if !r.LastUpdated.Before(month) && r.LastUpdated.Before(month.AddDate(0, 1, 0)) {
+			return false, nil
+		}
+	}
+	s.dropped = append(s.dropped, month)
+	return true, nil
+}
+
+func (s *fakeArchiveStore) RestoreRideDataHistory(ctx context.Context, records []*models.RideDataHistoryRecord) (int64, error) {
+	existing := make(map[int64]bool)
+	for _, r := range s.records {
+		existing[r.ID] = true
+	}
+
+	var restored int64
+	for _, r := range records {
+		if existing[r.ID] {
+			continue
+		}
+		s.records = append(s.records, r)
+		existing[r.ID] = true
+		restored++
+	}
+	return restored, nil
+}
+
+func seedStore() *fakeArchiveStore {
+	store := &fakeArchiveStore{}
+	id := int64(1)
+	add := func(parkID string, at time.Time) {
+		wait := 30
+		store.records = append(store.records, &models.RideDataHistoryRecord{
+			ID: id, RideID: "ride-" + parkID, ParkID: parkID, Name: "Ride", Status: "OPERATING",
+			LastUpdated: at, CreatedAt: at, UpdatedAt: at,
+			OperatingHours: "[]", Forecast: "[]", StandbyWaitTime: &wait,
+		})
+		id++
+	}
+
+	jan := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		add("park1", jan.Add(time.Duration(i)*time.Minute))
+		add("park2", feb.Add(time.Duration(i)*time.Minute))
+	}
+	add("park1", feb)
+	add("park1", recent)
+	return store
+}
+
+func TestArchiveCutoff(t *testing.T) {
+	now := time.Date(2025, 7, 15, 9, 30, 0, 0, time.UTC)
+
+	cutoff := archiveCutoff(now, 30*24*time.Hour)
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), cutoff)
+
+	cutoff = archiveCutoff(now, 180*24*time.Hour)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), cutoff)
+}
+
+func TestArchiveJob_Run(t *testing.T) {
+	store := seedStore()
+	dir := t.TempDir()
+	runAt := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+
+	job := &archiveJob{
+		store:     store,
+		logger:    &MockLogger{},
+		dir:       dir,
+		format:    archive.FormatNDJSON,
+		batchSize: 2,
+		now:       func() time.Time { return runAt },
+	}
+
+	summary, err := job.Run(context.Background(), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Partitions)
+	assert.Equal(t, int64(11), summary.Archived)
+	assert.Equal(t, int64(11), summary.Deleted)
+	require.Len(t, store.records, 1, "only the recent row should remain")
+	months := []time.Time{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(t, months, store.dropped, "each emptied month should be dropped once")
+	assert.Equal(t, months, summary.DroppedMonths)
+
+	expected := filepath.Join(dir, archive.FileName("park1", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), runAt, archive.FormatNDJSON))
+	assert.Contains(t, summary.Files, expected)
+
+	count, err := countArchiveFile(expected, archive.FormatNDJSON)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	_, err = os.Stat(expected + ".tmp")
+	assert.True(t, os.IsNotExist(err), "temporary file should be renamed")
+}
+
+func TestArchiveJob_KeepsRowsCommittedAfterRead(t *testing.T) {
+	store := seedStore()
+	late := &models.RideDataHistoryRecord{
+		ID: 100, RideID: "ride-park1", ParkID: "park1", Name: "Ride", Status: "OPERATING",
+		LastUpdated: time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC), OperatingHours: "[]", Forecast: "[]",
+	}
+	store.afterRead = func() {
+		if !slices.Contains(store.records, late) {
+			store.records = append(store.records, late)
+		}
+	}
+
+	job := &archiveJob{
+		store:     store,
+		logger:    &MockLogger{},
+		dir:       t.TempDir(),
+		format:    archive.FormatNDJSON,
+		batchSize: 2,
+		now:       time.Now,
+	}
+	summary, err := job.Run(context.Background(), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(11), summary.Deleted)
+	assert.Contains(t, store.records, late, "a row missing from the archive must not be deleted")
+	assert.Equal(t, []time.Time{time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}, summary.DroppedMonths, "a month that still has rows must be kept")
+}
+
+func TestArchiveJob_DryRun(t *testing.T) {
+	store := seedStore()
+	dir := t.TempDir()
+
+	job := &archiveJob{
+		store:     store,
+		logger:    &MockLogger{},
+		dir:       dir,
+		format:    archive.FormatCSV,
+		batchSize: 100,
+		dryRun:    true,
+		now:       time.Now,
+	}
+
+	summary, err := job.Run(context.Background(), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Partitions)
+	assert.Equal(t, int64(11), summary.Archived)
+	assert.Equal(t, int64(0), summary.Deleted)
+	assert.Len(t, store.records, 12, "dry run must not delete rows")
+	assert.Empty(t, store.dropped, "dry run must not drop partitions")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "dry run must not write files")
+}
+
+func TestRestoreJob_Run(t *testing.T) {
+	store := seedStore()
+	dir := t.TempDir()
+	runAt := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+
+	archiver := &archiveJob{
+		store:     store,
+		logger:    &MockLogger{},
+		dir:       dir,
+		format:    archive.FormatCSV,
+		batchSize: 100,
+		now:       func() time.Time { return runAt },
+	}
+	summary, err := archiver.Run(context.Background(), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, summary.Files, 1)
+	require.Len(t, store.records, 7)
+
+	restorer := &restoreJob{store: store, logger: &MockLogger{}, batchSize: 2}
+	restored, err := restorer.Run(context.Background(), summary.Files[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), restored.Read)
+	assert.Equal(t, int64(5), restored.Restored)
+	assert.Len(t, store.records, 12)
+
+	// Restoring the same file again is a no-op
+	restored, err = restorer.Run(context.Background(), summary.Files[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), restored.Restored)
+}
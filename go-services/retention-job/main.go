@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-services/shared/archive"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var logger service.Logger
+
+const usage = `Usage:
+  retention-job archive [-retention-days N] [-dir DIR] [-format ndjson|csv] [-batch-size N] [-dry-run]
+  retention-job restore -file PATH [-batch-size N] [-dry-run]`
+
+func main() {
+	// Load environment variables from .env file only in development
+	env := os.Getenv("ENV")
+	if env == "" || strings.ToLower(env) == "development" {
+		if err := godotenv.Load("../.env"); err != nil {
+			// Use fmt.Println since logger isn't initialized yet
+			fmt.Printf("Warning: No .env file found: %v\n", err)
+		}
+	}
+
+	// initialize default logger implementation
+	logger = service.NewDefaultLogger()
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Stop between batches on SIGTERM so Cloud Run jobs can be cancelled cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "archive":
+		err = runArchive(ctx, os.Args[2:])
+	case "restore":
+		err = runRestore(ctx, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logger.Fatalf("Retention job failed: %v", err)
+	}
+}
+
+func runArchive(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	retentionDays := fs.Int("retention-days", envInt("RETENTION_DAYS", DefaultRetentionDays), "archive rows older than this many days")
+	dir := fs.String("dir", envString("ARCHIVE_DIR", DefaultArchiveDir), "directory to write archive files to")
+	formatName := fs.String("format", envString("ARCHIVE_FORMAT", DefaultArchiveFormat), "archive format: ndjson or csv")
+	batchSize := fs.Int("batch-size", envInt("RETENTION_DELETE_BATCH_SIZE", DefaultDeleteBatchSize), "rows deleted per statement")
+	dryRun := fs.Bool("dry-run", false, "report what would be archived without writing files or deleting rows")
+	fs.Parse(args)
+
+	if *retentionDays <= 0 {
+		return fmt.Errorf("retention-days must be positive, got %d", *retentionDays)
+	}
+	if *batchSize <= 0 {
+		return fmt.Errorf("batch-size must be positive, got %d", *batchSize)
+	}
+	format, err := archive.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository.NewRideDataHistoryRepository()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	job := &archiveJob{
+		store:     repo,
+		logger:    logger,
+		dir:       *dir,
+		format:    format,
+		batchSize: *batchSize,
+		dryRun:    *dryRun,
+		now:       time.Now,
+	}
+
+	cutoff := archiveCutoff(time.Now(), time.Duration(*retentionDays)*24*time.Hour)
+	summary, err := job.Run(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Archive completed: %d partitions, %d rows archived, %d rows deleted, %d monthly tables dropped (dry_run=%v)",
+		summary.Partitions, summary.Archived, summary.Deleted, len(summary.DroppedMonths), *dryRun)
+	return nil
+}
+
+func runRestore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := fs.String("file", "", "archive file to reimport")
+	batchSize := fs.Int("batch-size", DefaultRestoreBatchSize, "rows inserted per transaction")
+	dryRun := fs.Bool("dry-run", false, "read and validate the archive without writing to the database")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	if *batchSize <= 0 {
+		return fmt.Errorf("batch-size must be positive, got %d", *batchSize)
+	}
+
+	repo, err := repository.NewRideDataHistoryRepository()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	job := &restoreJob{
+		store:     repo,
+		logger:    logger,
+		batchSize: *batchSize,
+		dryRun:    *dryRun,
+	}
+
+	summary, err := job.Run(ctx, *file)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		logger.Infof("[dry-run] Read and validated %d rows from %s", summary.Read, *file)
+		return nil
+	}
+
+	logger.Infof("Restore completed: %d rows read, %d rows restored, %d already present",
+		summary.Read, summary.Restored, summary.Read-summary.Restored)
+	return nil
+}
+
+func envString(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
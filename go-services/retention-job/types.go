@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"time"
+)
+
+// Defaults for the retention job, overridable through environment variables and flags
+const (
+	DefaultRetentionDays    = 180
+	DefaultArchiveDir       = "./archive"
+	DefaultArchiveFormat    = "ndjson"
+	DefaultDeleteBatchSize  = 1000
+	DefaultRestoreBatchSize = 500
+)
+
+// archiveStore is the subset of the repository used to archive and restore ride data history
+type archiveStore interface {
+	ListArchivePartitions(ctx context.Context, before time.Time) ([]repository.ArchivePartition, error)
+	CountRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time) (int64, error)
+	ForEachRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time, fn func(*models.RideDataHistoryRecord) error) error
+	DeleteRideDataHistoryByIDs(ctx context.Context, ids []int64) (int64, error)
+	DropEmptyMonthlyPartition(ctx context.Context, month time.Time) (bool, error)
+	RestoreRideDataHistory(ctx context.Context, records []*models.RideDataHistoryRecord) (int64, error)
+}
+
+// ArchiveSummary reports the outcome of an archive run
+type ArchiveSummary struct {
+	Partitions int
+	Archived   int64
+	Deleted    int64
+	Files      []string
+	// DroppedMonths are the months whose emptied table partition was dropped
+	DroppedMonths []time.Time
+}
+
+// RestoreSummary reports the outcome of a restore run
+type RestoreSummary struct {
+	Read     int64
+	Restored int64
+}
@@ -0,0 +1,366 @@
+// Package alerts evaluates the wait time alerts users register against freshly collected ride data
+// and delivers the matches. A rule fires while its ride is operating and the standby wait time
+// compares to its threshold inside its time window, and at most once per cooldown, which is claimed
+// in the database so several collector instances queue it once. Triggered alerts are queued in the
+// database and sent by a worker outside the collector's insert path, retrying failures with
+// exponential backoff. Every delivery attempt is logged.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/notify"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Retry policy. Alerts are only useful while the wait time lasts, so they are retried for a few
+// minutes rather than the hours webhook events are.
+const (
+	// MaxAttempts is how many times an alert is sent before it is dropped
+	MaxAttempts = 4
+	// BaseRetryDelay is the delay before the second attempt; every further delay doubles
+	BaseRetryDelay = 30 * time.Second
+)
+
+const (
+	// claimLease is how long a claimed alert is held before another collector may retry it; it
+	// outlasts the client and SMTP timeouts
+	claimLease = 2 * time.Minute
+	// claimBatch is how many alerts are claimed at once
+	claimBatch = 50
+	// deliveryConcurrency bounds the deliveries in flight
+	deliveryConcurrency = 8
+	// maxErrorLength bounds the error stored with a failed attempt
+	maxErrorLength = 500
+)
+
+// Store is the part of the alert rule repository the Evaluator uses
+type Store interface {
+	EnabledAlertRulesForRides(ctx context.Context, rideIDs []string) ([]*models.AlertRule, error)
+	QueueAlert(ctx context.Context, alert *models.QueuedAlert, now time.Time) (bool, error)
+	ClaimQueuedAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.QueuedAlert, error)
+	CompleteQueuedAlert(ctx context.Context, id int64) error
+	RetryQueuedAlert(ctx context.Context, id int64, next time.Time, lastError string) error
+	DisableAlertRule(ctx context.Context, id int64) error
+	RecordAlertDelivery(ctx context.Context, d *models.AlertDelivery) error
+}
+
+// Evaluator checks stored ride data against the alert rules and delivers the queue of triggered
+// alerts
+type Evaluator struct {
+	store Store
+	// notifier builds the notifier of a rule's channel
+	notifier func(rule *models.AlertRule) (notify.Notifier, error)
+	now      func() time.Time
+	// wake starts a delivery round right after alerts were queued
+	wake chan struct{}
+}
+
+// Channels configures delivery. Rules of a channel without its configuration fail to deliver.
+type Channels struct {
+	// SMTP sends email; its recipients are set per rule
+	SMTP *notify.SMTP
+	// VAPID signs Web Push requests
+	VAPID *notify.VAPID
+	// Client posts webhooks and push messages to user supplied URLs, normally notify.PublicClient
+	Client *http.Client
+}
+
+// NewEvaluator creates an Evaluator delivering through channels
+func NewEvaluator(store Store, channels Channels) *Evaluator {
+	return &Evaluator{store: store, notifier: channels.notifier, now: time.Now, wake: make(chan struct{}, 1)}
+}
+
+func (c Channels) notifier(rule *models.AlertRule) (notify.Notifier, error) {
+	switch rule.Channel {
+	case models.AlertChannelWebhook:
+		return &notify.Webhook{URL: rule.Target, Client: c.Client}, nil
+	case models.AlertChannelEmail:
+		if c.SMTP == nil {
+			return nil, errors.New("email delivery is not configured")
+		}
+		smtp := *c.SMTP
+		smtp.To = []string{rule.Target}
+		return &smtp, nil
+	case models.AlertChannelWebPush:
+		if c.VAPID == nil {
+			return nil, errors.New("web push delivery is not configured")
+		}
+		return &notify.WebPush{
+			Endpoint: rule.Target, P256DH: rule.PushP256DH, Auth: rule.PushAuth,
+			VAPID: c.VAPID, TTL: time.Duration(rule.CooldownMinutes) * time.Minute, Client: c.Client,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown channel %q", rule.Channel)
+}
+
+// Evaluate checks records against the enabled rules of their rides and queues the rules that match
+// and are out of their cooldown; Run delivers them. Failures are logged; they never fail the
+// caller's insert.
+func (e *Evaluator) Evaluate(ctx context.Context, records []*models.RideDataHistoryRecord) {
+	if len(records) == 0 {
+		return
+	}
+	byRide := make(map[string]*models.RideDataHistoryRecord, len(records))
+	rideIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		if _, ok := byRide[record.RideID]; !ok {
+			rideIDs = append(rideIDs, record.RideID)
+		}
+		byRide[record.RideID] = record
+	}
+
+	rules, err := e.store.EnabledAlertRulesForRides(ctx, rideIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load alert rules", "error", err)
+		return
+	}
+
+	now := e.now()
+	queued := 0
+	for _, rule := range rules {
+		record := byRide[rule.RideID]
+		if record == nil || !Matches(rule, record, now) {
+			continue
+		}
+		msg, err := json.Marshal(message(rule, record, now))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to encode alert", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		alert := &models.QueuedAlert{Rule: *rule, RideID: record.RideID, WaitTime: record.StandbyWaitTime, Message: msg}
+		ok, err := e.store.QueueAlert(ctx, alert, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to queue alert", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		if ok {
+			queued++
+		}
+	}
+	if queued > 0 {
+		slog.DebugContext(ctx, "Queued alerts", "alerts", queued)
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// RetryDelay is the delay after a failed attempt, the attempt'th one
+func RetryDelay(attempt int) time.Duration {
+	return BaseRetryDelay << (attempt - 1)
+}
+
+// Run delivers due alerts every interval, and right after alerts are queued, until ctx is done
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.Dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+	}
+}
+
+// Dispatch delivers the alerts that are due until none are left
+func (e *Evaluator) Dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		alerts, err := e.store.ClaimQueuedAlerts(ctx, e.now(), claimLease, claimBatch)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to claim queued alerts", "error", err)
+			return
+		}
+		if len(alerts) == 0 {
+			return
+		}
+		sem := make(chan struct{}, deliveryConcurrency)
+		var wg sync.WaitGroup
+		for _, alert := range alerts {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				e.deliver(ctx, alert)
+			}()
+		}
+		wg.Wait()
+		if len(alerts) < claimBatch {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt, logs it and records its outcome. An expired push subscription disables
+// its rule, and failures that cannot succeed on a retry, like an unconfigured channel, are dropped.
+func (e *Evaluator) deliver(ctx context.Context, alert *models.QueuedAlert) {
+	rule := &alert.Rule
+	delivery := &models.AlertDelivery{
+		RuleID: rule.ID, Channel: rule.Channel, Status: models.AlertDelivered,
+		RideID: alert.RideID, WaitTime: alert.WaitTime,
+	}
+	var msg notify.Message
+	n, err := e.notifier(rule)
+	if err == nil {
+		err = decodeMessage(alert.Message, &msg)
+	}
+	// Neither an unconfigured channel nor a malformed message gets better on a retry
+	retry := err == nil
+	if err == nil {
+		err = n.Notify(ctx, msg)
+	}
+
+	if err == nil {
+		e.record(ctx, delivery)
+		if err := e.store.CompleteQueuedAlert(ctx, alert.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to complete queued alert", "alert_id", alert.ID, "error", err)
+		}
+		return
+	}
+
+	text := err.Error()
+	if len(text) > maxErrorLength {
+		text = text[:maxErrorLength]
+	}
+	delivery.Status, delivery.Error = models.AlertFailed, &text
+	e.record(ctx, delivery)
+	if errors.Is(err, notify.ErrSubscriptionGone) {
+		retry = false
+		if err := e.store.DisableAlertRule(ctx, rule.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to disable alert rule", "rule_id", rule.ID, "error", err)
+		}
+	}
+	if !retry || alert.Attempts >= MaxAttempts {
+		slog.WarnContext(ctx, "Failed to deliver alert", "alert_id", alert.ID, "rule_id", rule.ID,
+			"channel", rule.Channel, "attempts", alert.Attempts, "error", err)
+		if err := e.store.CompleteQueuedAlert(ctx, alert.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to complete queued alert", "alert_id", alert.ID, "error", err)
+		}
+		return
+	}
+	next := e.now().Add(RetryDelay(alert.Attempts))
+	slog.InfoContext(ctx, "Alert delivery failed, retrying", "alert_id", alert.ID, "rule_id", rule.ID,
+		"channel", rule.Channel, "attempts", alert.Attempts, "next_attempt_at", next, "error", err)
+	if err := e.store.RetryQueuedAlert(ctx, alert.ID, next, text); err != nil {
+		slog.ErrorContext(ctx, "Failed to reschedule queued alert", "alert_id", alert.ID, "error", err)
+	}
+}
+
+// record appends an attempt to the delivery log
+func (e *Evaluator) record(ctx context.Context, delivery *models.AlertDelivery) {
+	if err := e.store.RecordAlertDelivery(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to record alert delivery", "rule_id", delivery.RuleID, "error", err)
+	}
+}
+
+// decodeMessage decodes a queued message, keeping the numbers of its data exact
+func decodeMessage(data []byte, msg *notify.Message) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(msg); err != nil {
+		return fmt.Errorf("failed to decode queued alert: %w", err)
+	}
+	return nil
+}
+
+// comparisonText describes the comparisons in messages
+var comparisonText = map[string]string{
+	models.AlertBelow:     "below",
+	models.AlertAtOrBelow: "at or below",
+	models.AlertAbove:     "above",
+	models.AlertAtOrAbove: "at or above",
+}
+
+// message renders a triggered rule
+func message(rule *models.AlertRule, record *models.RideDataHistoryRecord, now time.Time) notify.Message {
+	wait := *record.StandbyWaitTime
+	park, _ := shared.GetParkInfo(record.ParkID)
+	return notify.Message{
+		Event: "alert.triggered",
+		Title: fmt.Sprintf("%s: %d minute wait", record.Name, wait),
+		Text: fmt.Sprintf("The standby wait for %s at %s is %d minutes, %s your alert threshold of %d minutes.",
+			record.Name, park.Name, wait, comparisonText[rule.Comparison], rule.Threshold),
+		Data: map[string]any{
+			"rule_id":    rule.ID,
+			"ride_id":    record.RideID,
+			"ride_name":  record.Name,
+			"park_id":    record.ParkID,
+			"wait_time":  wait,
+			"comparison": rule.Comparison,
+			"threshold":  rule.Threshold,
+		},
+		Time: now,
+	}
+}
+
+// Matches reports whether a ride's record triggers rule at now, ignoring the cooldown
+func Matches(rule *models.AlertRule, record *models.RideDataHistoryRecord, now time.Time) bool {
+	// A closed ride reports no meaningful wait time
+	if record.Status != string(models.RideStatusOperating) || record.StandbyWaitTime == nil {
+		return false
+	}
+	if !compare(rule.Comparison, *record.StandbyWaitTime, rule.Threshold) {
+		return false
+	}
+	if rule.WindowStart != nil && rule.WindowEnd != nil {
+		loc, err := time.LoadLocation(rule.Timezone)
+		if err != nil || !inWindow(now.In(loc), *rule.WindowStart, *rule.WindowEnd) {
+			return false
+		}
+	}
+	if rule.ParkHoursOnly && !withinOperatingHours(record.OperatingHours, now) {
+		return false
+	}
+	return true
+}
+
+func compare(comparison string, wait, threshold int) bool {
+	switch comparison {
+	case models.AlertBelow:
+		return wait < threshold
+	case models.AlertAtOrBelow:
+		return wait <= threshold
+	case models.AlertAbove:
+		return wait > threshold
+	case models.AlertAtOrAbove:
+		return wait >= threshold
+	}
+	return false
+}
+
+// inWindow reports whether the local time of t is in [start, end) minutes after midnight. A window
+// whose end is before its start runs past midnight.
+func inWindow(t time.Time, start, end int) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// withinOperatingHours reports whether now falls in one of the operating hours the upstream API
+// reported. Rides without reported hours count as open, since they are operating.
+func withinOperatingHours(hoursJSON string, now time.Time) bool {
+	var hours []models.OperatingHours
+	if err := json.Unmarshal([]byte(hoursJSON), &hours); err != nil || len(hours) == 0 {
+		return true
+	}
+	for _, h := range hours {
+		if !now.Before(h.StartTime) && now.Before(h.EndTime) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-services/shared/models"
+	"go-services/shared/notify"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRideID = "9167db1d-e5e7-46da-a07f-ae30a87bc4c4"
+
+func record(status string, wait int) *models.RideDataHistoryRecord {
+	return &models.RideDataHistoryRecord{
+		RideID: testRideID, ParkID: "7340550b-c14d-4def-80bb-acdb51d49a66", Name: "Space Mountain",
+		Status: status, StandbyWaitTime: &wait, OperatingHours: "[]",
+	}
+}
+
+func minutes(m int) *int { return &m }
+
+func TestMatches(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	// 22:30 in Anaheim
+	now := time.Date(2026, 7, 1, 22, 30, 0, 0, la)
+	hours := `[{"startTime":"2026-07-01T08:00:00-07:00","endTime":"2026-07-01T23:00:00-07:00"}]`
+	closedHours := `[{"startTime":"2026-07-01T08:00:00-07:00","endTime":"2026-07-01T22:00:00-07:00"}]`
+
+	tests := []struct {
+		name  string
+		rule  models.AlertRule
+		rec   *models.RideDataHistoryRecord
+		hours string
+		want  bool
+	}{
+		{"below", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20}, record("OPERATING", 15), "", true},
+		{"below at threshold", models.AlertRule{Comparison: models.AlertBelow, Threshold: 15}, record("OPERATING", 15), "", false},
+		{"at or below", models.AlertRule{Comparison: models.AlertAtOrBelow, Threshold: 15}, record("OPERATING", 15), "", true},
+		{"above", models.AlertRule{Comparison: models.AlertAbove, Threshold: 15}, record("OPERATING", 15), "", false},
+		{"at or above", models.AlertRule{Comparison: models.AlertAtOrAbove, Threshold: 15}, record("OPERATING", 15), "", true},
+		{"closed ride", models.AlertRule{Comparison: models.AlertAtOrBelow, Threshold: 20}, record("CLOSED", 0), "", false},
+		{"unknown comparison", models.AlertRule{Comparison: "equal", Threshold: 15}, record("OPERATING", 15), "", false},
+		{"inside window", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, WindowStart: minutes(22 * 60), WindowEnd: minutes(23 * 60), Timezone: "America/Los_Angeles"}, record("OPERATING", 15), "", true},
+		{"outside window", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, WindowStart: minutes(9 * 60), WindowEnd: minutes(21 * 60), Timezone: "America/Los_Angeles"}, record("OPERATING", 15), "", false},
+		{"window past midnight", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, WindowStart: minutes(21 * 60), WindowEnd: minutes(60), Timezone: "America/Los_Angeles"}, record("OPERATING", 15), "", true},
+		{"window in another zone", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, WindowStart: minutes(22 * 60), WindowEnd: minutes(23 * 60), Timezone: "America/New_York"}, record("OPERATING", 15), "", false},
+		{"within park hours", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, ParkHoursOnly: true}, record("OPERATING", 15), hours, true},
+		{"after park hours", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, ParkHoursOnly: true}, record("OPERATING", 15), closedHours, false},
+		{"park hours ignored", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20}, record("OPERATING", 15), closedHours, true},
+		{"no reported hours", models.AlertRule{Comparison: models.AlertBelow, Threshold: 20, ParkHoursOnly: true}, record("OPERATING", 15), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.hours != "" {
+				tt.rec.OperatingHours = tt.hours
+			}
+			if got := Matches(&tt.rule, tt.rec, now); got != tt.want {
+				t.Errorf("Matches() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeStore serves rules, lets only the first claim of each rule through, like the cooldown, and
+// keeps the queue in memory
+type fakeStore struct {
+	mu         sync.Mutex
+	rules      []*models.AlertRule
+	claimed    map[int64]bool
+	queue      []*models.QueuedAlert
+	due        map[int64]time.Time
+	completed  []int64
+	disabled   []int64
+	deliveries []*models.AlertDelivery
+}
+
+func newFakeStore(rules ...*models.AlertRule) *fakeStore {
+	return &fakeStore{rules: rules, claimed: map[int64]bool{}, due: map[int64]time.Time{}}
+}
+
+func (f *fakeStore) EnabledAlertRulesForRides(ctx context.Context, rideIDs []string) ([]*models.AlertRule, error) {
+	return f.rules, nil
+}
+
+func (f *fakeStore) QueueAlert(ctx context.Context, alert *models.QueuedAlert, now time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.claimed[alert.Rule.ID] {
+		return false, nil
+	}
+	f.claimed[alert.Rule.ID] = true
+	queued := *alert
+	queued.ID = int64(len(f.claimed))
+	f.queue = append(f.queue, &queued)
+	f.due[queued.ID] = now
+	return true, nil
+}
+
+func (f *fakeStore) ClaimQueuedAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.QueuedAlert, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []*models.QueuedAlert
+	for _, a := range f.queue {
+		if len(claimed) < limit && !f.due[a.ID].After(now) {
+			a.Attempts++
+			f.due[a.ID] = now.Add(lease)
+			copied := *a
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeStore) CompleteQueuedAlert(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed = append(f.completed, id)
+	f.queue = slices.DeleteFunc(f.queue, func(a *models.QueuedAlert) bool { return a.ID == id })
+	return nil
+}
+
+func (f *fakeStore) RetryQueuedAlert(ctx context.Context, id int64, next time.Time, lastError string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.due[id] = next
+	return nil
+}
+
+func (f *fakeStore) DisableAlertRule(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disabled = append(f.disabled, id)
+	return nil
+}
+
+func (f *fakeStore) RecordAlertDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = append(f.deliveries, d)
+	return nil
+}
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	err  error
+	sent []notify.Message
+}
+
+func (f *fakeNotifier) Name() string { return "fake" }
+
+func (f *fakeNotifier) Notify(_ context.Context, msg notify.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func TestEvaluate(t *testing.T) {
+	store := newFakeStore(
+		&models.AlertRule{ID: 1, RideID: testRideID, Comparison: models.AlertBelow, Threshold: 20, Channel: models.AlertChannelWebhook},
+		&models.AlertRule{ID: 2, RideID: testRideID, Comparison: models.AlertAbove, Threshold: 20, Channel: models.AlertChannelWebhook},
+		&models.AlertRule{ID: 3, RideID: testRideID, Comparison: models.AlertBelow, Threshold: 30, Channel: models.AlertChannelWebPush},
+	)
+	webhook := &fakeNotifier{}
+	push := &fakeNotifier{err: notify.ErrSubscriptionGone}
+	e := NewEvaluator(store, Channels{})
+	e.notifier = func(rule *models.AlertRule) (notify.Notifier, error) {
+		if rule.Channel == models.AlertChannelWebPush {
+			return push, nil
+		}
+		return webhook, nil
+	}
+
+	// Evaluating only queues the matching rules
+	e.Evaluate(context.Background(), []*models.RideDataHistoryRecord{record("OPERATING", 15)})
+	if len(store.queue) != 2 || len(webhook.sent) != 0 || len(push.sent) != 0 {
+		t.Fatalf("Expected rules 1 and 3 to be queued without delivery, got %d queued and %d sent", len(store.queue), len(webhook.sent))
+	}
+	select {
+	case <-e.wake:
+	default:
+		t.Error("Expected queued alerts to wake the delivery")
+	}
+
+	e.Dispatch(context.Background())
+	if len(webhook.sent) != 1 || webhook.sent[0].Data["rule_id"] != json.Number("1") || webhook.sent[0].Data["wait_time"] != json.Number("15") {
+		t.Fatalf("Expected rule 1 to be delivered, got %+v", webhook.sent)
+	}
+	if webhook.sent[0].Title != "Space Mountain: 15 minute wait" {
+		t.Errorf("Unexpected title %q", webhook.sent[0].Title)
+	}
+	if len(store.deliveries) != 2 {
+		t.Fatalf("Expected two logged deliveries, got %d", len(store.deliveries))
+	}
+	for _, d := range store.deliveries {
+		switch {
+		case d.RuleID == 1 && d.Status == models.AlertDelivered && *d.WaitTime == 15:
+		case d.RuleID == 3 && d.Status == models.AlertFailed && d.Error != nil:
+		default:
+			t.Errorf("Unexpected delivery %+v", d)
+		}
+	}
+	if len(store.disabled) != 1 || store.disabled[0] != 3 {
+		t.Errorf("Expected the expired subscription's rule to be disabled, got %v", store.disabled)
+	}
+	if len(store.queue) != 0 {
+		t.Errorf("Expected the expired subscription not to be retried, got %+v", store.queue)
+	}
+
+	// Both rules are now in their cooldown
+	e.Evaluate(context.Background(), []*models.RideDataHistoryRecord{record("OPERATING", 10)})
+	e.Dispatch(context.Background())
+	if len(webhook.sent) != 1 || len(store.deliveries) != 2 {
+		t.Errorf("Expected no delivery within the cooldown, got %d sent", len(webhook.sent))
+	}
+}
+
+func TestDispatchRetries(t *testing.T) {
+	store := newFakeStore(&models.AlertRule{ID: 1, RideID: testRideID, Comparison: models.AlertBelow, Threshold: 20, Channel: models.AlertChannelWebhook})
+	webhook := &fakeNotifier{err: errors.New("receiver returned status 503")}
+	now := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	e := NewEvaluator(store, Channels{})
+	e.notifier = func(*models.AlertRule) (notify.Notifier, error) { return webhook, nil }
+	e.now = func() time.Time { return now }
+
+	e.Evaluate(context.Background(), []*models.RideDataHistoryRecord{record("OPERATING", 15)})
+	e.Dispatch(context.Background())
+	if want := now.Add(BaseRetryDelay); !store.due[1].Equal(want) {
+		t.Fatalf("Expected the second attempt at %v, got %v", want, store.due[1])
+	}
+
+	// Not due yet
+	e.Dispatch(context.Background())
+	if len(webhook.sent) != 1 {
+		t.Fatalf("Expected no attempt before the retry is due, got %d", len(webhook.sent))
+	}
+
+	for attempt := 2; attempt <= MaxAttempts; attempt++ {
+		now = store.due[1]
+		e.Dispatch(context.Background())
+	}
+	if len(webhook.sent) != MaxAttempts || len(store.deliveries) != MaxAttempts || len(store.queue) != 0 {
+		t.Errorf("Expected %d logged attempts before the alert is dropped, got %d sent, %d logged, queue %v",
+			MaxAttempts, len(webhook.sent), len(store.deliveries), store.queue)
+	}
+}
+
+func TestDispatchDropsUnconfiguredChannels(t *testing.T) {
+	store := newFakeStore(&models.AlertRule{ID: 1, RideID: testRideID, Comparison: models.AlertBelow, Threshold: 20, Channel: models.AlertChannelEmail})
+	e := NewEvaluator(store, Channels{})
+
+	e.Evaluate(context.Background(), []*models.RideDataHistoryRecord{record("OPERATING", 15)})
+	e.Dispatch(context.Background())
+	if len(store.deliveries) != 1 || store.deliveries[0].Status != models.AlertFailed || len(store.queue) != 0 {
+		t.Errorf("Expected one failed attempt without a retry, got %+v and queue %v", store.deliveries, store.queue)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute} {
+		if got := RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %v, expected %v", attempt, got, want)
+		}
+	}
+}
+
+func TestChannelsNotifier(t *testing.T) {
+	c := Channels{SMTP: &notify.SMTP{Addr: "localhost:1025", From: "alerts@example.com", To: []string{"ops@example.com"}}}
+	n, err := c.notifier(&models.AlertRule{Channel: models.AlertChannelEmail, Target: "guest@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := n.(*notify.SMTP).To; len(to) != 1 || to[0] != "guest@example.com" {
+		t.Errorf("Expected the rule's recipient only, got %v", to)
+	}
+	if c.SMTP.To[0] != "ops@example.com" {
+		t.Error("Expected the shared SMTP configuration to be left alone")
+	}
+	if _, err := c.notifier(&models.AlertRule{Channel: models.AlertChannelWebPush}); err == nil {
+		t.Error("Expected web push without VAPID keys to fail")
+	}
+	if _, err := c.notifier(&models.AlertRule{Channel: "sms"}); err == nil {
+		t.Error("Expected an unknown channel to fail")
+	}
+}
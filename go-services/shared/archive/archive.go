@@ -0,0 +1,316 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-services/shared/models"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies the on-disk encoding of an archive file
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+// csvNull marks a NULL return_time_state in CSV archives, as in PostgreSQL's COPY, so it stays
+// distinct from an empty string. The other nullable columns are numbers and timestamps, which are
+// never empty, so an empty field is unambiguous for them.
+const csvNull = `\N`
+
+// csvHeader lists the archived columns in the order they are written
+var csvHeader = []string{
+	"id", "ride_id", "external_id", "park_id", "entity_type", "name", "status", "last_updated",
+	"created_at", "updated_at", "operating_hours", "standby_wait_time",
+	"return_time_state", "return_start", "return_end", "forecast",
+}
+
+// ParseFormat converts a user supplied format name into a Format
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q (expected ndjson or csv)", s)
+	}
+}
+
+// FormatFromPath infers the archive format from a file name such as "x.ndjson.gz"
+func FormatFromPath(path string) (Format, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// FileName returns the relative path for an archive of one park and month.
+// The run timestamp keeps repeated runs from overwriting an earlier archive.
+func FileName(parkID string, month time.Time, runAt time.Time, format Format) string {
+	return filepath.Join(
+		parkID,
+		month.UTC().Format("2006-01"),
+		fmt.Sprintf("ride_data_history_%s.%s.gz", runAt.UTC().Format("20060102T150405Z"), format),
+	)
+}
+
+// Writer encodes ride data history records into a gzip compressed archive
+type Writer struct {
+	format Format
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	csv    *csv.Writer
+	count  int64
+}
+
+// NewWriter creates a Writer that compresses its output into w
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	gz := gzip.NewWriter(w)
+	aw := &Writer{format: format, gz: gz}
+
+	switch format {
+	case FormatNDJSON:
+		aw.enc = json.NewEncoder(gz)
+	case FormatCSV:
+		aw.csv = csv.NewWriter(gz)
+		if err := aw.csv.Write(csvHeader); err != nil {
+			return nil, fmt.Errorf("failed to write csv header: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	return aw, nil
+}
+
+// Write appends a single record to the archive
+func (w *Writer) Write(record *models.RideDataHistoryRecord) error {
+	var err error
+	if w.enc != nil {
+		err = w.enc.Encode(record)
+	} else {
+		err = w.csv.Write(recordToCSV(record))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write record %d: %w", record.ID, err)
+	}
+
+	w.count++
+	return nil
+}
+
+// Count returns the number of records written so far
+func (w *Writer) Count() int64 {
+	return w.count
+}
+
+// Close flushes buffered data and finishes the gzip stream. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			w.gz.Close()
+			return fmt.Errorf("failed to flush csv: %w", err)
+		}
+	}
+	return w.gz.Close()
+}
+
+// Reader decodes ride data history records from a gzip compressed archive
+type Reader struct {
+	gz  *gzip.Reader
+	dec *json.Decoder
+	csv *csv.Reader
+}
+
+// NewReader creates a Reader over a compressed archive stream
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	ar := &Reader{gz: gz}
+
+	switch format {
+	case FormatNDJSON:
+		ar.dec = json.NewDecoder(gz)
+	case FormatCSV:
+		ar.csv = csv.NewReader(gz)
+		ar.csv.FieldsPerRecord = len(csvHeader)
+		header, err := ar.csv.Read()
+		if err != nil {
+			gz.Close()
+			return nil, fmt.Errorf("failed to read csv header: %w", err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+			gz.Close()
+			return nil, fmt.Errorf("unexpected csv header: %v", header)
+		}
+	default:
+		gz.Close()
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	return ar, nil
+}
+
+// Read returns the next record, or io.EOF once the archive is exhausted
+func (r *Reader) Read() (*models.RideDataHistoryRecord, error) {
+	if r.dec != nil {
+		record := &models.RideDataHistoryRecord{}
+		if err := r.dec.Decode(record); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to decode record: %w", err)
+		}
+		return record, nil
+	}
+
+	fields, err := r.csv.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read csv row: %w", err)
+	}
+	return recordFromCSV(fields)
+}
+
+// Close releases the gzip reader. It does not close the underlying reader.
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
+
+// CountRecords decodes an entire archive stream and returns the number of records in it
+func CountRecords(r io.Reader, format Format) (int64, error) {
+	ar, err := NewReader(r, format)
+	if err != nil {
+		return 0, err
+	}
+	defer ar.Close()
+
+	var count int64
+	for {
+		if _, err := ar.Read(); err != nil {
+			if err == io.EOF {
+				return count, nil
+			}
+			return count, err
+		}
+		count++
+	}
+}
+
+func recordToCSV(record *models.RideDataHistoryRecord) []string {
+	return []string{
+		strconv.FormatInt(record.ID, 10),
+		record.RideID,
+		record.ExternalID,
+		record.ParkID,
+		record.EntityType,
+		record.Name,
+		record.Status,
+		record.LastUpdated.UTC().Format(time.RFC3339Nano),
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+		record.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		record.OperatingHours,
+		formatOptionalInt(record.StandbyWaitTime),
+		formatNullableString(record.ReturnTimeState),
+		formatOptionalTime(record.ReturnStart),
+		formatOptionalTime(record.ReturnEnd),
+		record.Forecast,
+	}
+}
+
+func recordFromCSV(fields []string) (*models.RideDataHistoryRecord, error) {
+	record := &models.RideDataHistoryRecord{
+		RideID:         fields[1],
+		ExternalID:     fields[2],
+		ParkID:         fields[3],
+		EntityType:     fields[4],
+		Name:           fields[5],
+		Status:         fields[6],
+		OperatingHours: fields[10],
+		Forecast:       fields[15],
+	}
+
+	var err error
+	if record.ID, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid id %q: %w", fields[0], err)
+	}
+	if record.LastUpdated, err = time.Parse(time.RFC3339Nano, fields[7]); err != nil {
+		return nil, fmt.Errorf("invalid last_updated %q: %w", fields[7], err)
+	}
+	if record.CreatedAt, err = time.Parse(time.RFC3339Nano, fields[8]); err != nil {
+		return nil, fmt.Errorf("invalid created_at %q: %w", fields[8], err)
+	}
+	if record.UpdatedAt, err = time.Parse(time.RFC3339Nano, fields[9]); err != nil {
+		return nil, fmt.Errorf("invalid updated_at %q: %w", fields[9], err)
+	}
+	if record.StandbyWaitTime, err = parseOptionalInt(fields[11]); err != nil {
+		return nil, fmt.Errorf("invalid standby_wait_time %q: %w", fields[11], err)
+	}
+	if fields[12] != csvNull {
+		state := fields[12]
+		record.ReturnTimeState = &state
+	}
+	if record.ReturnStart, err = parseOptionalTime(fields[13]); err != nil {
+		return nil, fmt.Errorf("invalid return_start %q: %w", fields[13], err)
+	}
+	if record.ReturnEnd, err = parseOptionalTime(fields[14]); err != nil {
+		return nil, fmt.Errorf("invalid return_end %q: %w", fields[14], err)
+	}
+
+	return record, nil
+}
+
+func formatOptionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func formatNullableString(v *string) string {
+	if v == nil {
+		return csvNull
+	}
+	return *v
+}
+
+func formatOptionalTime(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.UTC().Format(time.RFC3339Nano)
+}
+
+func parseOptionalInt(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func parseOptionalTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRecords() []*models.RideDataHistoryRecord {
+	base := time.Date(2025, 9, 14, 18, 30, 0, 0, time.UTC)
+	wait := 45
+	state := "AVAILABLE"
+	start := base.Add(time.Hour)
+	empty := ""
+	return []*models.RideDataHistoryRecord{
+		{
+			ID:              101,
+			RideID:          "ride1",
+			ExternalID:      "ext1",
+			ParkID:          "park1",
+			EntityType:      "ATTRACTION",
+			Name:            "Space Mountain, \"Tomorrowland\"",
+			Status:          "OPERATING",
+			LastUpdated:     base,
+			CreatedAt:       base,
+			UpdatedAt:       base,
+			OperatingHours:  `[{"startTime":"2025-09-14T08:00:00Z"}]`,
+			StandbyWaitTime: &wait,
+			ReturnTimeState: &state,
+			ReturnStart:     &start,
+			Forecast:        "[]",
+		},
+		{
+			ID:             102,
+			RideID:         "ride2",
+			ExternalID:     "ext2",
+			ParkID:         "park1",
+			EntityType:     "ATTRACTION",
+			Name:           "Jungle Cruise",
+			Status:         "CLOSED",
+			LastUpdated:    base.Add(time.Minute),
+			CreatedAt:      base,
+			UpdatedAt:      base,
+			OperatingHours: "[]",
+			Forecast:       "[]",
+		},
+		{
+			ID:              103,
+			RideID:          "ride3",
+			ExternalID:      "ext3",
+			ParkID:          "park1",
+			EntityType:      "ATTRACTION",
+			Name:            "Haunted Mansion",
+			Status:          "OPERATING",
+			LastUpdated:     base.Add(2 * time.Minute),
+			CreatedAt:       base,
+			UpdatedAt:       base,
+			OperatingHours:  "[]",
+			ReturnTimeState: &empty,
+			Forecast:        "[]",
+		},
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatNDJSON, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+
+			records := sampleRecords()
+			for _, record := range records {
+				require.NoError(t, w.Write(record))
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, int64(len(records)), w.Count())
+
+			count, err := CountRecords(bytes.NewReader(buf.Bytes()), format)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(records)), count)
+
+			r, err := NewReader(bytes.NewReader(buf.Bytes()), format)
+			require.NoError(t, err)
+			defer r.Close()
+
+			for _, want := range records {
+				got, err := r.Read()
+				require.NoError(t, err)
+				assert.Equal(t, want.ID, got.ID)
+				assert.Equal(t, want.Name, got.Name)
+				assert.True(t, want.LastUpdated.Equal(got.LastUpdated))
+				assert.Equal(t, want.StandbyWaitTime, got.StandbyWaitTime)
+				assert.Equal(t, want.ReturnTimeState, got.ReturnTimeState)
+				assert.Equal(t, want.OperatingHours, got.OperatingHours)
+				if want.ReturnStart == nil {
+					assert.Nil(t, got.ReturnStart)
+				} else {
+					require.NotNil(t, got.ReturnStart)
+					assert.True(t, want.ReturnStart.Equal(*got.ReturnStart))
+				}
+			}
+
+			_, err = r.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat(" CSV ")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, f)
+
+	_, err = ParseFormat("parquet")
+	assert.Error(t, err)
+}
+
+func TestFormatFromPath(t *testing.T) {
+	f, err := FormatFromPath("/tmp/park1/2025-09/ride_data_history_20260101T000000Z.ndjson.gz")
+	require.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, f)
+
+	f, err = FormatFromPath("export.csv.gz")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, f)
+
+	_, err = FormatFromPath("export.txt")
+	assert.Error(t, err)
+}
+
+func TestFileName(t *testing.T) {
+	month := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	runAt := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+
+	name := FileName("park1", month, runAt, FormatCSV)
+	assert.Equal(t, filepath.Join("park1", "2025-09", "ride_data_history_20261019T020000Z.csv.gz"), name)
+}
@@ -0,0 +1,71 @@
+// Package auth authenticates service-to-service requests. An Authenticator checks one kind of
+// credential; Require accepts a request when any of them succeeds and rejects it otherwise.
+package auth
+
+import (
+	"context"
+	"errors"
+	"go-services/shared/response"
+	"log/slog"
+	"net/http"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries none of the credentials
+// it checks, so the next Authenticator can be tried
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal identifies an authenticated caller
+type Principal struct {
+	// Method is the authenticator that accepted the request, e.g. "hmac" or "oidc"
+	Method string
+	// Subject is the caller, such as the service account email of an OIDC token
+	Subject string
+}
+
+// Authenticator checks the credentials of a request. It returns ErrNoCredentials when its kind of
+// credential is absent and any other error when the credential is present but invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+type principalKey struct{}
+
+// PrincipalFrom returns the caller Require accepted
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Require only lets requests through that one of the authenticators accepts. Without authenticators
+// every request is rejected, so a missing configuration fails closed. CORS preflight requests carry
+// no credentials and are passed through.
+func Require(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, a := range authenticators {
+				principal, err := a.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					slog.WarnContext(r.Context(), "Rejected credentials", "method", r.Method, "path", r.URL.Path, "error", err)
+					unauthorized(w, r, "Invalid credentials")
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+				return
+			}
+			unauthorized(w, r, "Authentication required")
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL.Path+`"`)
+	response.WriteProblem(w, r, response.NewError(http.StatusUnauthorized, detail))
+}
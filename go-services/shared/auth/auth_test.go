@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var hmacSecret = []byte("s3cret")
+
+func signedRequest(t *testing.T, at time.Time, body string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/collect", strings.NewReader(body))
+	r.Header.Set(SignatureHeader, Sign(hmacSecret, at, "POST", "/collect", []byte(body)))
+	return r
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Unix(1757894400, 0)
+	h := NewHMAC(hmacSecret, 5*time.Minute)
+	h.now = func() time.Time { return now }
+
+	r := signedRequest(t, now.Add(-time.Minute), `{"parkIds":["a"]}`)
+	p, err := h.Authenticate(r)
+	if err != nil || p.Method != "hmac" {
+		t.Fatalf("Expected a valid signature, got %+v (%v)", p, err)
+	}
+	if body, _ := io.ReadAll(r.Body); string(body) != `{"parkIds":["a"]}` {
+		t.Errorf("Expected the body to be restored, got %q", body)
+	}
+
+	tampered := signedRequest(t, now, `{"parkIds":["a"]}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"parkIds":["b"]}`))
+	otherPath := signedRequest(t, now, "")
+	otherPath.URL.Path = "/other"
+	wrongSecret := httptest.NewRequest("POST", "/collect", nil)
+	wrongSecret.Header.Set(SignatureHeader, Sign([]byte("guess"), now, "POST", "/collect", nil))
+	malformed := httptest.NewRequest("POST", "/collect", nil)
+	malformed.Header.Set(SignatureHeader, "v1=abc")
+
+	for name, r := range map[string]*http.Request{
+		"tampered body": tampered,
+		"other path":    otherPath,
+		"wrong secret":  wrongSecret,
+		"stale":         signedRequest(t, now.Add(-10*time.Minute), ""),
+		"future":        signedRequest(t, now.Add(10*time.Minute), ""),
+		"malformed":     malformed,
+	} {
+		if _, err := h.Authenticate(r); err == nil || err == ErrNoCredentials {
+			t.Errorf("%s: expected the signature to be rejected, got %v", name, err)
+		}
+	}
+
+	if _, err := h.Authenticate(httptest.NewRequest("POST", "/collect", nil)); err != ErrNoCredentials {
+		t.Errorf("Expected ErrNoCredentials without a signature, got %v", err)
+	}
+}
+
+// testKeys is a local key set with one RSA and one EC key
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+	jwks []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "alg": "RS256", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": b64(rsaKey.N), "e": "AQAB"},
+		{"kty": "oct", "kid": "sym-1", "k": "c2VjcmV0"},
+	}})
+	return testKeys{rsa: rsaKey, ec: ecKey, jwks: jwks}
+}
+
+func (k testKeys) token(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	tok.Header["kid"] = kid
+	var key any = k.rsa
+	if method == jwt.SigningMethodES256 {
+		key = k.ec
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            GoogleIssuer,
+		"aud":            "https://collector.example.com",
+		"sub":            "1234",
+		"email":          "scheduler@project.iam.gserviceaccount.com",
+		"email_verified": true,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest("POST", "/collect", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys, err := ParseJWKS(newTestKeys(t).jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys["rsa-1"] == nil || keys["ec-1"] == nil {
+		t.Errorf("Expected only the RSA and EC signature keys, got %v", keys)
+	}
+	if _, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AQ","y":"AQ"}]}`)); err == nil {
+		t.Error("Expected a point off the curve to be rejected")
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	k := newTestKeys(t)
+	keys, err := ParseJWKS(k.jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := NewOIDC(OIDCConfig{
+		Issuer:        GoogleIssuer,
+		Audience:      "https://collector.example.com",
+		AllowedEmails: []string{"Scheduler@project.iam.gserviceaccount.com"},
+		Keys:          keys,
+	})
+
+	for name, method := range map[string]jwt.SigningMethod{"RS256": jwt.SigningMethodRS256, "ES256": jwt.SigningMethodES256} {
+		kid := "rsa-1"
+		if method == jwt.SigningMethodES256 {
+			kid = "ec-1"
+		}
+		p, err := o.Authenticate(bearer(k.token(t, method, kid, validClaims())))
+		if err != nil || p.Method != "oidc" || p.Subject != "scheduler@project.iam.gserviceaccount.com" {
+			t.Errorf("%s: expected the token to be accepted, got %+v (%v)", name, p, err)
+		}
+	}
+
+	with := func(key string, value any) jwt.MapClaims {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+	rejected := map[string]string{
+		"wrong audience":  k.token(t, jwt.SigningMethodRS256, "rsa-1", with("aud", "https://other.example.com")),
+		"wrong issuer":    k.token(t, jwt.SigningMethodRS256, "rsa-1", with("iss", "https://evil.example.com")),
+		"expired":         k.token(t, jwt.SigningMethodRS256, "rsa-1", with("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":       k.token(t, jwt.SigningMethodRS256, "rsa-1", with("exp", nil)),
+		"other email":     k.token(t, jwt.SigningMethodRS256, "rsa-1", with("email", "someone@example.com")),
+		"unverified":      k.token(t, jwt.SigningMethodRS256, "rsa-1", with("email_verified", false)),
+		"unknown key":     k.token(t, jwt.SigningMethodRS256, "rsa-2", validClaims()),
+		"wrong key":       k.token(t, jwt.SigningMethodRS256, "ec-1", validClaims()),
+		"HMAC algorithm":  signHS256(t, validClaims()),
+		"not a JWT":       "abc.def.ghi",
+		"unsigned (none)": unsigned(t, validClaims()),
+	}
+	for name, token := range rejected {
+		if _, err := o.Authenticate(bearer(token)); err == nil || err == ErrNoCredentials {
+			t.Errorf("%s: expected the token to be rejected, got %v", name, err)
+		}
+	}
+
+	basic := httptest.NewRequest("POST", "/collect", nil)
+	basic.SetBasicAuth("user", "pass")
+	if _, err := o.Authenticate(basic); err != ErrNoCredentials {
+		t.Errorf("Expected ErrNoCredentials for basic auth, got %v", err)
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func unsigned(t *testing.T, claims jwt.MapClaims) string {
+	s, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	k := newTestKeys(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(k.jwks)
+	}))
+	t.Cleanup(server.Close)
+
+	keys := NewRemoteKeySet(server.URL)
+	o := NewOIDC(OIDCConfig{Issuer: GoogleIssuer, Audience: "https://collector.example.com", Keys: keys})
+	for i := 0; i < 3; i++ {
+		if _, err := o.Authenticate(bearer(k.token(t, jwt.SigningMethodRS256, "rsa-1", validClaims()))); err != nil {
+			t.Fatalf("Expected the token to be accepted: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the key set to be fetched once, got %d", fetches.Load())
+	}
+
+	// Unknown key IDs refetch, but no more than once per minRefetch
+	for i := 0; i < 3; i++ {
+		o.Authenticate(bearer(k.token(t, jwt.SigningMethodRS256, "rotated", validClaims())))
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected unknown keys within minRefetch not to refetch, got %d fetches", fetches.Load())
+	}
+	keys.minRefetch = 0
+	o.Authenticate(bearer(k.token(t, jwt.SigningMethodRS256, "rotated", validClaims())))
+	if fetches.Load() != 2 {
+		t.Errorf("Expected an unknown key to refetch the set, got %d fetches", fetches.Load())
+	}
+}
+
+func TestRequire(t *testing.T) {
+	now := time.Now()
+	var got Principal
+	handler := Require(NewHMAC(hmacSecret, time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFrom(r.Context())
+	}))
+
+	badSignature := httptest.NewRequest("POST", "/collect", nil)
+	badSignature.Header.Set(SignatureHeader, Sign([]byte("guess"), now, "POST", "/collect", nil))
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"signed", signedRequest(t, now, "{}"), http.StatusOK},
+		{"no credentials", httptest.NewRequest("POST", "/collect", nil), http.StatusUnauthorized},
+		{"invalid credentials", badSignature, http.StatusUnauthorized},
+		{"preflight", httptest.NewRequest("OPTIONS", "/collect", nil), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = Principal{}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+			if tt.name == "signed" && got.Method != "hmac" {
+				t.Errorf("Expected the principal in the context, got %+v", got)
+			}
+		})
+	}
+
+	// Without authenticators every request is rejected
+	w := httptest.NewRecorder()
+	Require()(handler).ServeHTTP(w, signedRequest(t, now, "{}"))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unconfigured Require to fail closed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GoogleIssuer and GoogleJWKSURL verify the OIDC tokens Cloud Scheduler and other Google services
+// attach to requests
+const (
+	GoogleIssuer  = "https://accounts.google.com"
+	GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// OIDCConfig configures an OIDC authenticator
+type OIDCConfig struct {
+	// Issuer must match the iss claim
+	Issuer string
+	// Audience must be one of the aud values, typically the URL of the service
+	Audience string
+	// AllowedEmails, when not empty, limits callers to these verified email claims
+	AllowedEmails []string
+	// Keys verifies token signatures
+	Keys KeySet
+}
+
+// OIDC accepts requests with an "Authorization: Bearer" ID token signed by a key in the key set
+type OIDC struct {
+	cfg     OIDCConfig
+	allowed map[string]bool
+	parser  *jwt.Parser
+}
+
+// NewOIDC creates an OIDC authenticator
+func NewOIDC(cfg OIDCConfig) *OIDC {
+	o := &OIDC{
+		cfg: cfg,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{"RS256", "ES256"}),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(time.Minute),
+		),
+	}
+	if len(cfg.AllowedEmails) > 0 {
+		o.allowed = make(map[string]bool, len(cfg.AllowedEmails))
+		for _, email := range cfg.AllowedEmails {
+			o.allowed[strings.ToLower(email)] = true
+		}
+	}
+	return o
+}
+
+// idClaims are the registered claims plus the email claims of Google ID tokens
+type idClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// Authenticate verifies the bearer token's signature, issuer, audience and lifetime
+func (o *OIDC) Authenticate(r *http.Request) (Principal, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	var claims idClaims
+	_, err := o.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.cfg.Keys.Key(r.Context(), kid)
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	subject := claims.Subject
+	if o.allowed != nil {
+		if !claims.EmailVerified || !o.allowed[strings.ToLower(claims.Email)] {
+			return Principal{}, errors.New("token email is not allowed")
+		}
+		subject = claims.Email
+	}
+	return Principal{Method: "oidc", Subject: subject}, nil
+}
@@ -0,0 +1,122 @@
+package circuit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a Breaker
+type State string
+
+const (
+	// Closed lets every call through
+	Closed State = "closed"
+	// Open rejects calls until the cooldown has passed
+	Open State = "open"
+	// HalfOpen lets a single trial call through after the cooldown
+	HalfOpen State = "half-open"
+)
+
+// Breaker stops calls to a dependency after Threshold consecutive failures. Once Cooldown has
+// passed one trial call is let through; its success closes the breaker and its failure opens it
+// again.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	failures    int
+	openedAt    time.Time
+	trialActive bool
+	lastErr     error
+}
+
+// New creates a closed breaker
+func New(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now, state: Closed}
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen if not. Every allowed call must be
+// followed by Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case Closed:
+		return nil
+	case HalfOpen:
+		if b.trialActive {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+		b.trialActive = true
+		return nil
+	default:
+		return ErrOpen
+	}
+}
+
+// Success records a successful call and closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = Closed
+	b.failures = 0
+	b.trialActive = false
+	b.lastErr = nil
+}
+
+// Failure records a failed call, opening the breaker when the threshold is reached or a trial
+// call failed
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state = Open
+		b.openedAt = b.now()
+		b.trialActive = false
+	}
+}
+
+// Snapshot describes a breaker for health reports
+type Snapshot struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Snapshot returns the current state of the breaker
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := Snapshot{State: b.currentState(), ConsecutiveFailures: b.failures}
+	if s.State != Closed {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	if b.lastErr != nil {
+		s.LastError = b.lastErr.Error()
+	}
+	return s
+}
+
+// currentState reports an open breaker whose cooldown has passed as half-open
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
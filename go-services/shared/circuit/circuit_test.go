@@ -0,0 +1,55 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	b := New(3, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := errors.New("upstream returned 503")
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Expected call %d to be allowed, got %v", i, err)
+		}
+		b.Failure(failure)
+	}
+	if s := b.Snapshot(); s.State != Closed || s.ConsecutiveFailures != 2 {
+		t.Fatalf("Expected a closed breaker below the threshold, got %+v", s)
+	}
+
+	b.Allow()
+	b.Failure(failure)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Expected the breaker to open after 3 failures, got %v", err)
+	}
+	if s := b.Snapshot(); s.State != Open || s.OpenedAt == nil || s.LastError != failure.Error() {
+		t.Errorf("Unexpected snapshot %+v", s)
+	}
+
+	now = now.Add(time.Minute)
+	if s := b.Snapshot(); s.State != HalfOpen {
+		t.Fatalf("Expected half-open after the cooldown, got %s", s.State)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the cooldown, got %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Expected only one trial call, got %v", err)
+	}
+	b.Failure(failure)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Expected a failed trial to reopen the breaker, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Success()
+	if s := b.Snapshot(); s.State != Closed || s.ConsecutiveFailures != 0 || s.OpenedAt != nil || s.LastError != "" {
+		t.Errorf("Expected a successful trial to close the breaker, got %+v", s)
+	}
+}
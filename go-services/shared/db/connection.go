@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"go-services/shared/tracing"
 	"os"
 	"strings"
 	"time"
@@ -65,6 +66,8 @@ func NewConnection(ctx context.Context, config *Config) (*pgxpool.Pool, error) {
 	poolConfig.MinConns = config.MinConns
 	poolConfig.MaxConnLifetime = config.MaxConnLifetime
 	poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	// Trace every query as a span of the request that ran it
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	// Create the connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
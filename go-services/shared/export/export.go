@@ -0,0 +1,357 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-services/shared/models"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format identifies the encoding of an export
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+const (
+	// parquetRowGroupSize bounds how many rows a parquet export buffers before writing a row group
+	parquetRowGroupSize = 10000
+	// parquetWriteBatch is how many rows are handed to the parquet writer at a time
+	parquetWriteBatch = 1024
+)
+
+// ParseFormat converts a user supplied format name into a Format
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q (expected csv, ndjson or parquet)", s)
+	}
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Compressible reports whether the output benefits from gzip. Parquet pages are already compressed.
+func (f Format) Compressible() bool {
+	return f != FormatParquet
+}
+
+// columnKind is the value type of a column, which decides its parquet type and text encoding
+type columnKind int
+
+const (
+	kindInt64 columnKind = iota
+	kindString
+	kindTime
+	kindOptionalInt
+	kindOptionalString
+	kindOptionalTime
+)
+
+// Column is one exportable field of a ride data history record
+type Column struct {
+	Name  string
+	kind  columnKind
+	value func(*models.RideDataHistoryRecord) any
+}
+
+// columns lists every exportable column in table order. Names match the database columns.
+var columns = []Column{
+	{"id", kindInt64, func(r *models.RideDataHistoryRecord) any { return r.ID }},
+	{"ride_id", kindString, func(r *models.RideDataHistoryRecord) any { return r.RideID }},
+	{"external_id", kindString, func(r *models.RideDataHistoryRecord) any { return r.ExternalID }},
+	{"park_id", kindString, func(r *models.RideDataHistoryRecord) any { return r.ParkID }},
+	{"entity_type", kindString, func(r *models.RideDataHistoryRecord) any { return r.EntityType }},
+	{"name", kindString, func(r *models.RideDataHistoryRecord) any { return r.Name }},
+	{"status", kindString, func(r *models.RideDataHistoryRecord) any { return r.Status }},
+	{"last_updated", kindTime, func(r *models.RideDataHistoryRecord) any { return r.LastUpdated }},
+	{"created_at", kindTime, func(r *models.RideDataHistoryRecord) any { return r.CreatedAt }},
+	{"updated_at", kindTime, func(r *models.RideDataHistoryRecord) any { return r.UpdatedAt }},
+	{"operating_hours", kindString, func(r *models.RideDataHistoryRecord) any { return r.OperatingHours }},
+	{"standby_wait_time", kindOptionalInt, func(r *models.RideDataHistoryRecord) any { return r.StandbyWaitTime }},
+	{"return_time_state", kindOptionalString, func(r *models.RideDataHistoryRecord) any { return r.ReturnTimeState }},
+	{"return_start", kindOptionalTime, func(r *models.RideDataHistoryRecord) any { return r.ReturnStart }},
+	{"return_end", kindOptionalTime, func(r *models.RideDataHistoryRecord) any { return r.ReturnEnd }},
+	{"forecast", kindString, func(r *models.RideDataHistoryRecord) any { return r.Forecast }},
+}
+
+// ColumnNames returns the name of every exportable column in table order
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// ParseColumns resolves a comma separated list of column names, keeping the requested order.
+// An empty list selects every column.
+func ParseColumns(s string) ([]Column, error) {
+	if strings.TrimSpace(s) == "" {
+		return columns, nil
+	}
+
+	byName := make(map[string]Column, len(columns))
+	for _, column := range columns {
+		byName[column.Name] = column
+	}
+
+	var selected []Column
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (expected any of %s)", name, strings.Join(ColumnNames(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is listed twice", name)
+		}
+		seen[name] = true
+		selected = append(selected, column)
+	}
+	return selected, nil
+}
+
+// Writer encodes ride data history records one at a time
+type Writer interface {
+	Write(record *models.RideDataHistoryRecord) error
+	// Close flushes buffered data. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a Writer for the selected columns in the given format
+func NewWriter(w io.Writer, format Format, selected []Column) (Writer, error) {
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+
+	switch format {
+	case FormatCSV:
+		cw := &csvWriter{csv: csv.NewWriter(w), columns: selected}
+		header := make([]string, len(selected))
+		for i, column := range selected {
+			header[i] = column.Name
+		}
+		if err := cw.csv.Write(header); err != nil {
+			return nil, fmt.Errorf("failed to write csv header: %w", err)
+		}
+		return cw, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{buf: bufio.NewWriter(w), columns: selected}, nil
+	case FormatParquet:
+		return newParquetWriter(w, selected), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+type csvWriter struct {
+	csv     *csv.Writer
+	columns []Column
+	fields  []string
+}
+
+func (w *csvWriter) Write(record *models.RideDataHistoryRecord) error {
+	w.fields = w.fields[:0]
+	for _, column := range w.columns {
+		w.fields = append(w.fields, formatText(column.value(record)))
+	}
+	if err := w.csv.Write(w.fields); err != nil {
+		return fmt.Errorf("failed to write record %d: %w", record.ID, err)
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// formatText renders a column value for CSV; null values become empty fields
+func formatText(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *int:
+		if v != nil {
+			return strconv.Itoa(*v)
+		}
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case *time.Time:
+		if v != nil {
+			return v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
+
+// ndjsonWriter writes one JSON object per line with the keys in column order
+type ndjsonWriter struct {
+	buf     *bufio.Writer
+	columns []Column
+}
+
+func (w *ndjsonWriter) Write(record *models.RideDataHistoryRecord) error {
+	w.buf.WriteByte('{')
+	for i, column := range w.columns {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.buf.WriteString(strconv.Quote(column.Name))
+		w.buf.WriteByte(':')
+
+		v := column.value(record)
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		} else if t, ok := v.(*time.Time); ok && t != nil {
+			v = t.UTC()
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s of record %d: %w", column.Name, record.ID, err)
+		}
+		w.buf.Write(value)
+	}
+	// Write errors stick to the bufio.Writer and surface on the next flush
+	_, err := w.buf.WriteString("}\n")
+	return err
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buf.Flush()
+}
+
+// parquetWriter writes rows against a schema built from the selected columns. Rows are buffered
+// into row groups of parquetRowGroupSize, so memory stays bounded however long the export is.
+type parquetWriter struct {
+	writer  *parquet.Writer
+	columns []Column
+	// indexes maps each selected column to its leaf index in the schema, which orders fields by name
+	indexes []int
+	rows    []parquet.Row
+}
+
+func newParquetWriter(w io.Writer, selected []Column) *parquetWriter {
+	group := make(parquet.Group, len(selected))
+	for _, column := range selected {
+		group[column.Name] = parquetNode(column.kind)
+	}
+	schema := parquet.NewSchema("ride_data_history", group)
+
+	pw := &parquetWriter{
+		writer: parquet.NewWriter(w, schema,
+			parquet.Compression(&parquet.Zstd),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+		),
+		columns: selected,
+		indexes: make([]int, len(selected)),
+	}
+	for i, column := range selected {
+		leaf, _ := schema.Lookup(column.Name)
+		pw.indexes[i] = leaf.ColumnIndex
+	}
+	return pw
+}
+
+func parquetNode(kind columnKind) parquet.Node {
+	switch kind {
+	case kindInt64:
+		return parquet.Int(64)
+	case kindTime:
+		return parquet.Timestamp(parquet.Microsecond)
+	case kindOptionalInt:
+		return parquet.Optional(parquet.Int(32))
+	case kindOptionalString:
+		return parquet.Optional(parquet.String())
+	case kindOptionalTime:
+		return parquet.Optional(parquet.Timestamp(parquet.Microsecond))
+	default:
+		return parquet.String()
+	}
+}
+
+// parquetValue converts a column value; optional columns have a definition level of 1 when set
+func parquetValue(v any, index int) parquet.Value {
+	switch v := v.(type) {
+	case int64:
+		return parquet.Int64Value(v).Level(0, 0, index)
+	case string:
+		return parquet.ByteArrayValue([]byte(v)).Level(0, 0, index)
+	case time.Time:
+		return parquet.Int64Value(v.UnixMicro()).Level(0, 0, index)
+	case *int:
+		if v != nil {
+			return parquet.Int32Value(int32(*v)).Level(0, 1, index)
+		}
+	case *string:
+		if v != nil {
+			return parquet.ByteArrayValue([]byte(*v)).Level(0, 1, index)
+		}
+	case *time.Time:
+		if v != nil {
+			return parquet.Int64Value(v.UnixMicro()).Level(0, 1, index)
+		}
+	}
+	return parquet.NullValue().Level(0, 0, index)
+}
+
+func (w *parquetWriter) Write(record *models.RideDataHistoryRecord) error {
+	row := make(parquet.Row, len(w.columns))
+	for i, column := range w.columns {
+		index := w.indexes[i]
+		row[index] = parquetValue(column.value(record), index)
+	}
+	w.rows = append(w.rows, row)
+	if len(w.rows) >= parquetWriteBatch {
+		return w.flushRows()
+	}
+	return nil
+}
+
+func (w *parquetWriter) flushRows() error {
+	if _, err := w.writer.WriteRows(w.rows); err != nil {
+		return fmt.Errorf("failed to write parquet rows: %w", err)
+	}
+	w.rows = w.rows[:0]
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flushRows(); err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
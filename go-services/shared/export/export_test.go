@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"go-services/shared/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func sampleRecords() []*models.RideDataHistoryRecord {
+	at := time.Date(2025, 9, 14, 12, 30, 0, 123456000, time.UTC)
+	wait := 35
+	state := "AVAILABLE"
+	return []*models.RideDataHistoryRecord{
+		{
+			ID: 1, RideID: "ride-1", ParkID: "park-1", Name: "Space Mountain", Status: "OPERATING",
+			LastUpdated: at, StandbyWaitTime: &wait, ReturnTimeState: &state, ReturnStart: &at,
+		},
+		{
+			ID: 2, RideID: "ride-2", ParkID: "park-1", Name: "Matterhorn, \"Bobsleds\"", Status: "CLOSED",
+			LastUpdated: at.Add(time.Minute),
+		},
+	}
+}
+
+func writeAll(t *testing.T, format Format, selection string) []byte {
+	t.Helper()
+	selected, err := ParseColumns(selection)
+	if err != nil {
+		t.Fatalf("ParseColumns failed: %v", err)
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, selected)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	for _, record := range sampleRecords() {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVExport(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewReader(writeAll(t, FormatCSV, "name,standby_wait_time,last_updated"))).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		{"name", "standby_wait_time", "last_updated"},
+		{"Space Mountain", "35", "2025-09-14T12:30:00.123456Z"},
+		{"Matterhorn, \"Bobsleds\"", "", "2025-09-14T12:31:00.123456Z"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("Row %d: expected %v, got %v", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestNDJSONExport(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(writeAll(t, FormatNDJSON, "ride_id,standby_wait_time,return_start")), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", lines)
+	}
+	if lines[0] != `{"ride_id":"ride-1","standby_wait_time":35,"return_start":"2025-09-14T12:30:00.123456Z"}` {
+		t.Errorf("Unexpected first line %s", lines[0])
+	}
+	var second map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("Second line is not JSON: %v", err)
+	}
+	if second["standby_wait_time"] != nil || second["return_start"] != nil || len(second) != 3 {
+		t.Errorf("Expected nulls for unset columns, got %v", second)
+	}
+}
+
+func TestParquetExport(t *testing.T) {
+	data := writeAll(t, FormatParquet, "status,id,standby_wait_time,last_updated,return_time_state")
+
+	type row struct {
+		ID              int64     `parquet:"id"`
+		Status          string    `parquet:"status"`
+		StandbyWaitTime *int32    `parquet:"standby_wait_time,optional"`
+		LastUpdated     time.Time `parquet:"last_updated,timestamp(microsecond)"`
+		ReturnTimeState *string   `parquet:"return_time_state,optional"`
+	}
+	rows, err := parquet.Read[row](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Output is not readable parquet: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	first, second := rows[0], rows[1]
+	if first.ID != 1 || first.Status != "OPERATING" || first.StandbyWaitTime == nil || *first.StandbyWaitTime != 35 ||
+		first.ReturnTimeState == nil || *first.ReturnTimeState != "AVAILABLE" {
+		t.Errorf("Unexpected first row %+v", first)
+	}
+	if !first.LastUpdated.Equal(sampleRecords()[0].LastUpdated) {
+		t.Errorf("Expected last_updated %v, got %v", sampleRecords()[0].LastUpdated, first.LastUpdated)
+	}
+	if second.ID != 2 || second.StandbyWaitTime != nil || second.ReturnTimeState != nil {
+		t.Errorf("Expected nulls in the second row, got %+v", second)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	all, err := ParseColumns("")
+	if err != nil || len(all) != len(ColumnNames()) {
+		t.Errorf("Expected every column for an empty selection, got %d (%v)", len(all), err)
+	}
+
+	selected, err := ParseColumns(" Name , id")
+	if err != nil || len(selected) != 2 || selected[0].Name != "name" || selected[1].Name != "id" {
+		t.Errorf("Expected name then id, got %v (%v)", selected, err)
+	}
+
+	for _, bad := range []string{"nope", "id,id", "id,"} {
+		if _, err := ParseColumns(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"csv", "NDJSON", " parquet "} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected an error for xlsx")
+	}
+}
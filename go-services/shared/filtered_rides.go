@@ -77,3 +77,15 @@ func GetParkInfo(parkID string) (ParkInfo, bool) {
 func GetAllParkInfos() map[string]ParkInfo {
 	return ParkNames
 }
+
+// FindFilteredRide looks up a ride in the filtered list and returns it with the ID of its park
+func FindFilteredRide(rideID string) (FilteredRide, string, bool) {
+	for parkID, rides := range FilteredAttractions {
+		for _, ride := range rides {
+			if ride.ID == rideID {
+				return ride, parkID, true
+			}
+		}
+	}
+	return FilteredRide{}, "", false
+}
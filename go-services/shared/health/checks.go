@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"go-services/shared/circuit"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Database pings the database and reports pool usage. The check warns once acquired connections
+// reach saturation (a fraction of the pool size).
+func Database(ping func(context.Context) error, stat func() *pgxpool.Stat, saturation float64) CheckFunc {
+	return func(ctx context.Context) Result {
+		if err := ping(ctx); err != nil {
+			return Result{Status: Fail, Message: err.Error()}
+		}
+		s := stat()
+		used := 0.0
+		if s.MaxConns() > 0 {
+			used = float64(s.AcquiredConns()) / float64(s.MaxConns())
+		}
+		result := Result{Status: Pass, Details: map[string]any{
+			"acquired_conns":         s.AcquiredConns(),
+			"idle_conns":             s.IdleConns(),
+			"total_conns":            s.TotalConns(),
+			"max_conns":              s.MaxConns(),
+			"empty_acquire_count":    s.EmptyAcquireCount(),
+			"acquire_wait_time_ms":   s.AcquireDuration().Milliseconds(),
+			"pool_saturation":        used,
+			"pool_saturation_target": saturation,
+		}}
+		if used >= saturation {
+			result.Status = Warn
+			result.Message = "connection pool is saturated"
+		}
+		return result
+	}
+}
+
+// Migrations fails while the newest applied migration is older than required. Migration names
+// start with a timestamp, so they order by name.
+func Migrations(latest func(context.Context) (string, error), required string) CheckFunc {
+	return func(ctx context.Context) Result {
+		applied, err := latest(ctx)
+		if err != nil {
+			return Result{Status: Fail, Message: err.Error()}
+		}
+		result := Result{Status: Pass, Details: map[string]any{"applied": applied, "required": required}}
+		if applied < required {
+			result.Status = Fail
+			result.Message = "database schema is behind the migration this build requires"
+		}
+		return result
+	}
+}
+
+// ParkFreshness is the freshness of one park's data
+type ParkFreshness struct {
+	Name        string     `json:"name"`
+	LastUpdated *time.Time `json:"last_updated"`
+	AgeSeconds  *int64     `json:"age_seconds"`
+	Stale       bool       `json:"stale"`
+}
+
+// Freshness fails when the newest ride data of any of parks (ID to name) is older than staleAfter
+func Freshness(latest func(context.Context) (map[string]time.Time, error), parks map[string]string, staleAfter time.Duration) CheckFunc {
+	return func(ctx context.Context) Result {
+		updates, err := latest(ctx)
+		if err != nil {
+			return Result{Status: Fail, Message: err.Error()}
+		}
+
+		now := time.Now()
+		report := make(map[string]ParkFreshness, len(parks))
+		var stale []string
+		for id, name := range parks {
+			p := ParkFreshness{Name: name, Stale: true}
+			if t, ok := updates[id]; ok {
+				age := int64(now.Sub(t).Seconds())
+				p.LastUpdated, p.AgeSeconds = &t, &age
+				p.Stale = now.Sub(t) > staleAfter
+			}
+			if p.Stale {
+				stale = append(stale, id)
+			}
+			report[id] = p
+		}
+		sort.Strings(stale)
+
+		result := Result{Status: Pass, Details: map[string]any{"stale_after_seconds": int64(staleAfter.Seconds()), "parks": report}}
+		if len(stale) > 0 {
+			result.Status = Fail
+			result.Message = "ride data is stale"
+			result.Details["stale_parks"] = stale
+		}
+		return result
+	}
+}
+
+// Upstream reports the circuit breaker of an upstream API and whether probe reaches it. Probe
+// results are cached for cacheTTL so readiness polling does not hammer the upstream. An unreachable
+// upstream or an open breaker only warns: the service itself is still able to serve.
+func Upstream(probe func(context.Context) error, breaker *circuit.Breaker, cacheTTL time.Duration) CheckFunc {
+	var mu sync.Mutex
+	var checkedAt time.Time
+	var lastErr error
+
+	return func(ctx context.Context) Result {
+		mu.Lock()
+		if checkedAt.IsZero() || time.Since(checkedAt) >= cacheTTL {
+			lastErr = probe(ctx)
+			checkedAt = time.Now()
+		}
+		probeErr, probedAt := lastErr, checkedAt
+		mu.Unlock()
+
+		snapshot := breaker.Snapshot()
+		result := Result{Status: Pass, Details: map[string]any{
+			"reachable": probeErr == nil,
+			"probed_at": probedAt.UTC(),
+			"circuit":   snapshot,
+		}}
+		switch {
+		case probeErr != nil:
+			result.Status = Warn
+			result.Message = probeErr.Error()
+		case snapshot.State != circuit.Closed:
+			result.Status = Warn
+			result.Message = "circuit breaker is " + string(snapshot.State)
+		}
+		return result
+	}
+}
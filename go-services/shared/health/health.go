@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check, following the draft IETF health check response format
+type Status string
+
+const (
+	Pass Status = "pass"
+	// Warn reports a degraded dependency that does not make the service unready
+	Warn Status = "warn"
+	// Fail makes /readyz answer 503
+	Fail Status = "fail"
+)
+
+// worse reports whether a is more severe than b
+func worse(a, b Status) bool {
+	rank := map[Status]int{Pass: 0, Warn: 1, Fail: 2}
+	return rank[a] > rank[b]
+}
+
+// Result is the outcome of one dependency check
+type Result struct {
+	Status  Status         `json:"status"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+	// DurationMS is filled in by the Checker
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// CheckFunc checks a dependency. It should return once ctx is done.
+type CheckFunc func(ctx context.Context) Result
+
+// Report is the body of /readyz
+type Report struct {
+	Status  Status            `json:"status"`
+	Service string            `json:"service"`
+	Version string            `json:"version,omitempty"`
+	Time    time.Time         `json:"time"`
+	Checks  map[string]Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the readiness checks of a service
+type Checker struct {
+	service string
+	version string
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// New creates a Checker whose checks each get timeout to finish
+func New(service, version string, timeout time.Duration) *Checker {
+	return &Checker{service: service, version: version, timeout: timeout}
+}
+
+// Add registers a check under name
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check concurrently. A check that does not return within the timeout fails.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: Pass, Service: c.service, Version: c.version, Time: time.Now().UTC(), Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.runOne(ctx, nc.check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if worse(result.Status, report.Status) {
+				report.Status = result.Status
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) runOne(ctx context.Context, check CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() { done <- check(ctx) }()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Result{Status: Fail, Message: "check timed out"}
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result
+}
+
+// ReadyHandler serves the report, with 503 when any check fails
+func (c *Checker) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		status := http.StatusOK
+		if report.Status == Fail {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+// LiveHandler reports that the process is up and serving requests. It checks no dependencies, so
+// an outage of the database never gets healthy instances restarted.
+func LiveHandler(service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, Report{Status: Pass, Service: service, Time: time.Now().UTC(), Checks: map[string]Result{}})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,135 @@
+// Package metrics exposes Prometheus metrics for the services: HTTP traffic per route, the
+// collector's upstream fetches and inserts, and the database connection pool. Metrics are registered
+// with the default registry, which also carries the Go runtime and process collectors, and served by
+// Handler.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served, including open streams.",
+	})
+)
+
+// Collector metrics, labelled by park ID
+var (
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "collector_fetch_duration_seconds",
+		Help:    "Duration of upstream live data fetches per park.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"park"})
+
+	upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "collector_upstream_errors_total",
+		Help: "Failed upstream live data fetches per park and reason (request, status, decode).",
+	}, []string{"park", "reason"})
+
+	recordsStored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "collector_records_total",
+		Help: "Ride records per park that were inserted or skipped because the ride state was unchanged.",
+	}, []string{"park", "result"})
+
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "collector_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful collection per park.",
+	}, []string{"park"})
+)
+
+// Reasons an upstream fetch fails
+const (
+	UpstreamRequest = "request"
+	UpstreamStatus  = "status"
+	UpstreamDecode  = "decode"
+	// UpstreamCircuitOpen counts fetches skipped because the circuit breaker is open
+	UpstreamCircuitOpen = "circuit_open"
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveFetch records the duration of one upstream fetch of a park
+func ObserveFetch(parkID string, d time.Duration) {
+	fetchDuration.WithLabelValues(parkID).Observe(d.Seconds())
+}
+
+// UpstreamError counts a failed upstream fetch of a park
+func UpstreamError(parkID, reason string) {
+	upstreamErrors.WithLabelValues(parkID, reason).Inc()
+}
+
+// RecordsStored counts the outcome of storing a park's records and marks the collection successful
+func RecordsStored(parkID string, inserted, skipped int, at time.Time) {
+	recordsStored.WithLabelValues(parkID, "inserted").Add(float64(inserted))
+	recordsStored.WithLabelValues(parkID, "skipped").Add(float64(skipped))
+	lastSuccess.WithLabelValues(parkID).Set(float64(at.Unix()))
+}
+
+// Instrument records the duration and status of every request served by mux. Requests are labelled
+// with the ServeMux pattern that matched them, such as "GET /v1/rides/{id}", which keeps the number
+// of series bounded; requests no pattern matched are labelled "unmatched".
+//
+// Instrument must wrap the mux directly: the mux records the pattern on the request it is given,
+// which is where Instrument reads it after the handler returns.
+func Instrument(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		httpRequestsInFlight.Inc()
+		defer func() {
+			httpRequestsInFlight.Dec()
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			httpRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		}()
+		mux.ServeHTTP(sw, r)
+	})
+}
+
+// statusWriter remembers the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach Flush and Hijack of the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
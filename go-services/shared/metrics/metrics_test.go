@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestInstrument(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rides/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := Instrument(mux)
+
+	for _, target := range []string{"/rides/a", "/rides/b", "/rides/missing", "/elsewhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+
+	tests := []struct {
+		route, status string
+		want          int
+	}{
+		{"GET /rides/{id}", "200", 2},
+		{"GET /rides/{id}", "404", 1},
+		{"unmatched", "404", 1},
+	}
+	for _, tt := range tests {
+		if got := histogramCount(t, httpRequestDuration.WithLabelValues("GET", tt.route, tt.status)); got != tt.want {
+			t.Errorf("Expected %d requests for %s %s, got %d", tt.want, tt.route, tt.status, got)
+		}
+	}
+	if got := testutil.ToFloat64(httpRequestsInFlight); got != 0 {
+		t.Errorf("Expected no requests in flight, got %v", got)
+	}
+}
+
+func histogramCount(t *testing.T, o prometheus.Observer) int {
+	t.Helper()
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return int(m.GetHistogram().GetSampleCount())
+}
+
+func TestCollectorMetrics(t *testing.T) {
+	at := time.Unix(1760870000, 0)
+	RecordsStored("park-1", 3, 7, at)
+	UpstreamError("park-1", UpstreamStatus)
+
+	if got := testutil.ToFloat64(recordsStored.WithLabelValues("park-1", "inserted")); got != 3 {
+		t.Errorf("Expected 3 inserted, got %v", got)
+	}
+	if got := testutil.ToFloat64(recordsStored.WithLabelValues("park-1", "skipped")); got != 7 {
+		t.Errorf("Expected 7 skipped, got %v", got)
+	}
+	if got := testutil.ToFloat64(lastSuccess.WithLabelValues("park-1")); got != 1760870000 {
+		t.Errorf("Expected the last success timestamp, got %v", got)
+	}
+	if got := testutil.ToFloat64(upstreamErrors.WithLabelValues("park-1", UpstreamStatus)); got != 1 {
+		t.Errorf("Expected one upstream error, got %v", got)
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	// The pool connects lazily, so no database is needed to read its statistics
+	pool, err := pgxpool.New(context.Background(), "postgres://test@127.0.0.1:1/test?pool_max_conns=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c := newPoolCollector(pool.Stat)
+	if got := testutil.CollectAndCount(c); got != 12 {
+		t.Errorf("Expected 12 pool metrics, got %d", got)
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP pgxpool_max_conns Maximum size of the pool.
+# TYPE pgxpool_max_conns gauge
+pgxpool_max_conns 4
+`), "pgxpool_max_conns"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the cross-origin policy of a service
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://example.com". "*" allows any origin, and a single
+	// "*" in the host matches any run of letters, digits, hyphens and dots, so
+	// "https://*.example.com" allows every subdomain and "https://app-*.vercel.app" allows preview
+	// deployments. The part after the "*" must contain a dot, which keeps matches under a fixed domain.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers browser scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP auth. The request origin is echoed instead
+	// of "*" then, as browsers require.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// Validate reports origins that can never match
+func (c CORSConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("invalid CORS origin %q, expected scheme://host[:port]", origin)
+		}
+		if n := strings.Count(host, "*"); n > 1 {
+			return fmt.Errorf("invalid CORS origin %q, only one * is allowed", origin)
+		} else if n == 1 {
+			if _, suffix, _ := strings.Cut(host, "*"); !strings.Contains(suffix, ".") {
+				return fmt.Errorf("invalid CORS origin %q, the * must be followed by a domain", origin)
+			}
+		}
+	}
+	return nil
+}
+
+// CORSFromEnv overrides the defaults with CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS,
+// CORS_ALLOWED_HEADERS and CORS_EXPOSED_HEADERS (comma separated lists), CORS_ALLOW_CREDENTIALS
+// (true/false) and CORS_MAX_AGE (seconds). CORS_ALLOWED_ORIGINS="" keeps the defaults; set it to "-"
+// to disallow every cross-origin request.
+func CORSFromEnv(defaults CORSConfig) (CORSConfig, error) {
+	cfg := defaults
+	if v := strings.TrimSpace(os.Getenv("CORS_ALLOWED_ORIGINS")); v == "-" {
+		cfg.AllowedOrigins = nil
+	} else if v != "" {
+		cfg.AllowedOrigins = splitList(v)
+	}
+	if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+		cfg.AllowedMethods = splitList(strings.ToUpper(v))
+	}
+	if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+		cfg.AllowedHeaders = splitList(v)
+	}
+	if v := os.Getenv("CORS_EXPOSED_HEADERS"); v != "" {
+		cfg.ExposedHeaders = splitList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("CORS_ALLOW_CREDENTIALS")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("CORS_ALLOW_CREDENTIALS must be true or false, got %q", v)
+		}
+		cfg.AllowCredentials = b
+	}
+	if v := strings.TrimSpace(os.Getenv("CORS_MAX_AGE")); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return cfg, fmt.Errorf("CORS_MAX_AGE must be a non-negative number of seconds, got %q", v)
+		}
+		cfg.MaxAge = time.Duration(seconds) * time.Second
+	}
+	return cfg, cfg.Validate()
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// originPattern is an allowed origin with an optional wildcard between prefix and suffix
+type originPattern struct {
+	prefix, suffix string
+	wildcard       bool
+}
+
+func (p originPattern) match(origin string) bool {
+	if !p.wildcard {
+		return origin == p.prefix
+	}
+	if len(origin) <= len(p.prefix)+len(p.suffix) || !strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	for _, c := range origin[len(p.prefix) : len(origin)-len(p.suffix)] {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// CORS applies a CORSConfig. It answers every OPTIONS request itself, so handlers never see
+// preflights.
+type CORS struct {
+	cfg      CORSConfig
+	any      bool
+	patterns []originPattern
+	methods  string
+	headers  string
+	exposed  string
+	maxAge   string
+}
+
+// NewCORS creates the middleware for cfg
+func NewCORS(cfg CORSConfig) *CORS {
+	c := &CORS{
+		cfg:     cfg,
+		methods: strings.Join(cfg.AllowedMethods, ", "),
+		headers: strings.Join(cfg.AllowedHeaders, ", "),
+		exposed: strings.Join(cfg.ExposedHeaders, ", "),
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			c.any = true
+			continue
+		}
+		prefix, suffix, wildcard := strings.Cut(origin, "*")
+		c.patterns = append(c.patterns, originPattern{prefix: prefix, suffix: suffix, wildcard: wildcard})
+	}
+	return c
+}
+
+// AllowsOrigin reports whether browsers on origin may call the service
+func (c *CORS) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, p := range c.patterns {
+		if p.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware sets the CORS headers for allowed origins and answers preflight requests
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		origin := r.Header.Get("Origin")
+		// Responses differ by origin unless every origin gets "*", so caches must key on it
+		if !c.any || c.cfg.AllowCredentials {
+			h.Add("Vary", "Origin")
+		}
+
+		allowed := c.AllowsOrigin(origin)
+		if allowed {
+			if c.any && !c.cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if c.cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions {
+			if allowed && c.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", c.exposed)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if allowed {
+			if c.methods != "" {
+				h.Set("Access-Control-Allow-Methods", c.methods)
+			}
+			if c.headers != "" {
+				h.Set("Access-Control-Allow-Headers", c.headers)
+			}
+			if c.maxAge != "" {
+				h.Set("Access-Control-Max-Age", c.maxAge)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"encoding/json"
+	"go-services/shared/response"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"forwarded", "abc-123.def", true},
+		{"rejected", "bad id\n", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(response.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(response.RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("Expected the header and context to carry the same ID, got %q and %q", got, seen)
+			}
+			if (got == tt.incoming) != tt.keep {
+				t.Errorf("Incoming %q: unexpected ID %q", tt.incoming, got)
+			}
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("fine"))
+	})
+	mux.HandleFunc("GET /teapot", func(w http.ResponseWriter, r *http.Request) {
+		response.WriteProblem(w, r, response.NewError(http.StatusTeapot, "short and stout"))
+	})
+	handler := RequestID(Problems(mux))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		detail string
+	}{
+		{"not found", "GET", "/missing", http.StatusNotFound, "Not Found"},
+		{"method not allowed", "DELETE", "/ok", http.StatusMethodNotAllowed, "Method Not Allowed"},
+		{"problem passes through", "GET", "/teapot", http.StatusTeapot, "short and stout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.status || w.Header().Get("Content-Type") != response.ProblemContentType {
+				t.Fatalf("Expected a %d problem, got %d %v", tt.status, w.Code, w.Header())
+			}
+			var problem response.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Body is not a single problem document: %q", w.Body.String())
+			}
+			if problem.Detail != tt.detail || problem.RequestID != w.Header().Get(response.RequestIDHeader) {
+				t.Errorf("Unexpected problem %+v", problem)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/ok", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "fine" {
+		t.Errorf("Expected successful responses to pass through, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	serve := func(cfg CORSConfig, method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		NewCORS(cfg).Middleware(next).ServeHTTP(w, r)
+		return w
+	}
+
+	cfg := CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://preview-*.vercel.app", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         5 * time.Minute,
+	}
+
+	origins := []struct {
+		origin string
+		ok     bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://preview-git-main-team.vercel.app", true},
+		{"https://preview-.vercel.app", false},
+		{"https://other.vercel.app", false},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evil.com/.example.org", false},
+		{"https://evil_host.example.org", false},
+		{"", false},
+	}
+	for _, tt := range origins {
+		w := serve(cfg, "GET", tt.origin)
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.ok && got != tt.origin || !tt.ok && got != "" {
+			t.Errorf("Origin %q: expected allowed=%v, got Allow-Origin %q", tt.origin, tt.ok, got)
+		}
+		if w.Code != http.StatusTeapot {
+			t.Errorf("Origin %q: expected the request to reach the handler, got %d", tt.origin, w.Code)
+		}
+		if tt.ok && w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+			t.Errorf("Origin %q: expected exposed headers, got %v", tt.origin, w.Header())
+		}
+	}
+
+	w := serve(cfg, "OPTIONS", "https://app.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected preflights to be answered, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "Content-Type, X-API-Key" ||
+		w.Header().Get("Access-Control-Max-Age") != "300" {
+		t.Errorf("Unexpected preflight headers %v", w.Header())
+	}
+	if w := serve(cfg, "OPTIONS", "https://evil.com"); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("Expected a preflight from a disallowed origin to get no CORS headers, got %d %v", w.Code, w.Header())
+	}
+
+	anyOrigin := CORSConfig{AllowedOrigins: []string{"*"}}
+	if w := serve(anyOrigin, "GET", "https://evil.com"); w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Errorf("Expected * without Vary, got %v", w.Header())
+	}
+	anyOrigin.AllowCredentials = true
+	w = serve(anyOrigin, "GET", "https://evil.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://evil.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected the origin to be echoed with credentials, got %v", w.Header())
+	}
+}
+
+func TestCORSFromEnv(t *testing.T) {
+	defaults := CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}, AllowedMethods: []string{"GET"}}
+
+	cfg, err := CORSFromEnv(defaults)
+	if err != nil || len(cfg.AllowedOrigins) != 1 || cfg.AllowedOrigins[0] != "http://localhost:3000" {
+		t.Fatalf("Expected the defaults without environment, got %+v (%v)", cfg, err)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://*.vercel.app")
+	t.Setenv("CORS_ALLOWED_METHODS", "get,post")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("CORS_MAX_AGE", "120")
+	cfg, err = CORSFromEnv(defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.AllowedOrigins) != 2 || cfg.AllowedOrigins[1] != "https://*.vercel.app" ||
+		strings.Join(cfg.AllowedMethods, ",") != "GET,POST" || !cfg.AllowCredentials || cfg.MaxAge != 2*time.Minute {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "-")
+	if cfg, err = CORSFromEnv(defaults); err != nil || cfg.AllowedOrigins != nil {
+		t.Errorf("Expected - to disallow every origin, got %+v (%v)", cfg, err)
+	}
+
+	for _, bad := range []string{"example.com", "https://*", "https://*.*.example.com", "https://example.com/path"} {
+		t.Setenv("CORS_ALLOWED_ORIGINS", bad)
+		if _, err := CORSFromEnv(defaults); err == nil {
+			t.Errorf("Expected origin %q to be rejected", bad)
+		}
+	}
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("CORS_MAX_AGE", "soon")
+	if _, err := CORSFromEnv(defaults); err == nil {
+		t.Error("Expected an invalid CORS_MAX_AGE to be rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"go-services/shared/response"
+	"net/http"
+	"strings"
+)
+
+// Problems turns plain-text error responses into problem documents. Handlers write their errors
+// with response.WriteProblem, but http.ServeMux answers unmatched paths and methods itself through
+// http.Error; this keeps those 404 and 405 responses in the same shape as every other error.
+func Problems(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&problemWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+// problemWriter swaps a text/plain error status for a problem document and drops the text body
+type problemWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	replaced    bool
+}
+
+func (p *problemWriter) WriteHeader(status int) {
+	if p.wroteHeader {
+		return
+	}
+	p.wroteHeader = true
+
+	header := p.Header()
+	if status >= 400 && strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		p.replaced = true
+		header.Del("X-Content-Type-Options")
+		response.WriteProblem(p.ResponseWriter, p.r, response.NewError(status, http.StatusText(status)))
+		return
+	}
+	p.ResponseWriter.WriteHeader(status)
+}
+
+func (p *problemWriter) Write(b []byte) (int, error) {
+	if !p.wroteHeader {
+		p.WriteHeader(http.StatusOK)
+	}
+	if p.replaced {
+		return len(b), nil
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, which streaming and WebSocket
+// handlers use to flush and hijack the connection
+func (p *problemWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
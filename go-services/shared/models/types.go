@@ -41,8 +41,17 @@ const (
 type RideStatus string
 
 const (
-	RideStatusOperating RideStatus = "OPERATING"
-	RideStatusClosed    RideStatus = "CLOSED"
+	RideStatusOperating     RideStatus = "OPERATING"
+	RideStatusClosed        RideStatus = "CLOSED"
+	RideStatusDown          RideStatus = "DOWN"
+	RideStatusRefurbishment RideStatus = "REFURBISHMENT"
+)
+
+// Return time queue states reported by the upstream API
+const (
+	ReturnTimeAvailable = "AVAILABLE"
+	ReturnTimeTempFull  = "TEMP_FULL"
+	ReturnTimeFinished  = "FINISHED"
 )
 
 // OperatingHours represents the operating hours for a ride
@@ -193,3 +202,182 @@ type WaitTimesResponse struct {
 	SortedRides     []Ride                   `json:"sorted_rides"`
 	RideDataHistory []*RideDataHistoryRecord `json:"ride_data_history"`
 }
+
+// APIKey represents an issued wait-times-api key. The key itself is only shown once at creation;
+// Prefix identifies it afterwards.
+type APIKey struct {
+	ID                int64      `json:"id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	RequestsPerMinute int        `json:"requestsPerMinute"`
+	Burst             int        `json:"burst"`
+	DailyQuota        int        `json:"dailyQuota"` // 0 means unlimited
+	CreatedAt         time.Time  `json:"createdAt"`
+	RevokedAt         *time.Time `json:"revokedAt,omitempty"`
+}
+
+// StalenessIncident is a period in which a park's ride data went stale during operating hours
+type StalenessIncident struct {
+	ID         int64      `json:"id"`
+	ParkID     string     `json:"parkId"`
+	LastDataAt *time.Time `json:"lastDataAt,omitempty"`
+	OpenedAt   time.Time  `json:"openedAt"`
+	NotifiedAt *time.Time `json:"notifiedAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Alert rule comparisons of the standby wait time against the threshold
+const (
+	AlertBelow     = "below"
+	AlertAtOrBelow = "at_or_below"
+	AlertAbove     = "above"
+	AlertAtOrAbove = "at_or_above"
+)
+
+// Alert delivery channels
+const (
+	AlertChannelWebhook = "webhook"
+	AlertChannelEmail   = "email"
+	AlertChannelWebPush = "webpush"
+)
+
+// AlertRule notifies an API key holder when a ride's standby wait time compares to Threshold.
+// WindowStart and WindowEnd limit the rule to minutes after midnight in Timezone; a window whose end
+// is before its start runs past midnight. Target is the webhook URL, email address or push endpoint
+// of Channel; push subscriptions also carry their keys.
+type AlertRule struct {
+	ID              int64      `json:"id"`
+	APIKeyID        int64      `json:"-"`
+	RideID          string     `json:"rideId"`
+	Comparison      string     `json:"comparison"`
+	Threshold       int        `json:"threshold"`
+	WindowStart     *int       `json:"windowStart,omitempty"`
+	WindowEnd       *int       `json:"windowEnd,omitempty"`
+	Timezone        string     `json:"timezone"`
+	ParkHoursOnly   bool       `json:"parkHoursOnly"`
+	CooldownMinutes int        `json:"cooldownMinutes"`
+	Channel         string     `json:"channel"`
+	Target          string     `json:"target"`
+	PushP256DH      string     `json:"-"`
+	PushAuth        string     `json:"-"`
+	Enabled         bool       `json:"enabled"`
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// AlertDelivery is one attempt to deliver a triggered alert
+type AlertDelivery struct {
+	ID        int64     `json:"id"`
+	RuleID    int64     `json:"ruleId"`
+	Channel   string    `json:"channel"`
+	Status    string    `json:"status"`
+	Error     *string   `json:"error,omitempty"`
+	RideID    string    `json:"rideId"`
+	WaitTime  *int      `json:"waitTime,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Alert delivery statuses
+const (
+	AlertDelivered = "delivered"
+	AlertFailed    = "failed"
+)
+
+// QueuedAlert is a triggered alert waiting for delivery. Message is the rendered notification. A
+// claimed alert carries the channel, target and push keys of its Rule.
+type QueuedAlert struct {
+	ID       int64
+	Rule     AlertRule
+	RideID   string
+	WaitTime *int
+	Message  json.RawMessage
+	// Attempts counts the attempts so far, including the claimed one
+	Attempts int
+}
+
+// Webhook event types
+const (
+	// WebhookRideOpened is sent when a closed ride or one under refurbishment starts operating
+	WebhookRideOpened = "ride.opened"
+	// WebhookRideClosed is sent when an operating ride stops, for any reason
+	WebhookRideClosed = "ride.closed"
+	// WebhookRideReopened is sent when a ride that was down operates again
+	WebhookRideReopened = "ride.reopened"
+	// WebhookReturnTimeSoldOut is sent when the available return times of a ride run out
+	WebhookReturnTimeSoldOut = "return_time.sold_out"
+)
+
+// WebhookEventTypes lists every webhook event type
+var WebhookEventTypes = []string{WebhookRideOpened, WebhookRideClosed, WebhookRideReopened, WebhookReturnTimeSoldOut}
+
+// WebhookSubscription sends the Events of RideIDs, or of every ride when RideIDs is empty, to URL.
+// Secret signs the payloads and is only shown when the subscription is created.
+type WebhookSubscription struct {
+	ID        int64     `json:"id"`
+	APIKeyID  int64     `json:"-"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	RideIDs   []string  `json:"rideIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEvent is a ride state change to send to the subscriptions of its type and ride. ID is
+// stable across delivery attempts so receivers can drop duplicates.
+type WebhookEvent struct {
+	ID      string
+	Type    string
+	RideID  string
+	Payload json.RawMessage
+}
+
+// WebhookPayload is the JSON body of a webhook request
+type WebhookPayload struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Data      WebhookRideChange `json:"data"`
+}
+
+// WebhookRideChange describes the change of a ride in a webhook payload
+type WebhookRideChange struct {
+	RideID                  string     `json:"rideId"`
+	RideName                string     `json:"rideName"`
+	ParkID                  string     `json:"parkId"`
+	Status                  string     `json:"status"`
+	PreviousStatus          string     `json:"previousStatus"`
+	StandbyWaitTime         *int       `json:"standbyWaitTime"`
+	ReturnTimeState         *string    `json:"returnTimeState"`
+	PreviousReturnTimeState *string    `json:"previousReturnTimeState"`
+	ReturnStart             *time.Time `json:"returnStart"`
+	ReturnEnd               *time.Time `json:"returnEnd"`
+	LastUpdated             time.Time  `json:"lastUpdated"`
+}
+
+// WebhookDelivery is a queued webhook event claimed for a delivery attempt, with the subscription
+// it goes to
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	URL            string
+	Secret         string
+	EventID        string
+	EventType      string
+	Payload        json.RawMessage
+	// Attempts counts the attempts so far, including the claimed one
+	Attempts int
+}
+
+// WebhookDeadLetter is a webhook event that failed every delivery attempt
+type WebhookDeadLetter struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscriptionId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	LastError      *string         `json:"lastError,omitempty"`
+	LastStatus     *int            `json:"lastStatus,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	FailedAt       time.Time       `json:"failedAt"`
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared/tracing"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a notification. Event names what happened, such as "staleness.alert"; Data carries
+// machine readable details for webhook consumers.
+type Message struct {
+	Event string         `json:"event"`
+	Title string         `json:"title"`
+	Text  string         `json:"text"`
+	Data  map[string]any `json:"data,omitempty"`
+	Time  time.Time      `json:"time"`
+}
+
+// Notifier delivers messages to one destination
+type Notifier interface {
+	// Name identifies the notifier in logs
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Multi delivers every message to each of its notifiers
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	names := make([]string, len(m))
+	for i, n := range m {
+		names[i] = n.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Notify tries every notifier and joins their errors, so one failing destination does not keep the
+// message from the others
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// defaultClient is used by notifiers without a Client
+var defaultClient = &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+
+// postJSON posts body as JSON and treats any non-2xx response as an error
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Webhook posts the Message as JSON
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	return postJSON(ctx, w.Client, w.URL, msg)
+}
+
+// Slack posts to a Slack incoming webhook, or any service accepting the same {"text": ...} payload
+// such as Mattermost or Google Chat
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Notify(ctx context.Context, msg Message) error {
+	return postJSON(ctx, s.Client, s.URL, map[string]string{"text": "*" + msg.Title + "*\n" + msg.Text})
+}
+
+// FromEnv configures the operator notifiers: ALERT_WEBHOOK_URL, ALERT_SLACK_WEBHOOK_URL and email to
+// ALERT_EMAIL_TO (comma separated) through the SMTP_* server. It returns an empty Multi when none is
+// set.
+func FromEnv() (Multi, error) {
+	var notifiers Multi
+	if url := strings.TrimSpace(os.Getenv("ALERT_WEBHOOK_URL")); url != "" {
+		notifiers = append(notifiers, &Webhook{URL: url})
+	}
+	if url := strings.TrimSpace(os.Getenv("ALERT_SLACK_WEBHOOK_URL")); url != "" {
+		notifiers = append(notifiers, &Slack{URL: url})
+	}
+	if to := strings.TrimSpace(os.Getenv("ALERT_EMAIL_TO")); to != "" {
+		smtp, err := SMTPFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range strings.Split(to, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				smtp.To = append(smtp.To, addr)
+			}
+		}
+		notifiers = append(notifiers, smtp)
+	}
+	return notifiers, nil
+}
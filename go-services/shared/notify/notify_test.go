@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookAndSlack(t *testing.T) {
+	var bodies []map[string]any
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON body, got %q", r.Header.Get("Content-Type"))
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	msg := Message{Event: "staleness.alert", Title: "Stale", Text: "No data", Data: map[string]any{"park_id": "dl"}, Time: time.Now()}
+	if err := (&Webhook{URL: srv.URL}).Notify(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Slack{URL: srv.URL}).Notify(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if bodies[0]["event"] != "staleness.alert" || bodies[0]["data"].(map[string]any)["park_id"] != "dl" {
+		t.Errorf("Unexpected webhook payload %v", bodies[0])
+	}
+	if bodies[1]["text"] != "*Stale*\nNo data" {
+		t.Errorf("Unexpected Slack payload %v", bodies[1])
+	}
+
+	status = http.StatusInternalServerError
+	if err := (&Webhook{URL: srv.URL}).Notify(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected a non-2xx response to fail, got %v", err)
+	}
+}
+
+type fakeNotifier struct {
+	name string
+	err  error
+	sent []Message
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Notify(_ context.Context, msg Message) error {
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func TestMulti(t *testing.T) {
+	failing := &fakeNotifier{name: "webhook", err: errors.New("endpoint returned status 502")}
+	working := &fakeNotifier{name: "slack"}
+	m := Multi{failing, working}
+
+	err := m.Notify(context.Background(), Message{Title: "Stale"})
+	if err == nil || err.Error() != "webhook: endpoint returned status 502" {
+		t.Errorf("Expected the failing notifier's error, got %v", err)
+	}
+	if len(working.sent) != 1 {
+		t.Error("Expected a failing notifier not to stop the others")
+	}
+	if m.Name() != "webhook,slack" {
+		t.Errorf("Unexpected name %q", m.Name())
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	s := &SMTP{
+		Addr: "localhost:2525",
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com", "oncall@example.com"},
+		send: func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+			return nil
+		},
+	}
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if err := s.Notify(context.Background(), Message{Title: "Ride data for Disneyland is stale", Text: "Line one\nLine two", Time: at}); err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != "localhost:2525" || gotFrom != "alerts@example.com" || len(gotTo) != 2 {
+		t.Errorf("Unexpected envelope %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	email := string(gotMsg)
+	for _, want := range []string{
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Subject: Ride data for Disneyland is stale\r\n",
+		"Date: Mon, 19 Oct 2026 12:00:00 +0000\r\n",
+		"\r\n\r\nLine one\r\nLine two\r\n",
+	} {
+		if !strings.Contains(email, want) {
+			t.Errorf("Expected the email to contain %q, got\n%s", want, email)
+		}
+	}
+
+	// Header injection through the title is encoded away
+	s.Notify(context.Background(), Message{Title: "Stale\r\nBcc: someone@example.com"})
+	if strings.Contains(string(gotMsg), "\r\nBcc:") {
+		t.Errorf("Expected the subject to be encoded, got\n%s", gotMsg)
+	}
+
+	s.To = nil
+	if err := s.Notify(context.Background(), Message{}); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, name := range []string{"ALERT_WEBHOOK_URL", "ALERT_SLACK_WEBHOOK_URL", "ALERT_EMAIL_TO", "SMTP_ADDR", "SMTP_FROM"} {
+		t.Setenv(name, "")
+	}
+	if m, err := FromEnv(); err != nil || len(m) != 0 {
+		t.Fatalf("Expected no notifiers, got %v, %v", m, err)
+	}
+
+	t.Setenv("ALERT_WEBHOOK_URL", "https://example.com/hook")
+	t.Setenv("ALERT_EMAIL_TO", "ops@example.com, oncall@example.com")
+	if _, err := FromEnv(); err == nil {
+		t.Error("Expected email without an SMTP server to fail")
+	}
+
+	t.Setenv("SMTP_ADDR", "smtp.example.com:587")
+	t.Setenv("SMTP_FROM", "alerts@example.com")
+	m, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name() != "webhook,smtp" || len(m[1].(*SMTP).To) != 2 {
+		t.Errorf("Unexpected notifiers %s", m.Name())
+	}
+}
+
+func TestPublicClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	if _, err := PublicClient(time.Second, false).Get(srv.URL); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Expected a loopback URL to be refused, got %v", err)
+	}
+	resp, err := PublicClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected allowPrivate to reach the loopback server: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected redirects not to be followed, got %d", resp.StatusCode)
+	}
+
+	for addr, public := range map[string]bool{
+		"8.8.8.8": true, "2606:4700::1111": true, "10.0.0.1": false, "169.254.169.254": false,
+		"100.64.0.1": false, "::1": false, "::ffff:127.0.0.1": false, "fd00::1": false, "0.0.0.0": false,
+	} {
+		if got := isPublic(netip.MustParseAddr(addr)); got != public {
+			t.Errorf("isPublic(%s) = %v, expected %v", addr, got, public)
+		}
+	}
+}
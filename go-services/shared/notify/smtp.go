@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// SMTP sends messages as plain text email
+type SMTP struct {
+	// Addr is the host:port of the server
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+
+	// send is smtp.SendMail; tests replace it
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// SMTPFromEnv configures a server from SMTP_ADDR (host:port), SMTP_FROM and the optional
+// SMTP_USERNAME and SMTP_PASSWORD. Recipients are left to the caller.
+func SMTPFromEnv() (*SMTP, error) {
+	s := &SMTP{
+		Addr:     strings.TrimSpace(os.Getenv("SMTP_ADDR")),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     strings.TrimSpace(os.Getenv("SMTP_FROM")),
+	}
+	if s.Addr == "" || s.From == "" {
+		return nil, errors.New("SMTP_ADDR and SMTP_FROM must be set to send email")
+	}
+	if _, _, err := net.SplitHostPort(s.Addr); err != nil {
+		return nil, fmt.Errorf("SMTP_ADDR must be host:port, got %q", s.Addr)
+	}
+	return s, nil
+}
+
+func (s *SMTP) Name() string { return "smtp" }
+
+// Notify sends msg to every recipient in To. Authentication uses PLAIN, which net/smtp only allows
+// over TLS or to localhost.
+func (s *SMTP) Notify(ctx context.Context, msg Message) error {
+	if len(s.To) == 0 {
+		return errors.New("no email recipients")
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := net.SplitHostPort(s.Addr)
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	send := s.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+
+	// net/smtp takes no context, so give up waiting once ctx is done
+	done := make(chan error, 1)
+	go func() { done <- send(s.Addr, auth, s.From, s.To, s.message(msg)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message renders msg as an RFC 5322 email
+func (s *SMTP) message(msg Message) []byte {
+	at := msg.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var id [12]byte
+	rand.Read(id[:])
+	domain := "localhost"
+	if _, d, ok := strings.Cut(s.From, "@"); ok {
+		domain = strings.Trim(d, "> ")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", at.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id[:]), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
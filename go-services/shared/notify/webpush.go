@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrSubscriptionGone is returned when the push service reports that a subscription has expired or
+// was unsubscribed; it should not be used again
+var ErrSubscriptionGone = errors.New("push subscription is no longer valid")
+
+// recordSize is the aes128gcm record size; one record holds the whole message
+const recordSize = 4096
+
+// VAPID identifies the application server to push services (RFC 8292)
+type VAPID struct {
+	// PublicKey is the uncompressed P-256 public key, base64url encoded; browsers subscribe with it as
+	// their applicationServerKey
+	PublicKey  string
+	PrivateKey *ecdsa.PrivateKey
+	// Subject is a mailto: or https: contact for the push service operator
+	Subject string
+}
+
+// NewVAPID parses a key pair in the base64url format of the web-push tools: the uncompressed public
+// point and the raw private scalar
+func NewVAPID(publicKey, privateKey, subject string) (*VAPID, error) {
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, fmt.Errorf("VAPID subject must be a mailto: or https: URL, got %q", subject)
+	}
+	d, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(privateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	pub := priv.PublicKey().Bytes()
+	if publicKey != "" && strings.TrimRight(publicKey, "=") != base64.RawURLEncoding.EncodeToString(pub) {
+		return nil, errors.New("VAPID public key does not belong to the private key")
+	}
+	// pub is the uncompressed point 0x04 || X || Y
+	x, y := new(big.Int).SetBytes(pub[1:33]), new(big.Int).SetBytes(pub[33:])
+	return &VAPID{
+		PublicKey: base64.RawURLEncoding.EncodeToString(pub),
+		PrivateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+			D:         new(big.Int).SetBytes(d),
+		},
+		Subject: subject,
+	}, nil
+}
+
+// GenerateVAPIDKeys returns a new key pair in the format NewVAPID reads
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(priv.Bytes()), nil
+}
+
+// VAPIDFromEnv reads VAPID_PUBLIC_KEY, VAPID_PRIVATE_KEY and VAPID_SUBJECT. It returns nil when no
+// private key is set.
+func VAPIDFromEnv() (*VAPID, error) {
+	private := strings.TrimSpace(os.Getenv("VAPID_PRIVATE_KEY"))
+	if private == "" {
+		return nil, nil
+	}
+	return NewVAPID(strings.TrimSpace(os.Getenv("VAPID_PUBLIC_KEY")), private, strings.TrimSpace(os.Getenv("VAPID_SUBJECT")))
+}
+
+// authorization returns the Authorization header for requests to the origin of endpoint
+func (v *VAPID) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": v.Subject,
+	})
+	signed, err := token.SignedString(v.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	return "vapid t=" + signed + ", k=" + v.PublicKey, nil
+}
+
+// WebPush sends messages to one browser push subscription. The JSON Message is encrypted for the
+// subscription (RFC 8291) and the request authenticated with VAPID.
+type WebPush struct {
+	Endpoint string
+	// P256DH and Auth are the subscription's keys, base64url encoded as browsers report them
+	P256DH string
+	Auth   string
+	VAPID  *VAPID
+	// TTL is how long the push service keeps an undelivered message
+	TTL    time.Duration
+	Client *http.Client
+}
+
+func (p *WebPush) Name() string { return "webpush" }
+
+func (p *WebPush) Notify(ctx context.Context, msg Message) error {
+	if p.VAPID == nil {
+		return errors.New("web push is not configured")
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	body, err := encryptPush(payload, p.P256DH, p.Auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := p.VAPID.authorization(p.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(p.TTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("push service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// hkdf derives one block of at most 32 bytes (RFC 5869)
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+
+// encryptPush encrypts payload for a subscription with the aes128gcm content coding of RFC 8291
+func encryptPush(payload []byte, p256dh, auth string) ([]byte, error) {
+	uaPublicBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(p256dh, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(auth, "="))
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("invalid subscription auth secret")
+	}
+	if len(payload)+1+16 > recordSize-86 {
+		return nil, fmt.Errorf("push payload of %d bytes is too large", len(payload))
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublicBytes...), asPublicBytes...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// A single record ends with the 0x02 delimiter and needs no padding
+	plaintext := append(append([]byte{}, payload...), 2)
+
+	header := make([]byte, 0, 21+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
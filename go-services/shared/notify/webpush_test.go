@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// decryptPush reverses encryptPush as a browser would with its subscription keys
+func decryptPush(t *testing.T, body []byte, ua *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("Body of %d bytes has no aes128gcm header", len(body))
+	}
+	salt, keyLen := body[:16], int(body[20])
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Errorf("Expected record size %d, got %d", recordSize, rs)
+	}
+	asPublicBytes, ciphertext := body[21:21+keyLen], body[21+keyLen:]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("Invalid sender key: %v", err)
+	}
+	sharedSecret, err := ua.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), ua.PublicKey().Bytes()...), asPublicBytes...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	block, err := aes.NewCipher(hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12), ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if plaintext[len(plaintext)-1] != 2 {
+		t.Fatalf("Expected the last record delimiter, got %x", plaintext[len(plaintext)-1])
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestWebPush(t *testing.T) {
+	ua, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	public, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := NewVAPID(public, private, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []byte
+	var header http.Header
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	push := &WebPush{
+		Endpoint: srv.URL + "/push/1",
+		P256DH:   base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes()),
+		Auth:     base64.URLEncoding.EncodeToString(authSecret),
+		VAPID:    vapid,
+		TTL:      time.Hour,
+	}
+	msg := Message{Event: "alert.triggered", Title: "Space Mountain", Text: "15 minutes", Time: time.Now()}
+	if err := push.Notify(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(decryptPush(t, received, ua, authSecret), &decoded); err != nil || decoded.Title != "Space Mountain" {
+		t.Errorf("Unexpected payload %+v: %v", decoded, err)
+	}
+	if header.Get("Content-Encoding") != "aes128gcm" || header.Get("TTL") != "3600" {
+		t.Errorf("Unexpected headers %v", header)
+	}
+
+	token, key, ok := strings.Cut(strings.TrimPrefix(header.Get("Authorization"), "vapid t="), ", k=")
+	if !ok || key != public {
+		t.Fatalf("Unexpected Authorization %q", header.Get("Authorization"))
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return &vapid.PrivateKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"})); err != nil {
+		t.Fatalf("Invalid VAPID token: %v", err)
+	}
+	if claims["aud"] != srv.URL || claims["sub"] != "mailto:ops@example.com" {
+		t.Errorf("Unexpected VAPID claims %v", claims)
+	}
+
+	status = http.StatusGone
+	if err := push.Notify(context.Background(), msg); !errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("Expected ErrSubscriptionGone, got %v", err)
+	}
+}
+
+func TestNewVAPID(t *testing.T) {
+	public, private, _ := GenerateVAPIDKeys()
+	other, _, _ := GenerateVAPIDKeys()
+	if _, err := NewVAPID(public, private, "ops@example.com"); err == nil {
+		t.Error("Expected a subject without mailto: or https: to be rejected")
+	}
+	if _, err := NewVAPID(other, private, "https://example.com"); err == nil {
+		t.Error("Expected a public key of another pair to be rejected")
+	}
+	vapid, err := NewVAPID("", private, "https://example.com")
+	if err != nil || vapid.PublicKey != public {
+		t.Errorf("Expected the public key to be derived, got %v %v", vapid, err)
+	}
+}
+
+func TestEncryptPushRejectsBadKeys(t *testing.T) {
+	ua, _ := ecdh.P256().GenerateKey(rand.Reader)
+	p256dh := base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes())
+	auth := base64.RawURLEncoding.EncodeToString(make([]byte, 16))
+	if _, err := encryptPush([]byte("{}"), "AAAA", auth); err == nil {
+		t.Error("Expected an invalid subscription key to be rejected")
+	}
+	if _, err := encryptPush([]byte("{}"), p256dh, "AAAA"); err == nil {
+		t.Error("Expected a short auth secret to be rejected")
+	}
+	if _, err := encryptPush(bytes.Repeat([]byte("a"), recordSize), p256dh, auth); err == nil {
+		t.Error("Expected a payload larger than a record to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor applies the limits to every unary gRPC call. The API key is read from the
+// x-api-key or authorization metadata like the HTTP headers, and the RateLimit-* values are sent as
+// response header metadata.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := l.allowCall(ctx, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies the limits when a gRPC stream is opened. A stream counts as one
+// request however long it stays open.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := l.allowCall(ss.Context(), ss.SetHeader)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &keyedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// allowCall checks a call against the limits, reporting them through setHeader, and returns the
+// call's context carrying its API key
+func (l *Limiter) allowCall(ctx context.Context, setHeader func(metadata.MD) error) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	secret := bearerOr(first(md.Get(strings.ToLower(APIKeyHeader))), first(md.Get("authorization")))
+
+	d, err := l.Allow(ctx, secret, l.forwardedFor(md.Get("x-forwarded-for"), remoteAddr))
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, grpcstatus.Error(codes.Unauthenticated, "invalid or revoked API key")
+		}
+		slog.ErrorContext(ctx, "Failed to verify API key", "error", err)
+		return nil, grpcstatus.Error(codes.Unavailable, "API keys cannot be verified right now")
+	}
+
+	header := metadata.MD{}
+	for name, values := range d.Headers() {
+		header.Set(name, values...)
+	}
+	if !d.Allowed {
+		header.Set("retry-after", strconv.Itoa(d.RetryAfter()))
+	}
+	if len(header) > 0 {
+		if err := setHeader(header); err != nil {
+			slog.WarnContext(ctx, "Failed to send rate limit metadata", "error", err)
+		}
+	}
+	if !d.Allowed {
+		return nil, grpcstatus.Error(codes.ResourceExhausted, d.Reason)
+	}
+	return WithKey(ctx, d.Key), nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// keyedStream replaces the context of a stream with one carrying its API key
+type keyedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *keyedStream) Context() context.Context { return s.ctx }
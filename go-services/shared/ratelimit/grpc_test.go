@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// callContext is the context of a gRPC call from addr with the given metadata
+func callContext(addr string, kv ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1234}})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+}
+
+// fakeServerStream records the header metadata a stream sends
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]*Key{"wtk_good": {ID: 7, Name: "partner"}}, usage: map[int64]int64{}}
+	l := New(Config{Anonymous: Tier{RequestsPerMinute: 60, Burst: 1}, Keys: keys, KeyCacheTTL: time.Minute})
+	intercept := l.UnaryServerInterceptor()
+
+	var got *Key
+	handler := func(ctx context.Context, req any) (any, error) {
+		got = KeyFrom(ctx)
+		return "ok", nil
+	}
+	call := func(ctx context.Context) error {
+		_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/waittimes.v1.WaitTimesService/ListParks"}, handler)
+		return err
+	}
+
+	if err := call(callContext("192.0.2.1")); err != nil || got != nil {
+		t.Fatalf("Expected the first anonymous call through, got %v with key %+v", err, got)
+	}
+	if err := call(callContext("192.0.2.1")); grpcstatus.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted once the bucket is empty, got %v", err)
+	}
+	if err := call(callContext("192.0.2.2")); err != nil {
+		t.Errorf("Expected another client to have its own bucket, got %v", err)
+	}
+	if err := call(callContext("192.0.2.1", "x-api-key", "wtk_good")); err != nil || got == nil || got.ID != 7 {
+		t.Errorf("Expected the key's limits and the key in the context, got %v with key %+v", err, got)
+	}
+	if err := call(callContext("192.0.2.1", "authorization", "Bearer wtk_bad")); grpcstatus.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for an unknown key, got %v", err)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l := New(Config{Anonymous: Tier{RequestsPerMinute: 60, Burst: 1}, TrustedProxies: 1})
+	intercept := l.StreamServerInterceptor()
+	handler := func(srv any, stream grpc.ServerStream) error { return nil }
+
+	// Behind a proxy, clients are told apart by X-Forwarded-For
+	stream := &fakeServerStream{ctx: callContext("10.0.0.1", "x-forwarded-for", "203.0.113.9")}
+	if err := intercept(nil, stream, &grpc.StreamServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if got := stream.header.Get("ratelimit-remaining"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("Expected RateLimit metadata, got %v", stream.header)
+	}
+
+	stream = &fakeServerStream{ctx: callContext("10.0.0.1", "x-forwarded-for", "203.0.113.9")}
+	if err := intercept(nil, stream, &grpc.StreamServerInfo{}, handler); grpcstatus.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+	if got := stream.header.Get("retry-after"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected Retry-After metadata, got %v", stream.header)
+	}
+
+	stream = &fakeServerStream{ctx: callContext("10.0.0.1", "x-forwarded-for", "198.51.100.7")}
+	if err := intercept(nil, stream, &grpc.StreamServerInfo{}, handler); err != nil {
+		t.Errorf("Expected another forwarded client to have its own bucket, got %v", err)
+	}
+}
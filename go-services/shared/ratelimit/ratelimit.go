@@ -0,0 +1,406 @@
+// Package ratelimit limits requests per client. Clients that send an API key get the limits of their
+// key; everyone else shares the anonymous tier, tracked per client IP. Each client has a token bucket
+// for short term rate and a daily quota, and every response reports the tighter of the two in the
+// RateLimit-* headers of the IETF httpapi rate limit draft.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-services/shared/response"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyHeader carries the API key of a request. "Authorization: Bearer <key>" is accepted as well.
+const APIKeyHeader = "X-API-Key"
+
+// ErrUnknownKey is returned by a KeyStore for keys that were never issued or have been revoked
+var ErrUnknownKey = errors.New("unknown api key")
+
+// Tier is the limits of a class of client
+type Tier struct {
+	// RequestsPerMinute is the sustained rate; 0 means unlimited
+	RequestsPerMinute int
+	// Burst is how many requests may be made at once, RequestsPerMinute when 0
+	Burst int
+	// DailyQuota caps requests per UTC day; 0 means unlimited
+	DailyQuota int
+}
+
+func (t Tier) burst() int {
+	if t.Burst > 0 {
+		return t.Burst
+	}
+	return t.RequestsPerMinute
+}
+
+// Key is an API key as the limiter sees it
+type Key struct {
+	ID   int64
+	Name string
+	Tier Tier
+}
+
+// KeyStore resolves API keys and counts their daily usage, which is shared by every instance
+type KeyStore interface {
+	// LookupKey returns the active key, or ErrUnknownKey
+	LookupKey(ctx context.Context, key string) (*Key, error)
+	// CountUsage records one request of the key on the UTC day of at and returns the day's total
+	CountUsage(ctx context.Context, id int64, at time.Time) (int64, error)
+}
+
+type keyContextKey struct{}
+
+// KeyFrom returns the API key the request was made with, as identified by Middleware. It returns
+// nil for anonymous requests.
+func KeyFrom(ctx context.Context) *Key {
+	key, _ := ctx.Value(keyContextKey{}).(*Key)
+	return key
+}
+
+// Config configures a Limiter
+type Config struct {
+	// Anonymous applies to requests without an API key, per client IP
+	Anonymous Tier
+	// Keys resolves API keys; when nil, API keys are rejected
+	Keys KeyStore
+	// TrustedProxies is how many proxies in front of the service append to X-Forwarded-For. The
+	// client IP is taken from that position counted from the end; 0 uses the connection address.
+	TrustedProxies int
+	// KeyCacheTTL is how long key lookups are cached, which bounds how long a revoked key keeps working
+	KeyCacheTTL time.Duration
+}
+
+// maxCachedKeys bounds the key cache; it is cleared when full, e.g. when flooded with random keys
+const maxCachedKeys = 10000
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+type cachedKey struct {
+	key     *Key
+	expires time.Time
+}
+
+// Limiter enforces the limits. Token buckets and the anonymous daily counts are kept in memory per
+// instance; API key quotas are counted by the KeyStore.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	anonDay   time.Time
+	anonUsage map[string]int64
+	keys      map[string]cachedKey
+	lastSweep time.Time
+}
+
+// New creates a Limiter
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		cfg:       cfg,
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		anonUsage: make(map[string]int64),
+		keys:      make(map[string]cachedKey),
+	}
+}
+
+// bucket is a token bucket refilled at the tier's rate up to its burst
+type bucket struct {
+	tokens float64
+	last   time.Time
+	tier   Tier
+}
+
+// take removes a token if one is available. It returns the tokens left and how long until the
+// bucket is full again, or until the next token when it is empty.
+func (b *bucket) take(now time.Time) (ok bool, remaining int, reset time.Duration) {
+	rate := float64(b.tier.RequestsPerMinute) / 60
+	capacity := float64(b.tier.burst())
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, 0, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, int(b.tokens), time.Duration((capacity - b.tokens) / rate * float64(time.Second))
+}
+
+// idle reports whether the bucket has refilled completely, so dropping it loses nothing
+func (b *bucket) idle(now time.Time) bool {
+	rate := float64(b.tier.RequestsPerMinute) / 60
+	return b.tokens+now.Sub(b.last).Seconds()*rate >= float64(b.tier.burst())
+}
+
+// status is what the RateLimit-* headers report
+type status struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+}
+
+// Decision is the outcome of checking one request against the limits
+type Decision struct {
+	// Key is the API key the request was made with, nil for anonymous requests
+	Key *Key
+	// Allowed is false once the rate limit or the daily quota is exhausted; Reason then says which
+	Allowed bool
+	Reason  string
+
+	policies []string
+	status   *status
+}
+
+// RetryAfter is how long a rejected client should wait before trying again
+func (d Decision) RetryAfter() int {
+	if d.status == nil {
+		return 0
+	}
+	return seconds(d.status.reset)
+}
+
+// Headers returns the RateLimit-* headers reporting the tighter of the limits that applied, or
+// nil when none did
+func (d Decision) Headers() http.Header {
+	if d.status == nil {
+		return nil
+	}
+	h := make(http.Header)
+	writeHeaders(h, d.policies, *d.status)
+	return h
+}
+
+// Allow checks a request made with the API key secret, or anonymously from clientIP when secret is
+// empty, and counts it against the client's limits. It returns ErrUnknownKey for keys that are not
+// active, and the KeyStore's error when keys cannot be verified.
+func (l *Limiter) Allow(ctx context.Context, secret, clientIP string) (Decision, error) {
+	client, key, tier, err := l.identify(ctx, secret, clientIP)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	now := l.now()
+	d := Decision{Key: key, Allowed: true, policies: make([]string, 0, 2)}
+
+	if tier.RequestsPerMinute > 0 {
+		d.policies = append(d.policies, fmt.Sprintf("%d;w=60;burst=%d", tier.RequestsPerMinute, tier.burst()))
+		ok, remaining, reset := l.take(client, tier, now)
+		d.status = &status{limit: tier.burst(), remaining: remaining, reset: reset}
+		if !ok {
+			d.Allowed, d.Reason = false, "Rate limit exceeded"
+			return d, nil
+		}
+	}
+
+	if tier.DailyQuota > 0 {
+		d.policies = append(d.policies, fmt.Sprintf("%d;w=86400", tier.DailyQuota))
+		used, err := l.countUsage(ctx, client, now)
+		if err != nil {
+			// Quotas are a soft limit; an unavailable store should not take the API down
+			slog.ErrorContext(ctx, "Failed to count usage", "client", client, "error", err)
+		} else {
+			remaining := tier.DailyQuota - int(used)
+			reset := untilMidnightUTC(now)
+			if d.status == nil || remaining < d.status.remaining {
+				d.status = &status{limit: tier.DailyQuota, remaining: max(remaining, 0), reset: reset}
+			}
+			if remaining < 0 {
+				d.Allowed, d.Reason = false, "Daily quota exceeded"
+				return d, nil
+			}
+		}
+	}
+	return d, nil
+}
+
+// WithKey returns a context carrying the API key of a request for KeyFrom
+func WithKey(ctx context.Context, key *Key) context.Context {
+	if key == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// Middleware applies the limits to every request except CORS preflights
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d, err := l.Allow(r.Context(), bearerOr(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization")), l.clientIP(r))
+		if err != nil {
+			if errors.Is(err, ErrUnknownKey) {
+				response.WriteProblem(w, r, response.NewError(http.StatusUnauthorized, "Invalid or revoked API key"))
+				return
+			}
+			response.WriteProblem(w, r, &response.Error{
+				Status: http.StatusServiceUnavailable, Code: response.CodeUnavailable,
+				Detail: "API keys cannot be verified right now", Err: err,
+			})
+			return
+		}
+
+		for name, values := range d.Headers() {
+			w.Header()[name] = values
+		}
+		if !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(d.RetryAfter()))
+			response.WriteProblem(w, r, response.NewError(http.StatusTooManyRequests, d.Reason))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), d.Key)))
+	})
+}
+
+// bearerOr returns key, or the token of a Bearer authorization when key is empty
+func bearerOr(key, authorization string) string {
+	if key != "" {
+		return key
+	}
+	if scheme, token, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return token
+	}
+	return ""
+}
+
+// identify returns the client a request is limited as: "key:<id>" for API keys, "ip:<address>" otherwise
+func (l *Limiter) identify(ctx context.Context, secret, clientIP string) (string, *Key, Tier, error) {
+	if secret == "" {
+		return "ip:" + clientIP, nil, l.cfg.Anonymous, nil
+	}
+
+	key, err := l.lookupKey(ctx, secret)
+	if err != nil {
+		return "", nil, Tier{}, err
+	}
+	return "key:" + strconv.FormatInt(key.ID, 10), key, key.Tier, nil
+}
+
+// lookupKey resolves a key through the cache; unknown keys are cached too
+func (l *Limiter) lookupKey(ctx context.Context, secret string) (*Key, error) {
+	now := l.now()
+	l.mu.Lock()
+	cached, ok := l.keys[secret]
+	l.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		if cached.key == nil {
+			return nil, ErrUnknownKey
+		}
+		return cached.key, nil
+	}
+
+	if l.cfg.Keys == nil {
+		return nil, ErrUnknownKey
+	}
+	key, err := l.cfg.Keys.LookupKey(ctx, secret)
+	if err != nil && !errors.Is(err, ErrUnknownKey) {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if len(l.keys) >= maxCachedKeys {
+		l.keys = make(map[string]cachedKey)
+	}
+	l.keys[secret] = cachedKey{key: key, expires: now.Add(l.cfg.KeyCacheTTL)}
+	l.mu.Unlock()
+
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// clientIP returns the address requests without an API key are limited by
+func (l *Limiter) clientIP(r *http.Request) string {
+	return l.forwardedFor(r.Header.Values("X-Forwarded-For"), r.RemoteAddr)
+}
+
+// forwardedFor picks the client address from X-Forwarded-For values, skipping the hops appended by
+// trusted proxies, or falls back to the connection's remote address
+func (l *Limiter) forwardedFor(values []string, remoteAddr string) string {
+	if l.cfg.TrustedProxies > 0 {
+		var hops []string
+		for _, header := range values {
+			for _, hop := range strings.Split(header, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			return hops[max(len(hops)-l.cfg.TrustedProxies, 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func (l *Limiter) take(client string, tier Tier, now time.Time) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		for id, b := range l.buckets {
+			if b.idle(now) {
+				delete(l.buckets, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok || b.tier != tier {
+		b = &bucket{tokens: float64(tier.burst()), last: now, tier: tier}
+		l.buckets[client] = b
+	}
+	return b.take(now)
+}
+
+// countUsage records a request against the client's daily quota and returns today's total
+func (l *Limiter) countUsage(ctx context.Context, client string, now time.Time) (int64, error) {
+	if id, ok := strings.CutPrefix(client, "key:"); ok {
+		n, _ := strconv.ParseInt(id, 10, 64)
+		return l.cfg.Keys.CountUsage(ctx, n, now)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if day := now.UTC().Truncate(24 * time.Hour); !day.Equal(l.anonDay) {
+		l.anonDay = day
+		l.anonUsage = make(map[string]int64)
+	}
+	l.anonUsage[client]++
+	return l.anonUsage[client], nil
+}
+
+func writeHeaders(h http.Header, policies []string, s status) {
+	h.Set("RateLimit-Policy", strings.Join(policies, ", "))
+	h.Set("RateLimit-Limit", strconv.Itoa(s.limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(s.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(s.reset)))
+}
+
+// seconds rounds up so clients never retry before the limit has reset
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func untilMidnightUTC(now time.Time) time.Duration {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
@@ -0,0 +1,245 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeKeys struct {
+	mu      sync.Mutex
+	keys    map[string]*Key
+	usage   map[int64]int64
+	lookups int
+	err     error
+}
+
+func (f *fakeKeys) LookupKey(ctx context.Context, key string) (*Key, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	k, ok := f.keys[key]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+func (f *fakeKeys) CountUsage(ctx context.Context, id int64, at time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage[id]++
+	return f.usage[id], nil
+}
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter(cfg Config) (*Limiter, *testClock, http.Handler) {
+	clock := &testClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	l := New(cfg)
+	l.now = clock.Now
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	return l, clock, h
+}
+
+func serve(h http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/v1/parks", nil)
+	r.RemoteAddr = remoteAddr
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestTokenBucket(t *testing.T) {
+	_, clock, h := newTestLimiter(Config{Anonymous: Tier{RequestsPerMinute: 60, Burst: 3}})
+
+	for i := 2; i >= 0; i-- {
+		w := serve(h, "192.0.2.1:1234", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected request within burst to pass, got %d", w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(i) {
+			t.Errorf("Expected RateLimit-Remaining %d, got %q", i, got)
+		}
+	}
+	if got := serve(h, "192.0.2.1:1234", nil).Header().Get("RateLimit-Policy"); got != "60;w=60;burst=3" {
+		t.Errorf("Unexpected RateLimit-Policy %q", got)
+	}
+
+	w := serve(h, "192.0.2.1:1234", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the burst is used, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("Expected a problem with Retry-After 1, got %v", w.Header())
+	}
+
+	if w := serve(h, "192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to have its own bucket, got %d", w.Code)
+	}
+
+	clock.Advance(time.Second)
+	if w := serve(h, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected a token after one second at 60/min, got %d", w.Code)
+	}
+}
+
+func TestDailyQuota(t *testing.T) {
+	_, clock, h := newTestLimiter(Config{Anonymous: Tier{DailyQuota: 2}})
+
+	for range 2 {
+		if w := serve(h, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+			t.Fatalf("Expected request within quota to pass, got %d", w.Code)
+		}
+	}
+	w := serve(h, "192.0.2.1:1234", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the quota is used, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "43200" {
+		t.Errorf("Expected Retry-After until midnight UTC, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=86400" {
+		t.Errorf("Unexpected RateLimit-Policy %q", got)
+	}
+
+	clock.Advance(12 * time.Hour)
+	if w := serve(h, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the quota to reset at midnight UTC, got %d", w.Code)
+	}
+}
+
+func TestHeadersReportTightestLimit(t *testing.T) {
+	_, _, h := newTestLimiter(Config{Anonymous: Tier{RequestsPerMinute: 60, Burst: 10, DailyQuota: 3}})
+
+	w := serve(h, "192.0.2.1:1234", nil)
+	if w.Header().Get("RateLimit-Limit") != "3" || w.Header().Get("RateLimit-Remaining") != "2" {
+		t.Errorf("Expected the daily quota to be reported, got %v", w.Header())
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "60;w=60;burst=10, 3;w=86400" {
+		t.Errorf("Unexpected RateLimit-Policy %q", got)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys := &fakeKeys{
+		keys:  map[string]*Key{"wtk_good": {ID: 7, Name: "partner", Tier: Tier{RequestsPerMinute: 600, Burst: 100, DailyQuota: 1}}},
+		usage: map[int64]int64{},
+	}
+	l, clock, h := newTestLimiter(Config{Anonymous: Tier{RequestsPerMinute: 1, Burst: 1}, Keys: keys, KeyCacheTTL: time.Minute})
+
+	serve(h, "192.0.2.1:1234", nil)
+	w := serve(h, "192.0.2.1:1234", http.Header{"X-Api-Key": {"wtk_good"}})
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("Expected the key's own limits after the anonymous bucket is empty, got %d %v", w.Code, w.Header())
+	}
+	if keys.usage[7] != 1 {
+		t.Errorf("Expected usage to be counted by the key store, got %d", keys.usage[7])
+	}
+	w = serve(h, "192.0.2.1:1234", http.Header{"Authorization": {"Bearer wtk_good"}})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a bearer key to share the key's quota, got %d", w.Code)
+	}
+	if keys.lookups != 1 {
+		t.Errorf("Expected the key lookup to be cached, got %d lookups", keys.lookups)
+	}
+
+	for range 2 {
+		if w := serve(h, "192.0.2.1:1234", http.Header{"X-Api-Key": {"wtk_bad"}}); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for an unknown key, got %d", w.Code)
+		}
+	}
+	if keys.lookups != 2 {
+		t.Errorf("Expected unknown keys to be cached, got %d lookups", keys.lookups)
+	}
+
+	clock.Advance(2 * time.Minute)
+	keys.err = errors.New("connection refused")
+	if w := serve(h, "192.0.2.1:1234", http.Header{"X-Api-Key": {"wtk_good"}}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when keys cannot be verified, got %d", w.Code)
+	}
+	if len(l.keys) != 2 {
+		t.Errorf("Expected failed lookups not to be cached, got %d entries", len(l.keys))
+	}
+}
+
+func TestKeyFrom(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]*Key{"wtk_good": {ID: 7, Name: "partner"}}, usage: map[int64]int64{}}
+	var got *Key
+	h := New(Config{Keys: keys}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = KeyFrom(r.Context())
+	}))
+
+	serve(h, "192.0.2.1:1234", http.Header{"X-Api-Key": {"wtk_good"}})
+	if got == nil || got.ID != 7 {
+		t.Errorf("Expected the request's key in its context, got %+v", got)
+	}
+	serve(h, "192.0.2.1:1234", nil)
+	if got != nil {
+		t.Errorf("Expected no key for anonymous requests, got %+v", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies int
+		xff     []string
+		want    string
+	}{
+		{"connection address", 0, []string{"203.0.113.9"}, "192.0.2.1"},
+		{"one proxy", 1, []string{"10.0.0.1, 203.0.113.9"}, "203.0.113.9"},
+		{"spoofed hops are ignored", 2, []string{"10.0.0.1, 203.0.113.9", "198.51.100.1"}, "203.0.113.9"},
+		{"fewer hops than proxies", 3, []string{"203.0.113.9"}, "203.0.113.9"},
+		{"no header", 1, nil, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(Config{TrustedProxies: tt.proxies})
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header["X-Forwarded-For"] = tt.xff
+			if got := l.clientIP(r); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreflightIsExempt(t *testing.T) {
+	_, _, h := newTestLimiter(Config{Anonymous: Tier{RequestsPerMinute: 1, Burst: 1}})
+	for range 3 {
+		r := httptest.NewRequest("OPTIONS", "/v1/parks", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("Expected preflights to bypass the limiter, got %d %v", w.Code, w.Header())
+		}
+	}
+}
+
+func TestIdleBucketsAreSwept(t *testing.T) {
+	l, clock, h := newTestLimiter(Config{Anonymous: Tier{RequestsPerMinute: 60, Burst: 5}})
+	serve(h, "192.0.2.1:1234", nil)
+	clock.Advance(2 * time.Minute)
+	serve(h, "192.0.2.2:1234", nil)
+	if _, ok := l.buckets["ip:192.0.2.1"]; ok || len(l.buckets) != 1 {
+		t.Errorf("Expected the refilled bucket to be dropped, got %v", l.buckets)
+	}
+}
@@ -0,0 +1,220 @@
+package realtime
+
+import (
+	"context"
+	"go-services/shared/repository"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Event is a change as the hub delivers it. ID is assigned by the hub when the change is published,
+// so events are numbered in the order subscribers see them. Row ids cannot serve as event ids: they
+// are assigned when a row is inserted, and concurrent collector transactions may commit, and so
+// notify, out of id order, which made a resume after the larger id skip the smaller one.
+//
+// The high 32 bits of an ID identify the hub and the low 32 bits count its events, so an id handed
+// out by another instance or an earlier process is recognised instead of being compared.
+type Event struct {
+	ID     int64
+	Change repository.RideDataChange
+}
+
+// seqBits is the width of the event counter in an event ID
+const seqBits = 32
+
+// Hub fans ride data changes out to subscribers and keeps a bounded backlog of recent events so
+// reconnecting clients can resume from the last event they saw.
+//
+// Publishing never blocks: a subscriber whose buffer is full is dropped and its channel closed, so a
+// slow consumer cannot hold up the others. Dropped clients reconnect and resume from the backlog.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	backlog     []Event
+	next        int
+	full        bool
+	closed      bool
+	// epoch holds the hub's identity in the high bits of its event ids; lastID is the newest id
+	epoch  int64
+	lastID int64
+}
+
+// NewHub creates a hub that remembers the last backlogSize changes
+func NewHub(backlogSize int) *Hub {
+	if backlogSize < 1 {
+		backlogSize = 1
+	}
+	epoch := (1 + rand.Int64N(1<<31-1)) << seqBits
+	return &Hub{
+		subscribers: make(map[*Subscription]struct{}),
+		backlog:     make([]Event, backlogSize),
+		epoch:       epoch,
+		lastID:      epoch,
+	}
+}
+
+// Subscription receives the changes accepted by its filter
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+	filter func(repository.RideDataChange) bool
+	once   sync.Once
+}
+
+// Events returns the channel of events. It is closed when the subscription is closed, when the
+// subscriber falls too far behind, or when the hub shuts down.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes and closes the events channel. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Subscription) closeLocked() {
+	s.once.Do(func() {
+		delete(s.hub.subscribers, s)
+		close(s.events)
+	})
+}
+
+// Subscribe registers a subscriber with room for buffer pending events. A nil filter accepts every change.
+// When afterID is positive the backlogged events published after it are returned for replay; an id this
+// hub did not hand out replays the whole backlog. Registration and replay happen atomically, so no event
+// is missed or delivered twice.
+func (h *Hub) Subscribe(afterID int64, buffer int, filter func(repository.RideDataChange) bool) (*Subscription, []Event) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub := &Subscription{
+		hub:    h,
+		events: make(chan Event, buffer),
+		filter: filter,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		sub.closeLocked()
+		return sub, nil
+	}
+	h.subscribers[sub] = struct{}{}
+
+	var replay []Event
+	if afterID > 0 {
+		if afterID>>seqBits != h.epoch>>seqBits {
+			afterID = 0
+		}
+		for _, event := range h.backlogLocked() {
+			if event.ID > afterID && (filter == nil || filter(event.Change)) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	return sub, replay
+}
+
+// LastID returns the id of the newest event, or an id that precedes every event before the first
+// is published. Subscribing after it replays everything published since.
+func (h *Hub) LastID() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastID
+}
+
+// backlogLocked returns the remembered events, oldest first
+func (h *Hub) backlogLocked() []Event {
+	if !h.full {
+		return h.backlog[:h.next]
+	}
+	ordered := make([]Event, 0, len(h.backlog))
+	ordered = append(ordered, h.backlog[h.next:]...)
+	return append(ordered, h.backlog[:h.next]...)
+}
+
+// Publish numbers a change, records it in the backlog and delivers it to every matching subscriber
+func (h *Hub) Publish(change repository.RideDataChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.lastID++
+	event := Event{ID: h.lastID, Change: change}
+	h.backlog[h.next] = event
+	h.next = (h.next + 1) % len(h.backlog)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	for sub := range h.subscribers {
+		if sub.filter != nil && !sub.filter(change) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.closeLocked()
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Close ends every subscription and rejects new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		sub.closeLocked()
+	}
+}
+
+// ChangeSource delivers ride data changes until ctx is cancelled or it fails.
+// RideDataHistoryRepository implements it with Postgres LISTEN/NOTIFY.
+type ChangeSource interface {
+	ListenRideDataChanges(ctx context.Context, fn func(repository.RideDataChange)) error
+}
+
+// Relay publishes changes from source to the hub until ctx is cancelled, reconnecting with exponential
+// backoff between one second and maxBackoff whenever the source fails. onError, if set, is told about each failure.
+func Relay(ctx context.Context, source ChangeSource, hub *Hub, maxBackoff time.Duration, onError func(error)) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := source.ListenRideDataChanges(ctx, hub.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		if onError != nil && err != nil {
+			onError(err)
+		}
+
+		// A listener that stayed up for a while earns a fresh backoff
+		if time.Since(started) > maxBackoff {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
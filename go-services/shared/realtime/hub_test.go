@@ -0,0 +1,183 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"go-services/shared/repository"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func change(id int64, rideID string) repository.RideDataChange {
+	return repository.RideDataChange{ID: id, RideID: rideID, ParkID: "park1"}
+}
+
+// rowIDs returns the row ids of the changes carried by events
+func rowIDs(events []Event) []int64 {
+	out := make([]int64, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.Change.ID)
+	}
+	return out
+}
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	hub := NewHub(10)
+	sub, replay := hub.Subscribe(0, 4, nil)
+	defer sub.Close()
+	assert.Empty(t, replay)
+
+	hub.Publish(change(1, "ride1"))
+	hub.Publish(change(2, "ride2"))
+
+	assert.Equal(t, int64(1), (<-sub.Events()).Change.ID)
+	assert.Equal(t, int64(2), (<-sub.Events()).Change.ID)
+}
+
+func TestHub_Filter(t *testing.T) {
+	hub := NewHub(10)
+	sub, _ := hub.Subscribe(0, 4, func(c repository.RideDataChange) bool { return c.RideID == "ride2" })
+	defer sub.Close()
+
+	hub.Publish(change(1, "ride1"))
+	hub.Publish(change(2, "ride2"))
+
+	assert.Equal(t, int64(2), (<-sub.Events()).Change.ID)
+	assert.Empty(t, sub.Events())
+}
+
+func TestHub_ReplayAfterID(t *testing.T) {
+	hub := NewHub(3)
+	var eventIDs []int64
+	for id := int64(1); id <= 5; id++ {
+		hub.Publish(change(id, "ride1"))
+		eventIDs = append(eventIDs, hub.LastID())
+	}
+
+	// Only the last three events are remembered
+	sub, replay := hub.Subscribe(eventIDs[0], 4, nil)
+	defer sub.Close()
+	assert.Equal(t, []int64{3, 4, 5}, rowIDs(replay))
+	assert.Equal(t, eventIDs[2:], []int64{replay[0].ID, replay[1].ID, replay[2].ID})
+
+	other, replay := hub.Subscribe(eventIDs[3], 4, nil)
+	defer other.Close()
+	assert.Equal(t, []int64{5}, rowIDs(replay))
+
+	hub.Publish(change(6, "ride1"))
+	event := <-other.Events()
+	assert.Equal(t, int64(6), event.Change.ID)
+	assert.Equal(t, hub.LastID(), event.ID)
+}
+
+func TestHub_ReplayInPublishOrder(t *testing.T) {
+	hub := NewHub(10)
+
+	// Row 5 commits before row 4, so it is notified first
+	hub.Publish(change(5, "ride1"))
+	afterFive := hub.LastID()
+	hub.Publish(change(4, "ride2"))
+
+	sub, replay := hub.Subscribe(afterFive, 4, nil)
+	defer sub.Close()
+	assert.Equal(t, []int64{4}, rowIDs(replay), "a change published after the last event seen must be replayed")
+	assert.Greater(t, replay[0].ID, afterFive)
+}
+
+func TestHub_ReplayForeignID(t *testing.T) {
+	hub := NewHub(10)
+	other := NewHub(10)
+	hub.Publish(change(1, "ride1"))
+	hub.Publish(change(2, "ride1"))
+	other.Publish(change(9, "ride1"))
+
+	// An id from another hub, such as another instance or a restarted process, replays the backlog
+	sub, replay := hub.Subscribe(other.LastID(), 4, nil)
+	defer sub.Close()
+	assert.Equal(t, []int64{1, 2}, rowIDs(replay))
+
+	// So does a row id handed out as an event id before hubs numbered their events
+	late, replay := hub.Subscribe(1, 4, nil)
+	defer late.Close()
+	assert.Equal(t, []int64{1, 2}, rowIDs(replay))
+}
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(10)
+	slow, _ := hub.Subscribe(0, 1, nil)
+	fast, _ := hub.Subscribe(0, 10, nil)
+	defer fast.Close()
+
+	for id := int64(1); id <= 3; id++ {
+		hub.Publish(change(id, "ride1"))
+	}
+
+	assert.Equal(t, int64(1), (<-slow.Events()).Change.ID)
+	_, open := <-slow.Events()
+	assert.False(t, open, "slow subscriber should be dropped")
+	assert.Len(t, fast.Events(), 3)
+	assert.Equal(t, 1, hub.Subscribers())
+
+	// Closing an already dropped subscription is harmless
+	slow.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(10)
+	sub, _ := hub.Subscribe(0, 1, nil)
+	hub.Close()
+
+	_, open := <-sub.Events()
+	assert.False(t, open)
+
+	late, _ := hub.Subscribe(0, 1, nil)
+	_, open = <-late.Events()
+	assert.False(t, open)
+	hub.Publish(change(1, "ride1"))
+}
+
+type flakySource struct {
+	calls atomic.Int32
+}
+
+func (s *flakySource) ListenRideDataChanges(ctx context.Context, fn func(repository.RideDataChange)) error {
+	if s.calls.Add(1) == 1 {
+		return errors.New("connection reset")
+	}
+	fn(change(7, "ride1"))
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRelay_Reconnects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping relay backoff test in short mode")
+	}
+	hub := NewHub(10)
+	sub, _ := hub.Subscribe(0, 4, nil)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var failures atomic.Int32
+	done := make(chan struct{})
+	source := &flakySource{}
+	go func() {
+		Relay(ctx, source, hub, 5*time.Second, func(error) { failures.Add(1) })
+		close(done)
+	}()
+
+	select {
+	case e := <-sub.Events():
+		assert.Equal(t, int64(7), e.Change.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not reconnect")
+	}
+	require.Equal(t, int32(1), failures.Load())
+
+	cancel()
+	<-done
+}
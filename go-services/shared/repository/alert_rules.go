@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxAlertRulesPerKey bounds how many rules one API key may register
+const MaxAlertRulesPerKey = 50
+
+// ErrAlertRuleNotFound is returned for rules that do not exist or belong to another API key
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+// ErrAlertRuleLimit is returned when an API key already has MaxAlertRulesPerKey rules
+var ErrAlertRuleLimit = errors.New("alert rule limit reached")
+
+// AlertRuleRepository stores the wait time alerts of API keys, the queue of triggered alerts and the
+// log of their deliveries
+type AlertRuleRepository struct {
+	pool *pgxpool.Pool
+}
+
+// AlertRules returns an alert rule repository sharing the connection pool of r
+func (r *RideDataHistoryRepository) AlertRules() *AlertRuleRepository {
+	return &AlertRuleRepository{pool: r.pool}
+}
+
+const alertRuleColumns = `id, api_key_id, ride_id, comparison, threshold, window_start, window_end, timezone,
+	park_hours_only, cooldown_minutes, channel, target, COALESCE(push_p256dh, ''), COALESCE(push_auth, ''),
+	enabled, last_triggered_at, created_at`
+
+func scanAlertRule(row pgx.Row) (*models.AlertRule, error) {
+	var a models.AlertRule
+	if err := row.Scan(&a.ID, &a.APIKeyID, &a.RideID, &a.Comparison, &a.Threshold, &a.WindowStart, &a.WindowEnd,
+		&a.Timezone, &a.ParkHoursOnly, &a.CooldownMinutes, &a.Channel, &a.Target, &a.PushP256DH, &a.PushAuth,
+		&a.Enabled, &a.LastTriggeredAt, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AlertRuleRepository) queryAlertRules(ctx context.Context, query string, args ...any) ([]*models.AlertRule, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return rules, nil
+}
+
+// nullIfEmpty stores empty strings as NULL
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// CreateAlertRule stores rule for its API key, or returns ErrAlertRuleLimit when the key has too
+// many rules already
+func (r *AlertRuleRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) (*models.AlertRule, error) {
+	query := `
+		INSERT INTO alert_rules (api_key_id, ride_id, comparison, threshold, window_start, window_end, timezone,
+			park_hours_only, cooldown_minutes, channel, target, push_p256dh, push_auth)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		WHERE (SELECT COUNT(*) FROM alert_rules WHERE api_key_id = $1) < $14
+		RETURNING ` + alertRuleColumns
+	created, err := scanAlertRule(r.pool.QueryRow(ctx, query, rule.APIKeyID, rule.RideID, rule.Comparison,
+		rule.Threshold, rule.WindowStart, rule.WindowEnd, rule.Timezone, rule.ParkHoursOnly, rule.CooldownMinutes,
+		rule.Channel, rule.Target, nullIfEmpty(rule.PushP256DH), nullIfEmpty(rule.PushAuth), MaxAlertRulesPerKey))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlertRuleLimit
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return created, nil
+}
+
+// ListAlertRules returns the rules of an API key, oldest first
+func (r *AlertRuleRepository) ListAlertRules(ctx context.Context, apiKeyID int64) ([]*models.AlertRule, error) {
+	return r.queryAlertRules(ctx, `SELECT `+alertRuleColumns+` FROM alert_rules WHERE api_key_id = $1 ORDER BY id`, apiKeyID)
+}
+
+// GetAlertRule returns a rule of an API key, or ErrAlertRuleNotFound
+func (r *AlertRuleRepository) GetAlertRule(ctx context.Context, apiKeyID, id int64) (*models.AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE id = $1 AND api_key_id = $2`
+	rule, err := scanAlertRule(r.pool.QueryRow(ctx, query, id, apiKeyID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// DeleteAlertRule deletes a rule of an API key along with its queued alerts and delivery log
+func (r *AlertRuleRepository) DeleteAlertRule(ctx context.Context, apiKeyID, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1 AND api_key_id = $2`, id, apiKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// ListAlertDeliveries returns the newest deliveries of a rule of an API key, newest first
+func (r *AlertRuleRepository) ListAlertDeliveries(ctx context.Context, apiKeyID, ruleID int64, limit int) ([]*models.AlertDelivery, error) {
+	if _, err := r.GetAlertRule(ctx, apiKeyID, ruleID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT id, rule_id, channel, status, error, ride_id, wait_time, created_at
+		FROM alert_deliveries
+		WHERE rule_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+	rows, err := r.pool.Query(ctx, query, ruleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.AlertDelivery{}
+	for rows.Next() {
+		var d models.AlertDelivery
+		if err := rows.Scan(&d.ID, &d.RuleID, &d.Channel, &d.Status, &d.Error, &d.RideID, &d.WaitTime, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert delivery: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// EnabledAlertRulesForRides returns the enabled rules of the rides whose API key is not revoked
+func (r *AlertRuleRepository) EnabledAlertRulesForRides(ctx context.Context, rideIDs []string) ([]*models.AlertRule, error) {
+	query := `
+		SELECT ` + alertRuleColumns + `
+		FROM alert_rules
+		WHERE enabled AND ride_id = ANY($1)
+			AND api_key_id IN (SELECT id FROM api_keys WHERE revoked_at IS NULL)`
+	return r.queryAlertRules(ctx, query, rideIDs)
+}
+
+// QueueAlert claims the cooldown of the alert's rule at now and queues the alert for delivery in
+// the same statement, reporting whether it was queued. A rule that is disabled or triggered within
+// its cooldown is not claimed, so concurrent collectors queue a rule once per cooldown.
+func (r *AlertRuleRepository) QueueAlert(ctx context.Context, alert *models.QueuedAlert, now time.Time) (bool, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE alert_rules SET last_triggered_at = $2
+			WHERE id = $1 AND enabled
+				AND (last_triggered_at IS NULL OR last_triggered_at <= $2 - make_interval(mins => cooldown_minutes))
+			RETURNING id
+		)
+		INSERT INTO alert_queue (rule_id, ride_id, wait_time, message, next_attempt_at, created_at)
+		SELECT id, $3, $4, $5::jsonb, $2, $2 FROM claimed`
+	tag, err := r.pool.Exec(ctx, query, alert.Rule.ID, now.UTC(), alert.RideID, alert.WaitTime, string(alert.Message))
+	if err != nil {
+		return false, fmt.Errorf("failed to queue alert of rule %d: %w", alert.Rule.ID, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ClaimQueuedAlerts claims up to limit queued alerts that are due at now, counting an attempt for
+// each. A claim holds an alert for lease, after which another collector may retry it if the
+// claiming one never reported back.
+func (r *AlertRuleRepository) ClaimQueuedAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.QueuedAlert, error) {
+	query := `
+		UPDATE alert_queue q
+		SET attempts = q.attempts + 1, next_attempt_at = $2
+		FROM alert_rules r
+		WHERE r.id = q.rule_id AND q.id IN (
+			SELECT id FROM alert_queue
+			WHERE next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING q.id, q.ride_id, q.wait_time, q.message, q.attempts, r.id, r.channel, r.target,
+			COALESCE(r.push_p256dh, ''), COALESCE(r.push_auth, ''), r.cooldown_minutes`
+	rows, err := r.pool.Query(ctx, query, now.UTC(), now.Add(lease).UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim queued alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.QueuedAlert
+	for rows.Next() {
+		var a models.QueuedAlert
+		if err := rows.Scan(&a.ID, &a.RideID, &a.WaitTime, &a.Message, &a.Attempts, &a.Rule.ID, &a.Rule.Channel,
+			&a.Rule.Target, &a.Rule.PushP256DH, &a.Rule.PushAuth, &a.Rule.CooldownMinutes); err != nil {
+			return nil, fmt.Errorf("failed to scan queued alert: %w", err)
+		}
+		alerts = append(alerts, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return alerts, nil
+}
+
+// CompleteQueuedAlert removes an alert that was delivered or will not be retried from the queue
+func (r *AlertRuleRepository) CompleteQueuedAlert(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM alert_queue WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to complete queued alert %d: %w", id, err)
+	}
+	return nil
+}
+
+// RetryQueuedAlert records a failed attempt and schedules the next one at next
+func (r *AlertRuleRepository) RetryQueuedAlert(ctx context.Context, id int64, next time.Time, lastError string) error {
+	query := `UPDATE alert_queue SET next_attempt_at = $2, last_error = $3 WHERE id = $1`
+	if _, err := r.pool.Exec(ctx, query, id, next.UTC(), lastError); err != nil {
+		return fmt.Errorf("failed to reschedule queued alert %d: %w", id, err)
+	}
+	return nil
+}
+
+// DisableAlertRule stops evaluating a rule, e.g. once its push subscription has expired
+func (r *AlertRuleRepository) DisableAlertRule(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `UPDATE alert_rules SET enabled = false WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to disable alert rule %d: %w", id, err)
+	}
+	return nil
+}
+
+// RecordAlertDelivery appends to the delivery log
+func (r *AlertRuleRepository) RecordAlertDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	query := `
+		INSERT INTO alert_deliveries (rule_id, channel, status, error, ride_id, wait_time)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.pool.Exec(ctx, query, d.RuleID, d.Channel, d.Status, d.Error, d.RideID, d.WaitTime); err != nil {
+		return fmt.Errorf("failed to record alert delivery for rule %d: %w", d.RuleID, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"go-services/shared/models"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRuleRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, name := range []string{"20261019110000_add_api_keys", "20261019130000_add_alert_rules", "20261019150000_add_alert_queue"} {
+		migration, err := os.ReadFile("../../../prisma/migrations/" + name + "/migration.sql")
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, string(migration))
+		require.NoError(t, err)
+	}
+
+	base := newRideDataHistoryRepositoryForTest(pool)
+	owner, _, err := base.APIKeys().CreateAPIKey(ctx, "owner", APIKeyLimits{})
+	require.NoError(t, err)
+	other, _, err := base.APIKeys().CreateAPIKey(ctx, "other", APIKeyLimits{})
+	require.NoError(t, err)
+	repo := base.AlertRules()
+
+	start, end := 21*60, 60
+	created, err := repo.CreateAlertRule(ctx, &models.AlertRule{
+		APIKeyID: owner.ID, RideID: "ride-1", Comparison: models.AlertAtOrBelow, Threshold: 20,
+		WindowStart: &start, WindowEnd: &end, Timezone: "America/Los_Angeles", CooldownMinutes: 30,
+		Channel: models.AlertChannelWebhook, Target: "https://example.com/hook",
+	})
+	require.NoError(t, err)
+	assert.True(t, created.Enabled)
+	assert.Equal(t, 21*60, *created.WindowStart)
+	assert.Empty(t, created.PushP256DH)
+
+	// Rules are scoped to their API key
+	rules, err := repo.ListAlertRules(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	_, err = repo.GetAlertRule(ctx, other.ID, created.ID)
+	assert.ErrorIs(t, err, ErrAlertRuleNotFound)
+	assert.ErrorIs(t, repo.DeleteAlertRule(ctx, other.ID, created.ID), ErrAlertRuleNotFound)
+	_, err = repo.ListAlertDeliveries(ctx, other.ID, created.ID, 10)
+	assert.ErrorIs(t, err, ErrAlertRuleNotFound)
+
+	enabled, err := repo.EnabledAlertRulesForRides(ctx, []string{"ride-1", "ride-2"})
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, created.ID, enabled[0].ID)
+
+	// The cooldown lets one alert through
+	wait := 15
+	alert := &models.QueuedAlert{Rule: *created, RideID: "ride-1", WaitTime: &wait, Message: []byte(`{"event":"alert.triggered"}`)}
+	now := time.Now().Truncate(time.Millisecond)
+	queued, err := repo.QueueAlert(ctx, alert, now)
+	require.NoError(t, err)
+	assert.True(t, queued)
+	queued, err = repo.QueueAlert(ctx, alert, now.Add(29*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, queued, "a rule is queued once per cooldown")
+
+	// A claimed alert carries its rule's destination and is held for the lease
+	claimed, err := repo.ClaimQueuedAlerts(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, created.ID, claimed[0].Rule.ID)
+	assert.Equal(t, "https://example.com/hook", claimed[0].Rule.Target)
+	assert.Equal(t, 15, *claimed[0].WaitTime)
+	assert.JSONEq(t, `{"event":"alert.triggered"}`, string(claimed[0].Message))
+	assert.Equal(t, 1, claimed[0].Attempts)
+	again, err := repo.ClaimQueuedAlerts(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again, "a claimed alert is not claimed again within its lease")
+
+	require.NoError(t, repo.RetryQueuedAlert(ctx, claimed[0].ID, now.Add(30*time.Second), "receiver returned status 503"))
+	again, err = repo.ClaimQueuedAlerts(ctx, now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, 2, again[0].Attempts)
+	require.NoError(t, repo.CompleteQueuedAlert(ctx, again[0].ID))
+	again, err = repo.ClaimQueuedAlerts(ctx, now.Add(time.Hour), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	queued, err = repo.QueueAlert(ctx, alert, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, queued, "a rule can be queued again after its cooldown")
+
+	require.NoError(t, repo.RecordAlertDelivery(ctx, &models.AlertDelivery{
+		RuleID: created.ID, Channel: created.Channel, Status: models.AlertDelivered, RideID: "ride-1", WaitTime: &wait,
+	}))
+	deliveries, err := repo.ListAlertDeliveries(ctx, owner.ID, created.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 15, *deliveries[0].WaitTime)
+
+	// Disabled rules and rules of revoked keys are not evaluated
+	require.NoError(t, repo.DisableAlertRule(ctx, created.ID))
+	enabled, err = repo.EnabledAlertRulesForRides(ctx, []string{"ride-1"})
+	require.NoError(t, err)
+	assert.Empty(t, enabled)
+	queued, err = repo.QueueAlert(ctx, alert, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, queued)
+
+	second, err := repo.CreateAlertRule(ctx, &models.AlertRule{
+		APIKeyID: owner.ID, RideID: "ride-1", Comparison: models.AlertAbove, Threshold: 60, Timezone: "UTC",
+		CooldownMinutes: 60, Channel: models.AlertChannelEmail, Target: "guest@example.com",
+	})
+	require.NoError(t, err)
+	require.NoError(t, base.APIKeys().RevokeAPIKey(ctx, owner.ID))
+	enabled, err = repo.EnabledAlertRulesForRides(ctx, []string{"ride-1"})
+	require.NoError(t, err)
+	assert.Empty(t, enabled)
+
+	// Deleting a rule deletes its queued alerts and delivery log
+	require.NoError(t, repo.DeleteAlertRule(ctx, owner.ID, created.ID))
+	_, err = repo.GetAlertRule(ctx, owner.ID, created.ID)
+	assert.ErrorIs(t, err, ErrAlertRuleNotFound)
+	var logged, pending int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM alert_deliveries`).Scan(&logged))
+	assert.Zero(t, logged)
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM alert_queue`).Scan(&pending))
+	assert.Zero(t, pending)
+
+	rules, err = repo.ListAlertRules(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, second.ID, rules[0].ID)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// APIKeyPrefix starts every issued key, which makes keys recognisable to secret scanners
+const APIKeyPrefix = "wtk_"
+
+// apiKeyDisplayLength is how many leading characters of a key are stored and shown as its prefix
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// ErrAPIKeyNotFound is returned for keys that were never issued or have been revoked
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyLimits are the rate limits of a key
+type APIKeyLimits struct {
+	RequestsPerMinute int
+	Burst             int
+	// DailyQuota caps requests per UTC day; 0 means unlimited
+	DailyQuota int
+}
+
+// APIKeyRepository stores API keys and their daily usage
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// APIKeys returns an API key repository sharing the connection pool of r
+func (r *RideDataHistoryRepository) APIKeys() *APIKeyRepository {
+	return &APIKeyRepository{pool: r.pool}
+}
+
+// HashAPIKey returns the stored form of a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKey generates a random key with 256 bits of entropy
+func newAPIKey() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + hex.EncodeToString(b[:]), nil
+}
+
+const apiKeyColumns = `id, name, prefix, requests_per_minute, burst, daily_quota, created_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var k models.APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.RequestsPerMinute, &k.Burst, &k.DailyQuota, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateAPIKey issues a key. The returned key is the only copy of it; only its hash is stored.
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, name string, limits APIKeyLimits) (*models.APIKey, string, error) {
+	key, err := newAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, requests_per_minute, burst, daily_quota)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + apiKeyColumns
+	created, err := scanAPIKey(r.pool.QueryRow(ctx, query, name, key[:apiKeyDisplayLength], HashAPIKey(key),
+		limits.RequestsPerMinute, limits.Burst, limits.DailyQuota))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	return created, key, nil
+}
+
+// LookupAPIKey finds the active key matching key, returning ErrAPIKeyNotFound when there is none
+func (r *APIKeyRepository) LookupAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	found, err := scanAPIKey(r.pool.QueryRow(ctx, query, HashAPIKey(key)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	return found, nil
+}
+
+// ListAPIKeys returns every key, including revoked ones, oldest first
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey disables a key. Revoking an unknown or already revoked key returns ErrAPIKeyNotFound.
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// IncrementAPIKeyUsage counts one request of a key on the UTC day of at and returns the day's total
+func (r *APIKeyRepository) IncrementAPIKeyUsage(ctx context.Context, id int64, at time.Time) (int64, error) {
+	query := `
+		INSERT INTO api_key_usage (api_key_id, day, requests)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (api_key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+		RETURNING requests`
+	var requests int64
+	day := at.UTC().Truncate(24 * time.Hour)
+	if err := r.pool.QueryRow(ctx, query, id, day).Scan(&requests); err != nil {
+		return 0, fmt.Errorf("failed to count api key usage: %w", err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	migration, err := os.ReadFile("../../../prisma/migrations/20261019110000_add_api_keys/migration.sql")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, string(migration))
+	require.NoError(t, err)
+
+	repo := newRideDataHistoryRepositoryForTest(pool).APIKeys()
+	limits := APIKeyLimits{RequestsPerMinute: 120, Burst: 20, DailyQuota: 1000}
+
+	created, key, err := repo.CreateAPIKey(ctx, "partner", limits)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+	assert.Equal(t, key[:len(created.Prefix)], created.Prefix)
+	assert.Equal(t, 120, created.RequestsPerMinute)
+
+	found, err := repo.LookupAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+
+	_, err = repo.LookupAPIKey(ctx, key+"x")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	// Usage is counted per UTC day
+	day := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC)
+	for want := int64(1); want <= 3; want++ {
+		n, err := repo.IncrementAPIKeyUsage(ctx, created.ID, day)
+		require.NoError(t, err)
+		assert.Equal(t, want, n)
+	}
+	n, err := repo.IncrementAPIKeyUsage(ctx, created.ID, day.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, repo.RevokeAPIKey(ctx, created.ID))
+	_, err = repo.LookupAPIKey(ctx, key)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.ErrorIs(t, repo.RevokeAPIKey(ctx, created.ID), ErrAPIKeyNotFound)
+
+	keys, err := repo.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RequiredMigration is the newest Prisma migration the Go services rely on. Readiness checks fail
+// while the database is behind it.
+const RequiredMigration = "20261019160000_add_ride_data_history_default_partition"
+
+// freshnessLookback bounds the freshness query to recent partitions. Parks without rows in this
+// period are reported without a last update.
+const freshnessLookback = 7 * 24 * time.Hour
+
+// LatestMigration returns the name of the newest migration Prisma applied successfully
+func (r *RideDataHistoryRepository) LatestMigration(ctx context.Context) (string, error) {
+	query := `
+		SELECT migration_name
+		FROM _prisma_migrations
+		WHERE finished_at IS NOT NULL AND rolled_back_at IS NULL
+		ORDER BY migration_name DESC
+		LIMIT 1`
+
+	var name string
+	if err := r.pool.QueryRow(ctx, query).Scan(&name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read the applied migrations: %w", err)
+	}
+	return name, nil
+}
+
+// LatestUpdateByPark returns the newest last_updated of each park's ride data history over the past
+// week
+func (r *RideDataHistoryRepository) LatestUpdateByPark(ctx context.Context) (map[string]time.Time, error) {
+	query := `
+		SELECT park_id, MAX(last_updated)
+		FROM ride_data_history
+		WHERE last_updated >= $1
+		GROUP BY park_id`
+
+	rows, err := r.pool.Query(ctx, query, time.Now().Add(-freshnessLookback))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ride data freshness: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[string]time.Time)
+	for rows.Next() {
+		var parkID string
+		var lastUpdated time.Time
+		if err := rows.Scan(&parkID, &lastUpdated); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		latest[parkID] = lastUpdated
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return latest, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"go-services/shared/models"
+	"sync"
+	"time"
+)
+
+// LatestStateTTL is how long a cached state is trusted before it is checked against the database
+// again. Collectors do not see each other's writes, so this bounds how long a state written by
+// another instance can go unnoticed by the throttle.
+const LatestStateTTL = ThrottleWindow
+
+// latestState is the cached state of a ride. A nil record means the database had no recent row.
+type latestState struct {
+	record    *models.RideDataHistoryRecord
+	checkedAt time.Time
+}
+
+// LatestStateCache remembers the last record written for each ride so the insert throttle
+// does not have to re-query the newest row of every ride on each poll. Rides without a recent
+// row are remembered too, and every entry is re-read once it is older than LatestStateTTL.
+// A nil *LatestStateCache is valid and caches nothing.
+type LatestStateCache struct {
+	mu      sync.RWMutex
+	records map[string]latestState
+	now     func() time.Time
+}
+
+// NewLatestStateCache creates an empty cache
+func NewLatestStateCache() *LatestStateCache {
+	return &LatestStateCache{
+		records: make(map[string]latestState),
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached latest record for a ride. It reports false for rides that are not
+// cached and for rides known to have no recent row.
+func (c *LatestStateCache) Get(rideID string) (*models.RideDataHistoryRecord, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, ok := c.records[rideID]
+	return state.record, ok && state.record != nil
+}
+
+// Set stores record as the latest state for its ride unless a newer record is already cached
+func (c *LatestStateCache) Set(record *models.RideDataHistoryRecord) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := latestState{record: record, checkedAt: c.now()}
+	if existing, ok := c.records[record.RideID]; ok && existing.record != nil && existing.record.LastUpdated.After(record.LastUpdated) {
+		state.record = existing.record
+	}
+	c.records[record.RideID] = state
+}
+
+// SetAbsent records that the database has no recent row for a ride. A record that is already
+// cached is kept, since it is still the ride's last known state.
+func (c *LatestStateCache) SetAbsent(rideID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records[rideID] = latestState{record: c.records[rideID].record, checkedAt: c.now()}
+}
+
+// Delete forgets the cached state of a ride so the next insert re-reads it from the database
+func (c *LatestStateCache) Delete(rideID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.records, rideID)
+}
+
+// Stale returns the ride IDs that have no cached state or whose state was last checked more than
+// LatestStateTTL ago
+func (c *LatestStateCache) Stale(rideIDs []string) []string {
+	if c == nil {
+		return rideIDs
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cutoff := c.now().Add(-LatestStateTTL)
+	var stale []string
+	for _, id := range rideIDs {
+		if state, ok := c.records[id]; !ok || state.checkedAt.Before(cutoff) {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+// Len returns the number of rides with a cached record
+func (c *LatestStateCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := 0
+	for _, state := range c.records {
+		if state.record != nil {
+			n++
+		}
+	}
+	return n
+}
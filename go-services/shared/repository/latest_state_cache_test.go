@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestStateCache(t *testing.T) {
+	cache := NewLatestStateCache()
+	base := time.Now().UTC()
+
+	older := &models.RideDataHistoryRecord{RideID: "ride1", Status: "OPERATING", LastUpdated: base}
+	newer := &models.RideDataHistoryRecord{RideID: "ride1", Status: "CLOSED", LastUpdated: base.Add(time.Minute)}
+
+	assert.Equal(t, []string{"ride1", "ride2"}, cache.Stale([]string{"ride1", "ride2"}))
+
+	cache.Set(newer)
+	cache.Set(older) // an older record must not replace a newer one
+
+	got, ok := cache.Get("ride1")
+	assert.True(t, ok)
+	assert.Equal(t, "CLOSED", got.Status)
+	assert.Equal(t, []string{"ride2"}, cache.Stale([]string{"ride1", "ride2"}))
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Delete("ride1")
+	_, ok = cache.Get("ride1")
+	assert.False(t, ok)
+}
+
+func TestLatestStateCache_Nil(t *testing.T) {
+	var cache *LatestStateCache
+
+	cache.Set(&models.RideDataHistoryRecord{RideID: "ride1"})
+	_, ok := cache.Get("ride1")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"ride1"}, cache.Stale([]string{"ride1"}))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLatestStateCache_Staleness(t *testing.T) {
+	cache := NewLatestStateCache()
+	now := time.Now().UTC()
+	cache.now = func() time.Time { return now }
+
+	cache.Set(&models.RideDataHistoryRecord{RideID: "ride1", Status: "OPERATING", LastUpdated: now})
+	cache.SetAbsent("ride2")
+	assert.Empty(t, cache.Stale([]string{"ride1", "ride2"}), "rides without a recent row are cached too")
+	_, ok := cache.Get("ride2")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.Len())
+
+	// Entries are checked against the database again once they are older than the TTL
+	now = now.Add(LatestStateTTL + time.Second)
+	assert.Equal(t, []string{"ride1", "ride2"}, cache.Stale([]string{"ride1", "ride2"}))
+
+	// Confirming that a ride has no recent row keeps its last known record
+	cache.SetAbsent("ride1")
+	got, ok := cache.Get("ride1")
+	assert.True(t, ok)
+	assert.Equal(t, "OPERATING", got.Status)
+	assert.Equal(t, []string{"ride2"}, cache.Stale([]string{"ride1", "ride2"}))
+}
+
+func TestIsThrottled(t *testing.T) {
+	base := time.Now().UTC()
+	wait := 30
+	last := &models.RideDataHistoryRecord{RideID: "ride1", Status: "OPERATING", StandbyWaitTime: &wait, LastUpdated: base}
+
+	same := *last
+	same.LastUpdated = base.Add(ThrottleWindow - time.Minute)
+	assert.True(t, isThrottled(last, &same))
+
+	stale := *last
+	stale.LastUpdated = base.Add(ThrottleWindow)
+	assert.False(t, isThrottled(last, &stale))
+
+	changed := *last
+	changed.Status = "CLOSED"
+	changed.LastUpdated = base.Add(time.Minute)
+	assert.False(t, isThrottled(last, &changed))
+}
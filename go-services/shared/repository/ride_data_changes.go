@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RideDataChangesChannel is the Postgres NOTIFY channel that announces inserted records whose
+// wait time, status or return time differs from the previous state of the ride
+const RideDataChangesChannel = "ride_data_changes"
+
+// RideDataChange is the NOTIFY payload for a changed ride state. ID is the ride_data_history row id.
+// Ids are assigned on insert while notifications arrive in commit order, so changes are not
+// necessarily notified in id order.
+type RideDataChange struct {
+	ID              int64      `json:"id"`
+	RideID          string     `json:"rideId"`
+	ParkID          string     `json:"parkId"`
+	Name            string     `json:"name"`
+	Status          string     `json:"status"`
+	WaitTime        *int       `json:"waitTime"`
+	ReturnTimeState *string    `json:"returnTimeState,omitempty"`
+	ReturnStart     *time.Time `json:"returnStart,omitempty"`
+	ReturnEnd       *time.Time `json:"returnEnd,omitempty"`
+	LastUpdated     time.Time  `json:"lastUpdated"`
+}
+
+// NewRideDataChange describes an inserted record as a change notification
+func NewRideDataChange(record *models.RideDataHistoryRecord) RideDataChange {
+	return RideDataChange{
+		ID:              record.ID,
+		RideID:          record.RideID,
+		ParkID:          record.ParkID,
+		Name:            record.Name,
+		Status:          record.Status,
+		WaitTime:        record.StandbyWaitTime,
+		ReturnTimeState: record.ReturnTimeState,
+		ReturnStart:     record.ReturnStart,
+		ReturnEnd:       record.ReturnEnd,
+		LastUpdated:     record.LastUpdated,
+	}
+}
+
+// notifyRideDataChanges queues one notification per record on the transaction. Postgres delivers
+// them to listeners when the transaction commits and drops them if it rolls back.
+func notifyRideDataChanges(ctx context.Context, tx pgx.Tx, records []*models.RideDataHistoryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	payloads := make([]string, 0, len(records))
+	for _, record := range records {
+		payload, err := json.Marshal(NewRideDataChange(record))
+		if err != nil {
+			return fmt.Errorf("failed to encode change for ride %s: %w", record.RideID, err)
+		}
+		payloads = append(payloads, string(payload))
+	}
+
+	query := `SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload`
+	if _, err := tx.Exec(ctx, query, RideDataChangesChannel, payloads); err != nil {
+		return fmt.Errorf("failed to notify ride data changes: %w", err)
+	}
+	return nil
+}
+
+// ListenRideDataChanges subscribes to RideDataChangesChannel on a dedicated connection and passes each
+// change to fn until ctx is cancelled or the connection fails. Malformed payloads are skipped.
+// The connection is taken out of the pool for the lifetime of the subscription.
+func (r *RideDataHistoryRepository) ListenRideDataChanges(ctx context.Context, fn func(RideDataChange)) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listener connection: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{RideDataChangesChannel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", RideDataChangesChannel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed waiting for ride data changes: %w", err)
+		}
+
+		var change RideDataChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			continue
+		}
+		fn(change)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRideDataChange_JSON(t *testing.T) {
+	wait := 35
+	at := time.Date(2025, 9, 14, 18, 30, 0, 0, time.UTC)
+	change := NewRideDataChange(&models.RideDataHistoryRecord{
+		ID: 42, RideID: "ride1", ParkID: "park1", Name: "Space Mountain",
+		Status: "OPERATING", StandbyWaitTime: &wait, LastUpdated: at,
+	})
+
+	b, err := json.Marshal(change)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":42,"rideId":"ride1","parkId":"park1","name":"Space Mountain","status":"OPERATING","waitTime":35,"lastUpdated":"2025-09-14T18:30:00Z"}`, string(b))
+
+	var decoded RideDataChange
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, change, decoded)
+}
+
+func TestRideDataHistoryRepository_ListenRideDataChanges_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo := newRideDataHistoryRepositoryForTest(pool)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	changes := make(chan RideDataChange, 10)
+	listening := make(chan error, 1)
+	go func() {
+		listening <- repo.ListenRideDataChanges(ctx, func(change RideDataChange) { changes <- change })
+	}()
+	// Give the listener time to issue LISTEN before the first insert
+	time.Sleep(500 * time.Millisecond)
+
+	base := time.Now().UTC().Truncate(time.Second)
+	record := func(wait int, at time.Time) *models.RideDataHistoryRecord {
+		return &models.RideDataHistoryRecord{
+			RideID: "ride1", ExternalID: "ride1", ParkID: "park1", EntityType: "ATTRACTION",
+			Name: "Ride 1", Status: "OPERATING", LastUpdated: at, CreatedAt: at, UpdatedAt: at,
+			OperatingHours: "[]", Forecast: "[]", StandbyWaitTime: &wait,
+		}
+	}
+
+	// First record for the ride is a change
+	_, _, err := repo.InsertRideDataHistoryWithCounts(ctx, []*models.RideDataHistoryRecord{record(10, base)})
+	require.NoError(t, err)
+	first := <-changes
+	assert.Equal(t, "ride1", first.RideID)
+	assert.Equal(t, 10, *first.WaitTime)
+	assert.NotZero(t, first.ID)
+
+	// Same state after the throttle window is stored but not announced
+	_, _, err = repo.InsertRideDataHistoryWithCounts(ctx, []*models.RideDataHistoryRecord{record(10, base.Add(ThrottleWindow))})
+	require.NoError(t, err)
+
+	// A new wait time is announced with a larger id
+	_, _, err = repo.InsertRideDataHistoryWithCounts(ctx, []*models.RideDataHistoryRecord{record(25, base.Add(2*ThrottleWindow))})
+	require.NoError(t, err)
+	second := <-changes
+	assert.Equal(t, 25, *second.WaitTime)
+	assert.Greater(t, second.ID, first.ID)
+
+	select {
+	case extra := <-changes:
+		t.Fatalf("unexpected change %+v", extra)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-listening, context.Canceled)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+)
+
+// Page size limits for keyset-paginated history queries
+const (
+	DefaultHistoryPageSize = 200
+	MaxHistoryPageSize     = 1000
+)
+
+// HistoryCursor identifies a position in ride data history ordered newest first by (last_updated, id)
+type HistoryCursor struct {
+	LastUpdated time.Time `json:"t"`
+	ID          int64     `json:"id"`
+}
+
+// Encode renders the cursor as an opaque URL-safe token
+func (c HistoryCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeHistoryCursor parses a token produced by HistoryCursor.Encode
+func DecodeHistoryCursor(token string) (HistoryCursor, error) {
+	var c HistoryCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.LastUpdated.IsZero() || c.ID <= 0 {
+		return c, fmt.Errorf("invalid cursor: missing position")
+	}
+	return c, nil
+}
+
+// HistoryPage is one page of ride data history plus the cursor for the following page.
+// Next is nil on the last page.
+type HistoryPage struct {
+	Records []*models.RideDataHistoryRecord
+	Next    *HistoryCursor
+}
+
+// ClampHistoryPageSize applies the default and maximum page sizes
+func ClampHistoryPageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return DefaultHistoryPageSize
+	}
+	if pageSize > MaxHistoryPageSize {
+		return MaxHistoryPageSize
+	}
+	return pageSize
+}
+
+// PageRideDataHistory returns up to pageSize rows matching the filter, newest first, starting strictly
+// after the given cursor. The filter's OrderBy and Limit are replaced by the keyset ordering.
+func (r *RideDataHistoryRepository) PageRideDataHistory(ctx context.Context, filter HistoryFilter, after *HistoryCursor, pageSize int) (HistoryPage, error) {
+	pageSize = ClampHistoryPageSize(pageSize)
+
+	filter.OlderThan = after
+	filter.OrderBy = []OrderBy{Desc("last_updated"), Desc("id")}
+	// Fetch one extra row to learn whether another page follows
+	filter.Limit = pageSize + 1
+
+	records, err := r.QueryRideDataHistory(ctx, filter)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	page := HistoryPage{Records: records}
+	if len(records) > pageSize {
+		page.Records = records[:pageSize]
+		last := page.Records[pageSize-1]
+		page.Next = &HistoryCursor{LastUpdated: last.LastUpdated, ID: last.ID}
+	}
+
+	return page, nil
+}
+
+// GetAllRideDataHistoryPage retrieves one page of all ride data history, newest first
+func (r *RideDataHistoryRepository) GetAllRideDataHistoryPage(ctx context.Context, after *HistoryCursor, pageSize int) (HistoryPage, error) {
+	page, err := r.PageRideDataHistory(ctx, HistoryFilter{}, after, pageSize)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to get ride data history page: %w", err)
+	}
+	return page, nil
+}
+
+// GetRideDataHistoryPageByPark retrieves one page of ride data history for a specific park, newest first
+func (r *RideDataHistoryRepository) GetRideDataHistoryPageByPark(ctx context.Context, parkID string, after *HistoryCursor, pageSize int) (HistoryPage, error) {
+	page, err := r.PageRideDataHistory(ctx, HistoryFilter{ParkID: parkID}, after, pageSize)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to get ride data history page for park %s: %w", parkID, err)
+	}
+	return page, nil
+}
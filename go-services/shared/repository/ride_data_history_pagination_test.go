@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	cursor := HistoryCursor{LastUpdated: time.Date(2025, 9, 14, 18, 30, 0, 0, time.UTC), ID: 12345}
+
+	token := cursor.Encode()
+	assert.NotContains(t, token, "=", "token should be URL safe without padding")
+
+	decoded, err := DecodeHistoryCursor(token)
+	require.NoError(t, err)
+	assert.True(t, cursor.LastUpdated.Equal(decoded.LastUpdated))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeHistoryCursor_Invalid(t *testing.T) {
+	for _, token := range []string{"", "not base64!", "e30", "eyJ0IjoiMjAyNS0wOS0xNFQxODozMDowMFoifQ"} {
+		_, err := DecodeHistoryCursor(token)
+		assert.Error(t, err, "token %q", token)
+	}
+}
+
+func TestClampHistoryPageSize(t *testing.T) {
+	assert.Equal(t, DefaultHistoryPageSize, ClampHistoryPageSize(0))
+	assert.Equal(t, DefaultHistoryPageSize, ClampHistoryPageSize(-5))
+	assert.Equal(t, 50, ClampHistoryPageSize(50))
+	assert.Equal(t, MaxHistoryPageSize, ClampHistoryPageSize(MaxHistoryPageSize+1))
+}
+
+func TestHistoryFilter_BuildOlderThan(t *testing.T) {
+	cursor := &HistoryCursor{LastUpdated: time.Now().UTC(), ID: 7}
+	query, args, err := HistoryFilter{ParkID: "park1", OlderThan: cursor}.build()
+	require.NoError(t, err)
+	assert.Contains(t, query, "(last_updated, id) < ($2, $3)")
+	assert.Equal(t, []any{"park1", cursor.LastUpdated, int64(7)}, args)
+}
+
+func TestRideDataHistoryRepository_PageRideDataHistory_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := newRideDataHistoryRepositoryForTest(pool)
+
+	// Two rides share every timestamp so the id tiebreak is exercised
+	base := time.Now().UTC().Truncate(time.Minute).Add(-2 * time.Hour)
+	for i := 0; i < 7; i++ {
+		at := base.Add(time.Duration(i) * ThrottleWindow)
+		var batch []*models.RideDataHistoryRecord
+		for _, rideID := range []string{"ride1", "ride2"} {
+			wait := i
+			batch = append(batch, &models.RideDataHistoryRecord{
+				RideID: rideID, ExternalID: rideID, ParkID: "park1", EntityType: "ATTRACTION",
+				Name: rideID, Status: "OPERATING", LastUpdated: at, CreatedAt: at, UpdatedAt: at,
+				OperatingHours: "[]", Forecast: "[]", StandbyWaitTime: &wait,
+			})
+		}
+		_, _, err := repo.InsertRideDataHistoryWithCounts(ctx, batch)
+		require.NoError(t, err)
+	}
+
+	seen := make(map[int64]bool)
+	var after *HistoryCursor
+	var previous *models.RideDataHistoryRecord
+	pages := 0
+	for {
+		page, err := repo.GetRideDataHistoryPageByPark(ctx, "park1", after, 4)
+		require.NoError(t, err)
+		pages++
+
+		for _, record := range page.Records {
+			assert.False(t, seen[record.ID], "record %d returned twice", record.ID)
+			seen[record.ID] = true
+			if previous != nil {
+				assert.False(t, record.LastUpdated.After(previous.LastUpdated), "records must be newest first")
+			}
+			previous = record
+		}
+
+		if page.Next == nil {
+			break
+		}
+		after = page.Next
+	}
+
+	assert.Len(t, seen, 14)
+	assert.Equal(t, 4, pages)
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// partitionedTableName is the parent table that is range-partitioned by month of last_updated
+const partitionedTableName = "ride_data_history"
+
+// defaultPartitionName is the DEFAULT partition that catches rows outside every monthly partition,
+// such as restored archives of months whose partition was dropped
+const defaultPartitionName = partitionedTableName + "_default"
+
+// MonthlyPartitionName returns the partition table name holding rows for the month containing t
+func MonthlyPartitionName(t time.Time) string {
+	return fmt.Sprintf("%s_%s", partitionedTableName, t.UTC().Format("2006_01"))
+}
+
+// monthStart truncates t to the first instant of its month in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionCache remembers the months whose partition is known to exist, so the collector does not
+// look them up on every run
+type partitionCache struct {
+	mu     sync.Mutex
+	months map[time.Time]bool
+}
+
+// missing returns the months that are not known to have a partition
+func (c *partitionCache) missing(months []time.Time) []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var missing []time.Time
+	for _, month := range months {
+		if !c.months[month] {
+			missing = append(missing, month)
+		}
+	}
+	return missing
+}
+
+// set records whether the partition of month exists
+func (c *partitionCache) set(month time.Time, exists bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.months == nil {
+		c.months = make(map[time.Time]bool)
+	}
+	if exists {
+		c.months[month] = true
+	} else {
+		delete(c.months, month)
+	}
+}
+
+// IsPartitioned reports whether ride_data_history is a declaratively partitioned table
+func (r *RideDataHistoryRepository) IsPartitioned(ctx context.Context) (bool, error) {
+	query := `
+		SELECT c.relkind = 'p'
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1 AND n.nspname = current_schema()`
+
+	var partitioned bool
+	err := r.pool.QueryRow(ctx, query, partitionedTableName).Scan(&partitioned)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", partitionedTableName, err)
+	}
+	return partitioned, nil
+}
+
+// EnsureMonthlyPartitions creates the partitions for the month containing from and the following
+// monthsAhead months if they do not exist yet, returning the names of newly created partitions.
+// Months already ensured by this repository are not looked up again. It is a no-op when
+// ride_data_history has not been migrated to a partitioned table.
+func (r *RideDataHistoryRepository) EnsureMonthlyPartitions(ctx context.Context, from time.Time, monthsAhead int) ([]string, error) {
+	months := make([]time.Time, 0, monthsAhead+1)
+	for i := 0; i <= monthsAhead; i++ {
+		months = append(months, monthStart(from).AddDate(0, i, 0))
+	}
+	months = r.partitions.missing(months)
+	if len(months) == 0 {
+		return nil, nil
+	}
+
+	partitioned, err := r.IsPartitioned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !partitioned {
+		return nil, nil
+	}
+
+	var created []string
+	for _, month := range months {
+		name := MonthlyPartitionName(month)
+		exists, err := r.tableExists(ctx, name)
+		if err != nil {
+			return created, err
+		}
+		if !exists {
+			if err := r.createMonthlyPartition(ctx, month); err != nil {
+				return created, err
+			}
+			created = append(created, name)
+		}
+		r.partitions.set(month, true)
+	}
+
+	return created, nil
+}
+
+// tableExists reports whether a table with the given name is visible in the search path
+func (r *RideDataHistoryRepository) tableExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	return exists, nil
+}
+
+// createMonthlyPartition creates the partition of month. PostgreSQL refuses to add a partition while
+// the DEFAULT partition holds rows in its range, so the table is created detached, those rows are
+// moved into it, and it is then attached, all in one transaction.
+func (r *RideDataHistoryRepository) createMonthlyPartition(ctx context.Context, month time.Time) error {
+	name := MonthlyPartitionName(month)
+	table := pgx.Identifier{name}.Sanitize()
+	parent := pgx.Identifier{partitionedTableName}.Sanitize()
+	// Bounds are literal timestamps because DDL does not accept bind parameters;
+	// last_updated is stored as UTC timestamp without time zone
+	lower := month.Format("2006-01-02 15:04:05")
+	upper := month.AddDate(0, 1, 0).Format("2006-01-02 15:04:05")
+
+	hasDefault, err := r.tableExists(ctx, defaultPartitionName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, table, parent),
+	}
+	if hasDefault {
+		statements = append(statements, fmt.Sprintf(
+			`WITH moved AS (DELETE FROM %s WHERE last_updated >= '%s' AND last_updated < '%s' RETURNING *) INSERT INTO %s SELECT * FROM moved`,
+			pgx.Identifier{defaultPartitionName}.Sanitize(), lower, upper, table,
+		))
+	}
+	statements = append(statements, fmt.Sprintf(
+		`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+		parent, table, lower, upper,
+	))
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", name, err)
+	}
+	return nil
+}
+
+// DropEmptyMonthlyPartition drops the partition of the month containing month if it holds no rows,
+// as after the retention job archived it, and reports whether it was dropped. Rows restored into the
+// month later land in the DEFAULT partition. It is a no-op when ride_data_history is not partitioned
+// or the partition does not exist.
+func (r *RideDataHistoryRepository) DropEmptyMonthlyPartition(ctx context.Context, month time.Time) (bool, error) {
+	month = monthStart(month)
+	name := MonthlyPartitionName(month)
+
+	partitioned, err := r.IsPartitioned(ctx)
+	if err != nil || !partitioned {
+		return false, err
+	}
+	exists, err := r.tableExists(ctx, name)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The lock keeps rows from arriving between the check and the drop
+	table := pgx.Identifier{name}.Sanitize()
+	if _, err := tx.Exec(ctx, `LOCK TABLE `+table+` IN ACCESS EXCLUSIVE MODE`); err != nil {
+		return false, fmt.Errorf("failed to lock partition %s: %w", name, err)
+	}
+	var empty bool
+	if err := tx.QueryRow(ctx, `SELECT NOT EXISTS (SELECT 1 FROM `+table+`)`).Scan(&empty); err != nil {
+		return false, fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	if !empty {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE `+table); err != nil {
+		return false, fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+
+	r.partitions.set(month, false)
+	return true, nil
+}
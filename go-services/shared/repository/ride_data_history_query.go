@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// historySelectColumns lists the ride_data_history columns in the order scanRecord expects them
+const historySelectColumns = `id, ride_id, external_id, park_id, entity_type, name, status, last_updated,
+		       created_at, updated_at, operating_hours, standby_wait_time,
+		       return_time_state, return_start, return_end, forecast`
+
+// orderableColumns whitelists the columns a HistoryFilter may sort by
+var orderableColumns = map[string]bool{
+	"id":           true,
+	"ride_id":      true,
+	"park_id":      true,
+	"entity_type":  true,
+	"name":         true,
+	"status":       true,
+	"last_updated": true,
+}
+
+// OrderBy sorts query results by a single column
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// Asc sorts by column in ascending order
+func Asc(column string) OrderBy {
+	return OrderBy{Column: column}
+}
+
+// Desc sorts by column in descending order
+func Desc(column string) OrderBy {
+	return OrderBy{Column: column, Desc: true}
+}
+
+// HistoryFilter selects ride data history rows. Zero-valued fields do not filter.
+type HistoryFilter struct {
+	ParkID     string
+	RideIDs    []string
+	EntityType string
+	Status     string
+	// Since is inclusive and Until is exclusive
+	Since time.Time
+	Until time.Time
+	// OlderThan keeps only rows strictly before the cursor in (last_updated, id) order
+	OlderThan *HistoryCursor
+	OrderBy   []OrderBy
+	Limit     int
+}
+
+// build renders the filter into a SELECT statement and its arguments
+func (f HistoryFilter) build() (string, []any, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.ParkID != "" {
+		where = append(where, "park_id = "+arg(f.ParkID))
+	}
+	switch len(f.RideIDs) {
+	case 0:
+	case 1:
+		where = append(where, "ride_id = "+arg(f.RideIDs[0]))
+	default:
+		where = append(where, "ride_id = ANY("+arg(f.RideIDs)+")")
+	}
+	if f.EntityType != "" {
+		where = append(where, "entity_type = "+arg(f.EntityType))
+	}
+	if f.Status != "" {
+		where = append(where, "status = "+arg(f.Status))
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "last_updated >= "+arg(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "last_updated < "+arg(f.Until))
+	}
+	if f.OlderThan != nil {
+		where = append(where, "(last_updated, id) < ("+arg(f.OlderThan.LastUpdated)+", "+arg(f.OlderThan.ID)+")")
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(historySelectColumns)
+	b.WriteString("\n\t\tFROM ride_data_history")
+	if len(where) > 0 {
+		b.WriteString("\n\t\tWHERE ")
+		b.WriteString(strings.Join(where, " AND "))
+	}
+
+	if len(f.OrderBy) > 0 {
+		order := make([]string, 0, len(f.OrderBy))
+		for _, o := range f.OrderBy {
+			if !orderableColumns[o.Column] {
+				return "", nil, fmt.Errorf("cannot order by column %q", o.Column)
+			}
+			if o.Desc {
+				order = append(order, o.Column+" DESC")
+			} else {
+				order = append(order, o.Column+" ASC")
+			}
+		}
+		b.WriteString("\n\t\tORDER BY ")
+		b.WriteString(strings.Join(order, ", "))
+	}
+
+	if f.Limit < 0 {
+		return "", nil, fmt.Errorf("limit must not be negative, got %d", f.Limit)
+	}
+	if f.Limit > 0 {
+		b.WriteString("\n\t\tLIMIT ")
+		b.WriteString(arg(f.Limit))
+	}
+
+	return b.String(), args, nil
+}
+
+// scanRecord reads one row selected with historySelectColumns
+func scanRecord(row pgx.Row) (*models.RideDataHistoryRecord, error) {
+	record := &models.RideDataHistoryRecord{}
+	err := row.Scan(
+		&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
+		&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
+		&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
+		&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	return record, nil
+}
+
+// collectRecords runs query and scans every row into a slice
+func (r *RideDataHistoryRepository) collectRecords(ctx context.Context, query string, args ...any) ([]*models.RideDataHistoryRecord, error) {
+	var records []*models.RideDataHistoryRecord
+	err := r.eachRecord(ctx, query, args, func(record *models.RideDataHistoryRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// eachRecord runs query and passes every scanned row to fn, stopping at the first error
+func (r *RideDataHistoryRepository) eachRecord(ctx context.Context, query string, args []any, fn func(*models.RideDataHistoryRecord) error) error {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRideDataHistory returns every row matching the filter
+func (r *RideDataHistoryRepository) QueryRideDataHistory(ctx context.Context, filter HistoryFilter) ([]*models.RideDataHistoryRecord, error) {
+	query, args, err := filter.build()
+	if err != nil {
+		return nil, err
+	}
+	return r.collectRecords(ctx, query, args...)
+}
+
+// EachRideDataHistory streams the rows matching the filter to fn one at a time without
+// materialising the result set. Returning an error from fn stops iteration and is returned as-is.
+func (r *RideDataHistoryRepository) EachRideDataHistory(ctx context.Context, filter HistoryFilter, fn func(*models.RideDataHistoryRecord) error) error {
+	query, args, err := filter.build()
+	if err != nil {
+		return err
+	}
+	return r.eachRecord(ctx, query, args, fn)
+}
+
+// errStreamCancelled stops EachRideDataHistory when a stream consumer goes away
+var errStreamCancelled = errors.New("stream cancelled")
+
+// StreamRideDataHistory streams the rows matching the filter over a channel with the given buffer size.
+// Once iteration ends the error channel receives exactly one value, nil on success, and the record
+// channel is closed. Cancelling ctx stops the producer.
+func (r *RideDataHistoryRepository) StreamRideDataHistory(ctx context.Context, filter HistoryFilter, buffer int) (<-chan *models.RideDataHistoryRecord, <-chan error) {
+	records := make(chan *models.RideDataHistoryRecord, buffer)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(records)
+		err := r.EachRideDataHistory(ctx, filter, func(record *models.RideDataHistoryRecord) error {
+			select {
+			case records <- record:
+				return nil
+			case <-ctx.Done():
+				return errStreamCancelled
+			}
+		})
+		if errors.Is(err, errStreamCancelled) {
+			err = ctx.Err()
+		}
+		errc <- err
+	}()
+
+	return records, errc
+}
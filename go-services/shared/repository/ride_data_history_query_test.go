@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryFilter_Build(t *testing.T) {
+	since := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		filter    HistoryFilter
+		wantWhere string
+		wantOrder string
+		wantLimit bool
+		wantArgs  []any
+	}{
+		{
+			name:   "no filter",
+			filter: HistoryFilter{},
+		},
+		{
+			name:      "park and single ride",
+			filter:    HistoryFilter{ParkID: "park1", RideIDs: []string{"ride1"}},
+			wantWhere: "WHERE park_id = $1 AND ride_id = $2",
+			wantArgs:  []any{"park1", "ride1"},
+		},
+		{
+			name:      "several rides use ANY",
+			filter:    HistoryFilter{RideIDs: []string{"ride1", "ride2"}},
+			wantWhere: "WHERE ride_id = ANY($1)",
+			wantArgs:  []any{[]string{"ride1", "ride2"}},
+		},
+		{
+			name: "all fields",
+			filter: HistoryFilter{
+				ParkID:     "park1",
+				EntityType: "ATTRACTION",
+				Status:     "OPERATING",
+				Since:      since,
+				Until:      until,
+				OrderBy:    []OrderBy{Desc("last_updated"), Asc("name")},
+				Limit:      50,
+			},
+			wantWhere: "WHERE park_id = $1 AND entity_type = $2 AND status = $3 AND last_updated >= $4 AND last_updated < $5",
+			wantOrder: "ORDER BY last_updated DESC, name ASC",
+			wantLimit: true,
+			wantArgs:  []any{"park1", "ATTRACTION", "OPERATING", since, until, 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.filter.build()
+			require.NoError(t, err)
+
+			assert.True(t, strings.HasPrefix(query, "SELECT id, ride_id"))
+			assert.Contains(t, query, "FROM ride_data_history")
+			if tt.wantWhere != "" {
+				assert.Contains(t, query, tt.wantWhere)
+			} else {
+				assert.NotContains(t, query, "WHERE")
+			}
+			if tt.wantOrder != "" {
+				assert.Contains(t, query, tt.wantOrder)
+			} else {
+				assert.NotContains(t, query, "ORDER BY")
+			}
+			if tt.wantLimit {
+				assert.Contains(t, query, "LIMIT $")
+			}
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestHistoryFilter_BuildRejectsInvalidInput(t *testing.T) {
+	_, _, err := HistoryFilter{OrderBy: []OrderBy{Asc("name; DROP TABLE ride_data_history")}}.build()
+	assert.Error(t, err)
+
+	_, _, err = HistoryFilter{Limit: -1}.build()
+	assert.Error(t, err)
+}
+
+func TestRideDataHistoryRepository_EachAndStream_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := newRideDataHistoryRepositoryForTest(pool)
+
+	base := time.Now().UTC().Truncate(time.Minute).Add(-time.Hour)
+	for i := 0; i < 10; i++ {
+		at := base.Add(time.Duration(i) * ThrottleWindow)
+		wait := i * 5
+		_, _, err := repo.InsertRideDataHistoryWithCounts(ctx, []*models.RideDataHistoryRecord{{
+			RideID: "ride1", ExternalID: "ext1", ParkID: "park1", EntityType: "ATTRACTION",
+			Name: "Space Mountain", Status: "OPERATING", LastUpdated: at, CreatedAt: at, UpdatedAt: at,
+			OperatingHours: "[]", Forecast: "[]", StandbyWaitTime: &wait,
+		}})
+		require.NoError(t, err)
+	}
+
+	filter := HistoryFilter{RideIDs: []string{"ride1"}, OrderBy: []OrderBy{Asc("last_updated")}}
+
+	var seen int
+	err := repo.EachRideDataHistory(ctx, filter, func(record *models.RideDataHistoryRecord) error {
+		seen++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 10, seen)
+
+	records, errc := repo.StreamRideDataHistory(ctx, filter, 2)
+	var streamed []*models.RideDataHistoryRecord
+	for record := range records {
+		streamed = append(streamed, record)
+	}
+	require.NoError(t, <-errc)
+	require.Len(t, streamed, 10)
+	assert.True(t, streamed[0].LastUpdated.Before(streamed[9].LastUpdated))
+
+	// Cancelling the context stops the producer
+	cancelCtx, cancel := context.WithCancel(ctx)
+	records, errc = repo.StreamRideDataHistory(cancelCtx, filter, 0)
+	<-records
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	for range records {
+	}
+}
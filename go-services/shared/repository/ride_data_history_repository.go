@@ -5,14 +5,59 @@ import (
 	"fmt"
 	"go-services/shared/db"
 	"go-services/shared/models"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ThrottleWindow is how long an unchanged ride state is suppressed before it is written again
+const ThrottleWindow = 5 * time.Minute
+
+// insertColumns lists the columns written by the collector, in COPY order
+var insertColumns = []string{
+	"ride_id", "external_id", "park_id", "entity_type", "name", "status", "last_updated",
+	"created_at", "updated_at", "operating_hours", "standby_wait_time",
+	"return_time_state", "return_start", "return_end", "forecast",
+}
+
+var insertColumnList = strings.Join(insertColumns, ", ")
+
+// InsertHook is called after an insert commits with the records it wrote
+type InsertHook func(ctx context.Context, records []*models.RideDataHistoryRecord)
+
+// StateChange is an inserted record whose state differs from the ride's previous record according
+// to isRideStateIdentical. Previous is nil for the first record of a ride and for rides without a
+// record within the throttle's lookback.
+type StateChange struct {
+	Previous *models.RideDataHistoryRecord
+	Current  *models.RideDataHistoryRecord
+}
+
+// ChangeHook is called after an insert commits with the state changes it wrote
+type ChangeHook func(ctx context.Context, changes []StateChange)
+
 // RideDataHistoryRepository handles database operations for ride data history
 type RideDataHistoryRepository struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	cache    *LatestStateCache
+	onInsert []InsertHook
+	onChange []ChangeHook
+	// partitions remembers the monthly partitions EnsureMonthlyPartitions has ensured
+	partitions partitionCache
+}
+
+// OnInsert registers a hook that runs after every insert that wrote records, in the inserting
+// goroutine. Register hooks before the repository is used.
+func (r *RideDataHistoryRepository) OnInsert(hook InsertHook) {
+	r.onInsert = append(r.onInsert, hook)
+}
+
+// OnChange registers a hook that runs after every insert that changed the state of rides, in the
+// inserting goroutine. Register hooks before the repository is used.
+func (r *RideDataHistoryRepository) OnChange(hook ChangeHook) {
+	r.onChange = append(r.onChange, hook)
 }
 
 // NewRideDataHistoryRepository creates a new repository instance
@@ -24,7 +69,8 @@ func NewRideDataHistoryRepository() (*RideDataHistoryRepository, error) {
 	}
 
 	return &RideDataHistoryRepository{
-		pool: pool,
+		pool:  pool,
+		cache: NewLatestStateCache(),
 	}, nil
 }
 
@@ -79,23 +125,27 @@ func isRideStateIdentical(r1, r2 *models.RideDataHistoryRecord) bool {
 	return true
 }
 
-// InsertRideDataHistoryWithCounts inserts new ride data history records and returns counts of inserted/skipped
+// isThrottled reports whether record repeats the state of last and arrived within the throttle window
+func isThrottled(last, record *models.RideDataHistoryRecord) bool {
+	if !isRideStateIdentical(last, record) {
+		return false
+	}
+	return record.LastUpdated.Sub(last.LastUpdated) < ThrottleWindow
+}
+
+// InsertRideDataHistoryWithCounts inserts new ride data history records and returns counts of inserted/skipped.
+// Records are bulk loaded with COPY into a temporary staging table and merged into ride_data_history
+// with a single statement. The throttle compares against the cached last-written state of each ride and
+// only queries the database for rides whose cached state is missing or older than LatestStateTTL.
+// Inserted records that change the state of a ride are announced on RideDataChangesChannel when the
+// transaction commits; afterwards the OnInsert hooks receive every inserted record and the OnChange
+// hooks the state changes with their previous state.
 func (r *RideDataHistoryRepository) InsertRideDataHistoryWithCounts(ctx context.Context, records []*models.RideDataHistoryRecord) (inserted int, skipped int, err error) {
 	if len(records) == 0 {
 		return 0, 0, nil
 	}
 
-	// Fetch the latest database record for each ride to check state differences and age
-	latestDBRecords, err := r.GetLatestRideDataForAllRides(ctx)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to fetch latest ride data: %w", err)
-	}
-	dbLatestMap := make(map[string]*models.RideDataHistoryRecord)
-	for _, rec := range latestDBRecords {
-		dbLatestMap[rec.RideID] = rec
-	}
-
-		// Deduplicate by RideID in the input batch, keeping the most recent
+	// Deduplicate by RideID in the input batch, keeping the most recent
 	latestRecords := make(map[string]*models.RideDataHistoryRecord)
 	for _, record := range records {
 		key := record.RideID
@@ -108,9 +158,51 @@ func (r *RideDataHistoryRepository) InsertRideDataHistoryWithCounts(ctx context.
 		}
 	}
 
-	// Set updated timestamp
+	// Load the latest database state for rides the cache doesn't know about yet or has not
+	// checked within LatestStateTTL, which picks up rows written by other collectors
+	rideIDs := make([]string, 0, len(latestRecords))
+	for rideID := range latestRecords {
+		rideIDs = append(rideIDs, rideID)
+	}
+	if stale := r.cache.Stale(rideIDs); len(stale) > 0 {
+		dbRecords, err := r.GetLatestRideDataForRides(ctx, stale)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch latest ride data: %w", err)
+		}
+		found := make(map[string]bool, len(dbRecords))
+		for _, rec := range dbRecords {
+			r.cache.Set(rec)
+			found[rec.RideID] = true
+		}
+		for _, rideID := range stale {
+			if !found[rideID] {
+				r.cache.SetAbsent(rideID)
+			}
+		}
+	}
+
+	// Throttle logic: skip records whose state is identical to the last write and less than 5 minutes newer.
+	// Records that differ from the last write, or are the first for their ride, are announced once inserted.
+	now := time.Now()
+	pending := make([]*models.RideDataHistoryRecord, 0, len(latestRecords))
+	changed := make(map[string]bool, len(latestRecords))
+	previous := make(map[string]*models.RideDataHistoryRecord, len(latestRecords))
 	for _, record := range latestRecords {
-		record.UpdatedAt = time.Now()
+		last, ok := r.cache.Get(record.RideID)
+		if ok && isThrottled(last, record) {
+			skipped++
+			continue
+		}
+		record.UpdatedAt = now
+		pending = append(pending, record)
+		changed[record.RideID] = !ok || !isRideStateIdentical(last, record)
+		if ok {
+			previous[record.RideID] = last
+		}
+	}
+
+	if len(pending) == 0 {
+		return 0, skipped, nil
 	}
 
 	// Begin transaction
@@ -120,51 +212,101 @@ func (r *RideDataHistoryRepository) InsertRideDataHistoryWithCounts(ctx context.
 	}
 	defer tx.Rollback(ctx)
 
-	for _, record := range latestRecords {
-		// Throttle logic: check if the state is identical and it has been less than 5 minutes since the last insert
-		if dbRec, exists := dbLatestMap[record.RideID]; exists {
-			if isRideStateIdentical(dbRec, record) {
-				age := record.LastUpdated.Sub(dbRec.LastUpdated)
-				if age < 5*time.Minute {
-					skipped++
-					continue
-				}
-			}
-		}
-
-		insertQuery := `
-			INSERT INTO ride_data_history (
-				ride_id, external_id, park_id, entity_type, name, status, last_updated,
-				created_at, updated_at, operating_hours, standby_wait_time,
-				return_time_state, return_start, return_end, forecast
-			) VALUES (
-				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
-			) ON CONFLICT (ride_id, last_updated) DO NOTHING`
+	// The staging table copies the column types of ride_data_history but none of its constraints or defaults
+	stagingQuery := `
+		CREATE TEMP TABLE ride_data_history_staging ON COMMIT DROP AS
+		SELECT ` + insertColumnList + `
+		FROM ride_data_history
+		WITH NO DATA`
+	if _, err := tx.Exec(ctx, stagingQuery); err != nil {
+		return 0, 0, fmt.Errorf("failed to create staging table: %w", err)
+	}
 
-		tag, err := tx.Exec(ctx, insertQuery,
+	rows := make([][]any, 0, len(pending))
+	for _, record := range pending {
+		rows = append(rows, []any{
 			record.RideID, record.ExternalID, record.ParkID, record.EntityType,
 			record.Name, record.Status, record.LastUpdated, record.CreatedAt,
 			record.UpdatedAt, record.OperatingHours, record.StandbyWaitTime,
 			record.ReturnTimeState, record.ReturnStart, record.ReturnEnd, record.Forecast,
-		)
+		})
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"ride_data_history_staging"}, insertColumns, pgx.CopyFromRows(rows)); err != nil {
+		return 0, 0, fmt.Errorf("failed to copy records into staging table: %w", err)
+	}
 
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to insert record for ride %s: %w", record.Name, err)
+	mergeQuery := `
+		INSERT INTO ride_data_history (` + insertColumnList + `)
+		SELECT ` + insertColumnList + `
+		FROM ride_data_history_staging
+		ON CONFLICT (ride_id, last_updated) DO NOTHING
+		RETURNING id, ride_id`
+
+	insertedRows, err := tx.Query(ctx, mergeQuery)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to merge staged records: %w", err)
+	}
+	insertedIDs := make(map[string]int64, len(pending))
+	for insertedRows.Next() {
+		var id int64
+		var rideID string
+		if err := insertedRows.Scan(&id, &rideID); err != nil {
+			insertedRows.Close()
+			return 0, 0, fmt.Errorf("failed to scan merged row: %w", err)
 		}
-		
-		if tag.RowsAffected() > 0 {
-			inserted++
-			dbLatestMap[record.RideID] = record
-		} else {
-			skipped++
+		insertedIDs[rideID] = id
+	}
+	insertedRows.Close()
+	if err := insertedRows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to merge staged records: %w", err)
+	}
+
+	var changes []*models.RideDataHistoryRecord
+	for _, record := range pending {
+		if id, ok := insertedIDs[record.RideID]; ok {
+			record.ID = id
+			if changed[record.RideID] {
+				changes = append(changes, record)
+			}
 		}
 	}
+	if err := notifyRideDataChanges(ctx, tx, changes); err != nil {
+		return 0, 0, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	written := make([]*models.RideDataHistoryRecord, 0, len(insertedIDs))
+	for _, record := range pending {
+		if _, ok := insertedIDs[record.RideID]; ok {
+			inserted++
+			r.cache.Set(record)
+			written = append(written, record)
+		} else {
+			// A row already existed for this timestamp, most likely written by another collector;
+			// forget the cached state so the next poll re-reads it
+			skipped++
+			r.cache.Delete(record.RideID)
+		}
+	}
+	if len(written) > 0 {
+		for _, hook := range r.onInsert {
+			hook(ctx, written)
+		}
+	}
+	if len(changes) > 0 && len(r.onChange) > 0 {
+		stateChanges := make([]StateChange, 0, len(changes))
+		for _, record := range changes {
+			stateChanges = append(stateChanges, StateChange{Previous: previous[record.RideID], Current: record})
+		}
+		for _, hook := range r.onChange {
+			hook(ctx, stateChanges)
+		}
+	}
+
 	return inserted, skipped, nil
 }
 
@@ -176,232 +318,86 @@ func (r *RideDataHistoryRepository) InsertRideDataHistory(ctx context.Context, r
 
 // GetRideDataHistoryByPark retrieves all ride data history for a specific park
 func (r *RideDataHistoryRepository) GetRideDataHistoryByPark(ctx context.Context, parkID string) ([]*models.RideDataHistoryRecord, error) {
-	query := `
-		SELECT id, ride_id, external_id, park_id, entity_type, name, status, last_updated,
-		       created_at, updated_at, operating_hours, standby_wait_time,
-		       return_time_state, return_start, return_end, forecast
-		FROM ride_data_history
-		WHERE park_id = $1
-		ORDER BY name ASC`
-
-	rows, err := r.pool.Query(ctx, query, parkID)
+	records, err := r.QueryRideDataHistory(ctx, HistoryFilter{
+		ParkID:  parkID,
+		OrderBy: []OrderBy{Asc("name")},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ride data history for park %s: %w", parkID, err)
 	}
-	defer rows.Close()
-
-	var records []*models.RideDataHistoryRecord
-	for rows.Next() {
-		record := &models.RideDataHistoryRecord{}
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return records, nil
 }
 
 // GetRideDataHistoryByType retrieves all ride data history for a specific entity type
 func (r *RideDataHistoryRepository) GetRideDataHistoryByType(ctx context.Context, entityType string) ([]*models.RideDataHistoryRecord, error) {
-	query := `
-		SELECT id, ride_id, external_id, park_id, entity_type, name, status, last_updated,
-		       created_at, updated_at, operating_hours, standby_wait_time,
-		       return_time_state, return_start, return_end, forecast
-		FROM ride_data_history
-		WHERE entity_type = $1
-		ORDER BY park_id ASC, name ASC`
-
-	rows, err := r.pool.Query(ctx, query, entityType)
+	records, err := r.QueryRideDataHistory(ctx, HistoryFilter{
+		EntityType: entityType,
+		OrderBy:    []OrderBy{Asc("park_id"), Asc("name")},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ride data history for type %s: %w", entityType, err)
 	}
-	defer rows.Close()
-
-	var records []*models.RideDataHistoryRecord
-	for rows.Next() {
-		record := &models.RideDataHistoryRecord{}
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return records, nil
 }
 
 // GetAllRideDataHistory retrieves all ride data history
 func (r *RideDataHistoryRepository) GetAllRideDataHistory(ctx context.Context) ([]*models.RideDataHistoryRecord, error) {
-	query := `
-		SELECT id, ride_id, external_id, park_id, entity_type, name, status, last_updated, 
-		       created_at, updated_at, operating_hours, standby_wait_time, 
-		       return_time_state, return_start, return_end, forecast
-		FROM ride_data_history 
-		ORDER BY park_id ASC, entity_type ASC, name ASC`
-
-	rows, err := r.pool.Query(ctx, query)
+	records, err := r.QueryRideDataHistory(ctx, HistoryFilter{
+		OrderBy: []OrderBy{Asc("park_id"), Asc("entity_type"), Asc("name")},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all ride data history: %w", err)
 	}
-	defer rows.Close()
-
-	var records []*models.RideDataHistoryRecord
-	for rows.Next() {
-		record := &models.RideDataHistoryRecord{}
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return records, nil
 }
 
 // GetRideDataHistorySince retrieves ride data history since a specific time
 func (r *RideDataHistoryRepository) GetRideDataHistorySince(ctx context.Context, since time.Time) ([]*models.RideDataHistoryRecord, error) {
-	query := `
-		SELECT id, ride_id, external_id, park_id, entity_type, name, status, last_updated, 
-		       created_at, updated_at, operating_hours, standby_wait_time, 
-		       return_time_state, return_start, return_end, forecast
-		FROM ride_data_history 
-		WHERE last_updated >= $1 
-		ORDER BY last_updated DESC, park_id ASC, name ASC`
-
-	rows, err := r.pool.Query(ctx, query, since)
+	records, err := r.QueryRideDataHistory(ctx, HistoryFilter{
+		Since:   since,
+		OrderBy: []OrderBy{Desc("last_updated"), Asc("park_id"), Asc("name")},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ride data history since %v: %w", since, err)
 	}
-	defer rows.Close()
-
-	var records []*models.RideDataHistoryRecord
-	for rows.Next() {
-		record := &models.RideDataHistoryRecord{}
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return records, nil
 }
 
 // GetRideDataHistorySinceForRide retrieves ride data history since a specific time for a specific ride
 func (r *RideDataHistoryRepository) GetRideDataHistorySinceForRide(ctx context.Context, since time.Time, rideID string) ([]*models.RideDataHistoryRecord, error) {
-	query := `
-		SELECT id, ride_id, external_id, park_id, entity_type, name, status, last_updated, 
-		       created_at, updated_at, operating_hours, standby_wait_time, 
-		       return_time_state, return_start, return_end, forecast
-		FROM ride_data_history 
-		WHERE last_updated >= $1 AND ride_id = $2
-		ORDER BY last_updated DESC`
-
-	rows, err := r.pool.Query(ctx, query, since, rideID)
+	records, err := r.QueryRideDataHistory(ctx, HistoryFilter{
+		RideIDs: []string{rideID},
+		Since:   since,
+		OrderBy: []OrderBy{Desc("last_updated")},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ride data history for ride %s since %v: %w", rideID, since, err)
 	}
-	defer rows.Close()
-
-	var records []*models.RideDataHistoryRecord
-	for rows.Next() {
-		record := &models.RideDataHistoryRecord{}
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return records, nil
 }
 
 // GetLatestRideDataForAllRides retrieves the most recent entry for each ride
 func (r *RideDataHistoryRepository) GetLatestRideDataForAllRides(ctx context.Context) ([]*models.RideDataHistoryRecord, error) {
-	var records []*models.RideDataHistoryRecord
-
 	// Use pgx directly to avoid prepared statement caching issues
 	// Added a 24-hour bounding window to prevent full table scans and timeouts
 	query := `
-		SELECT DISTINCT ON (ride_id) id, ride_id, external_id, park_id, entity_type, name, status, last_updated,
-		       created_at, updated_at, operating_hours, standby_wait_time,
-		       return_time_state, return_start, return_end, forecast
+		SELECT DISTINCT ON (ride_id) ` + historySelectColumns + `
 		FROM ride_data_history
 		WHERE last_updated >= NOW() - INTERVAL '24 hours'
 		ORDER BY ride_id, last_updated DESC`
 
-	rows, err := r.pool.Query(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var record models.RideDataHistoryRecord
-		err := rows.Scan(
-			&record.ID, &record.RideID, &record.ExternalID, &record.ParkID, &record.EntityType,
-			&record.Name, &record.Status, &record.LastUpdated, &record.CreatedAt, &record.UpdatedAt,
-			&record.OperatingHours, &record.StandbyWaitTime, &record.ReturnTimeState,
-			&record.ReturnStart, &record.ReturnEnd, &record.Forecast,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		records = append(records, &record)
-	}
+	return r.collectRecords(ctx, query)
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
+// GetLatestRideDataForRides retrieves the most recent entry within the last 24 hours for each of the given rides
+func (r *RideDataHistoryRepository) GetLatestRideDataForRides(ctx context.Context, rideIDs []string) ([]*models.RideDataHistoryRecord, error) {
+	query := `
+		SELECT DISTINCT ON (ride_id) ` + historySelectColumns + `
+		FROM ride_data_history
+		WHERE ride_id = ANY($1) AND last_updated >= NOW() - INTERVAL '24 hours'
+		ORDER BY ride_id, last_updated DESC`
 
-	return records, nil
+	return r.collectRecords(ctx, query, rideIDs)
 }
 
 // Close closes the database connection
@@ -417,6 +413,11 @@ func (r *RideDataHistoryRepository) HealthCheck(ctx context.Context) error {
 	return db.HealthCheck(ctx, r.pool)
 }
 
+// PoolStat reports the state of the connection pool for metrics
+func (r *RideDataHistoryRepository) PoolStat() *pgxpool.Stat {
+	return r.pool.Stat()
+}
+
 func init() {
 	// Set the default timezone to UTC
 	time.Local = time.UTC
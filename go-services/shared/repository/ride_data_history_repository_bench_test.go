@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-services/shared/models"
+)
+
+// insertRideDataHistoryPerRow is the previous insert path, kept for comparison: it re-queries the
+// latest row of every ride and then runs one INSERT per record inside a transaction.
+func insertRideDataHistoryPerRow(ctx context.Context, r *RideDataHistoryRepository, records []*models.RideDataHistoryRecord) (inserted int, skipped int, err error) {
+	latestDBRecords, err := r.GetLatestRideDataForAllRides(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	dbLatestMap := make(map[string]*models.RideDataHistoryRecord)
+	for _, rec := range latestDBRecords {
+		dbLatestMap[rec.RideID] = rec
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, record := range records {
+		if dbRec, exists := dbLatestMap[record.RideID]; exists && isThrottled(dbRec, record) {
+			skipped++
+			continue
+		}
+
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO ride_data_history (`+insertColumnList+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (ride_id, last_updated) DO NOTHING`,
+			record.RideID, record.ExternalID, record.ParkID, record.EntityType,
+			record.Name, record.Status, record.LastUpdated, record.CreatedAt,
+			record.UpdatedAt, record.OperatingHours, record.StandbyWaitTime,
+			record.ReturnTimeState, record.ReturnStart, record.ReturnEnd, record.Forecast,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+		if tag.RowsAffected() > 0 {
+			inserted++
+		} else {
+			skipped++
+		}
+	}
+
+	return inserted, skipped, tx.Commit(ctx)
+}
+
+// benchmarkRecords builds one poll worth of records for rides, all stamped at the given time
+func benchmarkRecords(rides int, at time.Time) []*models.RideDataHistoryRecord {
+	records := make([]*models.RideDataHistoryRecord, 0, rides)
+	for i := 0; i < rides; i++ {
+		wait := (i * 5) % 120
+		records = append(records, &models.RideDataHistoryRecord{
+			RideID:          fmt.Sprintf("ride%d", i),
+			ExternalID:      fmt.Sprintf("ext%d", i),
+			ParkID:          "park1",
+			EntityType:      "ATTRACTION",
+			Name:            fmt.Sprintf("Ride %d", i),
+			Status:          "OPERATING",
+			LastUpdated:     at,
+			CreatedAt:       at,
+			UpdatedAt:       at,
+			OperatingHours:  "[]",
+			StandbyWaitTime: &wait,
+			Forecast:        "[]",
+		})
+	}
+	return records
+}
+
+func BenchmarkInsertRideDataHistory(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping database benchmark in short mode")
+	}
+	pool, cleanup := setupTestDatabase(b)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, rides := range []int{20, 200} {
+		// Every iteration is a new poll, past the throttle window, so each record is written
+		b.Run(fmt.Sprintf("PerRow/rides=%d", rides), func(b *testing.B) {
+			repo := newRideDataHistoryRepositoryForTest(pool)
+			at := time.Now().UTC().Truncate(time.Minute)
+			for i := 0; i < b.N; i++ {
+				at = at.Add(ThrottleWindow)
+				if _, _, err := insertRideDataHistoryPerRow(ctx, repo, benchmarkRecords(rides, at)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("CopyMerge/rides=%d", rides), func(b *testing.B) {
+			repo := newRideDataHistoryRepositoryForTest(pool)
+			at := time.Now().UTC().Truncate(time.Minute).Add(24 * time.Hour)
+			for i := 0; i < b.N; i++ {
+				at = at.Add(ThrottleWindow)
+				if _, _, err := repo.InsertRideDataHistoryWithCounts(ctx, benchmarkRecords(rides, at)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
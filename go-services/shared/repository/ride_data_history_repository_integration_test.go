@@ -18,11 +18,12 @@ import (
 // newRideDataHistoryRepositoryForTest creates a repository with injected pool for testing
 func newRideDataHistoryRepositoryForTest(pool *pgxpool.Pool) *RideDataHistoryRepository {
 	return &RideDataHistoryRepository{
-		pool: pool,
+		pool:  pool,
+		cache: NewLatestStateCache(),
 	}
 }
 
-func setupTestDatabase(t *testing.T) (*pgxpool.Pool, func()) {
+func setupTestDatabase(t testing.TB) (*pgxpool.Pool, func()) {
 	ctx := context.Background()
 
 	// Start PostgreSQL container
@@ -321,6 +322,8 @@ func TestRideDataHistoryRepository_InsertRideDataHistoryWithCounts_Integration(t
 			// Clear table before each test
 			_, err := pool.Exec(ctx, "DELETE FROM ride_data_history")
 			require.NoError(t, err)
+			// The latest-state cache must not outlive the rows it describes
+			repo.cache = NewLatestStateCache()
 
 			// Insert setup records if any
 			if len(tt.setupRecords) > 0 {
@@ -425,3 +428,180 @@ func TestRideDataHistoryRepository_GetRideDataHistory_Integration(t *testing.T)
 		})
 	}
 }
+
+// createPartitionedRideDataHistory replaces ride_data_history with a table partitioned by month,
+// as the partitioning migration does
+func createPartitionedRideDataHistory(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `
+		DROP TABLE ride_data_history;
+		CREATE TABLE ride_data_history (
+			id BIGSERIAL NOT NULL,
+			ride_id TEXT NOT NULL,
+			external_id TEXT NOT NULL,
+			park_id TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			last_updated TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			operating_hours JSONB,
+			standby_wait_time INTEGER,
+			return_time_state TEXT,
+			return_start TIMESTAMP WITH TIME ZONE,
+			return_end TIMESTAMP WITH TIME ZONE,
+			forecast JSONB,
+			PRIMARY KEY (id, last_updated),
+			UNIQUE (ride_id, last_updated)
+		) PARTITION BY RANGE (last_updated);
+	`)
+	require.NoError(t, err)
+}
+
+func TestRideDataHistoryRepository_EnsureMonthlyPartitions_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := newRideDataHistoryRepositoryForTest(pool)
+
+	// An unpartitioned table is left alone
+	created, err := repo.EnsureMonthlyPartitions(ctx, time.Now(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	// Recreate the table partitioned by month, as the partitioning migration does
+	createPartitionedRideDataHistory(t, pool)
+
+	now := time.Now().UTC()
+	created, err = repo.EnsureMonthlyPartitions(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		MonthlyPartitionName(now),
+		MonthlyPartitionName(monthStart(now).AddDate(0, 1, 0)),
+		MonthlyPartitionName(monthStart(now).AddDate(0, 2, 0)),
+	}, created)
+
+	// Running again creates nothing new
+	created, err = repo.EnsureMonthlyPartitions(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	// Inserts are routed to the partitions and still honour (ride_id, last_updated) uniqueness
+	record := &models.RideDataHistoryRecord{
+		RideID: "ride1", ExternalID: "ext1", ParkID: "park1", EntityType: "ATTRACTION",
+		Name: "Space Mountain", Status: "OPERATING", LastUpdated: now.Truncate(time.Minute),
+		CreatedAt: now, UpdatedAt: now, OperatingHours: "[]", Forecast: "[]",
+		StandbyWaitTime: &[]int{30}[0],
+	}
+	inserted, _, err := repo.InsertRideDataHistoryWithCounts(ctx, []*models.RideDataHistoryRecord{record})
+	require.NoError(t, err)
+	assert.Equal(t, 1, inserted)
+
+	_, err = pool.Exec(ctx, `
+		INSERT INTO ride_data_history (ride_id, external_id, park_id, entity_type, name, status, last_updated, created_at, updated_at)
+		VALUES ('ride1', 'ext1', 'park1', 'ATTRACTION', 'Space Mountain', 'CLOSED', $1, now(), now())`,
+		record.LastUpdated)
+	assert.Error(t, err, "duplicate (ride_id, last_updated) must be rejected")
+}
+
+func TestRideDataHistoryRepository_DefaultPartition_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := newRideDataHistoryRepositoryForTest(pool)
+	createPartitionedRideDataHistory(t, pool)
+	_, err := pool.Exec(ctx, `CREATE TABLE ride_data_history_default PARTITION OF ride_data_history DEFAULT`)
+	require.NoError(t, err)
+
+	// A row for a month without a partition lands in the DEFAULT partition
+	month := monthStart(time.Now()).AddDate(0, 6, 0)
+	_, err = pool.Exec(ctx, `
+		INSERT INTO ride_data_history (ride_id, external_id, park_id, entity_type, name, status, last_updated, created_at, updated_at)
+		VALUES ('ride1', 'ext1', 'park1', 'ATTRACTION', 'Space Mountain', 'OPERATING', $1, now(), now())`,
+		month.Add(time.Hour))
+	require.NoError(t, err)
+
+	partitionOf := func() string {
+		var name string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM ride_data_history`).Scan(&name))
+		return name
+	}
+	assert.Equal(t, "ride_data_history_default", partitionOf())
+
+	// Creating the month's partition moves the row out of the DEFAULT partition
+	created, err := repo.EnsureMonthlyPartitions(ctx, month, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{MonthlyPartitionName(month)}, created)
+	assert.Equal(t, MonthlyPartitionName(month), partitionOf())
+
+	// A partition with rows is kept, an emptied one is dropped
+	dropped, err := repo.DropEmptyMonthlyPartition(ctx, month)
+	require.NoError(t, err)
+	assert.False(t, dropped)
+
+	_, err = pool.Exec(ctx, `DELETE FROM ride_data_history`)
+	require.NoError(t, err)
+	dropped, err = repo.DropEmptyMonthlyPartition(ctx, month)
+	require.NoError(t, err)
+	assert.True(t, dropped)
+	exists, err := repo.tableExists(ctx, MonthlyPartitionName(month))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The dropped month is no longer cached, so the next run creates it again
+	created, err = repo.EnsureMonthlyPartitions(ctx, month, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{MonthlyPartitionName(month)}, created)
+}
+
+func TestRideDataHistoryRepository_Readiness_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo := newRideDataHistoryRepositoryForTest(pool)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	_, err := pool.Exec(ctx, `
+		INSERT INTO ride_data_history (ride_id, external_id, park_id, entity_type, name, status, last_updated, created_at, updated_at)
+		VALUES ('ride1', 'ext1', 'park1', 'ATTRACTION', 'Space Mountain', 'OPERATING', $1, now(), now()),
+		       ('ride2', 'ext2', 'park1', 'ATTRACTION', 'Matterhorn', 'OPERATING', $2, now(), now()),
+		       ('ride3', 'ext3', 'park2', 'ATTRACTION', 'Soarin', 'OPERATING', $3, now(), now())`,
+		now.Add(-time.Hour), now.Add(-10*time.Minute), now.Add(-30*24*time.Hour))
+	require.NoError(t, err)
+
+	latest, err := repo.LatestUpdateByPark(ctx)
+	require.NoError(t, err)
+	assert.Len(t, latest, 1, "rows older than the lookback are ignored")
+	assert.True(t, latest["park1"].Equal(now.Add(-10*time.Minute)))
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE _prisma_migrations (
+			id VARCHAR(36) PRIMARY KEY,
+			migration_name VARCHAR(255) NOT NULL,
+			finished_at TIMESTAMPTZ,
+			rolled_back_at TIMESTAMPTZ
+		);
+		INSERT INTO _prisma_migrations VALUES
+			('1', '20261019100000_add_ride_data_history_keyset_index', now(), NULL),
+			('2', '20261019110000_add_api_keys', now(), NULL),
+			('3', '20261101000000_failed', NULL, NULL),
+			('4', '20261102000000_rolled_back', now(), now());`)
+	require.NoError(t, err)
+
+	applied, err := repo.LatestMigration(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "20261019110000_add_api_keys", applied)
+}
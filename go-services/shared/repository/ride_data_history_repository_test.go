@@ -939,3 +939,17 @@ func TestRideDataHistoryRepository_GetAllRideDataHistory(t *testing.T) {
 		t.Error("Expected to find ride2 in results")
 	}
 }
+
+func TestMonthlyPartitionName(t *testing.T) {
+	got := MonthlyPartitionName(time.Date(2025, 9, 14, 23, 59, 0, 0, time.UTC))
+	if got != "ride_data_history_2025_09" {
+		t.Errorf("Expected ride_data_history_2025_09, got %s", got)
+	}
+
+	// Times are bucketed by their UTC month
+	pacific := time.FixedZone("PDT", -7*60*60)
+	got = MonthlyPartitionName(time.Date(2025, 9, 30, 20, 0, 0, 0, pacific))
+	if got != "ride_data_history_2025_10" {
+		t.Errorf("Expected ride_data_history_2025_10, got %s", got)
+	}
+}
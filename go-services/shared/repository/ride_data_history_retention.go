@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ArchivePartition describes the rows of a single park and calendar month that are eligible for archival
+type ArchivePartition struct {
+	ParkID string
+	Month  time.Time
+	Rows   int64
+}
+
+// ListArchivePartitions groups all ride data history older than before by park and month
+func (r *RideDataHistoryRepository) ListArchivePartitions(ctx context.Context, before time.Time) ([]ArchivePartition, error) {
+	query := `
+		SELECT park_id, date_trunc('month', last_updated AT TIME ZONE 'UTC') AS month, COUNT(*)
+		FROM ride_data_history
+		WHERE last_updated < $1
+		GROUP BY park_id, month
+		ORDER BY month ASC, park_id ASC`
+
+	rows, err := r.pool.Query(ctx, query, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive partitions before %v: %w", before, err)
+	}
+	defer rows.Close()
+
+	var partitions []ArchivePartition
+	for rows.Next() {
+		var p ArchivePartition
+		if err := rows.Scan(&p.ParkID, &p.Month, &p.Rows); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		p.Month = time.Date(p.Month.Year(), p.Month.Month(), 1, 0, 0, 0, 0, time.UTC)
+		partitions = append(partitions, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return partitions, nil
+}
+
+// CountRideDataHistoryInRange counts the rows for a park with from <= last_updated < to
+func (r *RideDataHistoryRepository) CountRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM ride_data_history
+		WHERE park_id = $1 AND last_updated >= $2 AND last_updated < $3`
+
+	var count int64
+	if err := r.pool.QueryRow(ctx, query, parkID, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count ride data history for park %s: %w", parkID, err)
+	}
+	return count, nil
+}
+
+// ForEachRideDataHistoryInRange streams every row for a park with from <= last_updated < to to fn,
+// ordered by id, without materialising the result set
+func (r *RideDataHistoryRepository) ForEachRideDataHistoryInRange(ctx context.Context, parkID string, from, to time.Time, fn func(*models.RideDataHistoryRecord) error) error {
+	filter := HistoryFilter{
+		ParkID:  parkID,
+		Since:   from,
+		Until:   to,
+		OrderBy: []OrderBy{Asc("id")},
+	}
+	if err := r.EachRideDataHistory(ctx, filter, fn); err != nil {
+		return fmt.Errorf("failed to read ride data history for park %s: %w", parkID, err)
+	}
+	return nil
+}
+
+// DeleteRideDataHistoryByIDs deletes the rows with the given ids and returns how many were removed.
+// The archive job passes the ids it wrote, in batches to keep each transaction short, so rows that
+// commit into an archived range after it was read are left for the next run.
+func (r *RideDataHistoryRepository) DeleteRideDataHistoryByIDs(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query := `DELETE FROM ride_data_history WHERE id = ANY($1)`
+
+	tag, err := r.pool.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %d ride data history rows: %w", len(ids), err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RestoreRideDataHistory reinserts archived records with their original ids.
+// Rows that already exist are left untouched, so a restore can safely be repeated.
+func (r *RideDataHistoryRepository) RestoreRideDataHistory(ctx context.Context, records []*models.RideDataHistoryRecord) (int64, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO ride_data_history (
+			id, ride_id, external_id, park_id, entity_type, name, status, last_updated,
+			created_at, updated_at, operating_hours, standby_wait_time,
+			return_time_state, return_start, return_end, forecast
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+		) ON CONFLICT DO NOTHING`
+
+	batch := &pgx.Batch{}
+	for _, record := range records {
+		batch.Queue(query,
+			record.ID, record.RideID, record.ExternalID, record.ParkID, record.EntityType,
+			record.Name, record.Status, record.LastUpdated, record.CreatedAt,
+			record.UpdatedAt, record.OperatingHours, record.StandbyWaitTime,
+			record.ReturnTimeState, record.ReturnStart, record.ReturnEnd, record.Forecast,
+		)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := tx.SendBatch(ctx, batch)
+	var restored int64
+	for _, record := range records {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return 0, fmt.Errorf("failed to restore record %d: %w", record.ID, err)
+		}
+		restored += tag.RowsAffected()
+	}
+	if err := results.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return restored, nil
+}
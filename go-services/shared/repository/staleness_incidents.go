@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StalenessIncidentRepository records the stale data incidents of the collector's watchdog. The
+// partial unique index on open incidents makes opening, claiming and resolving safe across
+// collector instances.
+type StalenessIncidentRepository struct {
+	pool *pgxpool.Pool
+}
+
+// StalenessIncidents returns an incident repository sharing the connection pool of r
+func (r *RideDataHistoryRepository) StalenessIncidents() *StalenessIncidentRepository {
+	return &StalenessIncidentRepository{pool: r.pool}
+}
+
+const stalenessIncidentColumns = `id, park_id, last_data_at, opened_at, notified_at, resolved_at`
+
+func scanStalenessIncident(row pgx.Row) (*models.StalenessIncident, error) {
+	var i models.StalenessIncident
+	if err := row.Scan(&i.ID, &i.ParkID, &i.LastDataAt, &i.OpenedAt, &i.NotifiedAt, &i.ResolvedAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// OpenStalenessIncident returns the open incident of a park, opening one if there is none.
+// lastDataAt is the newest data of the park, or nil if it has none.
+func (r *StalenessIncidentRepository) OpenStalenessIncident(ctx context.Context, parkID string, lastDataAt *time.Time) (*models.StalenessIncident, error) {
+	insert := `
+		INSERT INTO staleness_incidents (park_id, last_data_at)
+		VALUES ($1, $2)
+		ON CONFLICT (park_id) WHERE resolved_at IS NULL DO NOTHING`
+	if _, err := r.pool.Exec(ctx, insert, parkID, lastDataAt); err != nil {
+		return nil, fmt.Errorf("failed to open staleness incident for park %s: %w", parkID, err)
+	}
+
+	query := `SELECT ` + stalenessIncidentColumns + ` FROM staleness_incidents WHERE park_id = $1 AND resolved_at IS NULL`
+	incident, err := scanStalenessIncident(r.pool.QueryRow(ctx, query, parkID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load staleness incident for park %s: %w", parkID, err)
+	}
+	return incident, nil
+}
+
+// ClaimStalenessNotification marks an incident notified, reporting false if another instance
+// already did. Call ReleaseStalenessNotification when the notification could not be delivered.
+func (r *StalenessIncidentRepository) ClaimStalenessNotification(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE staleness_incidents SET notified_at = NOW() WHERE id = $1 AND notified_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim staleness incident %d: %w", id, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseStalenessNotification clears the claim on an incident so the alert is retried
+func (r *StalenessIncidentRepository) ReleaseStalenessNotification(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `UPDATE staleness_incidents SET notified_at = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to release staleness incident %d: %w", id, err)
+	}
+	return nil
+}
+
+// ResolveStalenessIncident closes the open incident of a park and returns it, or nil if the park
+// has none. Only one caller resolves a given incident.
+func (r *StalenessIncidentRepository) ResolveStalenessIncident(ctx context.Context, parkID string) (*models.StalenessIncident, error) {
+	query := `
+		UPDATE staleness_incidents SET resolved_at = NOW()
+		WHERE park_id = $1 AND resolved_at IS NULL
+		RETURNING ` + stalenessIncidentColumns
+	incident, err := scanStalenessIncident(r.pool.QueryRow(ctx, query, parkID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve staleness incident for park %s: %w", parkID, err)
+	}
+	return incident, nil
+}
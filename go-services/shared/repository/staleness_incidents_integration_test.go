@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStalenessIncidentRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	migration, err := os.ReadFile("../../../prisma/migrations/20261019120000_add_staleness_incidents/migration.sql")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, string(migration))
+	require.NoError(t, err)
+
+	repo := newRideDataHistoryRepositoryForTest(pool).StalenessIncidents()
+	lastData := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	opened, err := repo.OpenStalenessIncident(ctx, "park-1", &lastData)
+	require.NoError(t, err)
+	assert.Equal(t, "park-1", opened.ParkID)
+	assert.True(t, lastData.Equal(*opened.LastDataAt))
+	assert.Nil(t, opened.NotifiedAt)
+
+	// A park has one open incident at a time
+	again, err := repo.OpenStalenessIncident(ctx, "park-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, opened.ID, again.ID)
+
+	claimed, err := repo.ClaimStalenessNotification(ctx, opened.ID)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.ClaimStalenessNotification(ctx, opened.ID)
+	require.NoError(t, err)
+	assert.False(t, claimed, "an incident is claimed once")
+
+	require.NoError(t, repo.ReleaseStalenessNotification(ctx, opened.ID))
+	claimed, err = repo.ClaimStalenessNotification(ctx, opened.ID)
+	require.NoError(t, err)
+	assert.True(t, claimed, "a released incident can be claimed again")
+
+	resolved, err := repo.ResolveStalenessIncident(ctx, "park-1")
+	require.NoError(t, err)
+	require.NotNil(t, resolved)
+	assert.Equal(t, opened.ID, resolved.ID)
+	assert.NotNil(t, resolved.NotifiedAt)
+	assert.NotNil(t, resolved.ResolvedAt)
+
+	resolved, err = repo.ResolveStalenessIncident(ctx, "park-1")
+	require.NoError(t, err)
+	assert.Nil(t, resolved)
+
+	reopened, err := repo.OpenStalenessIncident(ctx, "park-1", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, opened.ID, reopened.ID)
+	assert.Nil(t, reopened.LastDataAt)
+}
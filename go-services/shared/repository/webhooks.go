@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookSecretPrefix starts every signing secret
+const WebhookSecretPrefix = "whsec_"
+
+// MaxWebhookSubscriptionsPerKey bounds how many subscriptions one API key may register
+const MaxWebhookSubscriptionsPerKey = 10
+
+// ErrWebhookSubscriptionNotFound is returned for subscriptions that do not exist or belong to
+// another API key
+var ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// ErrWebhookSubscriptionLimit is returned when an API key already has MaxWebhookSubscriptionsPerKey
+// subscriptions
+var ErrWebhookSubscriptionLimit = errors.New("webhook subscription limit reached")
+
+// ErrWebhookDeadLetterNotFound is returned for dead letters that do not exist or belong to another
+// subscription
+var ErrWebhookDeadLetterNotFound = errors.New("webhook dead letter not found")
+
+// WebhookRepository stores webhook subscriptions, the queue of events waiting for delivery and the
+// events that failed every attempt
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+// Webhooks returns a webhook repository sharing the connection pool of r
+func (r *RideDataHistoryRepository) Webhooks() *WebhookRepository {
+	return &WebhookRepository{pool: r.pool}
+}
+
+// newWebhookSecret generates a signing secret with 256 bits of entropy
+func newWebhookSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return WebhookSecretPrefix + hex.EncodeToString(b[:]), nil
+}
+
+const webhookSubscriptionColumns = `id, api_key_id, url, secret, events, ride_ids, created_at`
+
+func scanWebhookSubscription(row pgx.Row) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	if err := row.Scan(&s.ID, &s.APIKeyID, &s.URL, &s.Secret, &s.Events, &s.RideIDs, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateWebhookSubscription stores sub for its API key with a new signing secret, or returns
+// ErrWebhookSubscriptionLimit when the key has too many subscriptions already. The returned
+// subscription carries the secret.
+func (r *WebhookRepository) CreateWebhookSubscription(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	rideIDs := sub.RideIDs
+	if rideIDs == nil {
+		rideIDs = []string{}
+	}
+	query := `
+		INSERT INTO webhook_subscriptions (api_key_id, url, secret, events, ride_ids)
+		SELECT $1::bigint, $2::text, $3::text, $4::text[], $5::text[]
+		WHERE (SELECT COUNT(*) FROM webhook_subscriptions WHERE api_key_id = $1) < $6
+		RETURNING ` + webhookSubscriptionColumns
+	created, err := scanWebhookSubscription(r.pool.QueryRow(ctx, query, sub.APIKeyID, sub.URL, secret, sub.Events,
+		rideIDs, MaxWebhookSubscriptionsPerKey))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookSubscriptionLimit
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return created, nil
+}
+
+// ListWebhookSubscriptions returns the subscriptions of an API key, oldest first
+func (r *WebhookRepository) ListWebhookSubscriptions(ctx context.Context, apiKeyID int64) ([]*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE api_key_id = $1 ORDER BY id`
+	rows, err := r.pool.Query(ctx, query, apiKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*models.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return subs, nil
+}
+
+// GetWebhookSubscription returns a subscription of an API key, or ErrWebhookSubscriptionNotFound
+func (r *WebhookRepository) GetWebhookSubscription(ctx context.Context, apiKeyID, id int64) (*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1 AND api_key_id = $2`
+	sub, err := scanWebhookSubscription(r.pool.QueryRow(ctx, query, id, apiKeyID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription %d: %w", id, err)
+	}
+	return sub, nil
+}
+
+// DeleteWebhookSubscription deletes a subscription of an API key along with its queued events and
+// dead letters
+func (r *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, apiKeyID, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND api_key_id = $2`, id, apiKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookSubscriptionNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeadLetters returns the newest dead letters of a subscription of an API key, newest first
+func (r *WebhookRepository) ListWebhookDeadLetters(ctx context.Context, apiKeyID, subscriptionID int64, limit int) ([]*models.WebhookDeadLetter, error) {
+	if _, err := r.GetWebhookSubscription(ctx, apiKeyID, subscriptionID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT id, subscription_id, event_id, event_type, payload, attempts, last_error, last_status, created_at, failed_at
+		FROM webhook_dead_letters
+		WHERE subscription_id = $1
+		ORDER BY failed_at DESC, id DESC
+		LIMIT $2`
+	rows, err := r.pool.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := []*models.WebhookDeadLetter{}
+	for rows.Next() {
+		var l models.WebhookDeadLetter
+		if err := rows.Scan(&l.ID, &l.SubscriptionID, &l.EventID, &l.EventType, &l.Payload, &l.Attempts,
+			&l.LastError, &l.LastStatus, &l.CreatedAt, &l.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook dead letter: %w", err)
+		}
+		letters = append(letters, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return letters, nil
+}
+
+// RedeliverWebhookDeadLetter moves a dead letter of a subscription of an API key back into the
+// delivery queue with a fresh set of attempts
+func (r *WebhookRepository) RedeliverWebhookDeadLetter(ctx context.Context, apiKeyID, subscriptionID, id int64) error {
+	query := `
+		WITH letter AS (
+			DELETE FROM webhook_dead_letters l
+			USING webhook_subscriptions s
+			WHERE l.id = $1 AND l.subscription_id = $2 AND s.id = l.subscription_id AND s.api_key_id = $3
+			RETURNING l.subscription_id, l.event_id, l.event_type, l.payload, l.created_at
+		)
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, created_at)
+		SELECT subscription_id, event_id, event_type, payload, created_at FROM letter`
+	tag, err := r.pool.Exec(ctx, query, id, subscriptionID, apiKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook dead letter %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookDeadLetterNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookEvents queues every event for the subscriptions of its type and ride whose
+// API key is not revoked, and returns how many deliveries were queued
+func (r *WebhookRepository) EnqueueWebhookEvents(ctx context.Context, events []*models.WebhookEvent) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, len(events))
+	types := make([]string, len(events))
+	rideIDs := make([]string, len(events))
+	payloads := make([]string, len(events))
+	for i, e := range events {
+		ids[i], types[i], rideIDs[i], payloads[i] = e.ID, e.Type, e.RideID, string(e.Payload)
+	}
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT s.id, e.id, e.type, e.payload::jsonb
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS e(id, type, ride_id, payload)
+		JOIN webhook_subscriptions s
+			ON e.type = ANY(s.events) AND (cardinality(s.ride_ids) = 0 OR e.ride_id = ANY(s.ride_ids))
+		JOIN api_keys k ON k.id = s.api_key_id AND k.revoked_at IS NULL`
+	tag, err := r.pool.Exec(ctx, query, ids, types, rideIDs, payloads)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookDeliveries claims up to limit deliveries that are due at now, counting an attempt for
+// each. A claim holds a delivery for lease, after which another collector may retry it if the
+// claiming one never reported back.
+func (r *WebhookRepository) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = $2
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.subscription_id, s.url, s.secret, d.event_id, d.event_type, d.payload, d.attempts`
+	rows, err := r.pool.Query(ctx, query, now.UTC(), now.Add(lease).UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventID, &d.EventType, &d.Payload, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// CompleteWebhookDelivery removes a delivered event from the queue
+func (r *WebhookRepository) CompleteWebhookDelivery(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM webhook_deliveries WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to complete webhook delivery %d: %w", id, err)
+	}
+	return nil
+}
+
+// RetryWebhookDelivery records a failed attempt and schedules the next one at next. status is the
+// response status, or nil when no response arrived.
+func (r *WebhookRepository) RetryWebhookDelivery(ctx context.Context, id int64, next time.Time, status *int, lastError string) error {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $2, last_status = $3, last_error = $4 WHERE id = $1`
+	if _, err := r.pool.Exec(ctx, query, id, next.UTC(), status, lastError); err != nil {
+		return fmt.Errorf("failed to reschedule webhook delivery %d: %w", id, err)
+	}
+	return nil
+}
+
+// DeadLetterWebhookDelivery moves an event whose last attempt failed from the queue to the dead
+// letters
+func (r *WebhookRepository) DeadLetterWebhookDelivery(ctx context.Context, id int64, status *int, lastError string) error {
+	query := `
+		WITH delivery AS (
+			DELETE FROM webhook_deliveries WHERE id = $1
+			RETURNING subscription_id, event_id, event_type, payload, attempts, created_at
+		)
+		INSERT INTO webhook_dead_letters (subscription_id, event_id, event_type, payload, attempts, last_error, last_status, created_at)
+		SELECT subscription_id, event_id, event_type, payload, attempts, $3::text, $2::integer, created_at FROM delivery`
+	if _, err := r.pool.Exec(ctx, query, id, status, lastError); err != nil {
+		return fmt.Errorf("failed to dead letter webhook delivery %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"go-services/shared/models"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	pool, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, name := range []string{"20261019110000_add_api_keys", "20261019140000_add_webhooks"} {
+		migration, err := os.ReadFile("../../../prisma/migrations/" + name + "/migration.sql")
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, string(migration))
+		require.NoError(t, err)
+	}
+
+	base := newRideDataHistoryRepositoryForTest(pool)
+	owner, _, err := base.APIKeys().CreateAPIKey(ctx, "owner", APIKeyLimits{})
+	require.NoError(t, err)
+	other, _, err := base.APIKeys().CreateAPIKey(ctx, "other", APIKeyLimits{})
+	require.NoError(t, err)
+	repo := base.Webhooks()
+
+	all, err := repo.CreateWebhookSubscription(ctx, &models.WebhookSubscription{
+		APIKeyID: owner.ID, URL: "https://example.com/all", Events: []string{models.WebhookRideClosed, models.WebhookRideReopened}, RideIDs: []string{},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(all.Secret, WebhookSecretPrefix))
+	oneRide, err := repo.CreateWebhookSubscription(ctx, &models.WebhookSubscription{
+		APIKeyID: other.ID, URL: "https://example.com/one", Events: []string{models.WebhookRideClosed}, RideIDs: []string{"ride-2"},
+	})
+	require.NoError(t, err)
+
+	// Subscriptions are scoped to their API key
+	subs, err := repo.ListWebhookSubscriptions(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, all.ID, subs[0].ID)
+	_, err = repo.GetWebhookSubscription(ctx, other.ID, all.ID)
+	assert.ErrorIs(t, err, ErrWebhookSubscriptionNotFound)
+	assert.ErrorIs(t, repo.DeleteWebhookSubscription(ctx, other.ID, all.ID), ErrWebhookSubscriptionNotFound)
+	_, err = repo.ListWebhookDeadLetters(ctx, other.ID, all.ID, 10)
+	assert.ErrorIs(t, err, ErrWebhookSubscriptionNotFound)
+
+	// Events fan out to subscriptions for their type and ride
+	queued, err := repo.EnqueueWebhookEvents(ctx, []*models.WebhookEvent{
+		{ID: "evt_1_ride_closed", Type: models.WebhookRideClosed, RideID: "ride-1", Payload: []byte(`{"id":"evt_1_ride_closed"}`)},
+		{ID: "evt_2_ride_opened", Type: models.WebhookRideOpened, RideID: "ride-1", Payload: []byte(`{}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), queued, "only the first subscription listens for ride-1 closing")
+
+	now := time.Now().UTC()
+	claimed, err := repo.ClaimWebhookDeliveries(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	delivery := claimed[0]
+	assert.Equal(t, all.ID, delivery.SubscriptionID)
+	assert.Equal(t, all.Secret, delivery.Secret)
+	assert.Equal(t, "evt_1_ride_closed", delivery.EventID)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.JSONEq(t, `{"id":"evt_1_ride_closed"}`, string(delivery.Payload))
+
+	// A claimed delivery is leased until it is retried
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	status := 503
+	require.NoError(t, repo.RetryWebhookDelivery(ctx, delivery.ID, now.Add(2*time.Minute), &status, "receiver returned status 503"))
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now.Add(90*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "a retry waits for its backoff")
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, 2, claimed[0].Attempts)
+
+	// Failed deliveries become dead letters and can be redelivered
+	require.NoError(t, repo.DeadLetterWebhookDelivery(ctx, delivery.ID, &status, "receiver returned status 503"))
+	letters, err := repo.ListWebhookDeadLetters(ctx, owner.ID, all.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "evt_1_ride_closed", letters[0].EventID)
+	assert.Equal(t, 2, letters[0].Attempts)
+	require.NotNil(t, letters[0].LastStatus)
+	assert.Equal(t, 503, *letters[0].LastStatus)
+
+	assert.ErrorIs(t, repo.RedeliverWebhookDeadLetter(ctx, other.ID, all.ID, letters[0].ID), ErrWebhookDeadLetterNotFound)
+	require.NoError(t, repo.RedeliverWebhookDeadLetter(ctx, owner.ID, all.ID, letters[0].ID))
+	assert.ErrorIs(t, repo.RedeliverWebhookDeadLetter(ctx, owner.ID, all.ID, letters[0].ID), ErrWebhookDeadLetterNotFound)
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now.Add(3*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "evt_1_ride_closed", claimed[0].EventID, "a redelivery keeps its event id")
+	assert.Equal(t, 1, claimed[0].Attempts)
+	require.NoError(t, repo.CompleteWebhookDelivery(ctx, claimed[0].ID))
+
+	// Revoked keys stop receiving events
+	require.NoError(t, base.APIKeys().RevokeAPIKey(ctx, other.ID))
+	queued, err = repo.EnqueueWebhookEvents(ctx, []*models.WebhookEvent{
+		{ID: "evt_3_ride_closed", Type: models.WebhookRideClosed, RideID: "ride-2", Payload: []byte(`{}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), queued, "only the owner's subscription for all rides receives ride-2")
+
+	// Each key has a limited number of subscriptions
+	for i := 1; i < MaxWebhookSubscriptionsPerKey; i++ {
+		_, err := repo.CreateWebhookSubscription(ctx, &models.WebhookSubscription{
+			APIKeyID: owner.ID, URL: "https://example.com/more", Events: []string{models.WebhookRideOpened}, RideIDs: []string{},
+		})
+		require.NoError(t, err)
+	}
+	_, err = repo.CreateWebhookSubscription(ctx, &models.WebhookSubscription{
+		APIKeyID: owner.ID, URL: "https://example.com/more", Events: []string{models.WebhookRideOpened}, RideIDs: []string{},
+	})
+	assert.ErrorIs(t, err, ErrWebhookSubscriptionLimit)
+
+	require.NoError(t, repo.DeleteWebhookSubscription(ctx, other.ID, oneRide.ID))
+	assert.ErrorIs(t, repo.DeleteWebhookSubscription(ctx, other.ID, oneRide.ID), ErrWebhookSubscriptionNotFound)
+}
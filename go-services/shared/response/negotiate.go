@@ -0,0 +1,108 @@
+package response
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is one entry of an Accept or Accept-Encoding header
+type acceptRange struct {
+	value string
+	q     float64
+}
+
+// parseAccept splits an Accept style header into its ranges. Parameters other than q are dropped,
+// and a malformed q counts as 1 like most servers treat it.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{value: value, q: q})
+	}
+	return ranges
+}
+
+// mediaTypeQuality returns the q of the most specific range matching mediaType, or -1 when none does
+func mediaTypeQuality(ranges []acceptRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	best, specificity := -1.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.value == mediaType:
+			s = 2
+		case r.value == typ+"/*":
+			s = 1
+		case r.value == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			best, specificity = r.q, s
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the q the client gives a content coding. Unlisted codings take the q of
+// "*" when present; identity is acceptable unless it is explicitly refused.
+func encodingQuality(ranges []acceptRange, coding string) float64 {
+	wildcard := -1.0
+	for _, r := range ranges {
+		if r.value == coding {
+			return r.q
+		}
+		if r.value == "*" {
+			wildcard = r.q
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard
+	}
+	if coding == "identity" {
+		return 1
+	}
+	return -1
+}
+
+// negotiate picks the offer with the highest positive quality. offers are in server preference
+// order, which breaks ties; ok is false when the client accepts none of them.
+func negotiate(offers []string, quality func(offer string) float64) (string, bool) {
+	type candidate struct {
+		offer string
+		q     float64
+		rank  int
+	}
+	var candidates []candidate
+	for i, offer := range offers {
+		if q := quality(offer); q > 0 {
+			candidates = append(candidates, candidate{offer, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].rank < candidates[j].rank
+	})
+	return candidates[0].offer, true
+}
@@ -0,0 +1,96 @@
+package response
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	ranges := parseAccept("text/html, application/JSON;q=0.8 , */*;q=0.1, bad;q=x, ;q=1")
+	want := []acceptRange{{"text/html", 1}, {"application/json", 0.8}, {"*/*", 0.1}, {"bad", 1}}
+	if len(ranges) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, ranges)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("Range %d: expected %v, got %v", i, want[i], ranges[i])
+		}
+	}
+}
+
+func TestMediaTypeQuality(t *testing.T) {
+	ranges := parseAccept("text/*;q=0.3, text/csv;q=0.7, */*;q=0.1")
+	tests := map[string]float64{
+		"text/csv":         0.7,
+		"text/plain":       0.3,
+		"application/json": 0.1,
+	}
+	for mediaType, want := range tests {
+		if got := mediaTypeQuality(ranges, mediaType); got != want {
+			t.Errorf("%s: expected q=%v, got %v", mediaType, want, got)
+		}
+	}
+	if got := mediaTypeQuality(parseAccept("text/csv"), "application/json"); got != -1 {
+		t.Errorf("Expected no match, got %v", got)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		data   any
+		want   Format
+		fail   bool
+	}{
+		{"no accept header", "/", "", struct{}{}, FormatJSON, false},
+		{"anything", "/", "*/*", struct{}{}, FormatJSON, false},
+		{"msgpack", "/", "application/x-msgpack", struct{}{}, FormatMsgPack, false},
+		{"highest q wins", "/", "application/json;q=0.5, application/msgpack", struct{}{}, FormatMsgPack, false},
+		{"refused json", "/", "application/json;q=0, */*", struct{}{}, FormatMsgPack, false},
+		{"csv for a marshaler", "/", "text/*", csvRows{}, FormatCSV, false},
+		{"csv needs a marshaler", "/", "text/csv", struct{}{}, "", true},
+		{"protobuf needs a message", "/", "application/x-protobuf", struct{}{}, "", true},
+		{"format parameter wins", "/?format=csv", "application/json", csvRows{}, FormatCSV, false},
+		{"unknown format parameter", "/?format=xml", "", struct{}{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := negotiateFormat(r, tt.data)
+			if tt.fail {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %s, got %s (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                               "identity",
+		"gzip":                           "gzip",
+		"gzip, deflate, br":              "br",
+		"gzip, br, zstd":                 "zstd",
+		"gzip;q=1, br;q=0.9, zstd;q=0.5": "gzip",
+		"br;q=0, *":                      "zstd",
+		"deflate":                        "identity",
+		"identity;q=0, *;q=0":            "identity",
+	}
+	for header, want := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := negotiateEncoding(r); got != want {
+			t.Errorf("Accept-Encoding %q: expected %s, got %s", header, want, got)
+		}
+	}
+}
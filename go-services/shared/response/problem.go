@@ -0,0 +1,200 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 error documents
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader carries the request ID. The request ID middleware sets it on the response before
+// any handler runs, which is where problem documents read it from.
+const RequestIDHeader = "X-Request-ID"
+
+// Code is a stable, machine readable error identifier. Clients should branch on the code rather than
+// on the human readable title or detail.
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeValidation       Code = "validation_failed"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeNotAcceptable    Code = "not_acceptable"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal_error"
+	CodeUnavailable      Code = "unavailable"
+)
+
+// codeForStatus picks the generic code of a status for errors that do not carry their own
+func codeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusNotAcceptable:
+		return CodeNotAcceptable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// FieldError describes why one input was rejected
+type FieldError struct {
+	// Field names the query parameter, path segment or body field, e.g. "window_hours"
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an API error. Handlers return or construct one and hand it to WriteProblem, which renders
+// it as a problem document. The wrapped cause is logged but never sent to the client.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Err    error
+	// allow lists the methods sent in the Allow header of a 405
+	allow []string
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// NewError creates an Error with the generic code of the status
+func NewError(status int, detail string) *Error {
+	return &Error{Status: status, Code: codeForStatus(status), Detail: detail}
+}
+
+// BadRequest reports a request that cannot be processed as sent
+func BadRequest(detail string) *Error {
+	return NewError(http.StatusBadRequest, detail)
+}
+
+// Invalid reports rejected inputs, one FieldError per input
+func Invalid(fields ...FieldError) *Error {
+	detail := "The request has invalid parameters"
+	if len(fields) == 1 {
+		detail = fields[0].Message
+	}
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidation, Detail: detail, Fields: fields}
+}
+
+// InvalidField reports a single rejected input
+func InvalidField(field, message string) *Error {
+	return Invalid(FieldError{Field: field, Message: message})
+}
+
+// NotFound reports a resource that does not exist
+func NotFound(detail string) *Error {
+	return NewError(http.StatusNotFound, detail)
+}
+
+// MethodNotAllowed reports an unsupported method; allowed is sent in the Allow header
+func MethodNotAllowed(allowed ...string) *Error {
+	e := NewError(http.StatusMethodNotAllowed, "Method not allowed")
+	if len(allowed) > 0 {
+		e.Detail = "Allowed methods: " + strings.Join(allowed, ", ")
+	}
+	e.allow = allowed
+	return e
+}
+
+// Internal reports a server side failure. detail is shown to the client; err is only logged.
+func Internal(detail string, err error) *Error {
+	e := NewError(http.StatusInternalServerError, detail)
+	e.Err = err
+	return e
+}
+
+// Problem is the RFC 7807 document written for an Error
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// problemType returns the type URI of a code. The URI is a relative reference that identifies the
+// problem; it is not required to resolve.
+func problemType(code Code) string {
+	return "/problems/" + strings.ReplaceAll(string(code), "_", "-")
+}
+
+// asError converts any error into an Error; errors that are not an *Error become internal errors
+func asError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal("Internal server error", err)
+}
+
+// WriteProblem writes err as an application/problem+json response. Errors that are not an *Error
+// are reported as a 500 without their message; causes of server errors are logged with the request context.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := asError(err)
+	requestID := w.Header().Get(RequestIDHeader)
+
+	if apiErr.Status >= 500 && apiErr.Err != nil {
+		slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "status", apiErr.Status, "error", apiErr.Err)
+	}
+
+	problem := Problem{
+		Type:      problemType(apiErr.Code),
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: requestID,
+		Errors:    apiErr.Fields,
+	}
+
+	header := w.Header()
+	if len(apiErr.allow) > 0 {
+		header.Set("Allow", strings.Join(apiErr.allow, ", "))
+	}
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	header.Set("Content-Type", ProblemContentType)
+	header.Set("Cache-Control", "no-store")
+	w.WriteHeader(apiErr.Status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.WarnContext(r.Context(), "Failed to encode problem response", "error", err)
+	}
+}
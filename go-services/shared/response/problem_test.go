@@ -0,0 +1,80 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func writeProblem(t *testing.T, err error) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/v1/rides/x/history", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "req-1")
+	w.Header().Set("Content-Encoding", "gzip")
+	WriteProblem(w, r, err)
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Body is not a problem document: %q", w.Body.String())
+	}
+	return w, problem
+}
+
+func TestWriteProblem_Validation(t *testing.T) {
+	w, problem := writeProblem(t, InvalidField("window_hours", "window_hours must be a positive integer"))
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("Expected a 400 problem, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+	want := Problem{
+		Type:      "/problems/validation-failed",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "window_hours must be a positive integer",
+		Instance:  "/v1/rides/x/history",
+		Code:      CodeValidation,
+		RequestID: "req-1",
+		Errors:    []FieldError{{Field: "window_hours", Message: "window_hours must be a positive integer"}},
+	}
+	if fmt.Sprint(problem) != fmt.Sprint(want) {
+		t.Errorf("Expected %+v, got %+v", want, problem)
+	}
+}
+
+func TestWriteProblem_MethodNotAllowed(t *testing.T) {
+	w, problem := writeProblem(t, MethodNotAllowed(http.MethodGet, http.MethodPost))
+	if w.Header().Get("Allow") != "GET, POST" || problem.Code != CodeMethodNotAllowed {
+		t.Errorf("Unexpected response %v %+v", w.Header(), problem)
+	}
+}
+
+func TestWriteProblem_HidesCauses(t *testing.T) {
+	// A wrapped *Error keeps its status and code
+	_, problem := writeProblem(t, fmt.Errorf("lookup: %w", NotFound("Ride not found")))
+	if problem.Status != http.StatusNotFound || problem.Code != CodeNotFound {
+		t.Errorf("Expected the wrapped error to be used, got %+v", problem)
+	}
+
+	for _, err := range []error{errors.New("pq: password authentication failed"), Internal("Failed to retrieve ride data", errors.New("pq: timeout"))} {
+		w, problem := writeProblem(t, err)
+		if w.Code != http.StatusInternalServerError || problem.Code != CodeInternal {
+			t.Fatalf("Expected an internal error, got %d %+v", w.Code, problem)
+		}
+		if problem.Detail == err.Error() || problem.Detail == "" {
+			t.Errorf("Expected the cause to stay out of the detail, got %q", problem.Detail)
+		}
+	}
+}
+
+func TestInvalid_MultipleFields(t *testing.T) {
+	err := Invalid(FieldError{"from", "bad"}, FieldError{"to", "bad"})
+	if err.Status != http.StatusBadRequest || len(err.Fields) != 2 || err.Detail != "The request has invalid parameters" {
+		t.Errorf("Unexpected error %+v", err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 )
@@ -15,7 +14,8 @@ import (
 type Options struct {
 	// CacheMaxAge sets the max-age for Cache-Control header (in seconds)
 	CacheMaxAge int
-	// EnableGzip enables gzip compression if client supports it
+	// EnableGzip enables compression if the client supports it. WriteJSON uses gzip; Write also
+	// negotiates brotli and zstd.
 	EnableGzip bool
 	// EnableETag enables ETag generation and conditional responses
 	EnableETag bool
@@ -101,19 +101,3 @@ func WriteJSON(w http.ResponseWriter, r *http.Request, data interface{}, opts *O
 func WriteJSONWithDefaults(w http.ResponseWriter, r *http.Request, data interface{}) error {
 	return WriteJSON(w, r, data, &DefaultOptions)
 }
-
-// WriteError writes a JSON error response
-func WriteError(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	errorResp := map[string]interface{}{
-		"error":   true,
-		"message": message,
-		"status":  statusCode,
-	}
-
-	if err := json.NewEncoder(w).Encode(errorResp); err != nil {
-		log.Printf("Failed to encode error response: %v", err)
-	}
-}
@@ -0,0 +1,321 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format is a representation Write can encode a response in
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMsgPack  Format = "msgpack"
+	FormatProtobuf Format = "protobuf"
+)
+
+// formatOrder lists the formats in server preference order, which breaks ties between equally
+// acceptable formats; JSON stays the default for clients that accept anything
+var formatOrder = []Format{FormatJSON, FormatMsgPack, FormatProtobuf, FormatCSV}
+
+// formatMediaTypes lists the media types each format answers to. The first is sent as Content-Type.
+var formatMediaTypes = map[Format][]string{
+	FormatJSON:     {"application/json"},
+	FormatCSV:      {"text/csv"},
+	FormatMsgPack:  {"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+	FormatProtobuf: {"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"},
+}
+
+// encodingOrder lists the supported content codings in server preference order
+var encodingOrder = []string{"zstd", "br", "gzip", "identity"}
+
+// ErrNotAcceptable is returned by Write when the client accepts no representation of the data
+var ErrNotAcceptable = errors.New("no acceptable representation")
+
+// CSVMarshaler is implemented by values that can be written as CSV. Only such values are offered as
+// text/csv; the header row is up to the implementation.
+type CSVMarshaler interface {
+	MarshalCSV(w *csv.Writer) error
+}
+
+// ProtoMarshaler is implemented by values with a protobuf representation. Values that are not a
+// proto.Message themselves use it to be offered as protobuf while keeping their JSON shape.
+type ProtoMarshaler interface {
+	MarshalProto() proto.Message
+}
+
+// protoMessage returns the protobuf representation of data, or nil when it has none
+func protoMessage(data any) proto.Message {
+	switch v := data.(type) {
+	case proto.Message:
+		return v
+	case ProtoMarshaler:
+		return v.MarshalProto()
+	}
+	return nil
+}
+
+// supports reports whether data can be encoded in the format. CSV needs a CSVMarshaler and
+// protobuf a proto.Message or ProtoMarshaler; JSON and MessagePack encode any value.
+func (f Format) supports(data any) bool {
+	switch f {
+	case FormatCSV:
+		_, ok := data.(CSVMarshaler)
+		return ok
+	case FormatProtobuf:
+		switch data.(type) {
+		case proto.Message, ProtoMarshaler:
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// ContentType returns the media type sent for the format
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return formatMediaTypes[f][0]
+}
+
+// encoders write data in each format. Every encoder writes straight to w without buffering the
+// encoded body, so compression streams as well.
+var encoders = map[Format]func(w io.Writer, data any) error{
+	FormatJSON: func(w io.Writer, data any) error {
+		return json.NewEncoder(w).Encode(data)
+	},
+	FormatCSV: func(w io.Writer, data any) error {
+		cw := csv.NewWriter(w)
+		if err := data.(CSVMarshaler).MarshalCSV(cw); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	},
+	FormatMsgPack: func(w io.Writer, data any) error {
+		enc := msgpack.NewEncoder(w)
+		// Reuse the json tags so field names match the JSON representation
+		enc.SetCustomStructTag("json")
+		return enc.Encode(data)
+	},
+	FormatProtobuf: func(w io.Writer, data any) error {
+		b, err := proto.Marshal(protoMessage(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	},
+}
+
+// negotiateFormat picks the representation from the format query parameter, which wins over the
+// Accept header, or from the Accept header. Requests with neither get JSON.
+func negotiateFormat(r *http.Request, data any) (Format, error) {
+	if s := r.URL.Query().Get("format"); s != "" {
+		format := Format(strings.ToLower(s))
+		if _, ok := formatMediaTypes[format]; !ok {
+			return "", fmt.Errorf("%w: unknown format %q", ErrNotAcceptable, s)
+		}
+		if !format.supports(data) {
+			return "", fmt.Errorf("%w: this resource is not available as %s", ErrNotAcceptable, format)
+		}
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+	ranges := parseAccept(accept)
+
+	var offers []string
+	for _, format := range formatOrder {
+		if format.supports(data) {
+			offers = append(offers, string(format))
+		}
+	}
+	chosen, ok := negotiate(offers, func(offer string) float64 {
+		best := -1.0
+		for _, mediaType := range formatMediaTypes[Format(offer)] {
+			if q := mediaTypeQuality(ranges, mediaType); q > best {
+				best = q
+			}
+		}
+		return best
+	})
+	if !ok {
+		return "", fmt.Errorf("%w for Accept: %s", ErrNotAcceptable, accept)
+	}
+	return Format(chosen), nil
+}
+
+// negotiateEncoding picks the content coding from Accept-Encoding, falling back to identity
+func negotiateEncoding(r *http.Request) string {
+	ranges := parseAccept(r.Header.Get("Accept-Encoding"))
+	coding, ok := negotiate(encodingOrder, func(offer string) float64 {
+		return encodingQuality(ranges, offer)
+	})
+	if !ok {
+		return "identity"
+	}
+	return coding
+}
+
+// nopWriteCloser passes writes through for the identity coding
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressor wraps w in a streaming compressor for the content coding
+func newCompressor(w io.Writer, coding string) (io.WriteCloser, error) {
+	switch coding {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+	case "zstd":
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// deferredBody sends the status line with the first byte of the body, so an encoding error that
+// happens before any output can still be answered with an error status. Once discarding, writes
+// are dropped so a compressor can be closed without appending to the error response.
+type deferredBody struct {
+	w          http.ResponseWriter
+	started    bool
+	discarding bool
+}
+
+func (b *deferredBody) Write(p []byte) (int, error) {
+	if b.discarding {
+		return len(p), nil
+	}
+	if !b.started {
+		b.started = true
+		b.w.WriteHeader(http.StatusOK)
+	}
+	return b.w.Write(p)
+}
+
+// Write encodes data in the representation the client negotiated through the format query
+// parameter or the Accept header (JSON, CSV, MessagePack or protobuf) and compresses it with zstd,
+// brotli or gzip according to the Accept-Encoding q-values when opts.EnableGzip is set. With
+// opts.EnableETag the encoded body is buffered to derive an ETag, and a request whose If-None-Match
+// names it is answered with 304 Not Modified; otherwise the body is encoded and compressed as it is
+// written.
+//
+// When no representation is acceptable Write answers with a 406 problem and returns an error
+// wrapping ErrNotAcceptable.
+func Write(w http.ResponseWriter, r *http.Request, data any, opts *Options) error {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept")
+	format, err := negotiateFormat(r, data)
+	if err != nil {
+		WriteProblem(w, r, NewError(http.StatusNotAcceptable, err.Error()))
+		return err
+	}
+
+	coding := "identity"
+	if opts.EnableGzip {
+		header.Add("Vary", "Accept-Encoding")
+		coding = negotiateEncoding(r)
+	}
+
+	if opts.CacheMaxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", opts.CacheMaxAge, opts.CacheMaxAge))
+	}
+
+	encode := func(w io.Writer) error { return encoders[format](w, data) }
+	if opts.EnableETag {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			WriteProblem(w, r, Internal("Failed to encode response", nil))
+			return fmt.Errorf("failed to encode %s: %w", format, err)
+		}
+		etag := representationETag(buf.Bytes(), coding)
+		header.Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		encode = func(w io.Writer) error {
+			_, err := w.Write(buf.Bytes())
+			return err
+		}
+	}
+
+	header.Set("Content-Type", format.ContentType())
+	if coding != "identity" {
+		header.Set("Content-Encoding", coding)
+	}
+
+	body := &deferredBody{w: w}
+	compressor, err := newCompressor(body, coding)
+	if err != nil {
+		err = fmt.Errorf("failed to create %s compressor: %w", coding, err)
+		WriteProblem(w, r, Internal("Failed to encode response", err))
+		return err
+	}
+
+	if err := encode(compressor); err != nil {
+		if !body.started {
+			body.discarding = true
+			WriteProblem(w, r, Internal("Failed to encode response", nil))
+		}
+		compressor.Close()
+		return fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s stream: %w", coding, err)
+	}
+	if !body.started {
+		w.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
+
+// representationETag derives a strong ETag from the encoded body. Each content coding is a
+// different representation, so the coding is part of the tag.
+func representationETag(body []byte, coding string) string {
+	sum := sha256.Sum256(body)
+	if coding == "identity" {
+		return fmt.Sprintf(`"%x"`, sum)
+	}
+	return fmt.Sprintf(`"%x-%s"`, sum, coding)
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing weakly as RFC 9110
+// requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type payload struct {
+	RideID   string `json:"rideId"`
+	WaitTime *int   `json:"waitTime"`
+}
+
+// csvRows is a CSVMarshaler test value
+type csvRows []payload
+
+func (rows csvRows) MarshalCSV(w *csv.Writer) error {
+	if err := w.Write([]string{"rideId", "waitTime"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		wait := ""
+		if row.WaitTime != nil {
+			wait = "45"
+		}
+		if err := w.Write([]string{row.RideID, wait}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failingCSV fails before writing anything
+type failingCSV struct{}
+
+func (failingCSV) MarshalCSV(w *csv.Writer) error { return errors.New("boom") }
+
+func write(t *testing.T, target string, headers map[string]string, data any, opts *Options) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest("GET", target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	Write(w, r, data, opts)
+	return w
+}
+
+func TestWrite_JSON(t *testing.T) {
+	wait := 45
+	w := write(t, "/", nil, payload{RideID: "a", WaitTime: &wait}, &Options{CacheMaxAge: 60})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON response, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=60, s-maxage=60" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"rideId":"a","waitTime":45}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	wait := 45
+	w := write(t, "/?format=csv", nil, csvRows{{RideID: "a", WaitTime: &wait}, {RideID: "b"}}, &Options{})
+	if w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("Expected CSV, got %v", w.Header())
+	}
+	if w.Body.String() != "rideId,waitTime\na,45\nb,\n" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+}
+
+func TestWrite_MsgPack(t *testing.T) {
+	wait := 45
+	w := write(t, "/", map[string]string{"Accept": "application/msgpack"}, payload{RideID: "a", WaitTime: &wait}, &Options{})
+	if w.Header().Get("Content-Type") != "application/msgpack" {
+		t.Fatalf("Expected MessagePack, got %v", w.Header())
+	}
+	var decoded map[string]any
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("Body is not MessagePack: %v", err)
+	}
+	if decoded["rideId"] != "a" || decoded["waitTime"] != int8(45) {
+		t.Errorf("Expected the json field names, got %v", decoded)
+	}
+}
+
+func TestWrite_Protobuf(t *testing.T) {
+	w := write(t, "/", map[string]string{"Accept": "application/x-protobuf"}, wrapperspb.String("space mountain"), &Options{})
+	if w.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("Expected protobuf, got %v", w.Header())
+	}
+	var decoded wrapperspb.StringValue
+	if err := proto.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded.GetValue() != "space mountain" {
+		t.Errorf("Unexpected message %v (%v)", decoded.GetValue(), err)
+	}
+}
+
+// protoPayload has a protobuf representation without being a proto.Message
+type protoPayload struct {
+	RideID string `json:"rideId"`
+}
+
+func (p protoPayload) MarshalProto() proto.Message { return wrapperspb.String(p.RideID) }
+
+func TestWrite_ProtoMarshaler(t *testing.T) {
+	w := write(t, "/?format=protobuf", nil, protoPayload{RideID: "a"}, &Options{})
+	var decoded wrapperspb.StringValue
+	if err := proto.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded.GetValue() != "a" {
+		t.Errorf("Unexpected message %v (%v)", decoded.GetValue(), err)
+	}
+
+	w = write(t, "/", nil, protoPayload{RideID: "a"}, &Options{})
+	if body := strings.TrimSpace(w.Body.String()); body != `{"rideId":"a"}` {
+		t.Errorf("Expected the JSON shape to be kept, got %s", body)
+	}
+}
+
+func TestWrite_Compression(t *testing.T) {
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	data := make([]payload, 500)
+	for i := range data {
+		data[i].RideID = "9167db1d-e5e7-46da-a07f-ae30a87bc4c4"
+	}
+	want, _ := json.Marshal(data)
+
+	for coding, decode := range decoders {
+		t.Run(coding, func(t *testing.T) {
+			w := write(t, "/", map[string]string{"Accept-Encoding": coding}, data, &DefaultOptions)
+			if w.Header().Get("Content-Encoding") != coding {
+				t.Fatalf("Expected Content-Encoding %s, got %v", coding, w.Header())
+			}
+			if w.Body.Len() >= len(want) {
+				t.Errorf("Expected the body to be compressed, got %d bytes for %d", w.Body.Len(), len(want))
+			}
+			r, err := decode(bytes.NewReader(w.Body.Bytes()))
+			if err != nil {
+				t.Fatalf("Failed to open %s stream: %v", coding, err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || strings.TrimSpace(string(got)) != string(want) {
+				t.Errorf("Decompressed body does not match (%v)", err)
+			}
+		})
+	}
+
+	w := write(t, "/", map[string]string{"Accept-Encoding": "gzip"}, data, &Options{})
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected no compression when it is disabled, got %v", w.Header())
+	}
+}
+
+func TestWrite_ETag(t *testing.T) {
+	wait := 45
+	data := payload{RideID: "a", WaitTime: &wait}
+	w := write(t, "/", nil, data, &DefaultOptions)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a 200 with an ETag, got %d %v", w.Code, w.Header())
+	}
+
+	for _, match := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = write(t, "/", map[string]string{"If-None-Match": match}, data, &DefaultOptions)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d %q", match, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") == "" {
+			t.Errorf("If-None-Match %s: expected the ETag and Cache-Control on the 304, got %v", match, w.Header())
+		}
+	}
+
+	// Other representations of the same data have their own tags
+	for _, headers := range []map[string]string{{"Accept-Encoding": "gzip"}, {"Accept": "application/msgpack"}} {
+		w = write(t, "/", headers, data, &DefaultOptions)
+		if w.Header().Get("ETag") == etag {
+			t.Errorf("%v: expected a different ETag, got %s", headers, etag)
+		}
+		headers["If-None-Match"] = etag
+		if w = write(t, "/", headers, data, &DefaultOptions); w.Code != http.StatusOK {
+			t.Errorf("%v: expected status %d, got %d", headers, http.StatusOK, w.Code)
+		}
+	}
+
+	wait = 50
+	if w = write(t, "/", map[string]string{"If-None-Match": etag}, data, &DefaultOptions); w.Code != http.StatusOK {
+		t.Errorf("Expected changed data to be sent, got %d", w.Code)
+	}
+	if w = write(t, "/", nil, data, &Options{}); w.Header().Get("ETag") != "" {
+		t.Errorf("Expected no ETag when it is disabled, got %v", w.Header())
+	}
+}
+
+func TestWrite_NotAcceptable(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	err := Write(w, r, payload{}, nil)
+	if !errors.Is(err, ErrNotAcceptable) || w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected 406 and ErrNotAcceptable, got %d (%v)", w.Code, err)
+	}
+}
+
+func TestWrite_EncodingError(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?format=csv", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	if err := Write(w, r, failingCSV{}, &DefaultOptions); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected an uncompressed 500, got %d %v", w.Code, w.Header())
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Errorf("Expected only the JSON error in the body, got %q", w.Body.String())
+	}
+}
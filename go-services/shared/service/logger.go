@@ -1,134 +1,378 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"go-services/shared/middleware"
+	"io"
+	"log/slog"
 	"os"
+	"runtime"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-type logEntry struct {
-	Timestamp string                 `json:"timestamp"`
-	Severity  string                 `json:"severity"`
-	Message   string                 `json:"message"`
-	Fields    map[string]interface{} `json:"fields,omitempty"`
+// LevelCritical is the level of Fatal and Fatalf, reported to Cloud Logging as CRITICAL
+const LevelCritical = slog.Level(12)
+
+// Logger is the printf style logger the services and jobs log through. StructuredLogger implements
+// it on top of log/slog.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Fatal(args ...interface{})
+}
+
+// LogConfig controls the handler NewHandler builds
+type LogConfig struct {
+	// Level is the minimum level written
+	Level slog.Level
+	// JSON writes one Cloud Logging compatible JSON object per line instead of text
+	JSON bool
+	// Project is the Google Cloud project traces belong to. When set, log entries carry the
+	// logging.googleapis.com/trace fields so Cloud Logging links them to Cloud Trace.
+	Project string
 }
 
-var useJSON bool
+// LogConfigFromEnv reads LOG_LEVEL (debug, info, warn or error, default info), LOG_JSON (JSON unless
+// false or ENV=development) and GOOGLE_CLOUD_PROJECT
+func LogConfigFromEnv() (LogConfig, error) {
+	cfg := LogConfig{Level: slog.LevelInfo, JSON: true, Project: os.Getenv("GOOGLE_CLOUD_PROJECT")}
 
-func init() {
-	// Default to JSON logs unless explicitly disabled or in dev env.
-	v := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_JSON")))
-	if v == "" {
-		// detect common dev env markers
-		if strings.ToLower(os.Getenv("ENV")) == "development" {
-			useJSON = false
-		} else {
-			useJSON = true
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_JSON"))); v {
+	case "":
+		cfg.JSON = strings.ToLower(os.Getenv("ENV")) != "development"
+	case "false", "0", "no":
+		cfg.JSON = false
+	}
+
+	if v := strings.TrimSpace(os.Getenv("LOG_LEVEL")); v != "" {
+		if strings.EqualFold(v, "warning") {
+			v = "warn"
+		}
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			return cfg, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", v)
 		}
-	} else {
-		// accept "false", "0", "no" to disable JSON
-		useJSON = !(v == "false" || v == "0" || v == "no")
+	}
+	return cfg, nil
+}
+
+type parkKey struct{}
+type rideKey struct{}
+
+// WithPark returns a context whose log entries carry park_id
+func WithPark(ctx context.Context, parkID string) context.Context {
+	return context.WithValue(ctx, parkKey{}, parkID)
+}
+
+// WithRide returns a context whose log entries carry ride_id
+func WithRide(ctx context.Context, rideID string) context.Context {
+	return context.WithValue(ctx, rideKey{}, rideID)
+}
+
+// NewHandler builds the slog handler for cfg. Entries at ERROR and above go to stderr and the rest
+// to stdout, and the request ID, park, ride and trace of the context are added to every entry.
+func NewHandler(cfg LogConfig, stdout, stderr io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{AddSource: true, Level: cfg.Level, ReplaceAttr: replaceTextAttr}
+	newHandler := func(w io.Writer) slog.Handler { return slog.NewTextHandler(w, opts) }
+	if cfg.JSON {
+		opts.ReplaceAttr = replaceCloudLoggingAttr
+		newHandler = func(w io.Writer) slog.Handler { return slog.NewJSONHandler(w, opts) }
+	}
+	return &contextHandler{out: newHandler(stdout), errOut: newHandler(stderr), cfg: cfg}
+}
+
+// severity maps slog levels to Cloud Logging severities
+func severity(level slog.Level) string {
+	switch {
+	case level >= LevelCritical:
+		return "CRITICAL"
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
 	}
 }
 
-func logJSON(severity, msg string, fields map[string]interface{}) {
-	if !useJSON {
-		timestampUTC := time.Now().UTC().Format(time.RFC3339Nano)
-		var fieldParts []string
-		for k, v := range fields {
-			fieldParts = append(fieldParts, fmt.Sprintf("%s=%v", k, v))
+// replaceCloudLoggingAttr renames the built-in attributes to the fields Cloud Logging reads from
+// structured log lines
+func replaceCloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		return slog.String("severity", severity(a.Value.Any().(slog.Level)))
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.TimeKey:
+		return slog.String("time", a.Value.Time().UTC().Format(time.RFC3339Nano))
+	case slog.SourceKey:
+		a.Key = "logging.googleapis.com/sourceLocation"
+	}
+	return a
+}
+
+// replaceTextAttr shortens the source location and uses the Cloud Logging level names in text output
+func replaceTextAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		return slog.String(slog.LevelKey, severity(a.Value.Any().(slog.Level)))
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", trimPath(src.File), src.Line))
 		}
-		line := fmt.Sprintf("%s %s: %s", timestampUTC, severity, msg)
-		if len(fieldParts) > 0 {
-			line = fmt.Sprintf("%s [%s]", line, strings.Join(fieldParts, " "))
+	}
+	return a
+}
+
+// trimPath keeps the package directory and file name of a source path
+func trimPath(file string) string {
+	if i := strings.LastIndexByte(file, '/'); i > 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			return file[j+1:]
 		}
-		// send ERROR/CRITICAL to stderr
-		if severity == "ERROR" || severity == "CRITICAL" || severity == "FATAL" {
-			os.Stderr.Write(append([]byte(line), '\n'))
-			return
+	}
+	return file
+}
+
+// contextHandler adds the request attributes of the context and splits output by level
+type contextHandler struct {
+	out, errOut slog.Handler
+	cfg         LogConfig
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.out.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	if id := middleware.RequestIDFrom(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id, ok := ctx.Value(parkKey{}).(string); ok {
+		r.AddAttrs(slog.String("park_id", id))
+	}
+	if id, ok := ctx.Value(rideKey{}).(string); ok {
+		r.AddAttrs(slog.String("ride_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		if h.cfg.JSON && h.cfg.Project != "" {
+			r.AddAttrs(
+				slog.String("logging.googleapis.com/trace", "projects/"+h.cfg.Project+"/traces/"+sc.TraceID().String()),
+				slog.String("logging.googleapis.com/spanId", sc.SpanID().String()),
+				slog.Bool("logging.googleapis.com/trace_sampled", sc.IsSampled()),
+			)
+		} else {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
 		}
-		os.Stdout.Write(append([]byte(line), '\n'))
-		return
 	}
+	if r.Level >= slog.LevelError {
+		return h.errOut.Handle(ctx, r)
+	}
+	return h.out.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{out: h.out.WithAttrs(attrs), errOut: h.errOut.WithAttrs(attrs), cfg: h.cfg}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{out: h.out.WithGroup(name), errOut: h.errOut.WithGroup(name), cfg: h.cfg}
+}
 
-	entry := logEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
-		Severity:  severity,
-		Message:   msg,
-		Fields:    fields,
+// StructuredLogger is a Logger with key/value fields and context aware methods. A nil
+// *StructuredLogger logs through slog.Default.
+type StructuredLogger struct {
+	l *slog.Logger
+}
+
+// NewLogger returns a StructuredLogger writing to h
+func NewLogger(h slog.Handler) *StructuredLogger {
+	return &StructuredLogger{l: slog.New(h)}
+}
+
+// NewDefaultLogger configures logging from the environment, installs the handler as the slog and
+// log package default so libraries log the same way, and returns a logger for it
+func NewDefaultLogger() *StructuredLogger {
+	cfg, err := LogConfigFromEnv()
+	logger := NewLogger(NewHandler(cfg, os.Stdout, os.Stderr))
+	slog.SetDefault(logger.l)
+	if err != nil {
+		logger.Warnf("%v; using %s", err, cfg.Level)
+	}
+	return logger
+}
+
+// AsStructured returns l if it is a StructuredLogger, and otherwise a StructuredLogger whose entries
+// are formatted as "message key=value ..." and passed to l's printf methods
+func AsStructured(l Logger) *StructuredLogger {
+	if s, ok := l.(*StructuredLogger); ok {
+		return s
 	}
-	b, _ := json.Marshal(entry)
-	// send ERROR/CRITICAL to stderr so Cloud Logging treats as error severity
-	if severity == "ERROR" || severity == "CRITICAL" {
-		os.Stderr.Write(append(b, '\n'))
+	return NewLogger(&printfHandler{logger: l})
+}
+
+// Slog returns the underlying slog.Logger
+func (l *StructuredLogger) Slog() *slog.Logger {
+	if l == nil || l.l == nil {
+		return slog.Default()
+	}
+	return l.l
+}
+
+// With returns a logger that adds the key/value pairs to every entry
+func (l *StructuredLogger) With(args ...any) *StructuredLogger {
+	return &StructuredLogger{l: l.Slog().With(args...)}
+}
+
+// log writes an entry attributed to the caller skip frames above it
+func (l *StructuredLogger) log(ctx context.Context, skip int, level slog.Level, msg string, args ...any) {
+	logger := l.Slog()
+	if !logger.Enabled(ctx, level) {
 		return
 	}
-	os.Stdout.Write(append(b, '\n'))
+	var pcs [1]uintptr
+	runtime.Callers(skip+2, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = logger.Handler().Handle(ctx, r)
 }
 
-func Infof(format string, args ...interface{}) {
-	logJSON("INFO", fmt.Sprintf(format, args...), nil)
+// DebugContext logs msg with key/value args and the request attributes of ctx
+func (l *StructuredLogger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 1, slog.LevelDebug, msg, args...)
 }
 
-func Debugf(format string, args ...interface{}) {
-	logJSON("DEBUG", fmt.Sprintf(format, args...), nil)
+// InfoContext logs msg with key/value args and the request attributes of ctx
+func (l *StructuredLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 1, slog.LevelInfo, msg, args...)
 }
 
-func Warnf(format string, args ...interface{}) {
-	logJSON("WARN", fmt.Sprintf(format, args...), nil)
+// WarnContext logs msg with key/value args and the request attributes of ctx
+func (l *StructuredLogger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 1, slog.LevelWarn, msg, args...)
 }
 
-func Errorf(format string, args ...interface{}) {
-	logJSON("ERROR", fmt.Sprintf(format, args...), nil)
+// ErrorContext logs msg with key/value args and the request attributes of ctx
+func (l *StructuredLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 1, slog.LevelError, msg, args...)
 }
 
-func Fatalf(format string, args ...interface{}) {
-	logJSON("FATAL", fmt.Sprintf(format, args...), nil)
+func (l *StructuredLogger) Infof(format string, args ...interface{}) {
+	l.log(context.Background(), 1, slog.LevelInfo, fmt.Sprintf(format, args...))
 }
 
-func Fatal(args ...interface{}) {
-	logJSON("FATAL", fmt.Sprint(args...), nil)
-	os.Exit(1)
+func (l *StructuredLogger) Debugf(format string, args ...interface{}) {
+	l.log(context.Background(), 1, slog.LevelDebug, fmt.Sprintf(format, args...))
 }
 
-type Logger interface {
-	Infof(format string, args ...interface{})
-	Debugf(format string, args ...interface{})
-	Warnf(format string, args ...interface{})
-	Errorf(format string, args ...interface{})
-	Fatalf(format string, args ...interface{})
-	Fatal(args ...interface{})
+func (l *StructuredLogger) Warnf(format string, args ...interface{}) {
+	l.log(context.Background(), 1, slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *StructuredLogger) Errorf(format string, args ...interface{}) {
+	l.log(context.Background(), 1, slog.LevelError, fmt.Sprintf(format, args...))
 }
 
-// defaultLogger implements Logger by delegating to the package functions.
-type defaultLogger struct{}
+// exit ends the process after a Fatal or Fatalf entry; tests replace it
+var exit = os.Exit
 
-func NewDefaultLogger() Logger {
-	return &defaultLogger{}
+// Fatalf logs at CRITICAL and exits with status 1
+func (l *StructuredLogger) Fatalf(format string, args ...interface{}) {
+	l.log(context.Background(), 1, LevelCritical, fmt.Sprintf(format, args...))
+	exit(1)
 }
 
-func (l *defaultLogger) Infof(format string, args ...interface{}) {
-	Infof(format, args...)
+// Fatal logs at CRITICAL and exits with status 1
+func (l *StructuredLogger) Fatal(args ...interface{}) {
+	l.log(context.Background(), 1, LevelCritical, fmt.Sprint(args...))
+	exit(1)
 }
 
-func (l *defaultLogger) Debugf(format string, args ...interface{}) {
-	Debugf(format, args...)
+// The package functions log through slog.Default
+
+func Infof(format string, args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, slog.LevelInfo, fmt.Sprintf(format, args...))
 }
 
-func (l *defaultLogger) Warnf(format string, args ...interface{}) {
-	Warnf(format, args...)
+func Debugf(format string, args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, slog.LevelDebug, fmt.Sprintf(format, args...))
 }
 
-func (l *defaultLogger) Errorf(format string, args ...interface{}) {
-	Errorf(format, args...)
+func Warnf(format string, args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func Errorf(format string, args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func Fatalf(format string, args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, LevelCritical, fmt.Sprintf(format, args...))
+	exit(1)
 }
 
-func (l *defaultLogger) Fatalf(format string, args ...interface{}) {
-	Fatalf(format, args...)
+func Fatal(args ...interface{}) {
+	(*StructuredLogger)(nil).log(context.Background(), 1, LevelCritical, fmt.Sprint(args...))
+	exit(1)
+}
+
+// printfHandler adapts a printf style Logger to slog
+type printfHandler struct {
+	logger Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *printfHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *printfHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s%s=%v", h.group, a.Key, a.Value)
+		return true
+	})
+
+	switch msg := b.String(); {
+	case r.Level >= slog.LevelError:
+		h.logger.Errorf("%s", msg)
+	case r.Level >= slog.LevelWarn:
+		h.logger.Warnf("%s", msg)
+	case r.Level >= slog.LevelInfo:
+		h.logger.Infof("%s", msg)
+	default:
+		h.logger.Debugf("%s", msg)
+	}
+	return nil
+}
+
+func (h *printfHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	prefixed = append(prefixed, h.attrs...)
+	for _, a := range attrs {
+		prefixed = append(prefixed, slog.Attr{Key: h.group + a.Key, Value: a.Value})
+	}
+	return &printfHandler{logger: h.logger, attrs: prefixed, group: h.group}
 }
 
-func (l *defaultLogger) Fatal(args ...interface{}) {
-	Fatal(args...)
+func (h *printfHandler) WithGroup(name string) slog.Handler {
+	return &printfHandler{logger: h.logger, attrs: h.attrs, group: h.group + name + "."}
 }
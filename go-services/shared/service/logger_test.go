@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared/middleware"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestContext returns the context middleware.RequestID gives handlers for a request with id
+func requestContext(t *testing.T, id string) context.Context {
+	t.Helper()
+	var ctx context.Context
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ctx = r.Context() }))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", id)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	return ctx
+}
+
+func decodeEntry(t *testing.T, b *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON entry, got %q: %v", b.String(), err)
+	}
+	b.Reset()
+	return entry
+}
+
+func TestStructuredLoggerJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	logger := NewLogger(NewHandler(LogConfig{Level: slog.LevelInfo, JSON: true, Project: "demo"}, &stdout, &stderr))
+
+	ctx := WithRide(WithPark(requestContext(t, "req-1"), "park-1"), "ride-1")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x00, 0xf0},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	logger.With("component", "test").InfoContext(ctx, "Stored records", "inserted", 3)
+
+	entry := decodeEntry(t, &stdout)
+	want := map[string]any{
+		"severity":                             "INFO",
+		"message":                              "Stored records",
+		"component":                            "test",
+		"inserted":                             float64(3),
+		"request_id":                           "req-1",
+		"park_id":                              "park-1",
+		"ride_id":                              "ride-1",
+		"logging.googleapis.com/trace":         "projects/demo/traces/4bf90000000000000000000000000000",
+		"logging.googleapis.com/spanId":        "00f0000000000000",
+		"logging.googleapis.com/trace_sampled": true,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+	source, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]any)
+	if file, _ := source["file"].(string); !strings.HasSuffix(file, "logger_test.go") {
+		t.Errorf("Expected the source location of the caller, got %v", source)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected INFO entries on stdout only, got %q on stderr", stderr.String())
+	}
+}
+
+func TestStructuredLoggerLevels(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	logger := NewLogger(NewHandler(LogConfig{Level: slog.LevelInfo, JSON: true}, &stdout, &stderr))
+
+	logger.Debugf("hidden %d", 1)
+	if stdout.Len() != 0 {
+		t.Fatalf("Expected DEBUG to be filtered at INFO, got %q", stdout.String())
+	}
+
+	logger.Warnf("careful %d", 2)
+	if entry := decodeEntry(t, &stdout); entry["severity"] != "WARNING" || entry["message"] != "careful 2" {
+		t.Errorf("Unexpected warning entry %v", entry)
+	}
+
+	logger.ErrorContext(context.Background(), "Failed", "error", errors.New("boom"))
+	if entry := decodeEntry(t, &stderr); entry["severity"] != "ERROR" || entry["error"] != "boom" {
+		t.Errorf("Unexpected error entry %v", entry)
+	}
+}
+
+func TestStructuredLoggerText(t *testing.T) {
+	var stdout bytes.Buffer
+	logger := NewLogger(NewHandler(LogConfig{Level: slog.LevelDebug}, &stdout, &stdout))
+	logger.DebugContext(WithPark(context.Background(), "park-1"), "Fetched ride data", "entries", 12)
+
+	line := stdout.String()
+	for _, want := range []string{"level=DEBUG", `msg="Fetched ride data"`, "entries=12", "park_id=park-1", "source=service/logger_test.go:"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in %q", want, line)
+		}
+	}
+}
+
+func TestLogConfigFromEnv(t *testing.T) {
+	t.Setenv("ENV", "")
+	t.Setenv("LOG_JSON", "")
+	t.Setenv("LOG_LEVEL", "")
+	cfg, err := LogConfigFromEnv()
+	if err != nil || cfg.Level != slog.LevelInfo || !cfg.JSON {
+		t.Errorf("Expected JSON at INFO by default, got %+v, %v", cfg, err)
+	}
+
+	t.Setenv("LOG_JSON", "false")
+	t.Setenv("LOG_LEVEL", "Warning")
+	cfg, err = LogConfigFromEnv()
+	if err != nil || cfg.Level != slog.LevelWarn || cfg.JSON {
+		t.Errorf("Expected text at WARN, got %+v, %v", cfg, err)
+	}
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	if _, err := LogConfigFromEnv(); err == nil {
+		t.Error("Expected an unknown LOG_LEVEL to be rejected")
+	}
+}
+
+// recordingLogger is a printf Logger that keeps its error lines
+type recordingLogger struct {
+	MockLogger
+	lines []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(format, args...))
+}
+
+func TestAsStructured(t *testing.T) {
+	structured := NewLogger(NewHandler(LogConfig{}, &bytes.Buffer{}, &bytes.Buffer{}))
+	if AsStructured(structured) != structured {
+		t.Error("Expected a StructuredLogger to be returned as is")
+	}
+
+	printf := &recordingLogger{}
+	AsStructured(printf).With("park_id", "p1").ErrorContext(context.Background(), "Failed to process park", "error", "timeout")
+	if len(printf.lines) != 1 || printf.lines[0] != "ERROR Failed to process park park_id=p1 error=timeout" {
+		t.Errorf("Unexpected printf output %q", printf.lines)
+	}
+}
+
+func TestFatalExits(t *testing.T) {
+	var codes []int
+	prev := exit
+	exit = func(code int) { codes = append(codes, code) }
+	t.Cleanup(func() { exit = prev })
+
+	var stdout, stderr bytes.Buffer
+	logger := NewLogger(NewHandler(LogConfig{Level: slog.LevelInfo, JSON: true}, &stdout, &stderr))
+	logger.Fatalf("Invalid configuration: %v", errors.New("bad value"))
+	if entry := decodeEntry(t, &stderr); entry["severity"] != "CRITICAL" || entry["message"] != "Invalid configuration: bad value" {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+	logger.Fatal("Server failed")
+	if !slices.Equal(codes, []int{1, 1}) {
+		t.Errorf("Expected Fatalf and Fatal to exit with status 1, got %v", codes)
+	}
+}
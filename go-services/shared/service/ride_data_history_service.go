@@ -5,45 +5,96 @@ import (
 	"encoding/json"
 	"fmt"
 	"go-services/shared"
+	"go-services/shared/circuit"
+	"go-services/shared/metrics"
 	"go-services/shared/models"
 	"go-services/shared/repository"
+	"go-services/shared/tracing"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// UpstreamBaseURL is the themeparks.wiki API live ride data is fetched from
+const UpstreamBaseURL = "https://api.themeparks.wiki/v1"
+
+// UpstreamBreaker stops fetches after repeated upstream failures. It is shared by every service
+// instance so its state survives between collections.
+var UpstreamBreaker = circuit.New(5, 2*time.Minute)
+
+// probeClient is used by ProbeUpstream
+var probeClient = &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(nil)}
+
+// ProbeUpstream checks that the upstream API answers. Any response below 500 counts as reachable.
+func ProbeUpstream(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, UpstreamBaseURL+"/destinations", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "DisnelyandLinePredictor/1.0")
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upstream API unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("upstream API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // RideDataHistoryService handles fetching and processing ride data history
 type RideDataHistoryService struct {
 	repo   *repository.RideDataHistoryRepository
 	client *http.Client
-	logger Logger
+	logger *StructuredLogger
 }
 
-// NewRideDataHistoryService creates a new service instance
+// NewRideDataHistoryService creates a new service instance. Loggers other than StructuredLogger receive
+// their fields appended to the message.
 func NewRideDataHistoryService(repo *repository.RideDataHistoryRepository, logger Logger) *RideDataHistoryService {
 	return &RideDataHistoryService{
 		repo:   repo,
-		logger: logger,
+		logger: AsStructured(logger),
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.Transport(nil),
 		},
 	}
 }
 
 // FetchAndStoreParkData fetches ride data for a park and stores it in the database
 func (s *RideDataHistoryService) FetchAndStoreParkData(ctx context.Context, parkID string) (inserted int, skipped int, err error) {
-	s.logger.Infof("Fetching ride data for park: %s", parkID)
+	ctx, span := tracing.Tracer().Start(ctx, "FetchAndStoreParkData", trace.WithAttributes(attribute.String("park.id", parkID)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int("records.inserted", inserted), attribute.Int("records.skipped", skipped))
+		span.End()
+	}()
+	ctx = WithPark(ctx, parkID)
+
+	s.logger.InfoContext(ctx, "Fetching ride data")
 
 	// Fetch data from the API
+	start := time.Now()
 	parkData, err := s.fetchParkData(ctx, parkID)
+	metrics.ObserveFetch(parkID, time.Since(start))
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to fetch park data: %w", err)
 	}
 
 	// Log fetched data for debugging
-	s.logger.Debugf("Fetched ride data for park %s -- retrieved %+v entries", parkID, len(parkData.LiveData))
+	s.logger.DebugContext(ctx, "Fetched ride data", "entries", len(parkData.LiveData))
 
 	if len(parkData.LiveData) == 0 {
-		s.logger.Debugf("No ride data available for park %s", parkID)
+		s.logger.DebugContext(ctx, "No ride data available")
+		metrics.RecordsStored(parkID, 0, 0, time.Now())
 		return 0, 0, nil
 	}
 
@@ -64,8 +115,7 @@ func (s *RideDataHistoryService) FetchAndStoreParkData(ctx context.Context, park
 	}
 
 	// Log filtered data for debugging
-	s.logger.Debugf("Filtered ride data for park %s -- attractions: %+v, important: %+v entries",
-		parkID, len(filteredLiveData), len(importantRides))
+	s.logger.DebugContext(ctx, "Filtered ride data", "attractions", len(filteredLiveData), "important", len(importantRides))
 	parkData.LiveData = importantRides
 
 	// Convert to database records
@@ -73,7 +123,7 @@ func (s *RideDataHistoryService) FetchAndStoreParkData(ctx context.Context, park
 	for _, entry := range parkData.LiveData {
 		record, err := entry.ToRideDataHistoryRecord()
 		if err != nil {
-			s.logger.Errorf("Failed to convert entry %s to record: %v", entry.Name, err)
+			s.logger.ErrorContext(WithRide(ctx, entry.ID), "Failed to convert entry to record", "name", entry.Name, "error", err)
 			continue
 		}
 		records = append(records, record)
@@ -84,15 +134,30 @@ func (s *RideDataHistoryService) FetchAndStoreParkData(ctx context.Context, park
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to store ride data history: %w", err)
 	}
+	metrics.RecordsStored(parkID, inserted, skipped, time.Now())
 
-	s.logger.Infof("Successfully stored %d ride data history records for park %s (%d inserted, %d skipped)",
-		len(records), parkData.Name, inserted, skipped)
+	s.logger.InfoContext(ctx, "Stored ride data history records",
+		"park_name", parkData.Name, "records", len(records), "inserted", inserted, "skipped", skipped)
 	return inserted, skipped, nil
 }
 
-// fetchParkData makes an API call to fetch park data
-func (s *RideDataHistoryService) fetchParkData(ctx context.Context, parkID string) (*models.ParkData, error) {
-	url := fmt.Sprintf("https://api.themeparks.wiki/v1/entity/%s/live", parkID)
+// fetchParkData makes an API call to fetch park data. Failed requests, server errors, throttling and
+// undecodable responses count against UpstreamBreaker, and no request is made while it is open.
+func (s *RideDataHistoryService) fetchParkData(ctx context.Context, parkID string) (parkData *models.ParkData, err error) {
+	if err := UpstreamBreaker.Allow(); err != nil {
+		metrics.UpstreamError(parkID, metrics.UpstreamCircuitOpen)
+		return nil, err
+	}
+	upstreamFailed := false
+	defer func() {
+		if upstreamFailed {
+			UpstreamBreaker.Failure(err)
+		} else {
+			UpstreamBreaker.Success()
+		}
+	}()
+
+	url := fmt.Sprintf("%s/entity/%s/live", UpstreamBaseURL, parkID)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -105,20 +170,27 @@ func (s *RideDataHistoryService) fetchParkData(ctx context.Context, parkID strin
 
 	resp, err := s.client.Do(req)
 	if err != nil {
+		metrics.UpstreamError(parkID, metrics.UpstreamRequest)
+		// A cancelled collection says nothing about the upstream API
+		upstreamFailed = ctx.Err() == nil
 		return nil, fmt.Errorf("failed to make API request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.UpstreamError(parkID, metrics.UpstreamStatus)
+		upstreamFailed = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
 		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	var parkData models.ParkData
-	if err := json.NewDecoder(resp.Body).Decode(&parkData); err != nil {
+	parkData = &models.ParkData{}
+	if err := json.NewDecoder(resp.Body).Decode(parkData); err != nil {
+		metrics.UpstreamError(parkID, metrics.UpstreamDecode)
+		upstreamFailed = true
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &parkData, nil
+	return parkData, nil
 }
 
 // FetchAndStoreMultipleParks fetches and stores data for multiple parks
@@ -127,7 +199,7 @@ func (s *RideDataHistoryService) FetchAndStoreMultipleParks(ctx context.Context,
 
 	for _, parkID := range parkIDs {
 		if _, _, err := s.FetchAndStoreParkData(ctx, parkID); err != nil {
-			s.logger.Errorf("Failed to process park %s: %v", parkID, err)
+			s.logger.ErrorContext(WithPark(ctx, parkID), "Failed to process park", "error", err)
 			errors = append(errors, fmt.Errorf("park %s: %w", parkID, err))
 		}
 	}
@@ -154,6 +226,16 @@ func (s *RideDataHistoryService) GetAllRideDataHistory(ctx context.Context) ([]*
 	return s.repo.GetAllRideDataHistory(ctx)
 }
 
+// GetRideDataHistoryPageByPark retrieves one page of ride data for a specific park
+func (s *RideDataHistoryService) GetRideDataHistoryPageByPark(ctx context.Context, parkID string, after *repository.HistoryCursor, pageSize int) (repository.HistoryPage, error) {
+	return s.repo.GetRideDataHistoryPageByPark(ctx, parkID, after, pageSize)
+}
+
+// GetAllRideDataHistoryPage retrieves one page of all ride data
+func (s *RideDataHistoryService) GetAllRideDataHistoryPage(ctx context.Context, after *repository.HistoryCursor, pageSize int) (repository.HistoryPage, error) {
+	return s.repo.GetAllRideDataHistoryPage(ctx, after, pageSize)
+}
+
 // HealthCheck performs a health check on the service
 func (s *RideDataHistoryService) HealthCheck(ctx context.Context) error {
 	return s.repo.HealthCheck(ctx)
@@ -0,0 +1,125 @@
+// Package tracing sets up OpenTelemetry tracing for the services. Incoming requests, calls to the
+// upstream live data API and database queries become spans, and W3C trace context is read from
+// incoming requests and sent on outgoing ones, so a slow request can be followed from the HTTP
+// server through the repository to Postgres.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go-services/shared/middleware"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans this repository creates itself
+const instrumentationName = "go-services"
+
+// Tracer returns the tracer for spans created by the services
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider and the W3C trace context and baggage propagators.
+// OTEL_TRACES_EXPORTER picks the exporter:
+//   - "otlp" sends spans over OTLP/HTTP, configured by the standard OTEL_EXPORTER_OTLP_* variables
+//   - "stdout" prints spans as JSON, for local runs
+//   - "none", the default, records nothing but still propagates incoming trace context
+//
+// serviceName is used unless OTEL_SERVICE_NAME is set, and OTEL_TRACES_SAMPLER chooses the sampler.
+// The returned function flushes buffered spans and must be called before exit.
+func Setup(ctx context.Context, serviceName string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q, expected otlp, stdout or none", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res := resource.Default()
+	if os.Getenv("OTEL_SERVICE_NAME") == "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String("service.name", serviceName)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build trace resource: %w", err)
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware starts a server span for every request, continuing the trace of a traceparent header.
+// Health and readiness checks and metric scrapes are not traced.
+func Middleware(service string, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, service,
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/health", "/livez", "/readyz", "/metrics":
+				return false
+			}
+			return true
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// Route names the server span after the ServeMux pattern that matched, e.g. "GET /v1/rides/{id}",
+// and records the request ID. Like metrics.Instrument it must wrap the mux directly, because the
+// mux records the pattern on the request it is given; Middleware only sees a copy.
+func Route(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		span := trace.SpanFromContext(r.Context())
+		if !span.IsRecording() {
+			return
+		}
+		if id := middleware.RequestIDFrom(r.Context()); id != "" {
+			span.SetAttributes(attribute.String("http.request_id", id))
+		}
+		if r.Pattern != "" {
+			span.SetName(spanName(r))
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+	})
+}
+
+// spanName prefixes patterns that match any method with the request method
+func spanName(r *http.Request) string {
+	if strings.HasPrefix(r.Pattern, "/") {
+		return r.Method + " " + r.Pattern
+	}
+	return r.Pattern
+}
+
+// Transport traces outgoing requests and sends the trace context with them
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,331 @@
+// Package webhooks turns ride state changes into outgoing webhook events and delivers them to the
+// subscriptions of API key holders. Events are queued in the database when the collector stores a
+// change, so they survive restarts, and a Dispatcher sends them signed with the subscription's
+// secret, retrying failures with exponential backoff until they are moved to the dead letters.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request headers of a delivery
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+	SignatureHeader = "X-Webhook-Signature"
+	// EventIDHeader repeats the event id, which stays the same across attempts
+	EventIDHeader = "X-Webhook-Id"
+	// EventTypeHeader repeats the event type
+	EventTypeHeader = "X-Webhook-Event"
+)
+
+// Retry policy
+const (
+	// MaxAttempts is how many times an event is sent before it becomes a dead letter
+	MaxAttempts = 8
+	// BaseRetryDelay is the delay before the second attempt; every further delay doubles
+	BaseRetryDelay = 30 * time.Second
+	// MaxRetryDelay caps the delay between attempts
+	MaxRetryDelay = time.Hour
+)
+
+const (
+	// claimLease is how long a claimed delivery is held before another collector may retry it; it
+	// outlasts the client timeout
+	claimLease = 2 * time.Minute
+	// claimBatch is how many deliveries are claimed at once
+	claimBatch = 50
+	// deliveryConcurrency bounds the requests in flight
+	deliveryConcurrency = 8
+	// maxErrorLength bounds the error stored with a failed attempt
+	maxErrorLength = 500
+)
+
+// ErrInvalidSignature is returned by Verify when a signature does not match the body
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the SignatureHeader value for body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+func mac(secret, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify checks a SignatureHeader value against body, rejecting signatures older than tolerance so
+// a captured request cannot be replayed later. Receivers written in Go can use it as is.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+	expected := mac(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Classify returns the event types of a state change. A ride without a previous record, because it
+// is new or had no record within the throttle's lookback, is treated as closed, so one that comes
+// back operating after a long closure still produces ride.opened.
+func Classify(previous, current *models.RideDataHistoryRecord) []string {
+	previous = previousOrClosed(previous)
+	operating := string(models.RideStatusOperating)
+	var types []string
+	switch {
+	case previous.Status != operating && current.Status == operating:
+		if previous.Status == string(models.RideStatusDown) {
+			types = append(types, models.WebhookRideReopened)
+		} else {
+			types = append(types, models.WebhookRideOpened)
+		}
+	case previous.Status == operating && current.Status != operating:
+		types = append(types, models.WebhookRideClosed)
+	}
+	if soldOut(current.ReturnTimeState) && previous.ReturnTimeState != nil && *previous.ReturnTimeState == models.ReturnTimeAvailable {
+		types = append(types, models.WebhookReturnTimeSoldOut)
+	}
+	return types
+}
+
+// previousOrClosed returns previous, or a closed state without return times when it is nil
+func previousOrClosed(previous *models.RideDataHistoryRecord) *models.RideDataHistoryRecord {
+	if previous == nil {
+		return &models.RideDataHistoryRecord{Status: string(models.RideStatusClosed)}
+	}
+	return previous
+}
+
+// soldOut reports whether a return time state has no return times left, for now or for the day
+func soldOut(state *string) bool {
+	return state != nil && (*state == models.ReturnTimeTempFull || *state == models.ReturnTimeFinished)
+}
+
+// NewEvents builds the events of state changes. Event ids combine the inserted record and the type,
+// so an event keeps its id however often it is delivered.
+func NewEvents(changes []repository.StateChange, now time.Time) ([]*models.WebhookEvent, error) {
+	var events []*models.WebhookEvent
+	for _, change := range changes {
+		for _, eventType := range Classify(change.Previous, change.Current) {
+			id := fmt.Sprintf("evt_%d_%s", change.Current.ID, strings.ReplaceAll(eventType, ".", "_"))
+			current, previous := change.Current, previousOrClosed(change.Previous)
+			payload, err := json.Marshal(models.WebhookPayload{
+				ID:        id,
+				Type:      eventType,
+				CreatedAt: now.UTC(),
+				Data: models.WebhookRideChange{
+					RideID:                  current.RideID,
+					RideName:                current.Name,
+					ParkID:                  current.ParkID,
+					Status:                  current.Status,
+					PreviousStatus:          previous.Status,
+					StandbyWaitTime:         current.StandbyWaitTime,
+					ReturnTimeState:         current.ReturnTimeState,
+					PreviousReturnTimeState: previous.ReturnTimeState,
+					ReturnStart:             current.ReturnStart,
+					ReturnEnd:               current.ReturnEnd,
+					LastUpdated:             current.LastUpdated,
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+			}
+			events = append(events, &models.WebhookEvent{ID: id, Type: eventType, RideID: current.RideID, Payload: payload})
+		}
+	}
+	return events, nil
+}
+
+// RetryDelay is the delay after a failed attempt, the attempt'th one
+func RetryDelay(attempt int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempt && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
+
+// Store is the part of the webhook repository the Dispatcher uses
+type Store interface {
+	EnqueueWebhookEvents(ctx context.Context, events []*models.WebhookEvent) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, id int64) error
+	RetryWebhookDelivery(ctx context.Context, id int64, next time.Time, status *int, lastError string) error
+	DeadLetterWebhookDelivery(ctx context.Context, id int64, status *int, lastError string) error
+}
+
+// Dispatcher queues the events of state changes and delivers the queue
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	now    func() time.Time
+	// wake starts a delivery round right after events were queued
+	wake chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher sending with client, normally notify.PublicClient
+func NewDispatcher(store Store, client *http.Client) *Dispatcher {
+	return &Dispatcher{store: store, client: client, now: time.Now, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue queues the events of changes for their subscriptions. It is a repository.ChangeHook;
+// failures are logged and never fail the caller's insert.
+func (d *Dispatcher) Enqueue(ctx context.Context, changes []repository.StateChange) {
+	events, err := NewEvents(changes, d.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to build webhook events", "error", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	queued, err := d.store.EnqueueWebhookEvents(ctx, events)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to enqueue webhook events", "events", len(events), "error", err)
+		return
+	}
+	if queued > 0 {
+		slog.DebugContext(ctx, "Queued webhook deliveries", "events", len(events), "deliveries", queued)
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers due events every interval, and right after events are queued, until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.Dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Dispatch delivers the events that are due until none are left
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimWebhookDeliveries(ctx, d.now(), claimLease, claimBatch)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to claim webhook deliveries", "error", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		sem := make(chan struct{}, deliveryConcurrency)
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				d.deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+		if len(deliveries) < claimBatch {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.store.CompleteWebhookDelivery(ctx, delivery.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to complete webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	msg := err.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+	if delivery.Attempts >= MaxAttempts {
+		slog.WarnContext(ctx, "Webhook delivery failed for the last time", "delivery_id", delivery.ID,
+			"subscription_id", delivery.SubscriptionID, "event_id", delivery.EventID, "attempts", delivery.Attempts, "error", err)
+		if err := d.store.DeadLetterWebhookDelivery(ctx, delivery.ID, status, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to dead letter webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+	next := d.now().Add(RetryDelay(delivery.Attempts))
+	slog.InfoContext(ctx, "Webhook delivery failed, retrying", "delivery_id", delivery.ID,
+		"subscription_id", delivery.SubscriptionID, "attempts", delivery.Attempts, "next_attempt_at", next, "error", err)
+	if err := d.store.RetryWebhookDelivery(ctx, delivery.ID, next, status, msg); err != nil {
+		slog.ErrorContext(ctx, "Failed to reschedule webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send posts the signed payload and returns the response status, nil when none arrived
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now(), delivery.Payload))
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("receiver returned status %d", status)
+	}
+	return &status, nil
+}
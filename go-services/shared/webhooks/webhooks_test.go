@@ -0,0 +1,282 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1760870000, 0)
+	body := []byte(`{"id":"evt_1_ride_closed"}`)
+	header := Sign("whsec_test", now, body)
+
+	if err := Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected the signature to verify: %v", err)
+	}
+	for name, check := range map[string]func() error{
+		"wrong secret": func() error { return Verify("whsec_other", header, body, 5*time.Minute, now) },
+		"changed body": func() error { return Verify("whsec_test", header, []byte(`{}`), 5*time.Minute, now) },
+		"too old":      func() error { return Verify("whsec_test", header, body, 5*time.Minute, now.Add(6*time.Minute)) },
+		"malformed":    func() error { return Verify("whsec_test", "v1=abc", body, 5*time.Minute, now) },
+	} {
+		if err := check(); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
+
+func state(status string, returnTime string) *models.RideDataHistoryRecord {
+	record := &models.RideDataHistoryRecord{ID: 7, RideID: "ride-1", ParkID: "park-1", Name: "Space Mountain", Status: status}
+	if returnTime != "" {
+		record.ReturnTimeState = &returnTime
+	}
+	return record
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *models.RideDataHistoryRecord
+		current  *models.RideDataHistoryRecord
+		want     []string
+	}{
+		{"no previous record", nil, state("OPERATING", ""), []string{models.WebhookRideOpened}},
+		{"no previous record and closed", nil, state("CLOSED", "FINISHED"), nil},
+		{"opened", state("CLOSED", ""), state("OPERATING", ""), []string{models.WebhookRideOpened}},
+		{"opened after refurbishment", state("REFURBISHMENT", ""), state("OPERATING", ""), []string{models.WebhookRideOpened}},
+		{"reopened", state("DOWN", ""), state("OPERATING", ""), []string{models.WebhookRideReopened}},
+		{"went down", state("OPERATING", ""), state("DOWN", ""), []string{models.WebhookRideClosed}},
+		{"closed", state("OPERATING", ""), state("CLOSED", ""), []string{models.WebhookRideClosed}},
+		{"closed to down", state("CLOSED", ""), state("DOWN", ""), nil},
+		{"wait time changed", state("OPERATING", ""), state("OPERATING", ""), nil},
+		{"sold out", state("OPERATING", "AVAILABLE"), state("OPERATING", "FINISHED"), []string{models.WebhookReturnTimeSoldOut}},
+		{"temporarily full", state("OPERATING", "AVAILABLE"), state("OPERATING", "TEMP_FULL"), []string{models.WebhookReturnTimeSoldOut}},
+		{"full to finished", state("OPERATING", "TEMP_FULL"), state("OPERATING", "FINISHED"), nil},
+		{"closed and sold out", state("OPERATING", "AVAILABLE"), state("CLOSED", "FINISHED"), []string{models.WebhookRideClosed, models.WebhookReturnTimeSoldOut}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.previous, tt.current); !slices.Equal(got, tt.want) {
+				t.Errorf("Classify() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEvents(t *testing.T) {
+	now := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	reopened := state("OPERATING", "")
+	reopened.ID = 8
+	events, err := NewEvents([]repository.StateChange{
+		{Previous: state("OPERATING", ""), Current: state("DOWN", "")},
+		{Previous: state("DOWN", ""), Current: state("DOWN", "")},
+		{Previous: nil, Current: reopened},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID != "evt_7_ride_closed" || events[0].Type != models.WebhookRideClosed || events[0].RideID != "ride-1" {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != events[0].ID || payload.Data.Status != "DOWN" || payload.Data.PreviousStatus != "OPERATING" || !payload.CreatedAt.Equal(now) {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	// A ride without a previous record is reported as opened from closed
+	if events[1].ID != "evt_8_ride_opened" || events[1].Type != models.WebhookRideOpened {
+		t.Fatalf("Unexpected event %+v", events[1])
+	}
+	if err := json.Unmarshal(events[1].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Data.PreviousStatus != "CLOSED" || payload.Data.PreviousReturnTimeState != nil {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 7: 32 * time.Minute, 8: time.Hour, 20: time.Hour,
+	} {
+		if got := RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %v, expected %v", attempt, got, want)
+		}
+	}
+}
+
+// fakeStore is an in-memory queue. Claims hand out every due delivery once.
+type fakeStore struct {
+	mu          sync.Mutex
+	queue       []*models.WebhookDelivery
+	due         map[int64]time.Time
+	enqueued    []*models.WebhookEvent
+	completed   []int64
+	deadLetters map[int64]string
+	statuses    map[int64]*int
+}
+
+func newFakeStore(deliveries ...*models.WebhookDelivery) *fakeStore {
+	s := &fakeStore{due: map[int64]time.Time{}, deadLetters: map[int64]string{}, statuses: map[int64]*int{}}
+	for _, d := range deliveries {
+		s.queue = append(s.queue, d)
+		s.due[d.ID] = time.Time{}
+	}
+	return s
+}
+
+func (s *fakeStore) EnqueueWebhookEvents(ctx context.Context, events []*models.WebhookEvent) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueued = append(s.enqueued, events...)
+	return int64(len(events)), nil
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []*models.WebhookDelivery
+	for _, d := range s.queue {
+		if len(claimed) < limit && !s.due[d.ID].After(now) {
+			d.Attempts++
+			s.due[d.ID] = now.Add(lease)
+			copied := *d
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) remove(id int64) {
+	s.queue = slices.DeleteFunc(s.queue, func(d *models.WebhookDelivery) bool { return d.ID == id })
+}
+
+func (s *fakeStore) CompleteWebhookDelivery(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed = append(s.completed, id)
+	s.remove(id)
+	return nil
+}
+
+func (s *fakeStore) RetryWebhookDelivery(ctx context.Context, id int64, next time.Time, status *int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.due[id] = next
+	s.statuses[id] = status
+	return nil
+}
+
+func (s *fakeStore) DeadLetterWebhookDelivery(ctx context.Context, id int64, status *int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters[id] = lastError
+	s.statuses[id] = status
+	s.remove(id)
+	return nil
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received, bodies = append(received, r), append(bodies, body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	payload := []byte(`{"id":"evt_7_ride_closed"}`)
+	store := newFakeStore(&models.WebhookDelivery{ID: 1, URL: srv.URL, Secret: "whsec_test", EventID: "evt_7_ride_closed", EventType: models.WebhookRideClosed, Payload: payload})
+	d := NewDispatcher(store, srv.Client())
+	d.Dispatch(context.Background())
+
+	if !slices.Equal(store.completed, []int64{1}) || len(received) != 1 {
+		t.Fatalf("Expected delivery 1 to complete, got %v after %d requests", store.completed, len(received))
+	}
+	r := received[0]
+	if r.Header.Get(EventIDHeader) != "evt_7_ride_closed" || r.Header.Get(EventTypeHeader) != models.WebhookRideClosed {
+		t.Errorf("Unexpected headers %v", r.Header)
+	}
+	if err := Verify("whsec_test", r.Header.Get(SignatureHeader), bodies[0], time.Minute, time.Now()); err != nil {
+		t.Errorf("Expected a valid signature: %v", err)
+	}
+}
+
+func TestDispatcherRetriesThenDeadLetters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store := newFakeStore(&models.WebhookDelivery{ID: 1, URL: srv.URL, Secret: "whsec_test", EventID: "evt", EventType: models.WebhookRideOpened, Payload: []byte(`{}`)})
+	now := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	d := NewDispatcher(store, srv.Client())
+	d.now = func() time.Time { return now }
+
+	d.Dispatch(context.Background())
+	if want := now.Add(BaseRetryDelay); !store.due[1].Equal(want) {
+		t.Fatalf("Expected the second attempt at %v, got %v", want, store.due[1])
+	}
+	if status := store.statuses[1]; status == nil || *status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the response status to be recorded, got %v", status)
+	}
+
+	// Not due yet
+	d.Dispatch(context.Background())
+	if store.queue[0].Attempts != 1 {
+		t.Fatalf("Expected no attempt before the retry is due, got %d attempts", store.queue[0].Attempts)
+	}
+
+	for attempt := 2; attempt <= MaxAttempts; attempt++ {
+		now = store.due[1]
+		d.Dispatch(context.Background())
+	}
+	if len(store.queue) != 0 || store.deadLetters[1] != "receiver returned status 503" {
+		t.Errorf("Expected the delivery to become a dead letter after %d attempts, got queue %v, dead letters %v", MaxAttempts, store.queue, store.deadLetters)
+	}
+}
+
+func TestDispatcherRecordsTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	store := newFakeStore(&models.WebhookDelivery{ID: 1, URL: url, Secret: "s", EventID: "evt", EventType: models.WebhookRideOpened, Payload: []byte(`{}`)})
+	NewDispatcher(store, http.DefaultClient).Dispatch(context.Background())
+	if _, ok := store.statuses[1]; !ok || store.statuses[1] != nil {
+		t.Errorf("Expected a retry without a status, got %v", store.statuses)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	store := newFakeStore()
+	d := NewDispatcher(store, http.DefaultClient)
+	d.Enqueue(context.Background(), []repository.StateChange{
+		{Previous: state("OPERATING", "AVAILABLE"), Current: state("OPERATING", "TEMP_FULL")},
+		{Previous: state("OPERATING", ""), Current: state("OPERATING", "")},
+	})
+	if len(store.enqueued) != 1 || store.enqueued[0].Type != models.WebhookReturnTimeSoldOut {
+		t.Fatalf("Unexpected events %+v", store.enqueued)
+	}
+	select {
+	case <-d.wake:
+	default:
+		t.Error("Expected queued events to wake the dispatcher")
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/ratelimit"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAlertRuleBody bounds the body of POST /v1/alerts
+const maxAlertRuleBody = 16 << 10
+
+// alertComparisons are the accepted rule comparisons
+var alertComparisons = map[string]bool{
+	models.AlertBelow: true, models.AlertAtOrBelow: true, models.AlertAbove: true, models.AlertAtOrAbove: true,
+}
+
+// requireAPIKey returns the API key of the request. Alerts belong to the key that created them, so
+// anonymous requests are rejected.
+func requireAPIKey(r *http.Request) (*ratelimit.Key, error) {
+	key := ratelimit.KeyFrom(r.Context())
+	if key == nil {
+		return nil, response.NewError(http.StatusUnauthorized, "Alerts require an API key, sent as "+ratelimit.APIKeyHeader)
+	}
+	return key, nil
+}
+
+// alertRuleID parses the {id} path segment
+func alertRuleID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, response.NotFound(fmt.Sprintf("Alert %q does not exist", r.PathValue("id")))
+	}
+	return id, nil
+}
+
+// alertStoreError converts repository errors into API errors
+func alertStoreError(err error, id int64) error {
+	switch {
+	case errors.Is(err, repository.ErrAlertRuleNotFound):
+		return response.NotFound(fmt.Sprintf("Alert %d does not exist", id))
+	case errors.Is(err, repository.ErrAlertRuleLimit):
+		return response.NewError(http.StatusConflict, fmt.Sprintf("An API key may have at most %d alerts", repository.MaxAlertRulesPerKey))
+	}
+	return response.Internal("Failed to access alerts", err)
+}
+
+// writePrivateJSON writes the per key alert responses, which must not be cached by shared caches
+func writePrivateJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.WarnContext(r.Context(), "Failed to write alert response", "error", err)
+	}
+}
+
+// formatClock formats minutes after midnight as HH:MM
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func alertRuleResource(rule *models.AlertRule) AlertRuleResource {
+	resource := AlertRuleResource{
+		ID:              rule.ID,
+		RideID:          rule.RideID,
+		Comparison:      rule.Comparison,
+		Threshold:       rule.Threshold,
+		Timezone:        rule.Timezone,
+		ParkHoursOnly:   rule.ParkHoursOnly,
+		CooldownMinutes: rule.CooldownMinutes,
+		Channel:         rule.Channel,
+		Target:          rule.Target,
+		Enabled:         rule.Enabled,
+		LastTriggeredAt: rule.LastTriggeredAt,
+		CreatedAt:       rule.CreatedAt,
+	}
+	if ride, _, ok := shared.FindFilteredRide(rule.RideID); ok {
+		resource.RideName = ride.Name
+	}
+	if rule.WindowStart != nil && rule.WindowEnd != nil {
+		resource.Window = &AlertWindow{Start: formatClock(*rule.WindowStart), End: formatClock(*rule.WindowEnd)}
+	}
+	return resource
+}
+
+// validWebhookURL reports whether s is an absolute http or https URL without credentials
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.User == nil
+}
+
+// decodeBase64URL decodes the unpadded or padded base64url keys of push subscriptions
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// newAlertRule validates a request and builds the rule it describes
+func newAlertRule(req *AlertRuleRequest, apiKeyID int64) (*models.AlertRule, error) {
+	var fields []response.FieldError
+	invalid := func(field, format string, args ...any) {
+		fields = append(fields, response.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	rule := &models.AlertRule{
+		APIKeyID:        apiKeyID,
+		RideID:          req.RideID,
+		Comparison:      req.Comparison,
+		Timezone:        req.Timezone,
+		ParkHoursOnly:   req.ParkHoursOnly,
+		CooldownMinutes: DefaultAlertCooldownMinutes,
+		Channel:         req.Channel,
+	}
+	if _, _, ok := shared.FindFilteredRide(req.RideID); !ok {
+		invalid("rideId", "Ride %q is not tracked", req.RideID)
+	}
+	if !alertComparisons[req.Comparison] {
+		invalid("comparison", "comparison must be one of below, at_or_below, above or at_or_above")
+	}
+	if req.Threshold == nil || *req.Threshold < 0 || *req.Threshold > MaxAlertThreshold {
+		invalid("threshold", "threshold must be a wait time between 0 and %d minutes", MaxAlertThreshold)
+	} else {
+		rule.Threshold = *req.Threshold
+	}
+	if rule.Timezone == "" {
+		rule.Timezone = DefaultAlertTimezone
+	}
+	if _, err := time.LoadLocation(rule.Timezone); err != nil {
+		invalid("timezone", "Unknown time zone %q", req.Timezone)
+	}
+	if req.Window != nil {
+		start, okStart := parseClock(req.Window.Start)
+		end, okEnd := parseClock(req.Window.End)
+		switch {
+		case !okStart || !okEnd:
+			invalid("window", "window start and end must be HH:MM")
+		case start == end:
+			invalid("window", "window start and end must differ")
+		default:
+			rule.WindowStart, rule.WindowEnd = &start, &end
+		}
+	}
+	if req.CooldownMinutes != nil {
+		if *req.CooldownMinutes < MinAlertCooldownMinutes || *req.CooldownMinutes > MaxAlertCooldownMinutes {
+			invalid("cooldownMinutes", "cooldownMinutes must be between %d and %d", MinAlertCooldownMinutes, MaxAlertCooldownMinutes)
+		} else {
+			rule.CooldownMinutes = *req.CooldownMinutes
+		}
+	}
+
+	targets := 0
+	if req.WebhookURL != "" {
+		targets++
+	}
+	if req.Email != "" {
+		targets++
+	}
+	if req.PushSubscription != nil {
+		targets++
+	}
+	if targets > 1 {
+		invalid("channel", "Set only the target of the channel")
+	}
+	switch req.Channel {
+	case models.AlertChannelWebhook:
+		if !validWebhookURL(req.WebhookURL) {
+			invalid("webhookUrl", "webhookUrl must be an http or https URL")
+		}
+		rule.Target = req.WebhookURL
+	case models.AlertChannelEmail:
+		addr, err := mail.ParseAddress(req.Email)
+		if err != nil || addr.Address != req.Email || len(req.Email) > 254 {
+			invalid("email", "email must be a plain email address")
+		}
+		rule.Target = req.Email
+	case models.AlertChannelWebPush:
+		sub := req.PushSubscription
+		if sub == nil {
+			invalid("pushSubscription", "pushSubscription is required for web push alerts")
+			break
+		}
+		if u, err := url.Parse(sub.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			invalid("pushSubscription.endpoint", "endpoint must be an https URL")
+		}
+		if key, err := decodeBase64URL(sub.Keys.P256DH); err != nil || len(key) != 65 || key[0] != 4 {
+			invalid("pushSubscription.keys.p256dh", "p256dh must be an uncompressed P-256 public key, base64url encoded")
+		}
+		if secret, err := decodeBase64URL(sub.Keys.Auth); err != nil || len(secret) != 16 {
+			invalid("pushSubscription.keys.auth", "auth must be a 16 byte secret, base64url encoded")
+		}
+		rule.Target, rule.PushP256DH, rule.PushAuth = sub.Endpoint, sub.Keys.P256DH, sub.Keys.Auth
+	default:
+		invalid("channel", "channel must be one of webhook, email or webpush")
+	}
+
+	if len(fields) > 0 {
+		return nil, response.Invalid(fields...)
+	}
+	return rule, nil
+}
+
+// listAlertsHandler handles GET /v1/alerts
+func listAlertsHandler(store alertRuleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		rules, err := store.ListAlertRules(r.Context(), key.ID)
+		if err != nil {
+			response.WriteProblem(w, r, alertStoreError(err, 0))
+			return
+		}
+		resources := make([]AlertRuleResource, 0, len(rules))
+		for _, rule := range rules {
+			resources = append(resources, alertRuleResource(rule))
+		}
+		writePrivateJSON(w, r, http.StatusOK, AlertRulesResponse{Rules: resources})
+	}
+}
+
+// createAlertHandler handles POST /v1/alerts
+func createAlertHandler(store alertRuleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		var req AlertRuleRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAlertRuleBody))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest("The request body must be an alert rule in JSON: "+err.Error()))
+			return
+		}
+		rule, err := newAlertRule(&req, key.ID)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		created, err := store.CreateAlertRule(r.Context(), rule)
+		if err != nil {
+			response.WriteProblem(w, r, alertStoreError(err, 0))
+			return
+		}
+		logger.InfoContext(r.Context(), "Created alert rule", "rule_id", created.ID, "api_key_id", key.ID, "ride_id", created.RideID, "channel", created.Channel)
+		w.Header().Set("Location", fmt.Sprintf("/v1/alerts/%d", created.ID))
+		writePrivateJSON(w, r, http.StatusCreated, alertRuleResource(created))
+	}
+}
+
+// getAlertHandler handles GET /v1/alerts/{id}
+func getAlertHandler(store alertRuleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := alertRuleID(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		rule, err := store.GetAlertRule(r.Context(), key.ID, id)
+		if err != nil {
+			response.WriteProblem(w, r, alertStoreError(err, id))
+			return
+		}
+		writePrivateJSON(w, r, http.StatusOK, alertRuleResource(rule))
+	}
+}
+
+// deleteAlertHandler handles DELETE /v1/alerts/{id}
+func deleteAlertHandler(store alertRuleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := alertRuleID(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		if err := store.DeleteAlertRule(r.Context(), key.ID, id); err != nil {
+			response.WriteProblem(w, r, alertStoreError(err, id))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listAlertDeliveriesHandler handles GET /v1/alerts/{id}/deliveries
+func listAlertDeliveriesHandler(store alertRuleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := alertRuleID(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		deliveries, err := store.ListAlertDeliveries(r.Context(), key.ID, id, AlertDeliveriesLimit)
+		if err != nil {
+			response.WriteProblem(w, r, alertStoreError(err, id))
+			return
+		}
+		writePrivateJSON(w, r, http.StatusOK, AlertDeliveriesResponse{Deliveries: deliveries})
+	}
+}
+
+// pushKeyHandler handles GET /v1/alerts/push-key, the applicationServerKey browsers subscribe with
+func pushKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if VAPIDPublicKey == "" {
+		response.WriteProblem(w, r, response.NotFound("Web push alerts are not configured"))
+		return
+	}
+	if err := response.WriteJSONWithDefaults(w, r, PushKeyResponse{PublicKey: VAPIDPublicKey}); err != nil {
+		logger.WarnContext(r.Context(), "Failed to write push key response", "error", err)
+	}
+}
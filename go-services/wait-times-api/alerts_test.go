@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"go-services/shared/models"
+	"go-services/shared/ratelimit"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAPIKey      = "wtk_alerts"
+	otherTestAPIKey = "wtk_other"
+)
+
+// fakeKeys issues two API keys, IDs 1 and 2
+type fakeKeys struct{}
+
+func (fakeKeys) LookupKey(ctx context.Context, key string) (*ratelimit.Key, error) {
+	switch key {
+	case testAPIKey:
+		return &ratelimit.Key{ID: 1, Name: "alerts"}, nil
+	case otherTestAPIKey:
+		return &ratelimit.Key{ID: 2, Name: "other"}, nil
+	}
+	return nil, ratelimit.ErrUnknownKey
+}
+
+func (fakeKeys) CountUsage(ctx context.Context, id int64, at time.Time) (int64, error) {
+	return 1, nil
+}
+
+// fakeAlertStore keeps alert rules in memory, scoped by API key like the repository
+type fakeAlertStore struct {
+	mu         sync.Mutex
+	rules      []*models.AlertRule
+	deliveries []*models.AlertDelivery
+	limit      int
+	err        error
+}
+
+func (f *fakeAlertStore) CreateAlertRule(ctx context.Context, rule *models.AlertRule) (*models.AlertRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	owned := 0
+	for _, r := range f.rules {
+		if r.APIKeyID == rule.APIKeyID {
+			owned++
+		}
+	}
+	if f.limit > 0 && owned >= f.limit {
+		return nil, repository.ErrAlertRuleLimit
+	}
+	created := *rule
+	created.ID = int64(len(f.rules) + 1)
+	created.Enabled = true
+	created.CreatedAt = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	f.rules = append(f.rules, &created)
+	return &created, nil
+}
+
+func (f *fakeAlertStore) ListAlertRules(ctx context.Context, apiKeyID int64) ([]*models.AlertRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rules []*models.AlertRule
+	for _, r := range f.rules {
+		if r.APIKeyID == apiKeyID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeAlertStore) GetAlertRule(ctx context.Context, apiKeyID, id int64) (*models.AlertRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, r := range f.rules {
+		if r.ID == id && r.APIKeyID == apiKeyID {
+			return r, nil
+		}
+	}
+	return nil, repository.ErrAlertRuleNotFound
+}
+
+func (f *fakeAlertStore) DeleteAlertRule(ctx context.Context, apiKeyID, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	for i, r := range f.rules {
+		if r.ID == id && r.APIKeyID == apiKeyID {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrAlertRuleNotFound
+}
+
+func (f *fakeAlertStore) ListAlertDeliveries(ctx context.Context, apiKeyID, ruleID int64, limit int) ([]*models.AlertDelivery, error) {
+	if _, err := f.GetAlertRule(ctx, apiKeyID, ruleID); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deliveries := []*models.AlertDelivery{}
+	for _, d := range f.deliveries {
+		if d.RuleID == ruleID && len(deliveries) < limit {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// alertRouter serves the alert routes behind a limiter that knows the fake keys
+func alertRouter(store alertRuleStore) http.Handler {
+	limiter := ratelimit.New(ratelimit.Config{Keys: fakeKeys{}, KeyCacheTTL: time.Minute})
+	return newRouter(&fakeStore{}, closedHub(), limiter, nil, store, nil)
+}
+
+// serveAlert sends a request with the API key, or anonymously when apiKey is empty
+func serveAlert(router http.Handler, apiKey, method, target, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if apiKey != "" {
+		req.Header.Set(ratelimit.APIKeyHeader, apiKey)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const testWebhookAlert = `{"rideId":"` + testRideID + `","comparison":"at_or_below","threshold":20,
+	"window":{"start":"21:00","end":"01:00"},"cooldownMinutes":30,"channel":"webhook","webhookUrl":"https://example.com/hook"}`
+
+func TestAlertLifecycle(t *testing.T) {
+	store := &fakeAlertStore{}
+	router := alertRouter(store)
+
+	w := serveAlert(router, testAPIKey, "POST", "/v1/alerts", testWebhookAlert)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Location") != "/v1/alerts/1" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+	var created AlertRuleResource
+	decode(t, w, &created)
+	if created.RideName != "Space Mountain" || created.Threshold != 20 || created.CooldownMinutes != 30 ||
+		created.Timezone != DefaultAlertTimezone || created.Window == nil || *created.Window != (AlertWindow{Start: "21:00", End: "01:00"}) {
+		t.Errorf("Unexpected alert %+v", created)
+	}
+	if rule := store.rules[0]; rule.APIKeyID != 1 || *rule.WindowStart != 21*60 || *rule.WindowEnd != 60 {
+		t.Errorf("Unexpected stored rule %+v", rule)
+	}
+
+	var list AlertRulesResponse
+	decode(t, serveAlert(router, testAPIKey, "GET", "/v1/alerts", ""), &list)
+	if len(list.Rules) != 1 || list.Rules[0].ID != created.ID {
+		t.Errorf("Expected the created alert to be listed, got %+v", list.Rules)
+	}
+	decode(t, serveAlert(router, otherTestAPIKey, "GET", "/v1/alerts", ""), &list)
+	if len(list.Rules) != 0 {
+		t.Errorf("Expected another key to see no alerts, got %+v", list.Rules)
+	}
+
+	if w := serveAlert(router, otherTestAPIKey, "GET", "/v1/alerts/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's alert to be hidden, got %d", w.Code)
+	}
+	if w := serveAlert(router, otherTestAPIKey, "DELETE", "/v1/alerts/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key not to delete the alert, got %d", w.Code)
+	}
+	if w := serveAlert(router, testAPIKey, "GET", "/v1/alerts/1", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := serveAlert(router, testAPIKey, "DELETE", "/v1/alerts/1", ""); w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected an empty %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := serveAlert(router, testAPIKey, "GET", "/v1/alerts/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted alert to be gone, got %d", w.Code)
+	}
+}
+
+func TestAlertsRequireAPIKey(t *testing.T) {
+	router := alertRouter(&fakeAlertStore{})
+	for _, target := range []string{"/v1/alerts", "/v1/alerts/1", "/v1/alerts/1/deliveries"} {
+		w := serveAlert(router, "", "GET", target, "")
+		if w.Code != http.StatusUnauthorized || w.Header().Get("Content-Type") != response.ProblemContentType {
+			t.Errorf("Expected a 401 problem for anonymous %s, got %d %q", target, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestAlertsDisabledWithoutStore(t *testing.T) {
+	if w := serve(t, &fakeStore{}, "GET", "/v1/alerts"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected no alert routes without a store, got %d", w.Code)
+	}
+}
+
+func TestCreateAlertValidation(t *testing.T) {
+	const p256dh = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"unknown ride", `{"rideId":"nope","comparison":"below","threshold":10,"channel":"webhook","webhookUrl":"https://example.com"}`, "rideId"},
+		{"bad comparison", `{"rideId":"` + testRideID + `","comparison":"equal","threshold":10,"channel":"webhook","webhookUrl":"https://example.com"}`, "comparison"},
+		{"missing threshold", `{"rideId":"` + testRideID + `","comparison":"below","channel":"webhook","webhookUrl":"https://example.com"}`, "threshold"},
+		{"threshold too high", `{"rideId":"` + testRideID + `","comparison":"below","threshold":601,"channel":"webhook","webhookUrl":"https://example.com"}`, "threshold"},
+		{"bad timezone", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"timezone":"Mars/Base","channel":"webhook","webhookUrl":"https://example.com"}`, "timezone"},
+		{"bad window", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"window":{"start":"25:00","end":"10:00"},"channel":"webhook","webhookUrl":"https://example.com"}`, "window"},
+		{"empty window", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"window":{"start":"10:00","end":"10:00"},"channel":"webhook","webhookUrl":"https://example.com"}`, "window"},
+		{"short cooldown", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"cooldownMinutes":1,"channel":"webhook","webhookUrl":"https://example.com"}`, "cooldownMinutes"},
+		{"bad webhook", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webhook","webhookUrl":"ftp://example.com"}`, "webhookUrl"},
+		{"bad email", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"email","email":"Someone <a@example.com>"}`, "email"},
+		{"two targets", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"email","email":"a@example.com","webhookUrl":"https://example.com"}`, "channel"},
+		{"unknown channel", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"sms"}`, "channel"},
+		{"missing subscription", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webpush"}`, "pushSubscription"},
+		{"http push endpoint", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webpush",
+			"pushSubscription":{"endpoint":"http://push.example.com/1","keys":{"p256dh":"` + p256dh + `","auth":"AAAAAAAAAAAAAAAAAAAAAA"}}}`, "pushSubscription.endpoint"},
+		{"bad push key", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webpush",
+			"pushSubscription":{"endpoint":"https://push.example.com/1","keys":{"p256dh":"AAAA","auth":"AAAAAAAAAAAAAAAAAAAAAA"}}}`, "pushSubscription.keys.p256dh"},
+		{"bad push auth", `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webpush",
+			"pushSubscription":{"endpoint":"https://push.example.com/1","keys":{"p256dh":"` + p256dh + `","auth":"AAAA"}}}`, "pushSubscription.keys.auth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeAlertStore{}
+			w := serveAlert(alertRouter(store), testAPIKey, "POST", "/v1/alerts", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			var problem response.Problem
+			decode(t, w, &problem)
+			if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field {
+				t.Errorf("Expected a field error for %s, got %+v", tt.field, problem.Errors)
+			}
+			if len(store.rules) != 0 {
+				t.Error("Expected an invalid alert not to be stored")
+			}
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		body := strings.Replace(testWebhookAlert, `"threshold"`, `"sms":"1","threshold"`, 1)
+		if w := serveAlert(alertRouter(&fakeAlertStore{}), testAPIKey, "POST", "/v1/alerts", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected unknown fields to be rejected, got %d", w.Code)
+		}
+	})
+
+	t.Run("web push", func(t *testing.T) {
+		body := `{"rideId":"` + testRideID + `","comparison":"below","threshold":10,"channel":"webpush",
+			"pushSubscription":{"endpoint":"https://push.example.com/1","keys":{"p256dh":"` + p256dh + `","auth":"AAAAAAAAAAAAAAAAAAAAAA=="}}}`
+		store := &fakeAlertStore{}
+		w := serveAlert(alertRouter(store), testAPIKey, "POST", "/v1/alerts", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), p256dh) {
+			t.Error("Expected the push keys not to be returned")
+		}
+		if rule := store.rules[0]; rule.Target != "https://push.example.com/1" || rule.PushP256DH != p256dh {
+			t.Errorf("Unexpected stored rule %+v", rule)
+		}
+	})
+}
+
+func TestCreateAlertLimit(t *testing.T) {
+	router := alertRouter(&fakeAlertStore{limit: 1})
+	if w := serveAlert(router, testAPIKey, "POST", "/v1/alerts", testWebhookAlert); w.Code != http.StatusCreated {
+		t.Fatalf("Expected the first alert to be created, got %d", w.Code)
+	}
+	w := serveAlert(router, testAPIKey, "POST", "/v1/alerts", testWebhookAlert)
+	var problem response.Problem
+	decode(t, w, &problem)
+	if w.Code != http.StatusConflict || problem.Code != response.CodeConflict {
+		t.Errorf("Expected a conflict problem, got %d %+v", w.Code, problem)
+	}
+}
+
+func TestAlertDeliveries(t *testing.T) {
+	wait := 15
+	store := &fakeAlertStore{}
+	router := alertRouter(store)
+	serveAlert(router, testAPIKey, "POST", "/v1/alerts", testWebhookAlert)
+	store.deliveries = []*models.AlertDelivery{{ID: 1, RuleID: 1, Channel: "webhook", Status: models.AlertDelivered, RideID: testRideID, WaitTime: &wait}}
+
+	var deliveries AlertDeliveriesResponse
+	decode(t, serveAlert(router, testAPIKey, "GET", "/v1/alerts/1/deliveries", ""), &deliveries)
+	if len(deliveries.Deliveries) != 1 || *deliveries.Deliveries[0].WaitTime != 15 {
+		t.Errorf("Unexpected deliveries %+v", deliveries.Deliveries)
+	}
+	if w := serveAlert(router, otherTestAPIKey, "GET", "/v1/alerts/1/deliveries", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's deliveries to be hidden, got %d", w.Code)
+	}
+}
+
+func TestPushKey(t *testing.T) {
+	router := alertRouter(&fakeAlertStore{})
+	if w := serveAlert(router, "", "GET", "/v1/alerts/push-key", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a VAPID key, got %d", w.Code)
+	}
+
+	VAPIDPublicKey = "BPublic"
+	t.Cleanup(func() { VAPIDPublicKey = "" })
+	var key PushKeyResponse
+	decode(t, serveAlert(router, "", "GET", "/v1/alerts/push-key", ""), &key)
+	if key.PublicKey != "BPublic" {
+		t.Errorf("Expected the public key, got %q", key.PublicKey)
+	}
+}
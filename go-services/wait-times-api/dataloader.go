@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// batchLoader loads values by key with one fetch per batch of keys and caches the results for the
+// lifetime of a request. Keys registered with Prime are fetched together with the first Load that
+// misses the cache, so resolving a field on every element of a list costs a single query.
+type batchLoader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending map[K]struct{}
+	cache   map[K]V
+	fetched map[K]bool
+	batches int
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:   fetch,
+		pending: make(map[K]struct{}),
+		cache:   make(map[K]V),
+		fetched: make(map[K]bool),
+	}
+}
+
+// Prime schedules keys for the next batch without fetching them
+func (l *batchLoader[K, V]) Prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if !l.fetched[key] {
+			l.pending[key] = struct{}{}
+		}
+	}
+}
+
+// Load returns the value for key, fetching it along with every pending key on a cache miss.
+// Keys the fetch returns nothing for load as the zero value, and extra values it returns are
+// cached too. Concurrent loads wait for the batch in flight instead of issuing their own.
+// Failed fetches are not cached.
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fetched[key] {
+		return l.cache[key], nil
+	}
+
+	l.pending[key] = struct{}{}
+	keys := make([]K, 0, len(l.pending))
+	for k := range l.pending {
+		keys = append(keys, k)
+	}
+
+	values, err := l.fetch(ctx, keys)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	l.batches++
+	for k, v := range values {
+		l.cache[k] = v
+		l.fetched[k] = true
+		delete(l.pending, k)
+	}
+	for _, k := range keys {
+		l.fetched[k] = true
+		delete(l.pending, k)
+	}
+	return l.cache[key], nil
+}
+
+// Batches returns how many fetches the loader has made
+func (l *batchLoader[K, V]) Batches() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.batches
+}
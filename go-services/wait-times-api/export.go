@@ -0,0 +1,165 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"go-services/shared/export"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// exportRequest is a validated /v1/export query
+type exportRequest struct {
+	filter  repository.HistoryFilter
+	format  export.Format
+	columns []export.Column
+}
+
+// parseExportRequest validates the query of GET /v1/export. from and to are RFC 3339 timestamps;
+// to defaults to now and from to DataHours before to. Unknown parks and rides are not found.
+func parseExportRequest(r *http.Request, now time.Time) (exportRequest, error) {
+	params := newQueryParams(r)
+	req := exportRequest{format: export.FormatCSV}
+
+	if s := params.values.Get("format"); s != "" {
+		format, err := export.ParseFormat(s)
+		if err != nil {
+			params.invalid("format", "%v", err)
+		}
+		req.format = format
+	}
+
+	columns, err := export.ParseColumns(params.values.Get("columns"))
+	if err != nil {
+		params.invalid("columns", "%v", err)
+	}
+	req.columns = columns
+
+	to := params.timestamp("to", now)
+	from := params.timestamp("from", to.Add(-DataHours))
+	if !from.Before(to) {
+		params.invalid("from", "from must be before to")
+	} else if to.Sub(from) > ExportMaxWindow {
+		params.invalid("from", "exports may span at most %d days", int(ExportMaxWindow/(24*time.Hour)))
+	}
+	if err := params.err(); err != nil {
+		return req, err
+	}
+
+	if parkID := params.values.Get("park_id"); parkID != "" {
+		if _, err := lookupPark(parkID); err != nil {
+			return req, err
+		}
+		req.filter.ParkID = parkID
+	}
+	for _, rideID := range params.values["ride_id"] {
+		if _, _, err := lookupRide(rideID); err != nil {
+			return req, err
+		}
+		req.filter.RideIDs = append(req.filter.RideIDs, rideID)
+	}
+
+	req.filter.Since = from
+	req.filter.Until = to
+	req.filter.OrderBy = []repository.OrderBy{repository.Asc("last_updated"), repository.Asc("id")}
+	return req, nil
+}
+
+// fileName suggests a download name such as ride_data_history_20250914T000000Z_20250915T000000Z.csv
+func (req exportRequest) fileName() string {
+	const layout = "20060102T150405Z"
+	return fmt.Sprintf("ride_data_history_%s_%s.%s",
+		req.filter.Since.UTC().Format(layout), req.filter.Until.UTC().Format(layout), req.format)
+}
+
+// exportBody holds back the status line until the first byte of the export is written, so a query
+// that fails before any output reaches the client can still be answered with an error status
+type exportBody struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (b *exportBody) Write(p []byte) (int, error) {
+	if !b.started {
+		b.started = true
+		b.w.WriteHeader(http.StatusOK)
+	}
+	return b.w.Write(p)
+}
+
+// exportHandler handles GET /v1/export. Rows are read from the store one at a time and encoded
+// straight into the response, oldest first, so memory use does not grow with the size of the export.
+// CSV and NDJSON are gzip encoded for clients that accept it; parquet is compressed internally.
+func exportHandler(repo rideDataStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseExportRequest(r, time.Now())
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", req.format.ContentType())
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", req.fileName()))
+		header.Set("Cache-Control", "no-store")
+
+		body := &exportBody{w: w}
+		var out io.Writer = body
+		var gz *gzip.Writer
+		if req.format.Compressible() {
+			header.Add("Vary", "Accept-Encoding")
+			if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				header.Set("Content-Encoding", "gzip")
+				gz = gzip.NewWriter(body)
+				out = gz
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), ExportTimeout)
+		defer cancel()
+
+		rows := 0
+		err = func() error {
+			writer, err := export.NewWriter(out, req.format, req.columns)
+			if err != nil {
+				return err
+			}
+			err = repo.EachRideDataHistory(ctx, req.filter, func(record *models.RideDataHistoryRecord) error {
+				rows++
+				return writer.Write(record)
+			})
+			if err != nil {
+				return err
+			}
+			if err := writer.Close(); err != nil {
+				return err
+			}
+			if gz != nil {
+				return gz.Close()
+			}
+			return nil
+		}()
+		if err == nil {
+			if !body.started {
+				w.WriteHeader(http.StatusOK)
+			}
+			return
+		}
+
+		logger.ErrorContext(r.Context(), "Export failed", "rows", rows, "error", err)
+		if !body.started {
+			header.Del("Content-Disposition")
+			response.WriteProblem(w, r, response.Internal("Failed to export ride data", nil))
+			return
+		}
+		// Part of the export is already on the wire; abort the connection so the client sees a
+		// truncated transfer instead of a file that silently ends early
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"go-services/shared/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHandler_CSV(t *testing.T) {
+	from := time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC)
+	other := testRecord(3, 20, from.Add(2*time.Hour))
+	other.RideID = otherParkRideID
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{
+		testRecord(1, 30, from.Add(time.Hour)),
+		testRecord(2, 45, from.Add(2*time.Hour)),
+		other,
+		testRecord(4, 50, from.Add(48*time.Hour)),
+	}}
+
+	w := serve(t, store, "GET", "/v1/export?ride_id="+testRideID+"&from=2025-09-14T00:00:00Z&to=2025-09-15T00:00:00Z&columns=id,standby_wait_time")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected text/csv, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="ride_data_history_20250914T000000Z_20250915T000000Z.csv"` {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Body is not CSV: %v", err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "id,standby_wait_time" || rows[1][0] != "1" || rows[2][1] != "45" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	filter := store.lastFilter
+	if !filter.Since.Equal(from) || !filter.Until.Equal(from.Add(24*time.Hour)) || len(filter.OrderBy) != 2 || filter.OrderBy[0].Desc {
+		t.Errorf("Expected an oldest first filter over the requested range, got %+v", filter)
+	}
+}
+
+func TestExportHandler_Gzip(t *testing.T) {
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, time.Now().Add(-time.Hour))}}
+	req := httptest.NewRequest("GET", "/v1/export?format=ndjson&columns=id", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	newRouter(store, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected gzip encoded NDJSON, got %v", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Body is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil || string(body) != "{\"id\":1}\n" {
+		t.Errorf("Unexpected body %q (%v)", body, err)
+	}
+}
+
+func TestExportHandler_Parquet(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/export?format=parquet", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	newRouter(&fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, time.Now().Add(-time.Hour))}}, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("Expected an unencoded parquet file, got %d %v", w.Code, w.Header())
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "PAR1") || !strings.HasSuffix(body, "PAR1") {
+		t.Errorf("Expected parquet magic bytes around the body")
+	}
+}
+
+func TestExportHandler_InvalidParameters(t *testing.T) {
+	for _, query := range []string{
+		"format=xlsx",
+		"columns=id,password",
+		"from=yesterday",
+		"to=2025-09-14",
+		"from=2025-09-15T00:00:00Z&to=2025-09-14T00:00:00Z",
+		"from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z",
+	} {
+		if w := serve(t, &fakeStore{}, "GET", "/v1/export?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, w.Code)
+		}
+	}
+	for _, query := range []string{"park_id=nope", "ride_id=nope", "ride_id=" + testRideID + "&ride_id=nope"} {
+		if w := serve(t, &fakeStore{}, "GET", "/v1/export?"+query); w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestExportHandler_ErrorBeforeOutput(t *testing.T) {
+	for _, format := range []string{"csv", "parquet"} {
+		store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, time.Now().Add(-time.Hour))}, eachErr: errors.New("connection reset")}
+		w := serve(t, store, "GET", "/v1/export?format="+format)
+		if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Disposition") != "" {
+			t.Errorf("%s: expected a plain 500 while the output is still buffered, got %d %v", format, w.Code, w.Header())
+		}
+	}
+}
+
+func TestExportHandler_AbortsTruncatedStream(t *testing.T) {
+	now := time.Now()
+	store := &fakeStore{eachErr: errors.New("connection reset")}
+	for i := 0; i < 2000; i++ {
+		store.records = append(store.records, testRecord(int64(i), i, now.Add(-time.Duration(i)*time.Second)))
+	}
+	server := httptest.NewServer(newRouter(store, closedHub(), nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/v1/export")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the stream to start, got %d", resp.StatusCode)
+	}
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Error("Expected the truncated export to end with a read error")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"go-services/shared"
+	"strconv"
+	"time"
+
+	waittimesv1 "go-services/proto/waittimes/v1"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The /v1 resources below implement response.CSVMarshaler and response.ProtoMarshaler so clients can
+// ask for them as text/csv or protobuf. CSV headers use the JSON field names, and the protobuf
+// messages are the ones the gRPC service returns.
+
+// csvOptionalInt renders a nullable wait time, leaving closed periods empty
+func csvOptionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// csvTime renders a timestamp the way the JSON representation does
+func csvTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// MarshalCSV writes one row per park
+func (resp ParksResponse) MarshalCSV(w *csv.Writer) error {
+	if err := w.Write([]string{"parkId", "parkName", "rideCount"}); err != nil {
+		return err
+	}
+	for _, park := range resp.Parks {
+		if err := w.Write([]string{park.ParkID, park.ParkName, strconv.Itoa(park.RideCount)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalCSV writes one row per ride of the park
+func (resp ParkRidesResponse) MarshalCSV(w *csv.Writer) error {
+	if err := w.Write([]string{"rideId", "rideName", "parkId", "parkName"}); err != nil {
+		return err
+	}
+	for _, ride := range resp.Rides {
+		if err := w.Write([]string{ride.RideID, ride.RideName, ride.ParkID, ride.ParkName}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalProto returns the park as the gRPC Park message
+func (resp ParkRidesResponse) MarshalProto() proto.Message {
+	park := &waittimesv1.Park{ParkId: resp.ParkID, ParkName: resp.ParkName}
+	for _, ride := range resp.Rides {
+		park.Rides = append(park.Rides, &waittimesv1.Ride{RideId: ride.RideID, RideName: ride.RideName, ParkId: ride.ParkID})
+	}
+	return park
+}
+
+// MarshalCSV writes the live wait time as a single row
+func (entry *LiveWaitTimeEntry) MarshalCSV(w *csv.Writer) error {
+	if err := w.Write([]string{"rideId", "rideName", "waitTime", "status", "lastUpdated"}); err != nil {
+		return err
+	}
+	return w.Write([]string{entry.RideID, entry.RideName, csvOptionalInt(entry.WaitTime), entry.Status, csvTime(entry.LastUpdated)})
+}
+
+// MarshalProto returns the live wait time as the gRPC LiveStatus message
+func (entry *LiveWaitTimeEntry) MarshalProto() proto.Message {
+	_, parkID, _ := shared.FindFilteredRide(entry.RideID)
+	return &waittimesv1.LiveStatus{
+		RideId:      entry.RideID,
+		RideName:    entry.RideName,
+		ParkId:      parkID,
+		WaitTime:    optionalInt32(entry.WaitTime),
+		Status:      entry.Status,
+		LastUpdated: timestamppb.New(entry.LastUpdated),
+	}
+}
+
+// MarshalCSV writes one row per history entry. CSV has no place for the next page cursor, so clients
+// paging through history should ask for JSON, MessagePack or protobuf.
+func (resp RideHistoryResponse) MarshalCSV(w *csv.Writer) error {
+	if err := w.Write([]string{"rideId", "snapshotTime", "status", "waitTime"}); err != nil {
+		return err
+	}
+	for _, entry := range resp.History {
+		if err := w.Write([]string{resp.RideID, csvTime(entry.SnapshotTime), entry.Status, csvOptionalInt(entry.WaitTime)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalProto returns the page as the gRPC GetHistoryResponse message
+func (resp RideHistoryResponse) MarshalProto() proto.Message {
+	msg := &waittimesv1.GetHistoryResponse{RideId: resp.RideID, NextPageToken: resp.NextCursor}
+	for _, entry := range resp.History {
+		msg.History = append(msg.History, &waittimesv1.HistoryPoint{
+			WaitTime:     optionalInt32(entry.WaitTime),
+			Status:       entry.Status,
+			SnapshotTime: timestamppb.New(entry.SnapshotTime),
+		})
+	}
+	return msg
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var graphQLSchema string
+
+// errRideData is returned to GraphQL clients in place of store errors, which are only logged
+var errRideData = errors.New("failed to retrieve ride data")
+
+// graphQLRequest is the body of POST /graphql
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLHandler handles POST /graphql. Every request gets its own loaders, so the live state and
+// each history window are read from the store at most once per query however many rides it selects.
+func graphQLHandler(repo rideDataStore) http.HandlerFunc {
+	schema := graphql.MustParseSchema(graphQLSchema, &graphQLResolver{},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(GraphQLMaxDepth),
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			response.WriteProblem(w, r, response.MethodNotAllowed(http.MethodPost))
+			return
+		}
+
+		var req graphQLRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest("Invalid GraphQL request body"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+		ctx = withGraphQLLoaders(ctx, repo, time.Now())
+
+		result := schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+		if err := response.WriteJSON(w, r, result, &response.Options{EnableGzip: true}); err != nil {
+			logger.WarnContext(ctx, "Failed to write GraphQL response", "error", err)
+		}
+	}
+}
+
+// graphQLLoaders batches the store reads of one GraphQL request
+type graphQLLoaders struct {
+	repo rideDataStore
+	now  time.Time
+	live *batchLoader[string, *models.RideDataHistoryRecord]
+
+	mu      sync.Mutex
+	known   []string
+	history map[int32]*batchLoader[string, []*models.RideDataHistoryRecord]
+}
+
+type graphQLLoadersKey struct{}
+
+func withGraphQLLoaders(ctx context.Context, repo rideDataStore, now time.Time) context.Context {
+	loaders := &graphQLLoaders{
+		repo:    repo,
+		now:     now,
+		history: make(map[int32]*batchLoader[string, []*models.RideDataHistoryRecord]),
+	}
+	loaders.live = newBatchLoader(func(ctx context.Context, _ []string) (map[string]*models.RideDataHistoryRecord, error) {
+		latest, err := repo.GetLatestRideDataForAllRides(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get latest ride data for GraphQL", "error", err)
+			return nil, errRideData
+		}
+		byRide := make(map[string]*models.RideDataHistoryRecord, len(latest))
+		for _, record := range latest {
+			byRide[record.RideID] = record
+		}
+		return byRide, nil
+	})
+	return context.WithValue(ctx, graphQLLoadersKey{}, loaders)
+}
+
+func loadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// prime registers rides a list resolver returned so their history is fetched in one batch
+func (l *graphQLLoaders) prime(rideIDs []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.known = append(l.known, rideIDs...)
+	for _, loader := range l.history {
+		loader.Prime(rideIDs...)
+	}
+}
+
+// historyLoader returns the loader for a window, creating it primed with every known ride
+func (l *graphQLLoaders) historyLoader(windowHours int32) *batchLoader[string, []*models.RideDataHistoryRecord] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if loader, ok := l.history[windowHours]; ok {
+		return loader
+	}
+
+	since := l.now.Add(-time.Duration(windowHours) * time.Hour)
+	loader := newBatchLoader(func(ctx context.Context, rideIDs []string) (map[string][]*models.RideDataHistoryRecord, error) {
+		records, err := l.repo.QueryRideDataHistory(ctx, repository.HistoryFilter{RideIDs: rideIDs, Since: since})
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get ride data history for GraphQL", "rides", len(rideIDs), "error", err)
+			return nil, errRideData
+		}
+		byRide := make(map[string][]*models.RideDataHistoryRecord, len(rideIDs))
+		for _, record := range records {
+			byRide[record.RideID] = append(byRide[record.RideID], record)
+		}
+		return byRide, nil
+	})
+	loader.Prime(l.known...)
+	l.history[windowHours] = loader
+	return loader
+}
+
+// clampWindow limits a windowHours argument to between 1 and MaxWindowHours
+func clampWindow(windowHours int32) int32 {
+	return int32(clampWindowHours(int(windowHours)))
+}
+
+// graphQLResolver resolves the Query type
+type graphQLResolver struct{}
+
+// Parks primes the loaders with the rides of every park up front. Sibling parks resolve
+// concurrently, so waiting for each park's rides field would split the history into several batches.
+func (q *graphQLResolver) Parks(ctx context.Context) []*parkResolver {
+	parks := make([]*parkResolver, 0, len(shared.ParkNames))
+	var rideIDs []string
+	for parkID, park := range shared.GetAllParkInfos() {
+		parks = append(parks, &parkResolver{id: parkID, name: park.Name})
+		for _, ride := range shared.GetFilteredRidesForPark(parkID) {
+			rideIDs = append(rideIDs, ride.ID)
+		}
+	}
+	loadersFrom(ctx).prime(rideIDs)
+	sort.Slice(parks, func(i, j int) bool { return parks[i].name < parks[j].name })
+	return parks
+}
+
+func (q *graphQLResolver) Park(ctx context.Context, args struct{ ID graphql.ID }) *parkResolver {
+	park, ok := shared.GetParkInfo(string(args.ID))
+	if !ok {
+		return nil
+	}
+	return &parkResolver{id: string(args.ID), name: park.Name}
+}
+
+func (q *graphQLResolver) Ride(ctx context.Context, args struct{ ID graphql.ID }) *rideResolver {
+	ride, parkID, ok := shared.FindFilteredRide(string(args.ID))
+	if !ok {
+		return nil
+	}
+	return newRideResolvers(ctx, []shared.FilteredRide{ride}, []string{parkID})[0]
+}
+
+func (q *graphQLResolver) Rides(ctx context.Context, args struct{ IDs *[]graphql.ID }) ([]*rideResolver, error) {
+	var rides []shared.FilteredRide
+	var parkIDs []string
+	if args.IDs == nil {
+		for parkID := range shared.GetAllParkInfos() {
+			for _, ride := range shared.GetFilteredRidesForPark(parkID) {
+				rides = append(rides, ride)
+				parkIDs = append(parkIDs, parkID)
+			}
+		}
+	} else {
+		for _, id := range *args.IDs {
+			ride, parkID, ok := shared.FindFilteredRide(string(id))
+			if !ok {
+				return nil, fmt.Errorf("unknown ride %q", id)
+			}
+			rides = append(rides, ride)
+			parkIDs = append(parkIDs, parkID)
+		}
+	}
+
+	resolvers := newRideResolvers(ctx, rides, parkIDs)
+	if args.IDs == nil {
+		sort.Slice(resolvers, func(i, j int) bool { return resolvers[i].ride.Name < resolvers[j].ride.Name })
+	}
+	return resolvers, nil
+}
+
+// parkResolver resolves a tracked park
+type parkResolver struct {
+	id   string
+	name string
+}
+
+func (p *parkResolver) ID() graphql.ID { return graphql.ID(p.id) }
+func (p *parkResolver) Name() string   { return p.name }
+
+func (p *parkResolver) Rides(ctx context.Context) []*rideResolver {
+	rides := shared.GetFilteredRidesForPark(p.id)
+	parkIDs := make([]string, len(rides))
+	for i := range parkIDs {
+		parkIDs[i] = p.id
+	}
+	resolvers := newRideResolvers(ctx, rides, parkIDs)
+	sort.Slice(resolvers, func(i, j int) bool { return resolvers[i].ride.Name < resolvers[j].ride.Name })
+	return resolvers
+}
+
+// rideResolver resolves a tracked ride
+type rideResolver struct {
+	ride   shared.FilteredRide
+	parkID string
+}
+
+// newRideResolvers builds resolvers for rides and primes the history loaders with them
+func newRideResolvers(ctx context.Context, rides []shared.FilteredRide, parkIDs []string) []*rideResolver {
+	resolvers := make([]*rideResolver, len(rides))
+	ids := make([]string, len(rides))
+	for i, ride := range rides {
+		resolvers[i] = &rideResolver{ride: ride, parkID: parkIDs[i]}
+		ids[i] = ride.ID
+	}
+	loadersFrom(ctx).prime(ids)
+	return resolvers
+}
+
+func (r *rideResolver) ID() graphql.ID { return graphql.ID(r.ride.ID) }
+func (r *rideResolver) Name() string   { return r.ride.Name }
+
+func (r *rideResolver) Park() *parkResolver {
+	park, _ := shared.GetParkInfo(r.parkID)
+	return &parkResolver{id: r.parkID, name: park.Name}
+}
+
+func (r *rideResolver) Live(ctx context.Context) (*liveStatusResolver, error) {
+	record, err := loadersFrom(ctx).live.Load(ctx, r.ride.ID)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return &liveStatusResolver{record: record}, nil
+}
+
+func (r *rideResolver) History(ctx context.Context, args struct {
+	WindowHours       int32
+	ResolutionMinutes *int32
+}) ([]*historyPointResolver, error) {
+	args.WindowHours = clampWindow(args.WindowHours)
+	if args.ResolutionMinutes != nil && *args.ResolutionMinutes < 1 {
+		return nil, errors.New("resolutionMinutes must be a positive integer")
+	}
+
+	records, err := loadersFrom(ctx).historyLoader(args.WindowHours).Load(ctx, r.ride.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []historyPoint
+	if args.ResolutionMinutes != nil {
+		points = downsampleHistory(records, time.Duration(*args.ResolutionMinutes)*time.Minute)
+	} else {
+		points = make([]historyPoint, 0, len(records))
+		for _, record := range records {
+			points = append(points, historyPoint{waitTime: record.StandbyWaitTime, status: record.Status, snapshotTime: record.LastUpdated})
+		}
+	}
+
+	resolvers := make([]*historyPointResolver, len(points))
+	for i := range points {
+		resolvers[i] = &historyPointResolver{point: points[i]}
+	}
+	return resolvers, nil
+}
+
+func (r *rideResolver) Downtime(ctx context.Context, args struct{ WindowHours int32 }) ([]*downtimeEventResolver, error) {
+	args.WindowHours = clampWindow(args.WindowHours)
+
+	loaders := loadersFrom(ctx)
+	records, err := loaders.historyLoader(args.WindowHours).Load(ctx, r.ride.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := downtimeEvents(records)
+	resolvers := make([]*downtimeEventResolver, len(events))
+	for i := range events {
+		resolvers[i] = &downtimeEventResolver{event: events[i], now: loaders.now}
+	}
+	return resolvers, nil
+}
+
+// liveStatusResolver resolves the latest record of a ride
+type liveStatusResolver struct {
+	record *models.RideDataHistoryRecord
+}
+
+func (l *liveStatusResolver) WaitTime() *int32 { return toInt32(l.record.StandbyWaitTime) }
+func (l *liveStatusResolver) Status() string   { return l.record.Status }
+func (l *liveStatusResolver) LastUpdated() graphql.Time {
+	return graphql.Time{Time: l.record.LastUpdated}
+}
+
+// historyPoint is one snapshot, or one bucket of snapshots when a resolution is requested
+type historyPoint struct {
+	waitTime     *int
+	status       string
+	snapshotTime time.Time
+}
+
+type historyPointResolver struct {
+	point historyPoint
+}
+
+func (h *historyPointResolver) WaitTime() *int32 { return toInt32(h.point.waitTime) }
+func (h *historyPointResolver) Status() string   { return h.point.status }
+func (h *historyPointResolver) SnapshotTime() graphql.Time {
+	return graphql.Time{Time: h.point.snapshotTime}
+}
+
+// downsampleHistory averages newest-first records into buckets of the given size. A bucket is
+// stamped with its start, takes the status of its newest snapshot, and has a null wait when none
+// of its snapshots had one.
+func downsampleHistory(records []*models.RideDataHistoryRecord, resolution time.Duration) []historyPoint {
+	points := make([]historyPoint, 0)
+	var sum, count int
+	flush := func() {
+		if count > 0 {
+			avg := int(math.Round(float64(sum) / float64(count)))
+			points[len(points)-1].waitTime = &avg
+		}
+		sum, count = 0, 0
+	}
+
+	for _, record := range records {
+		bucket := record.LastUpdated.Truncate(resolution)
+		if len(points) == 0 || !points[len(points)-1].snapshotTime.Equal(bucket) {
+			if len(points) > 0 {
+				flush()
+			}
+			points = append(points, historyPoint{status: record.Status, snapshotTime: bucket})
+		}
+		if record.StandbyWaitTime != nil {
+			sum += *record.StandbyWaitTime
+			count++
+		}
+	}
+	if len(points) > 0 {
+		flush()
+	}
+	return points
+}
+
+// downtimeEvent is a run of consecutive DOWN snapshots; end is nil while the ride is still down
+type downtimeEvent struct {
+	start time.Time
+	end   *time.Time
+}
+
+// downtimeEvents finds the periods a ride was DOWN in newest-first records. An event ends at the
+// first snapshot with another status. Events are returned newest first.
+func downtimeEvents(records []*models.RideDataHistoryRecord) []downtimeEvent {
+	events := make([]downtimeEvent, 0)
+	var current *downtimeEvent
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		switch {
+		case record.Status == "DOWN" && current == nil:
+			current = &downtimeEvent{start: record.LastUpdated}
+		case record.Status != "DOWN" && current != nil:
+			end := record.LastUpdated
+			current.end = &end
+			events = append(events, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		events = append(events, *current)
+	}
+
+	slices.Reverse(events)
+	return events
+}
+
+// downtimeEventResolver resolves a downtime event; an ongoing one is measured up to the request time
+type downtimeEventResolver struct {
+	event downtimeEvent
+	now   time.Time
+}
+
+func (d *downtimeEventResolver) Start() graphql.Time { return graphql.Time{Time: d.event.start} }
+func (d *downtimeEventResolver) Ongoing() bool       { return d.event.end == nil }
+
+func (d *downtimeEventResolver) End() *graphql.Time {
+	if d.event.end == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *d.event.end}
+}
+
+func (d *downtimeEventResolver) DurationMinutes() int32 {
+	end := d.now
+	if d.event.end != nil {
+		end = *d.event.end
+	}
+	return int32(end.Sub(d.event.start).Round(time.Minute) / time.Minute)
+}
+
+// toInt32 converts a nullable wait time to a GraphQL Int
+func toInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
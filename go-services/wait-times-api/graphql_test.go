@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-services/shared/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// graphQLResult is the decoded body of a /graphql response
+type graphQLResult struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func queryGraphQL(t *testing.T, store rideDataStore, query string) (*httptest.ResponseRecorder, graphQLResult) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	newRouter(store, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+
+	var result graphQLResult
+	if w.Code == http.StatusOK {
+		decode(t, w, &result)
+	}
+	return w, result
+}
+
+func TestGraphQL_RideLiveAndHistory(t *testing.T) {
+	now := time.Now().UTC()
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{
+		testRecord(2, 45, now.Add(-time.Minute)),
+		testRecord(1, 30, now.Add(-2*time.Hour)),
+	}}
+
+	w, result := queryGraphQL(t, store, `{
+		ride(id: "`+testRideID+`") {
+			name
+			park { name }
+			live { waitTime status }
+			history(windowHours: 1) { waitTime }
+		}
+	}`)
+	if w.Code != http.StatusOK || len(result.Errors) > 0 {
+		t.Fatalf("Query failed with %d: %s", w.Code, w.Body.String())
+	}
+
+	ride := result.Data["ride"].(map[string]any)
+	if ride["name"] != "Space Mountain" || ride["park"].(map[string]any)["name"] != "Disneyland Park" {
+		t.Errorf("Unexpected ride %v", ride)
+	}
+	if live := ride["live"].(map[string]any); live["waitTime"] != float64(45) || live["status"] != "OPERATING" {
+		t.Errorf("Unexpected live status %v", live)
+	}
+	if history := ride["history"].([]any); len(history) != 1 {
+		t.Errorf("Expected only the snapshot inside the 1 hour window, got %v", history)
+	}
+
+	_, result = queryGraphQL(t, store, `{ ride(id: "nope") { name } }`)
+	if result.Data["ride"] != nil {
+		t.Errorf("Expected null for an unknown ride, got %v", result.Data["ride"])
+	}
+}
+
+func TestGraphQL_BatchesNestedHistory(t *testing.T) {
+	now := time.Now().UTC()
+	other := testRecord(3, 20, now)
+	other.RideID = otherParkRideID
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, now), other}}
+
+	w, result := queryGraphQL(t, store, `{
+		parks { rides { id live { waitTime } history { waitTime } downtime { start } } }
+	}`)
+	if w.Code != http.StatusOK || len(result.Errors) > 0 {
+		t.Fatalf("Query failed with %d: %s", w.Code, w.Body.String())
+	}
+	if store.queries != 1 {
+		t.Errorf("Expected one history query for every ride, got %d", store.queries)
+	}
+
+	withHistory := 0
+	for _, park := range result.Data["parks"].([]any) {
+		for _, ride := range park.(map[string]any)["rides"].([]any) {
+			if len(ride.(map[string]any)["history"].([]any)) > 0 {
+				withHistory++
+			}
+		}
+	}
+	if withHistory != 2 {
+		t.Errorf("Expected history for both recorded rides, got %d", withHistory)
+	}
+}
+
+func TestGraphQL_ClampsWindow(t *testing.T) {
+	now := time.Now().UTC()
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{
+		testRecord(2, 45, now.Add(-time.Minute)),
+		testRecord(1, 30, now.Add(-2*time.Hour)),
+	}}
+
+	w, result := queryGraphQL(t, store, `{ ride(id: "`+testRideID+`") { history(windowHours: 0) { waitTime } } }`)
+	if w.Code != http.StatusOK || len(result.Errors) > 0 {
+		t.Fatalf("Query failed with %d: %s", w.Code, w.Body.String())
+	}
+	if history := result.Data["ride"].(map[string]any)["history"].([]any); len(history) != 1 {
+		t.Errorf("Expected the window clamped to 1 hour, got %v", history)
+	}
+}
+
+func TestGraphQL_InvalidArguments(t *testing.T) {
+	for _, query := range []string{
+		`{ ride(id: "` + testRideID + `") { history(resolutionMinutes: 0) { waitTime } } }`,
+		`{ rides(ids: ["nope"]) { name } }`,
+	} {
+		w, result := queryGraphQL(t, &fakeStore{}, query)
+		if w.Code != http.StatusOK || len(result.Errors) == 0 {
+			t.Errorf("Expected a GraphQL error for %s, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGraphQL_StoreError(t *testing.T) {
+	_, result := queryGraphQL(t, &fakeStore{err: errors.New("database unavailable")},
+		`{ ride(id: "`+testRideID+`") { history { waitTime } } }`)
+	if len(result.Errors) != 1 || result.Errors[0].Message != errRideData.Error() {
+		t.Errorf("Expected the store error to be masked, got %v", result.Errors)
+	}
+}
+
+func TestGraphQL_RejectsOtherMethods(t *testing.T) {
+	w := serve(t, &fakeStore{}, "GET", "/graphql")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("Expected 405 with Allow: POST, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestGraphQL_ResponseMatchesSpec(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, time.Now())}}
+	w, _ := queryGraphQL(t, store, `{ parks { id name rides { name live { lastUpdated } } } }`)
+	validateAgainstSpec(t, doc, "POST", "/graphql", w)
+}
+
+func TestDownsampleHistory(t *testing.T) {
+	base := time.Date(2025, 9, 14, 12, 0, 0, 0, time.UTC)
+	closed := testRecord(4, 0, base.Add(5*time.Minute))
+	closed.StandbyWaitTime = nil
+	closed.Status = "CLOSED"
+	records := []*models.RideDataHistoryRecord{
+		testRecord(3, 50, base.Add(20*time.Minute)),
+		testRecord(2, 41, base.Add(17*time.Minute)),
+		closed,
+		testRecord(1, 30, base.Add(2*time.Minute)),
+	}
+
+	points := downsampleHistory(records, 15*time.Minute)
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 buckets, got %+v", points)
+	}
+	if !points[0].snapshotTime.Equal(base.Add(15*time.Minute)) || *points[0].waitTime != 46 || points[0].status != "OPERATING" {
+		t.Errorf("Unexpected newest bucket %+v", points[0])
+	}
+	if !points[1].snapshotTime.Equal(base) || *points[1].waitTime != 30 || points[1].status != "CLOSED" {
+		t.Errorf("Expected the closed snapshot to set the status but not the average, got %+v", points[1])
+	}
+}
+
+func TestDowntimeEvents(t *testing.T) {
+	base := time.Date(2025, 9, 14, 12, 0, 0, 0, time.UTC)
+	at := func(id int64, status string, minutes int) *models.RideDataHistoryRecord {
+		record := testRecord(id, 0, base.Add(time.Duration(minutes)*time.Minute))
+		record.Status = status
+		return record
+	}
+	// Newest first, as the store returns them
+	records := []*models.RideDataHistoryRecord{
+		at(6, "DOWN", 50),
+		at(5, "OPERATING", 40),
+		at(4, "DOWN", 30),
+		at(3, "DOWN", 20),
+		at(2, "OPERATING", 10),
+	}
+
+	events := downtimeEvents(records)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", events)
+	}
+	if events[0].end != nil || !events[0].start.Equal(base.Add(50*time.Minute)) {
+		t.Errorf("Expected the newest event to be ongoing, got %+v", events[0])
+	}
+	if events[1].end == nil || !events[1].end.Equal(base.Add(40*time.Minute)) || !events[1].start.Equal(base.Add(20*time.Minute)) {
+		t.Errorf("Unexpected finished event %+v", events[1])
+	}
+
+	ongoing := downtimeEventResolver{event: events[0], now: base.Add(65 * time.Minute)}
+	finished := downtimeEventResolver{event: events[1], now: base.Add(65 * time.Minute)}
+	if ongoing.DurationMinutes() != 15 || !ongoing.Ongoing() || ongoing.End() != nil {
+		t.Errorf("Expected an ongoing 15 minute event, got %d", ongoing.DurationMinutes())
+	}
+	if finished.DurationMinutes() != 20 || finished.Ongoing() {
+		t.Errorf("Expected a finished 20 minute event, got %d", finished.DurationMinutes())
+	}
+}
+
+func TestBatchLoader_DoesNotCacheErrors(t *testing.T) {
+	calls := 0
+	loader := newBatchLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("boom")
+		}
+		values := make(map[string]int)
+		for _, key := range keys {
+			values[key] = len(key)
+		}
+		return values, nil
+	})
+	loader.Prime("a", "bb")
+
+	if _, err := loader.Load(context.Background(), "a"); err == nil {
+		t.Fatal("Expected the first load to fail")
+	}
+	if v, err := loader.Load(context.Background(), "bb"); err != nil || v != 2 {
+		t.Fatalf("Expected 2, got %d (%v)", v, err)
+	}
+	if v, _ := loader.Load(context.Background(), "a"); v != 1 || loader.Batches() != 1 {
+		t.Errorf("Expected a to come from the retried batch, got %d after %d batches", v, loader.Batches())
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/ratelimit"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"go-services/shared/service"
+	"sort"
+	"time"
+
+	waittimesv1 "go-services/proto/waittimes/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// waitTimesGRPCServer implements waittimesv1.WaitTimesServiceServer on the same store and change hub
+// as the HTTP handlers
+type waitTimesGRPCServer struct {
+	waittimesv1.UnimplementedWaitTimesServiceServer
+	repo rideDataStore
+	hub  *realtime.Hub
+}
+
+// newGRPCServer creates a gRPC server with the wait times service and server reflection registered.
+// Calls are rate limited by limiter like the HTTP data endpoints; a nil limiter disables rate limiting.
+func newGRPCServer(repo rideDataStore, hub *realtime.Hub, limiter *ratelimit.Limiter) *grpc.Server {
+	var opts []grpc.ServerOption
+	if limiter != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+	}
+	server := grpc.NewServer(opts...)
+	waittimesv1.RegisterWaitTimesServiceServer(server, &waitTimesGRPCServer{repo: repo, hub: hub})
+	reflection.Register(server)
+	return server
+}
+
+// optionalInt32 converts a nullable wait time to a proto3 optional field
+func optionalInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
+
+// forecastPoints decodes the stored forecast JSON, returning nil when it is absent or malformed
+func forecastPoints(forecast string) []*waittimesv1.ForecastPoint {
+	var entries []models.ForecastEntry
+	if forecast == "" || json.Unmarshal([]byte(forecast), &entries) != nil {
+		return nil
+	}
+	points := make([]*waittimesv1.ForecastPoint, 0, len(entries))
+	for _, entry := range entries {
+		points = append(points, &waittimesv1.ForecastPoint{
+			Time:       timestamppb.New(entry.Time),
+			WaitTime:   int32(entry.WaitTime),
+			Percentage: entry.Percentage,
+		})
+	}
+	return points
+}
+
+// selectionError maps an invalid ride or park selection to a NotFound status
+func selectionError(err error) error {
+	return status.Error(codes.NotFound, err.Error())
+}
+
+func (s *waitTimesGRPCServer) ListParks(ctx context.Context, req *waittimesv1.ListParksRequest) (*waittimesv1.ListParksResponse, error) {
+	resp := &waittimesv1.ListParksResponse{}
+	for parkID, park := range shared.GetAllParkInfos() {
+		p := &waittimesv1.Park{ParkId: parkID, ParkName: park.Name}
+		for _, ride := range shared.GetFilteredRidesForPark(parkID) {
+			p.Rides = append(p.Rides, &waittimesv1.Ride{RideId: ride.ID, RideName: ride.Name, ParkId: parkID})
+		}
+		sort.Slice(p.Rides, func(i, j int) bool { return p.Rides[i].RideName < p.Rides[j].RideName })
+		resp.Parks = append(resp.Parks, p)
+	}
+	sort.Slice(resp.Parks, func(i, j int) bool { return resp.Parks[i].ParkName < resp.Parks[j].ParkName })
+	return resp, nil
+}
+
+func (s *waitTimesGRPCServer) GetLive(ctx context.Context, req *waittimesv1.GetLiveRequest) (*waittimesv1.GetLiveResponse, error) {
+	selection, err := newRideSubscription(req.GetRideIds(), req.GetParkIds())
+	if err != nil {
+		return nil, selectionError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+	latest, err := s.repo.GetLatestRideDataForAllRides(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get latest ride data", "error", err)
+		return nil, status.Error(codes.Internal, "failed to retrieve ride data")
+	}
+
+	resp := &waittimesv1.GetLiveResponse{}
+	for _, record := range latest {
+		if !selection.matches(record.ParkID, record.RideID) {
+			continue
+		}
+		resp.Rides = append(resp.Rides, &waittimesv1.LiveStatus{
+			RideId:      record.RideID,
+			RideName:    record.Name,
+			ParkId:      record.ParkID,
+			WaitTime:    optionalInt32(record.StandbyWaitTime),
+			Status:      record.Status,
+			LastUpdated: timestamppb.New(record.LastUpdated),
+			Forecast:    forecastPoints(record.Forecast),
+		})
+	}
+	sort.Slice(resp.Rides, func(i, j int) bool { return resp.Rides[i].RideName < resp.Rides[j].RideName })
+	return resp, nil
+}
+
+func (s *waitTimesGRPCServer) GetHistory(ctx context.Context, req *waittimesv1.GetHistoryRequest) (*waittimesv1.GetHistoryResponse, error) {
+	ride, _, ok := shared.FindFilteredRide(req.GetRideId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown ride %q", req.GetRideId())
+	}
+
+	window := DataHours
+	if hours := req.GetWindowHours(); hours > 0 {
+		window = time.Duration(min(hours, uint32(MaxWindowHours))) * time.Hour
+	}
+
+	var after *repository.HistoryCursor
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := repository.DecodeHistoryCursor(token)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		after = &cursor
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+	filter := repository.HistoryFilter{RideIDs: []string{ride.ID}, Since: time.Now().Add(-window)}
+	page, err := s.repo.PageRideDataHistory(ctx, filter, after, int(req.GetPageSize()))
+	if err != nil {
+		logger.ErrorContext(service.WithRide(ctx, ride.ID), "Failed to get ride data history", "error", err)
+		return nil, status.Error(codes.Internal, "failed to retrieve ride data")
+	}
+
+	resp := &waittimesv1.GetHistoryResponse{RideId: ride.ID}
+	for _, record := range page.Records {
+		resp.History = append(resp.History, &waittimesv1.HistoryPoint{
+			WaitTime:     optionalInt32(record.StandbyWaitTime),
+			Status:       record.Status,
+			SnapshotTime: timestamppb.New(record.LastUpdated),
+		})
+	}
+	if page.Next != nil {
+		resp.NextPageToken = page.Next.Encode()
+	}
+	return resp, nil
+}
+
+// StreamLive sends changes from the hub until the client cancels. A client that falls behind, or a
+// server that shuts down, ends the stream with Unavailable; clients resume with after_id.
+func (s *waitTimesGRPCServer) StreamLive(req *waittimesv1.StreamLiveRequest, stream grpc.ServerStreamingServer[waittimesv1.LiveUpdate]) error {
+	selection, err := newRideSubscription(req.GetRideIds(), req.GetParkIds())
+	if err != nil {
+		return selectionError(err)
+	}
+
+	sub, replay := s.hub.Subscribe(req.GetAfterId(), StreamBufferSize, func(change repository.RideDataChange) bool {
+		return selection.matches(change.ParkID, change.RideID)
+	})
+	defer sub.Close()
+
+	for _, event := range replay {
+		if err := stream.Send(liveUpdate(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "stream ended, resume with after_id")
+			}
+			if err := stream.Send(liveUpdate(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// liveUpdate converts a hub event into a stream message
+func liveUpdate(event realtime.Event) *waittimesv1.LiveUpdate {
+	change := event.Change
+	return &waittimesv1.LiveUpdate{
+		Id: event.ID,
+		Status: &waittimesv1.LiveStatus{
+			RideId:      change.RideID,
+			RideName:    change.Name,
+			ParkId:      change.ParkID,
+			WaitTime:    optionalInt32(change.WaitTime),
+			Status:      change.Status,
+			LastUpdated: timestamppb.New(change.LastUpdated),
+		},
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"go-services/shared/models"
+	"go-services/shared/ratelimit"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	waittimesv1 "go-services/proto/waittimes/v1"
+
+	"github.com/coder/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPC serves the wait times service over an in-memory listener and returns a connected client
+func startGRPC(t *testing.T, store rideDataStore, hub *realtime.Hub, limiter *ratelimit.Limiter) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(store, hub, limiter)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func grpcContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestGRPC_SharesHTTPPort(t *testing.T) {
+	root, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { root.Close() })
+	httpListener, grpcListener := splitByProtocol(root)
+
+	hub := realtime.NewHub(1)
+	httpServer := &http.Server{Handler: newRouter(&fakeStore{}, hub, nil, nil, nil, nil)}
+	go httpServer.Serve(httpListener)
+	t.Cleanup(func() { httpServer.Close() })
+	grpcServer := newGRPCServer(&fakeStore{}, hub, nil)
+	go grpcServer.Serve(grpcListener)
+	t.Cleanup(grpcServer.Stop)
+
+	resp, err := http.Get("http://" + root.Addr().String() + "/v1/parks")
+	if err != nil {
+		t.Fatalf("HTTP request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 over HTTP/1, got %d", resp.StatusCode)
+	}
+
+	ws, _, err := websocket.Dial(grpcContext(t), "ws://"+root.Addr().String()+"/v1/ws", nil)
+	if err != nil {
+		t.Fatalf("WebSocket upgrade failed: %v", err)
+	}
+	ws.CloseNow()
+
+	conn, err := grpc.NewClient(root.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	parks, err := waittimesv1.NewWaitTimesServiceClient(conn).ListParks(grpcContext(t), &waittimesv1.ListParksRequest{})
+	if err != nil || len(parks.Parks) == 0 {
+		t.Fatalf("Expected gRPC on the same port, got %v (%v)", parks, err)
+	}
+}
+
+func TestGRPC_ListParks(t *testing.T) {
+	client := waittimesv1.NewWaitTimesServiceClient(startGRPC(t, &fakeStore{}, realtime.NewHub(1), nil))
+
+	resp, err := client.ListParks(grpcContext(t), &waittimesv1.ListParksRequest{})
+	if err != nil {
+		t.Fatalf("ListParks failed: %v", err)
+	}
+	if len(resp.Parks) != 2 || resp.Parks[1].ParkName != "Disneyland Park" || len(resp.Parks[1].Rides) != 11 {
+		t.Errorf("Unexpected parks: %v", resp.Parks)
+	}
+}
+
+func TestGRPC_GetLive(t *testing.T) {
+	now := time.Now().UTC()
+	record := testRecord(1, 30, now)
+	record.Forecast = `[{"percentage":0.5,"waitTime":40,"time":"2025-09-14T19:00:00Z"}]`
+	closed := testRecord(2, 0, now)
+	closed.RideID = "0de1413a-73ee-46cf-af2e-c491cc7c7d3b"
+	closed.Name = "Big Thunder Mountain Railroad"
+	closed.StandbyWaitTime = nil
+	client := waittimesv1.NewWaitTimesServiceClient(startGRPC(t, &fakeStore{records: []*models.RideDataHistoryRecord{record, closed}}, realtime.NewHub(1), nil))
+
+	resp, err := client.GetLive(grpcContext(t), &waittimesv1.GetLiveRequest{RideIds: []string{testRideID}})
+	if err != nil {
+		t.Fatalf("GetLive failed: %v", err)
+	}
+	if len(resp.Rides) != 1 {
+		t.Fatalf("Expected one ride, got %v", resp.Rides)
+	}
+	live := resp.Rides[0]
+	if live.WaitTime == nil || *live.WaitTime != 30 || !live.LastUpdated.AsTime().Equal(now) {
+		t.Errorf("Unexpected live status %v", live)
+	}
+	if len(live.Forecast) != 1 || live.Forecast[0].WaitTime != 40 {
+		t.Errorf("Expected the decoded forecast, got %v", live.Forecast)
+	}
+
+	resp, err = client.GetLive(grpcContext(t), &waittimesv1.GetLiveRequest{ParkIds: []string{testParkID}})
+	if err != nil {
+		t.Fatalf("GetLive failed: %v", err)
+	}
+	if len(resp.Rides) != 2 || resp.Rides[0].WaitTime != nil {
+		t.Errorf("Expected both rides with an unset wait for the closed one, got %v", resp.Rides)
+	}
+
+	_, err = client.GetLive(grpcContext(t), &waittimesv1.GetLiveRequest{RideIds: []string{"nope"}})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unknown ride, got %v", err)
+	}
+}
+
+func TestGRPC_GetHistory(t *testing.T) {
+	now := time.Now().UTC()
+	next := &repository.HistoryCursor{LastUpdated: now.Add(-time.Hour), ID: 1}
+	store := &fakeStore{
+		records: []*models.RideDataHistoryRecord{testRecord(2, 45, now), testRecord(1, 30, now.Add(-time.Hour))},
+		next:    next,
+	}
+	client := waittimesv1.NewWaitTimesServiceClient(startGRPC(t, store, realtime.NewHub(1), nil))
+
+	resp, err := client.GetHistory(grpcContext(t), &waittimesv1.GetHistoryRequest{RideId: testRideID, WindowHours: 3, PageSize: 2})
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(resp.History) != 2 || *resp.History[0].WaitTime != 45 || resp.NextPageToken != next.Encode() {
+		t.Errorf("Unexpected history response %v", resp)
+	}
+	if since := time.Since(store.lastFilter.Since); since < 3*time.Hour || since > 3*time.Hour+time.Minute {
+		t.Errorf("Expected a 3 hour window, got %v", since)
+	}
+
+	if _, err := client.GetHistory(grpcContext(t), &waittimesv1.GetHistoryRequest{RideId: testRideID, PageToken: resp.NextPageToken}); err != nil {
+		t.Fatalf("GetHistory with page token failed: %v", err)
+	}
+	if store.lastAfter == nil || store.lastAfter.ID != next.ID {
+		t.Errorf("Expected the page token to be forwarded, got %v", store.lastAfter)
+	}
+
+	_, err = client.GetHistory(grpcContext(t), &waittimesv1.GetHistoryRequest{RideId: testRideID, PageToken: "bogus"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a bad token, got %v", err)
+	}
+	_, err = client.GetHistory(grpcContext(t), &waittimesv1.GetHistoryRequest{RideId: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unknown ride, got %v", err)
+	}
+}
+
+func TestGRPC_StreamLive(t *testing.T) {
+	hub := realtime.NewHub(10)
+	hub.Publish(repository.RideDataChange{ID: 1, RideID: testRideID, ParkID: testParkID})
+	afterID := hub.LastID()
+	hub.Publish(repository.RideDataChange{ID: 2, RideID: testRideID, ParkID: testParkID})
+	client := waittimesv1.NewWaitTimesServiceClient(startGRPC(t, &fakeStore{}, hub, nil))
+
+	stream, err := client.StreamLive(grpcContext(t), &waittimesv1.StreamLiveRequest{RideIds: []string{testRideID}, AfterId: afterID})
+	if err != nil {
+		t.Fatalf("StreamLive failed: %v", err)
+	}
+	update, err := stream.Recv()
+	if err != nil || update.Id != hub.LastID() {
+		t.Fatalf("Expected the replayed second update, got %v (%v)", update, err)
+	}
+
+	waitForSubscribers(t, hub, 1)
+	wait := 15
+	hub.Publish(repository.RideDataChange{ID: 3, RideID: otherParkRideID, ParkID: "832fcd51-ea19-4e77-85c7-75d5843b127c"})
+	hub.Publish(repository.RideDataChange{ID: 4, RideID: testRideID, ParkID: testParkID, WaitTime: &wait})
+	update, err = stream.Recv()
+	if err != nil || update.Id != hub.LastID() || update.Status.GetWaitTime() != 15 {
+		t.Fatalf("Expected the update of the selected ride, got %v (%v)", update, err)
+	}
+
+	hub.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable once the hub closes, got %v", err)
+	}
+
+	stream, err = client.StreamLive(grpcContext(t), &waittimesv1.StreamLiveRequest{ParkIds: []string{"nope"}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unknown park, got %v", err)
+	}
+}
+
+func TestGRPC_RateLimit(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{Anonymous: ratelimit.Tier{RequestsPerMinute: 1, Burst: 1}, Keys: fakeKeys{}, KeyCacheTTL: time.Minute})
+	client := waittimesv1.NewWaitTimesServiceClient(startGRPC(t, &fakeStore{}, realtime.NewHub(1), limiter))
+
+	var header metadata.MD
+	if _, err := client.ListParks(grpcContext(t), &waittimesv1.ListParksRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatalf("ListParks failed: %v", err)
+	}
+	if got := header.Get("ratelimit-remaining"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("Expected RateLimit metadata, got %v", header)
+	}
+
+	_, err := client.ListParks(grpcContext(t), &waittimesv1.ListParksRequest{}, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if len(header.Get("retry-after")) != 1 {
+		t.Errorf("Expected retry-after metadata, got %v", header)
+	}
+
+	keyed := metadata.AppendToOutgoingContext(grpcContext(t), "x-api-key", testAPIKey)
+	if _, err := client.ListParks(keyed, &waittimesv1.ListParksRequest{}); err != nil {
+		t.Errorf("Expected a keyed call to use its own bucket, got %v", err)
+	}
+	unknown := metadata.AppendToOutgoingContext(grpcContext(t), "x-api-key", "wtk_unknown")
+	if _, err := client.ListParks(unknown, &waittimesv1.ListParksRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for an unknown key, got %v", err)
+	}
+
+	stream, err := client.StreamLive(grpcContext(t), &waittimesv1.StreamLiveRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected streams to share the anonymous limit, got %v", err)
+	}
+}
+
+func TestGRPC_Reflection(t *testing.T) {
+	client := reflectionpb.NewServerReflectionClient(startGRPC(t, &fakeStore{}, realtime.NewHub(1), nil))
+
+	stream, err := client.ServerReflectionInfo(grpcContext(t))
+	if err != nil {
+		t.Fatalf("Failed to open reflection stream: %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Failed to send reflection request: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive reflection response: %v", err)
+	}
+
+	found := false
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		if service.GetName() == waittimesv1.WaitTimesService_ServiceDesc.ServiceName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected %s to be listed, got %v", waittimesv1.WaitTimesService_ServiceDesc.ServiceName, resp)
+	}
+}
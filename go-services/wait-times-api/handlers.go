@@ -3,106 +3,124 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"go-services/shared"
 	"go-services/shared/models"
 	"go-services/shared/repository"
 	"go-services/shared/response"
-	"log"
+	"go-services/shared/service"
+	"io"
 	"net/http"
 	"sort"
 	"time"
 )
 
-// waitTimesHandler handles the /wait-times endpoint
-func waitTimesHandler(repo *repository.RideDataHistoryRepository) http.HandlerFunc {
+// waitTimesHandler handles the /wait-times endpoint. It predates the /v1 resource routes and is kept
+// as a compatibility shim that returns live, atlas and history data together.
+func waitTimesHandler(repo rideDataStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Always set CORS headers so preflight works from browsers
-		origin := r.Header.Get("Origin")
-		if AllowedOrigins[origin] {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Handle preflight
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Allow both GET and POST
+		// Allow both GET and POST; preflights are answered by the CORS middleware
 		if r.Method != http.MethodGet && r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.WriteProblem(w, r, response.MethodNotAllowed(http.MethodGet, http.MethodPost, http.MethodOptions))
 			return
 		}
 
 		// Determine optional ride_id — prefer query param, fallback to JSON body (only for POST)
 		rideID := r.URL.Query().Get("ride_id")
 		if rideID == "" && r.Method == http.MethodPost {
-			// try JSON body (it's optional; ignore EOF / empty body)
+			// the body is optional, but when present it must be a JSON object
 			var body struct {
 				RideID string `json:"ride_id"`
 			}
-			if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
-				rideID = body.RideID
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+				response.WriteProblem(w, r, response.BadRequest("Request body must be a JSON object"))
+				return
 			}
+			rideID = body.RideID
 		}
 
-		// Support window_hours query parameter to limit history data size
-		windowHoursStr := r.URL.Query().Get("window_hours")
-		windowHours := 0
-		if windowHoursStr != "" {
-			fmt.Sscanf(windowHoursStr, "%d", &windowHours)
+		// window_hours limits the history data size; ride_id must name a tracked ride
+		params := newQueryParams(r)
+		window, hasWindow := params.window("window_hours", 0)
+		pageSize, after, paginated := params.pagination()
+		if err := params.err(); err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		params.setHeaders(w.Header())
+		if rideID != "" {
+			if _, _, err := lookupRide(rideID); err != nil {
+				response.WriteProblem(w, r, err)
+				return
+			}
 		}
 
 		// Default history window logic:
 		// 1. If window_hours is provided, use it.
 		// 2. If ride_id is provided, default to 24 hours (full detail for one ride).
 		// 3. If no ride_id and no window_hours, default to 4 hours (small payload for overview).
+		// 4. When paginating without window_hours, the whole archive can be walked.
 		var historyWindow time.Duration
-		if windowHours > 0 {
-			historyWindow = time.Duration(windowHours) * time.Hour
+		if hasWindow {
+			historyWindow = window
+		} else if paginated {
+			historyWindow = 0
 		} else if rideID != "" {
 			historyWindow = DataHours // 24 hours
 		} else {
 			historyWindow = 4 * time.Hour // Reduced default for overview
 		}
 
-		log.Printf("Processing wait times request (ride_id=%s, window=%v)", rideID, historyWindow)
+		logCtx := r.Context()
+		if rideID != "" {
+			logCtx = service.WithRide(logCtx, rideID)
+		}
+		logger.DebugContext(logCtx, "Processing wait times request", "window", historyWindow.String())
 
 		// Query for latest entries for all rides (always unfiltered)
-		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
 		defer cancel()
 
 		latestRideData, err := repo.GetLatestRideDataForAllRides(ctx)
 		if err != nil {
-			log.Printf("Failed to get latest ride data: %v", err)
-			http.Error(w, "Failed to retrieve ride data", http.StatusInternalServerError)
+			response.WriteProblem(w, r, response.Internal("Failed to retrieve ride data", err))
 			return
 		}
 
-		log.Printf("Retrieved %d latest ride data records for all rides", len(latestRideData))
+		logger.DebugContext(logCtx, "Retrieved latest ride data", "records", len(latestRideData))
 
 		// Query the ride_data_history table for data within the determined window
 		since := time.Now().Add(-historyWindow)
-		ctx2, cancel2 := context.WithTimeout(context.Background(), RequestTimeout)
+		ctx2, cancel2 := context.WithTimeout(r.Context(), RequestTimeout)
 		defer cancel2()
 
 		var rideDataHistory []*models.RideDataHistoryRecord
-		if rideID != "" {
+		var nextCursor string
+		if paginated {
+			filter := repository.HistoryFilter{}
+			if historyWindow > 0 {
+				filter.Since = since
+			}
+			if rideID != "" {
+				filter.RideIDs = []string{rideID}
+			}
+			var page repository.HistoryPage
+			page, err = repo.PageRideDataHistory(ctx2, filter, after, pageSize)
+			rideDataHistory = page.Records
+			if page.Next != nil {
+				nextCursor = page.Next.Encode()
+			}
+		} else if rideID != "" {
 			rideDataHistory, err = repo.GetRideDataHistorySinceForRide(ctx2, since, rideID)
 		} else {
 			rideDataHistory, err = repo.GetRideDataHistorySince(ctx2, since)
 		}
-		
+
 		if err != nil {
-			log.Printf("Failed to get ride data history: %v", err)
-			http.Error(w, "Failed to retrieve ride data", http.StatusInternalServerError)
+			response.WriteProblem(w, r, response.Internal("Failed to retrieve ride data", err))
 			return
 		}
 
-		log.Printf("Retrieved %d ride data history records from the past window", len(rideDataHistory))
+		logger.DebugContext(logCtx, "Retrieved ride data history", "records", len(rideDataHistory))
 
 		// Process filtered records to build history
 		groupedRidesHistory := make(map[string][]RideHistoryEntry)
@@ -130,7 +148,7 @@ func waitTimesHandler(repo *repository.RideDataHistoryRepository) http.HandlerFu
 		}
 
 		// Build liveWaitTime list from the latest entries for filtered rides only
-		var liveWaitTime []LiveWaitTimeEntry
+		liveWaitTime := make([]LiveWaitTimeEntry, 0)
 		for _, record := range latestRideData {
 			// Only include rides that are in our filtered list
 			if shared.IsRideFiltered(record.ParkID, record.RideID) {
@@ -187,12 +205,12 @@ func waitTimesHandler(repo *repository.RideDataHistoryRepository) http.HandlerFu
 			LiveWaitTime:        liveWaitTime,
 			AttractionAtlas:     attractionAtlas,
 			GroupedRidesHistory: groupedRidesHistory,
+			NextCursor:          nextCursor,
 		}
 
-		// Use shared response utility to handle JSON encoding, caching, and compression
-		if err := response.WriteJSONWithDefaults(w, r, waitTimesResponse); err != nil {
-			log.Printf("Failed to write response: %v", err)
-			response.WriteError(w, http.StatusInternalServerError, "Failed to encode response")
+		// Stream the negotiated representation; Write answers encoding failures itself
+		if err := response.Write(w, r, waitTimesResponse, &response.DefaultOptions); err != nil {
+			logger.WarnContext(logCtx, "Failed to write wait times response", "error", err)
 			return
 		}
 
@@ -201,7 +219,7 @@ func waitTimesHandler(repo *repository.RideDataHistoryRepository) http.HandlerFu
 		for _, history := range groupedRidesHistory {
 			totalHistory += len(history)
 		}
-		log.Printf("Successfully processed wait times request (live=%d, history=%d)", len(liveWaitTime), totalHistory)
+		logger.InfoContext(logCtx, "Processed wait times request", "live", len(liveWaitTime), "history", totalHistory)
 	}
 }
 
@@ -213,22 +231,44 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := response.WriteJSONWithDefaults(w, r, healthResponse); err != nil {
-		log.Printf("Failed to write health response: %v", err)
-		response.WriteError(w, http.StatusInternalServerError, "Failed to encode health response")
+		response.WriteProblem(w, r, response.Internal("Failed to encode health response", err))
 	}
 }
 
 // rootHandler handles the root endpoint for basic service info
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	serviceInfo := ServiceInfo{
-		Service:   ServiceName,
-		Version:   ServiceVersion,
-		Endpoints: []string{"/health", "/wait-times"},
-		Status:    "running",
+		Service: ServiceName,
+		Version: ServiceVersion,
+		Endpoints: []string{
+			"/health",
+			"/livez",
+			"/readyz",
+			"/openapi.json",
+			"/metrics",
+			"/wait-times",
+			"/graphql",
+			"/v1/parks",
+			"/v1/parks/{id}/rides",
+			"/v1/rides/{id}",
+			"/v1/rides/{id}/history",
+			"/v1/rides/{id}/live",
+			"/v1/export",
+			"/v1/stream",
+			"/v1/ws",
+			"/v1/alerts",
+			"/v1/alerts/push-key",
+			"/v1/alerts/{id}",
+			"/v1/alerts/{id}/deliveries",
+			"/v1/webhooks",
+			"/v1/webhooks/{id}",
+			"/v1/webhooks/{id}/dead-letters",
+			"/v1/webhooks/{id}/dead-letters/{letterId}/redeliver",
+		},
+		Status: "running",
 	}
 
 	if err := response.WriteJSONWithDefaults(w, r, serviceInfo); err != nil {
-		log.Printf("Failed to write root response: %v", err)
-		response.WriteError(w, http.StatusInternalServerError, "Failed to encode service info")
+		response.WriteProblem(w, r, response.Internal("Failed to encode service info", err))
 	}
 }
@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"go-services/shared/middleware"
+	"go-services/shared/response"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -81,35 +84,26 @@ func TestRootHandler(t *testing.T) {
 		t.Error("Expected endpoints to be populated")
 	}
 
+	for path := range loadOpenAPISpec(t).Paths.Map() {
+		if path != "/" && !slices.Contains(response.Endpoints, path) {
+			t.Errorf("Expected documented path %s to be listed", path)
+		}
+	}
+
 	if response.Status != "running" {
 		t.Errorf("Expected status 'running', got '%s'", response.Status)
 	}
 }
 
 func TestCORSHeaders(t *testing.T) {
-	// Test allowed origin
+	router := newRouter(&fakeStore{}, closedHub(), nil, nil, nil, nil)
+
+	// Preflight from an allowed origin
 	req := httptest.NewRequest("OPTIONS", "/wait-times", nil)
 	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "POST")
 	w := httptest.NewRecorder()
-
-	// We need to create a mock handler that includes CORS logic
-	// For now, just test that the handler doesn't panic
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Simulate CORS logic from waitTimesHandler
-		origin := r.Header.Get("Origin")
-		if AllowedOrigins[origin] {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-	})
-
-	handler.ServeHTTP(w, req)
+	router.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
@@ -120,9 +114,28 @@ func TestCORSHeaders(t *testing.T) {
 	if actualOrigin != expectedOrigin {
 		t.Errorf("Expected CORS origin '%s', got '%s'", expectedOrigin, actualOrigin)
 	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, DELETE, OPTIONS" {
+		t.Errorf("Unexpected Allow-Methods '%s'", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "X-API-Key") {
+		t.Errorf("Expected X-API-Key to be an allowed header, got '%s'", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected Max-Age 600, got '%s'", got)
+	}
+
+	// Simple request from a disallowed origin still works, without CORS headers
+	req = httptest.NewRequest("GET", "/wait-times", nil)
+	req.Header.Set("Origin", "https://malicious-site.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS origin for a disallowed origin, got %d '%s'", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
 }
 
 func TestAllowedOrigins(t *testing.T) {
+	cors := middleware.NewCORS(CORSPolicy)
 	tests := []struct {
 		origin   string
 		expected bool
@@ -135,7 +148,7 @@ func TestAllowedOrigins(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.origin, func(t *testing.T) {
-			result := AllowedOrigins[tt.origin]
+			result := cors.AllowsOrigin(tt.origin)
 			if result != tt.expected {
 				t.Errorf("Origin '%s': expected %v, got %v", tt.origin, tt.expected, result)
 			}
@@ -162,9 +175,14 @@ func TestConstants(t *testing.T) {
 }
 
 func TestWaitTimesHandler_MethodNotAllowed(t *testing.T) {
-	// Note: This test would require a proper mock repository
-	// For now, we'll skip the full handler test since it requires database setup
-	t.Skip("Skipping handler test that requires database repository")
+	req := httptest.NewRequest("DELETE", "/wait-times", nil)
+	w := httptest.NewRecorder()
+
+	waitTimesHandler(&fakeStore{})(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
 }
 
 // TestRideHistoryEntryJSON locks in the null-vs-0 distinction: a closed/no-standby
@@ -199,3 +217,91 @@ func TestRideHistoryEntryJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitTimesHandler_InvalidPagination(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"non-numeric page_size", "?page_size=abc"},
+		{"zero page_size", "?page_size=0"},
+		{"negative page_size", "?page_size=-10"},
+		{"malformed cursor", "?cursor=not-a-cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/wait-times"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			// Parameter validation happens before the repository is touched
+			waitTimesHandler(nil)(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestWaitTimesHandler_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		fields []string
+	}{
+		{"garbage window", "GET", "/wait-times?window_hours=abc", "", http.StatusBadRequest, []string{"window_hours"}},
+		{"trailing garbage window", "GET", "/wait-times?window_hours=12h", "", http.StatusBadRequest, []string{"window_hours"}},
+		{"fractional window", "GET", "/wait-times?window_hours=1.5", "", http.StatusBadRequest, []string{"window_hours"}},
+		{"every bad parameter is reported", "GET", "/wait-times?window_hours=x&page_size=0", "", http.StatusBadRequest, []string{"window_hours", "page_size"}},
+		{"malformed body", "POST", "/wait-times", "{", http.StatusBadRequest, nil},
+		{"unknown ride", "GET", "/wait-times?ride_id=nope", "", http.StatusNotFound, nil},
+		{"unknown ride in body", "POST", "/wait-times", `{"ride_id":"nope"}`, http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			// Validation happens before the repository is touched
+			waitTimesHandler(nil)(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			var problem response.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal problem: %v", err)
+			}
+			if len(problem.Errors) != len(tt.fields) {
+				t.Fatalf("Expected field errors for %v, got %+v", tt.fields, problem.Errors)
+			}
+			for i, field := range tt.fields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("Expected a field error for %s, got %+v", field, problem.Errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWaitTimesHandler_WindowIsClamped(t *testing.T) {
+	defer func(max int) { MaxWindowHours = max }(MaxWindowHours)
+	MaxWindowHours = 48
+
+	store := &fakeStore{}
+	for query, clamped := range map[string]string{"?window_hours=48": "", "?window_hours=49": "48", "?window_hours=100000": "48", "?window_hours=0": "1", "?window_hours=-1": "1"} {
+		w := httptest.NewRecorder()
+		waitTimesHandler(store)(w, httptest.NewRequest("GET", "/wait-times"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("X-Window-Hours"); got != clamped {
+			t.Errorf("%s: expected X-Window-Hours %q, got %q", query, clamped, got)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"go-services/shared/service"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// rideResource builds the /v1 representation of a ride from the filtered list
+func rideResource(ride shared.FilteredRide, parkID string) RideResource {
+	park, _ := shared.GetParkInfo(parkID)
+	return RideResource{
+		RideID:   ride.ID,
+		RideName: ride.Name,
+		ParkID:   parkID,
+		ParkName: park.Name,
+	}
+}
+
+// liveEntry converts the latest record of a ride into its live wait time
+func liveEntry(record *models.RideDataHistoryRecord) *LiveWaitTimeEntry {
+	return &LiveWaitTimeEntry{
+		RideID:      record.RideID,
+		RideName:    record.Name,
+		WaitTime:    record.StandbyWaitTime,
+		Status:      record.Status,
+		LastUpdated: record.LastUpdated,
+	}
+}
+
+// latestForRide returns the newest record of a ride, or nil when none has been collected yet
+func latestForRide(ctx context.Context, repo rideDataStore, rideID string) (*models.RideDataHistoryRecord, error) {
+	records, err := repo.GetLatestRideDataForRides(ctx, []string{rideID})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.RideID == rideID {
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+// listParksHandler handles GET /v1/parks
+func listParksHandler(w http.ResponseWriter, r *http.Request) {
+	parks := make([]ParkResource, 0, len(shared.ParkNames))
+	for parkID, park := range shared.GetAllParkInfos() {
+		parks = append(parks, ParkResource{
+			ParkID:    parkID,
+			ParkName:  park.Name,
+			RideCount: len(shared.GetFilteredRidesForPark(parkID)),
+		})
+	}
+	sort.Slice(parks, func(i, j int) bool { return parks[i].ParkName < parks[j].ParkName })
+
+	if err := response.Write(w, r, ParksResponse{Parks: parks}, &response.DefaultOptions); err != nil {
+		logger.WarnContext(r.Context(), "Failed to write parks response", "error", err)
+	}
+}
+
+// listParkRidesHandler handles GET /v1/parks/{id}/rides
+func listParkRidesHandler(w http.ResponseWriter, r *http.Request) {
+	parkID := r.PathValue("id")
+	park, err := lookupPark(parkID)
+	if err != nil {
+		response.WriteProblem(w, r, err)
+		return
+	}
+
+	filtered := shared.GetFilteredRidesForPark(parkID)
+	rides := make([]RideResource, 0, len(filtered))
+	for _, ride := range filtered {
+		rides = append(rides, rideResource(ride, parkID))
+	}
+	sort.Slice(rides, func(i, j int) bool { return rides[i].RideName < rides[j].RideName })
+
+	resp := ParkRidesResponse{ParkID: parkID, ParkName: park.Name, Rides: rides}
+	if err := response.Write(w, r, resp, &response.DefaultOptions); err != nil {
+		logger.WarnContext(service.WithPark(r.Context(), parkID), "Failed to write park rides response", "error", err)
+	}
+}
+
+// getRideHandler handles GET /v1/rides/{id}
+func getRideHandler(repo rideDataStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ride, parkID, err := lookupRide(r.PathValue("id"))
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+
+		latest, err := latestForRide(ctx, repo, ride.ID)
+		if err != nil {
+			response.WriteProblem(w, r, response.Internal("Failed to retrieve ride data", err))
+			return
+		}
+
+		resp := RideDetailResponse{RideResource: rideResource(ride, parkID)}
+		if latest != nil {
+			resp.Live = liveEntry(latest)
+		}
+		if err := response.Write(w, r, resp, &response.DefaultOptions); err != nil {
+			logger.WarnContext(service.WithRide(r.Context(), ride.ID), "Failed to write ride response", "error", err)
+		}
+	}
+}
+
+// getRideLiveHandler handles GET /v1/rides/{id}/live
+func getRideLiveHandler(repo rideDataStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ride, _, err := lookupRide(r.PathValue("id"))
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+
+		latest, err := latestForRide(ctx, repo, ride.ID)
+		if err != nil {
+			response.WriteProblem(w, r, response.Internal("Failed to retrieve ride data", err))
+			return
+		}
+		if latest == nil {
+			response.WriteProblem(w, r, response.NotFound("No live data for ride"))
+			return
+		}
+
+		if err := response.Write(w, r, liveEntry(latest), &response.DefaultOptions); err != nil {
+			logger.WarnContext(service.WithRide(r.Context(), ride.ID), "Failed to write live response", "error", err)
+		}
+	}
+}
+
+// getRideHistoryHandler handles GET /v1/rides/{id}/history. History is always paginated newest first;
+// window_hours limits how far back it goes and defaults to DataHours.
+func getRideHistoryHandler(repo rideDataStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ride, _, err := lookupRide(r.PathValue("id"))
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		params := newQueryParams(r)
+		window, _ := params.window("window_hours", DataHours)
+		pageSize, after, _ := params.pagination()
+		if err := params.err(); err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		params.setHeaders(w.Header())
+
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+
+		filter := repository.HistoryFilter{
+			RideIDs: []string{ride.ID},
+			Since:   time.Now().Add(-window),
+		}
+		page, err := repo.PageRideDataHistory(ctx, filter, after, pageSize)
+		if err != nil {
+			response.WriteProblem(w, r, response.Internal("Failed to retrieve ride data", err))
+			return
+		}
+
+		resp := RideHistoryResponse{
+			RideID:  ride.ID,
+			History: make([]RideHistoryEntry, 0, len(page.Records)),
+		}
+		for _, record := range page.Records {
+			resp.History = append(resp.History, RideHistoryEntry{
+				WaitTime:     record.StandbyWaitTime,
+				Status:       record.Status,
+				SnapshotTime: record.LastUpdated,
+			})
+		}
+		if page.Next != nil {
+			resp.NextCursor = page.Next.Encode()
+		}
+
+		if err := response.Write(w, r, resp, &response.DefaultOptions); err != nil {
+			logger.WarnContext(service.WithRide(r.Context(), ride.ID), "Failed to write history response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+// http2Preface opens every HTTP/2 connection. gRPC clients send it first on cleartext connections,
+// while no HTTP/1 request, WebSocket upgrades included, starts with it.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// prefaceTimeout bounds how long a new connection may take to show its protocol
+const prefaceTimeout = 10 * time.Second
+
+// splitByProtocol serves HTTP/1 and gRPC on one port. Connections accepted from root that begin with
+// the HTTP/2 preface are handed to the gRPC listener and the others to the HTTP listener. Both end
+// when root is closed or fails.
+func splitByProtocol(root net.Listener) (httpListener, grpcListener net.Listener) {
+	h := newConnListener(root.Addr())
+	g := newConnListener(root.Addr())
+	go func() {
+		defer h.Close()
+		defer g.Close()
+		for {
+			conn, err := root.Accept()
+			if err != nil {
+				return
+			}
+			go dispatchConn(conn, h, g)
+		}
+	}()
+	return h, g
+}
+
+// dispatchConn reads as much of the preface as the connection matches and hands it on with the bytes
+// it read still to be read
+func dispatchConn(conn net.Conn, h, g *connListener) {
+	conn.SetReadDeadline(time.Now().Add(prefaceTimeout))
+	reader := bufio.NewReaderSize(conn, len(http2Preface))
+	target := g
+	for i := 1; i <= len(http2Preface); i++ {
+		peeked, err := reader.Peek(i)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		if peeked[i-1] != http2Preface[i-1] {
+			target = h
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	target.deliver(&bufferedConn{Conn: conn, reader: reader})
+}
+
+// bufferedConn is a connection whose first bytes were read into reader
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// connListener is a net.Listener fed with connections accepted elsewhere
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// deliver hands conn to Accept, closing it if the listener is closed first
+func (l *connListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
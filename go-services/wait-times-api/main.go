@@ -3,11 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"go-services/shared/metrics"
+	"go-services/shared/middleware"
+	"go-services/shared/realtime"
 	"go-services/shared/repository"
 	"go-services/shared/service"
+	"go-services/shared/tracing"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,7 +21,7 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var logger service.Logger
+var logger *service.StructuredLogger
 
 func main() {
 	// Load environment variables from .env file only in development
@@ -31,30 +37,90 @@ func main() {
 	// initialize default logger implementation
 	logger = service.NewDefaultLogger()
 
+	// OTEL_TRACES_EXPORTER enables tracing; spans are flushed on shutdown
+	shutdownTracing, err := tracing.Setup(context.Background(), ServiceName)
+	if err != nil {
+		logger.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Errorf("Failed to flush traces: %v", err)
+		}
+	}()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// MAX_WINDOW_HOURS caps the history window any request may ask for
+	if v := strings.TrimSpace(os.Getenv("MAX_WINDOW_HOURS")); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours < 1 {
+			logger.Fatalf("MAX_WINDOW_HOURS must be a positive integer, got %q", v)
+		}
+		MaxWindowHours = hours
+	}
+
+	// CORS_* override the default cross-origin policy
+	cors, err := middleware.CORSFromEnv(CORSPolicy)
+	if err != nil {
+		logger.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	CORSPolicy = cors
+
+	// Browsers subscribe to web push alerts with the collector's VAPID public key
+	VAPIDPublicKey = strings.TrimSpace(os.Getenv("VAPID_PUBLIC_KEY"))
+
 	// Initialize repository
 	repo, err := repository.NewRideDataHistoryRepository()
 	if err != nil {
 		logger.Fatalf("Failed to initialize repository: %v", err)
 	}
 	defer repo.Close()
+	metrics.RegisterPool(repo.PoolStat)
 
-	// Set up HTTP handlers
-	http.HandleFunc("/wait-times", waitTimesHandler(repo))
-	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/", rootHandler)
+	limiter, err := newLimiter(repo.APIKeys())
+	if err != nil {
+		logger.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+
+	ready, err := newReadiness(repo)
+	if err != nil {
+		logger.Fatalf("Invalid readiness configuration: %v", err)
+	}
+
+	// Relay ride state changes announced by the collector to /v1/stream clients
+	hub := realtime.NewHub(StreamBacklogSize)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go realtime.Relay(relayCtx, repo, hub, StreamMaxReconnectBackoff, func(err error) {
+		logger.Errorf("Ride data change listener failed, reconnecting: %v", err)
+	})
 
-	logger.Infof("Wait Times API server starting on port %s", port)
+	logger.Infof("Wait Times API server starting on port %s (HTTP and gRPC)", port)
 
 	// Create HTTP server
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: nil, // Uses DefaultServeMux
+		Handler: newRouter(repo, hub, limiter, ready, repo.AlertRules(), repo.Webhooks()),
 	}
+	// Open streams never go idle, so end them as soon as shutdown begins
+	server.RegisterOnShutdown(hub.Close)
+
+	// HTTP and gRPC share PORT: connections that open with the HTTP/2 preface are gRPC
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logger.Fatalf("Failed to listen on port %s: %v", port, err)
+	}
+	httpListener, grpcListener := splitByProtocol(listener)
+	grpcServer := newGRPCServer(repo, hub, limiter)
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Errorf("gRPC server error: %v", err)
+		}
+	}()
 
 	// Set up graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -71,9 +137,11 @@ func main() {
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Errorf("HTTP server shutdown error: %v", err)
 		}
+		grpcServer.GracefulStop()
+		listener.Close()
 	}()
 
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	if err := server.Serve(httpListener); err != http.ErrServerClosed {
 		logger.Fatal(err)
 	}
 	logger.Infof("Server stopped properly")
@@ -0,0 +1,21 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"go-services/shared/response"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 description of every route served by newRouter.
+// It is maintained by hand; the contract tests fail when a handler response drifts from it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// openAPIHandler handles the /openapi.json endpoint
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if err := response.WriteJSONWithDefaults(w, r, json.RawMessage(openAPISpec)); err != nil {
+		response.WriteProblem(w, r, response.Internal("Failed to encode OpenAPI document", err))
+	}
+}
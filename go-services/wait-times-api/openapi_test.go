@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func loadOpenAPISpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(openAPISpec)
+	if err != nil {
+		t.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("OpenAPI document is invalid: %v", err)
+	}
+	return doc
+}
+
+// validateAgainstSpec checks that a recorded response is documented for the operation and that
+// JSON and problem bodies match the documented schema
+func validateAgainstSpec(t *testing.T, doc *openapi3.T, method, path string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	item := doc.Paths.Find(path)
+	if item == nil {
+		t.Fatalf("Path %s is not documented", path)
+	}
+	op := item.GetOperation(method)
+	if op == nil {
+		t.Fatalf("Operation %s %s is not documented", method, path)
+	}
+	resp := op.Responses.Status(w.Code)
+	if resp == nil || resp.Value == nil {
+		t.Fatalf("Status %d of %s %s is not documented", w.Code, method, path)
+	}
+	if w.Body.Len() == 0 && len(resp.Value.Content) == 0 {
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Invalid Content-Type %q: %v", w.Header().Get("Content-Type"), err)
+	}
+	content := resp.Value.Content.Get(mediaType)
+	if content == nil {
+		t.Fatalf("Content type %s of %s %s %d is not documented", mediaType, method, path, w.Code)
+	}
+	if mediaType != "application/json" && mediaType != response.ProblemContentType {
+		return
+	}
+
+	var body any
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response body %q: %v", w.Body.String(), err)
+	}
+	body = normalizeNumbers(body)
+	if err := content.Schema.Value.VisitJSON(body, openapi3.VisitAsResponse(), openapi3.MultiErrors()); err != nil {
+		t.Errorf("Response of %s %s %d does not match the schema: %v\nbody: %s", method, path, w.Code, err, w.Body.String())
+	}
+}
+
+// normalizeNumbers converts json.Number values to the float64 form the schema validator expects
+func normalizeNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := strconv.ParseFloat(v.String(), 64)
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+	}
+	return v
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	w := serve(t, &fakeStore{}, "GET", "/openapi.json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Served document does not load: %v", err)
+	}
+	if doc.Info.Version != ServiceVersion {
+		t.Errorf("Expected document version %s, got %s", ServiceVersion, doc.Info.Version)
+	}
+}
+
+// TestOpenAPIContract runs real handler responses through the documented schemas and
+// fails when an operation in the document is never exercised
+func TestOpenAPIContract(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+
+	now := time.Now().UTC()
+	closed := &models.RideDataHistoryRecord{
+		ID: 3, RideID: "0de1413a-73ee-46cf-af2e-c491cc7c7d3b", ParkID: testParkID,
+		Name: "Big Thunder Mountain Railroad", Status: "CLOSED", LastUpdated: now,
+	}
+	populated := &fakeStore{
+		records: []*models.RideDataHistoryRecord{testRecord(2, 45, now), testRecord(1, 0, now.Add(-time.Hour)), closed},
+		next:    &repository.HistoryCursor{LastUpdated: now.Add(-time.Hour), ID: 1},
+	}
+	failing := &fakeStore{err: errors.New("database unavailable")}
+
+	tests := []struct {
+		name   string
+		store  rideDataStore
+		method string
+		target string
+		path   string
+		status int
+	}{
+		{"service info", populated, "GET", "/", "/", http.StatusOK},
+		{"health", populated, "GET", "/health", "/health", http.StatusOK},
+		{"livez", populated, "GET", "/livez", "/livez", http.StatusOK},
+		{"readyz", populated, "GET", "/readyz", "/readyz", http.StatusOK},
+		{"openapi", populated, "GET", "/openapi.json", "/openapi.json", http.StatusOK},
+		{"metrics", populated, "GET", "/metrics", "/metrics", http.StatusOK},
+		{"wait times", populated, "GET", "/wait-times", "/wait-times", http.StatusOK},
+		{"wait times empty", &fakeStore{}, "GET", "/wait-times", "/wait-times", http.StatusOK},
+		{"wait times paginated", populated, "GET", "/wait-times?page_size=2", "/wait-times", http.StatusOK},
+		{"wait times post", populated, "POST", "/wait-times?ride_id=" + testRideID, "/wait-times", http.StatusOK},
+		{"wait times bad page size", populated, "GET", "/wait-times?page_size=x", "/wait-times", http.StatusBadRequest},
+		{"wait times bad window", populated, "GET", "/wait-times?window_hours=abc", "/wait-times", http.StatusBadRequest},
+		{"wait times unknown ride", populated, "GET", "/wait-times?ride_id=nope", "/wait-times", http.StatusNotFound},
+		{"wait times post unknown ride", populated, "POST", "/wait-times?ride_id=nope", "/wait-times", http.StatusNotFound},
+		{"wait times store error", failing, "GET", "/wait-times", "/wait-times", http.StatusInternalServerError},
+		{"parks", populated, "GET", "/v1/parks", "/v1/parks", http.StatusOK},
+		{"park rides", populated, "GET", "/v1/parks/" + testParkID + "/rides", "/v1/parks/{id}/rides", http.StatusOK},
+		{"park rides unknown", populated, "GET", "/v1/parks/nope/rides", "/v1/parks/{id}/rides", http.StatusNotFound},
+		{"ride", populated, "GET", "/v1/rides/" + testRideID, "/v1/rides/{id}", http.StatusOK},
+		{"ride without data", &fakeStore{}, "GET", "/v1/rides/" + testRideID, "/v1/rides/{id}", http.StatusOK},
+		{"ride unknown", populated, "GET", "/v1/rides/nope", "/v1/rides/{id}", http.StatusNotFound},
+		{"ride store error", failing, "GET", "/v1/rides/" + testRideID, "/v1/rides/{id}", http.StatusInternalServerError},
+		{"live", populated, "GET", "/v1/rides/" + testRideID + "/live", "/v1/rides/{id}/live", http.StatusOK},
+		{"live closed", populated, "GET", "/v1/rides/" + closed.RideID + "/live", "/v1/rides/{id}/live", http.StatusOK},
+		{"live without data", &fakeStore{}, "GET", "/v1/rides/" + testRideID + "/live", "/v1/rides/{id}/live", http.StatusNotFound},
+		{"live store error", failing, "GET", "/v1/rides/" + testRideID + "/live", "/v1/rides/{id}/live", http.StatusInternalServerError},
+		{"history", populated, "GET", "/v1/rides/" + testRideID + "/history", "/v1/rides/{id}/history", http.StatusOK},
+		{"history bad window", populated, "GET", "/v1/rides/" + testRideID + "/history?window_hours=abc", "/v1/rides/{id}/history", http.StatusBadRequest},
+		{"history unknown", populated, "GET", "/v1/rides/nope/history", "/v1/rides/{id}/history", http.StatusNotFound},
+		{"history csv", populated, "GET", "/v1/rides/" + testRideID + "/history?format=csv", "/v1/rides/{id}/history", http.StatusOK},
+		{"history protobuf", populated, "GET", "/v1/rides/" + testRideID + "/history?format=protobuf", "/v1/rides/{id}/history", http.StatusOK},
+		{"live msgpack", populated, "GET", "/v1/rides/" + testRideID + "/live?format=msgpack", "/v1/rides/{id}/live", http.StatusOK},
+		{"park rides csv", populated, "GET", "/v1/parks/" + testParkID + "/rides?format=csv", "/v1/parks/{id}/rides", http.StatusOK},
+		{"parks not acceptable", populated, "GET", "/v1/parks?format=protobuf", "/v1/parks", http.StatusNotAcceptable},
+		{"ride not acceptable", populated, "GET", "/v1/rides/" + testRideID + "?format=csv", "/v1/rides/{id}", http.StatusNotAcceptable},
+		{"wait times not acceptable", populated, "GET", "/wait-times?format=csv", "/wait-times", http.StatusNotAcceptable},
+		{"export", populated, "GET", "/v1/export", "/v1/export", http.StatusOK},
+		{"export bad format", populated, "GET", "/v1/export?format=xlsx", "/v1/export", http.StatusBadRequest},
+		{"export unknown park", populated, "GET", "/v1/export?park_id=nope", "/v1/export", http.StatusNotFound},
+		{"export store error", failing, "GET", "/v1/export", "/v1/export", http.StatusInternalServerError},
+		{"stream", populated, "GET", "/v1/stream", "/v1/stream", http.StatusOK},
+		{"stream bad last event id", populated, "GET", "/v1/stream?last_event_id=x", "/v1/stream", http.StatusBadRequest},
+		{"websocket unknown ride", populated, "GET", "/v1/ws?ride_id=nope", "/v1/ws", http.StatusNotFound},
+		{"graphql bad body", populated, "POST", "/graphql", "/graphql", http.StatusBadRequest},
+		{"history store error", failing, "GET", "/v1/rides/" + testRideID + "/history", "/v1/rides/{id}/history", http.StatusInternalServerError},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, tt.store, tt.method, tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			validateAgainstSpec(t, doc, tt.method, tt.path, w)
+		})
+		covered[tt.method+" "+tt.path] = true
+	}
+	t.Run("parks not modified", func(t *testing.T) {
+		etag := serve(t, populated, "GET", "/v1/parks").Header().Get("ETag")
+		w := serveWith(t, populated, "/v1/parks", map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+		validateAgainstSpec(t, doc, "GET", "/v1/parks", w)
+	})
+
+	alerts := &fakeAlertStore{}
+	router := alertRouter(alerts)
+	full := &fakeAlertStore{limit: 1}
+	fullRouter := alertRouter(full)
+	serveAlert(fullRouter, testAPIKey, "POST", "/v1/alerts", testWebhookAlert)
+	failingAlerts := alertRouter(&fakeAlertStore{err: errors.New("database unavailable")})
+	alertTests := []struct {
+		name   string
+		router http.Handler
+		apiKey string
+		method string
+		target string
+		body   string
+		path   string
+		status int
+	}{
+		{"create alert", router, testAPIKey, "POST", "/v1/alerts", testWebhookAlert, "/v1/alerts", http.StatusCreated},
+		{"create alert invalid", router, testAPIKey, "POST", "/v1/alerts", `{"channel":"sms"}`, "/v1/alerts", http.StatusBadRequest},
+		{"create alert anonymous", router, "", "POST", "/v1/alerts", testWebhookAlert, "/v1/alerts", http.StatusUnauthorized},
+		{"create alert limit", fullRouter, testAPIKey, "POST", "/v1/alerts", testWebhookAlert, "/v1/alerts", http.StatusConflict},
+		{"create alert store error", failingAlerts, testAPIKey, "POST", "/v1/alerts", testWebhookAlert, "/v1/alerts", http.StatusInternalServerError},
+		{"alerts", router, testAPIKey, "GET", "/v1/alerts", "", "/v1/alerts", http.StatusOK},
+		{"alerts anonymous", router, "", "GET", "/v1/alerts", "", "/v1/alerts", http.StatusUnauthorized},
+		{"alerts store error", failingAlerts, testAPIKey, "GET", "/v1/alerts", "", "/v1/alerts", http.StatusInternalServerError},
+		{"push key unconfigured", router, "", "GET", "/v1/alerts/push-key", "", "/v1/alerts/push-key", http.StatusNotFound},
+		{"alert", router, testAPIKey, "GET", "/v1/alerts/1", "", "/v1/alerts/{id}", http.StatusOK},
+		{"alert of another key", router, otherTestAPIKey, "GET", "/v1/alerts/1", "", "/v1/alerts/{id}", http.StatusNotFound},
+		{"alert deliveries", router, testAPIKey, "GET", "/v1/alerts/1/deliveries", "", "/v1/alerts/{id}/deliveries", http.StatusOK},
+		{"alert deliveries unknown", router, testAPIKey, "GET", "/v1/alerts/9/deliveries", "", "/v1/alerts/{id}/deliveries", http.StatusNotFound},
+		{"delete alert", router, testAPIKey, "DELETE", "/v1/alerts/1", "", "/v1/alerts/{id}", http.StatusNoContent},
+		{"delete alert unknown", router, testAPIKey, "DELETE", "/v1/alerts/1", "", "/v1/alerts/{id}", http.StatusNotFound},
+	}
+	for _, tt := range alertTests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAlert(tt.router, tt.apiKey, tt.method, tt.target, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			validateAgainstSpec(t, doc, tt.method, tt.path, w)
+		})
+		covered[tt.method+" "+tt.path] = true
+	}
+
+	hooks := &fakeWebhookStore{deadLetters: []*models.WebhookDeadLetter{testDeadLetter()}}
+	hookRouter := webhookRouter(hooks)
+	fullHooks := webhookRouter(&fakeWebhookStore{limit: 1})
+	serveAlert(fullHooks, testAPIKey, "POST", "/v1/webhooks", testWebhook)
+	failingHooks := webhookRouter(&fakeWebhookStore{err: errors.New("database unavailable")})
+	webhookTests := []struct {
+		name   string
+		router http.Handler
+		apiKey string
+		method string
+		target string
+		body   string
+		path   string
+		status int
+	}{
+		{"create webhook", hookRouter, testAPIKey, "POST", "/v1/webhooks", testWebhook, "/v1/webhooks", http.StatusCreated},
+		{"create webhook invalid", hookRouter, testAPIKey, "POST", "/v1/webhooks", `{"url":"x","events":[]}`, "/v1/webhooks", http.StatusBadRequest},
+		{"create webhook anonymous", hookRouter, "", "POST", "/v1/webhooks", testWebhook, "/v1/webhooks", http.StatusUnauthorized},
+		{"create webhook limit", fullHooks, testAPIKey, "POST", "/v1/webhooks", testWebhook, "/v1/webhooks", http.StatusConflict},
+		{"create webhook store error", failingHooks, testAPIKey, "POST", "/v1/webhooks", testWebhook, "/v1/webhooks", http.StatusInternalServerError},
+		{"webhooks", hookRouter, testAPIKey, "GET", "/v1/webhooks", "", "/v1/webhooks", http.StatusOK},
+		{"webhooks anonymous", hookRouter, "", "GET", "/v1/webhooks", "", "/v1/webhooks", http.StatusUnauthorized},
+		{"webhooks store error", failingHooks, testAPIKey, "GET", "/v1/webhooks", "", "/v1/webhooks", http.StatusInternalServerError},
+		{"webhook", hookRouter, testAPIKey, "GET", "/v1/webhooks/1", "", "/v1/webhooks/{id}", http.StatusOK},
+		{"webhook of another key", hookRouter, otherTestAPIKey, "GET", "/v1/webhooks/1", "", "/v1/webhooks/{id}", http.StatusNotFound},
+		{"dead letters", hookRouter, testAPIKey, "GET", "/v1/webhooks/1/dead-letters", "", "/v1/webhooks/{id}/dead-letters", http.StatusOK},
+		{"dead letters unknown", hookRouter, testAPIKey, "GET", "/v1/webhooks/9/dead-letters", "", "/v1/webhooks/{id}/dead-letters", http.StatusNotFound},
+		{"redeliver", hookRouter, testAPIKey, "POST", "/v1/webhooks/1/dead-letters/3/redeliver", "", "/v1/webhooks/{id}/dead-letters/{letterId}/redeliver", http.StatusAccepted},
+		{"redeliver unknown", hookRouter, testAPIKey, "POST", "/v1/webhooks/1/dead-letters/3/redeliver", "", "/v1/webhooks/{id}/dead-letters/{letterId}/redeliver", http.StatusNotFound},
+		{"delete webhook", hookRouter, testAPIKey, "DELETE", "/v1/webhooks/1", "", "/v1/webhooks/{id}", http.StatusNoContent},
+		{"delete webhook unknown", hookRouter, testAPIKey, "DELETE", "/v1/webhooks/1", "", "/v1/webhooks/{id}", http.StatusNotFound},
+	}
+	for _, tt := range webhookTests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAlert(tt.router, tt.apiKey, tt.method, tt.target, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			validateAgainstSpec(t, doc, tt.method, tt.path, w)
+		})
+		covered[tt.method+" "+tt.path] = true
+	}
+
+	VAPIDPublicKey = "BPublic"
+	t.Cleanup(func() { VAPIDPublicKey = "" })
+	validateAgainstSpec(t, doc, "GET", "/v1/alerts/push-key", serveAlert(router, "", "GET", "/v1/alerts/push-key", ""))
+
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !covered[method+" "+path] {
+				t.Errorf("Operation %s %s has no contract test", method, path)
+			}
+		}
+	}
+}
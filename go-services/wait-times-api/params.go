@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// queryParams reads and validates query parameters. Every rejected parameter is recorded instead of
+// stopping at the first, so a client learns about all of them from one 400 response.
+type queryParams struct {
+	values url.Values
+	fields []response.FieldError
+	// windowHours is the window a clamped window parameter was served with, or 0
+	windowHours int
+}
+
+func newQueryParams(r *http.Request) *queryParams {
+	return &queryParams{values: r.URL.Query()}
+}
+
+// invalid records a rejected parameter
+func (p *queryParams) invalid(field, format string, args ...any) {
+	p.fields = append(p.fields, response.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the validation error for every rejected parameter, or nil
+func (p *queryParams) err() error {
+	if len(p.fields) == 0 {
+		return nil
+	}
+	return response.Invalid(p.fields...)
+}
+
+// window reads a window in whole hours, clamped to between 1 and MaxWindowHours so clients asking for
+// more history than is served still get the longest window. ok is false when the parameter is absent
+// or not an integer, in which case def is returned.
+func (p *queryParams) window(name string, def time.Duration) (window time.Duration, ok bool) {
+	s := p.values.Get(name)
+	if s == "" {
+		return def, false
+	}
+	hours, err := strconv.Atoi(s)
+	if err != nil {
+		p.invalid(name, "%s must be an integer", name)
+		return def, false
+	}
+	if clamped := clampWindowHours(hours); clamped != hours {
+		hours = clamped
+		p.windowHours = clamped
+	}
+	return time.Duration(hours) * time.Hour, true
+}
+
+// setHeaders reports a clamped window in the X-Window-Hours header of the response
+func (p *queryParams) setHeaders(h http.Header) {
+	if p.windowHours != 0 {
+		h.Set("X-Window-Hours", strconv.Itoa(p.windowHours))
+	}
+}
+
+// clampWindowHours limits a window in hours to between 1 and MaxWindowHours
+func clampWindowHours(hours int) int {
+	return min(max(hours, 1), MaxWindowHours)
+}
+
+// pagination reads the optional page_size and cursor parameters. paginated reports whether either
+// was given; a cursor from a previous page's nextCursor continues where that page ended.
+func (p *queryParams) pagination() (pageSize int, after *repository.HistoryCursor, paginated bool) {
+	if s := p.values.Get("page_size"); s != "" {
+		paginated = true
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			p.invalid("page_size", "page_size must be a positive integer")
+		} else {
+			pageSize = repository.ClampHistoryPageSize(n)
+		}
+	}
+	if s := p.values.Get("cursor"); s != "" {
+		paginated = true
+		cursor, err := repository.DecodeHistoryCursor(s)
+		if err != nil {
+			p.invalid("cursor", "cursor is not a cursor returned by a previous page")
+		} else {
+			after = &cursor
+		}
+	}
+	return pageSize, after, paginated
+}
+
+// timestamp reads an optional RFC 3339 timestamp, returning def when it is absent or rejected
+func (p *queryParams) timestamp(name string, def time.Time) time.Time {
+	s := p.values.Get(name)
+	if s == "" {
+		return def
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		p.invalid(name, "%s must be an RFC 3339 timestamp", name)
+		return def
+	}
+	return t
+}
+
+// lookupRide finds a tracked ride in the catalog, reporting unknown IDs as not found
+func lookupRide(rideID string) (shared.FilteredRide, string, error) {
+	ride, parkID, ok := shared.FindFilteredRide(rideID)
+	if !ok {
+		return shared.FilteredRide{}, "", response.NotFound(fmt.Sprintf("Ride %q is not tracked", rideID))
+	}
+	return ride, parkID, nil
+}
+
+// lookupPark finds a park in the catalog, reporting unknown IDs as not found
+func lookupPark(parkID string) (shared.ParkInfo, error) {
+	park, ok := shared.GetParkInfo(parkID)
+	if !ok {
+		return shared.ParkInfo{}, response.NotFound(fmt.Sprintf("Park %q is not tracked", parkID))
+	}
+	return park, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-services/shared/ratelimit"
+	"go-services/shared/repository"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiKeyStore resolves API keys for the rate limiter from the api_keys table
+type apiKeyStore struct {
+	keys *repository.APIKeyRepository
+}
+
+func (s apiKeyStore) LookupKey(ctx context.Context, key string) (*ratelimit.Key, error) {
+	k, err := s.keys.LookupAPIKey(ctx, key)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ratelimit.ErrUnknownKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ratelimit.Key{
+		ID:   k.ID,
+		Name: k.Name,
+		Tier: ratelimit.Tier{RequestsPerMinute: k.RequestsPerMinute, Burst: k.Burst, DailyQuota: k.DailyQuota},
+	}, nil
+}
+
+func (s apiKeyStore) CountUsage(ctx context.Context, id int64, at time.Time) (int64, error) {
+	return s.keys.IncrementAPIKeyUsage(ctx, id, at)
+}
+
+// newLimiter configures rate limiting from the environment. TRUSTED_PROXY_HOPS is how many proxies,
+// such as the Cloud Run front end, append to X-Forwarded-For before a request reaches the service.
+func newLimiter(keys *repository.APIKeyRepository) (*ratelimit.Limiter, error) {
+	cfg := ratelimit.Config{Keys: apiKeyStore{keys: keys}, KeyCacheTTL: APIKeyCacheTTL}
+	var err error
+	if cfg.Anonymous.RequestsPerMinute, err = envInt("RATE_LIMIT_ANON_PER_MINUTE", DefaultAnonRequestsPerMinute); err != nil {
+		return nil, err
+	}
+	if cfg.Anonymous.Burst, err = envInt("RATE_LIMIT_ANON_BURST", DefaultAnonBurst); err != nil {
+		return nil, err
+	}
+	if cfg.Anonymous.DailyQuota, err = envInt("RATE_LIMIT_ANON_DAILY_QUOTA", DefaultAnonDailyQuota); err != nil {
+		return nil, err
+	}
+	if cfg.TrustedProxies, err = envInt("TRUSTED_PROXY_HOPS", 0); err != nil {
+		return nil, err
+	}
+	return ratelimit.New(cfg), nil
+}
+
+// envInt reads a non-negative integer, returning def when the variable is unset
+func envInt(name string, def int) (int, error) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, v)
+	}
+	return n, nil
+}
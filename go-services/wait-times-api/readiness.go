@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/health"
+	"go-services/shared/repository"
+	"time"
+)
+
+// newReadiness builds the /readyz checks: the database and its pool, the schema migration and the
+// freshness of every park's ride data. STALE_AFTER_MINUTES overrides how old that data may get.
+func newReadiness(repo *repository.RideDataHistoryRepository) (*health.Checker, error) {
+	staleAfter, err := envInt("STALE_AFTER_MINUTES", DefaultStaleAfterMinutes)
+	if err != nil {
+		return nil, err
+	}
+	if staleAfter == 0 {
+		return nil, fmt.Errorf("STALE_AFTER_MINUTES must be a positive integer, got 0")
+	}
+
+	parks := make(map[string]string)
+	for id, park := range shared.GetAllParkInfos() {
+		parks[id] = park.Name
+	}
+
+	ready := health.New(ServiceName, ServiceVersion, ReadinessTimeout)
+	ready.Add("database", health.Database(repo.HealthCheck, repo.PoolStat, PoolSaturationWarning))
+	ready.Add("migrations", health.Migrations(repo.LatestMigration, repository.RequiredMigration))
+	ready.Add("freshness", health.Freshness(repo.LatestUpdateByPark, parks, time.Duration(staleAfter)*time.Minute))
+	return ready, nil
+}
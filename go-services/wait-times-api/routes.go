@@ -0,0 +1,79 @@
+package main
+
+import (
+	"go-services/shared/health"
+	"go-services/shared/metrics"
+	"go-services/shared/middleware"
+	"go-services/shared/ratelimit"
+	"go-services/shared/realtime"
+	"go-services/shared/tracing"
+	"net/http"
+)
+
+// newRouter registers every route of the service behind the request ID, CORS, problem, tracing and
+// metrics middleware. A nil limiter disables rate limiting, a nil ready reports no dependencies, and
+// the alert and webhook routes are only registered when their store is given.
+func newRouter(repo rideDataStore, hub *realtime.Hub, limiter *ratelimit.Limiter, ready *health.Checker, alerts alertRuleStore, webhooks webhookStore) http.Handler {
+	limit := func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return limiter.Middleware(next)
+	}
+
+	if ready == nil {
+		ready = health.New(ServiceName, ServiceVersion, ReadinessTimeout)
+	}
+	cors := middleware.NewCORS(CORSPolicy)
+
+	// Each /v1 route is registered on mux itself so the route pattern reaches the metrics and tracing
+	// middleware, which only see the request of the outer mux. The same routes on a nested mux answer
+	// the /v1 requests that match none of them with its 404 and 405 problems.
+	mux := http.NewServeMux()
+	v1Fallback := http.NewServeMux()
+	v1 := func(pattern string, handler http.HandlerFunc) {
+		v1Fallback.Handle(pattern, handler)
+		mux.Handle(pattern, limit(handler))
+	}
+	// The data endpoints are rate limited per API key or client IP
+	v1("GET /v1/parks", listParksHandler)
+	v1("GET /v1/parks/{id}/rides", listParkRidesHandler)
+	v1("GET /v1/rides/{id}", getRideHandler(repo))
+	v1("GET /v1/rides/{id}/history", getRideHistoryHandler(repo))
+	v1("GET /v1/rides/{id}/live", getRideLiveHandler(repo))
+	v1("GET /v1/export", exportHandler(repo))
+	v1("GET /v1/stream", streamHandler(hub, StreamHeartbeatInterval))
+	v1("GET /v1/ws", websocketHandler(repo, hub, StreamHeartbeatInterval, cors.AllowsOrigin))
+	// Alerts and webhooks belong to the API key of the request
+	if alerts != nil {
+		v1("GET /v1/alerts", listAlertsHandler(alerts))
+		v1("POST /v1/alerts", createAlertHandler(alerts))
+		v1("GET /v1/alerts/push-key", pushKeyHandler)
+		v1("GET /v1/alerts/{id}", getAlertHandler(alerts))
+		v1("DELETE /v1/alerts/{id}", deleteAlertHandler(alerts))
+		v1("GET /v1/alerts/{id}/deliveries", listAlertDeliveriesHandler(alerts))
+	}
+	if webhooks != nil {
+		v1("GET /v1/webhooks", listWebhooksHandler(webhooks))
+		v1("POST /v1/webhooks", createWebhookHandler(webhooks))
+		v1("GET /v1/webhooks/{id}", getWebhookHandler(webhooks))
+		v1("DELETE /v1/webhooks/{id}", deleteWebhookHandler(webhooks))
+		v1("GET /v1/webhooks/{id}/dead-letters", listWebhookDeadLettersHandler(webhooks))
+		v1("POST /v1/webhooks/{id}/dead-letters/{letterId}/redeliver", redeliverWebhookHandler(webhooks))
+	}
+	mux.Handle("/v1/", limit(v1Fallback))
+	mux.Handle("/graphql", limit(graphQLHandler(repo)))
+	// /wait-times keeps its original catch-all behaviour for existing clients
+	mux.Handle("/wait-times", limit(waitTimesHandler(repo)))
+	// Health, discovery and metrics endpoints are never rate limited
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("GET /livez", health.LiveHandler(ServiceName))
+	mux.HandleFunc("GET /readyz", ready.ReadyHandler())
+	mux.HandleFunc("GET /openapi.json", openAPIHandler)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("/", rootHandler)
+	// Errors, including the mux's own 404 and 405, become problem documents; requests are counted and
+	// traced per route pattern
+	handler := middleware.RequestID(cors.Middleware(middleware.Problems(tracing.Route(metrics.Instrument(mux)))))
+	return tracing.Middleware(ServiceName, handler)
+}
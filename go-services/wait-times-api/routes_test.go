@@ -0,0 +1,694 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-services/shared/health"
+	"go-services/shared/models"
+	"go-services/shared/ratelimit"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"go-services/shared/tracing"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	waittimesv1 "go-services/proto/waittimes/v1"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jackc/pgx/v5"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testParkID = "7340550b-c14d-4def-80bb-acdb51d49a66"
+	testRideID = "9167db1d-e5e7-46da-a07f-ae30a87bc4c4" // Space Mountain
+)
+
+// fakeStore serves fixed records in place of the database
+type fakeStore struct {
+	records    []*models.RideDataHistoryRecord
+	err        error
+	lastFilter repository.HistoryFilter
+	lastAfter  *repository.HistoryCursor
+	next       *repository.HistoryCursor
+	queries    int
+	// eachErr is returned by EachRideDataHistory after every record was delivered
+	eachErr error
+}
+
+func (f *fakeStore) GetLatestRideDataForAllRides(ctx context.Context) ([]*models.RideDataHistoryRecord, error) {
+	return f.latest(nil), f.err
+}
+
+func (f *fakeStore) GetLatestRideDataForRides(ctx context.Context, rideIDs []string) ([]*models.RideDataHistoryRecord, error) {
+	return f.latest(rideIDs), f.err
+}
+
+func (f *fakeStore) GetRideDataHistorySince(ctx context.Context, since time.Time) ([]*models.RideDataHistoryRecord, error) {
+	return f.records, f.err
+}
+
+func (f *fakeStore) GetRideDataHistorySinceForRide(ctx context.Context, since time.Time, rideID string) ([]*models.RideDataHistoryRecord, error) {
+	return f.records, f.err
+}
+
+func (f *fakeStore) PageRideDataHistory(ctx context.Context, filter repository.HistoryFilter, after *repository.HistoryCursor, pageSize int) (repository.HistoryPage, error) {
+	f.lastFilter = filter
+	f.lastAfter = after
+	return repository.HistoryPage{Records: f.records, Next: f.next}, f.err
+}
+
+func (f *fakeStore) QueryRideDataHistory(ctx context.Context, filter repository.HistoryFilter) ([]*models.RideDataHistoryRecord, error) {
+	f.queries++
+	f.lastFilter = filter
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := f.matching(filter)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LastUpdated.After(out[j].LastUpdated) })
+	return out, nil
+}
+
+func (f *fakeStore) EachRideDataHistory(ctx context.Context, filter repository.HistoryFilter, fn func(*models.RideDataHistoryRecord) error) error {
+	f.queries++
+	f.lastFilter = filter
+	if f.err != nil {
+		return f.err
+	}
+	for _, record := range f.matching(filter) {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return f.eachErr
+}
+
+// matching applies the ride, park and time conditions of a filter in record order
+func (f *fakeStore) matching(filter repository.HistoryFilter) []*models.RideDataHistoryRecord {
+	var out []*models.RideDataHistoryRecord
+	for _, record := range f.records {
+		if len(filter.RideIDs) > 0 && !slices.Contains(filter.RideIDs, record.RideID) {
+			continue
+		}
+		if filter.ParkID != "" && record.ParkID != filter.ParkID {
+			continue
+		}
+		if !filter.Since.IsZero() && record.LastUpdated.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !record.LastUpdated.Before(filter.Until) {
+			continue
+		}
+		out = append(out, record)
+	}
+	return out
+}
+
+// latest returns the newest record of each ride, limited to rideIDs when given
+func (f *fakeStore) latest(rideIDs []string) []*models.RideDataHistoryRecord {
+	newest := make(map[string]*models.RideDataHistoryRecord)
+	for _, record := range f.records {
+		if current, ok := newest[record.RideID]; !ok || record.LastUpdated.After(current.LastUpdated) {
+			newest[record.RideID] = record
+		}
+	}
+	var out []*models.RideDataHistoryRecord
+	for id, record := range newest {
+		if rideIDs == nil {
+			out = append(out, record)
+			continue
+		}
+		for _, want := range rideIDs {
+			if want == id {
+				out = append(out, record)
+			}
+		}
+	}
+	return out
+}
+
+func testRecord(id int64, wait int, at time.Time) *models.RideDataHistoryRecord {
+	return &models.RideDataHistoryRecord{
+		ID:              id,
+		RideID:          testRideID,
+		ParkID:          testParkID,
+		Name:            "Space Mountain",
+		Status:          "OPERATING",
+		LastUpdated:     at,
+		StandbyWaitTime: &wait,
+	}
+}
+
+// closedHub returns a hub that has shut down, so /v1/stream responses end immediately and can be recorded
+func closedHub() *realtime.Hub {
+	hub := realtime.NewHub(1)
+	hub.Close()
+	return hub
+}
+
+func serve(t *testing.T, store rideDataStore, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	newRouter(store, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to unmarshal response %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestV1ListParks(t *testing.T) {
+	w := serve(t, &fakeStore{}, "GET", "/v1/parks")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp ParksResponse
+	decode(t, w, &resp)
+	if len(resp.Parks) != 2 {
+		t.Fatalf("Expected 2 parks, got %d", len(resp.Parks))
+	}
+	if resp.Parks[0].ParkName != "Disney California Adventure Park" || resp.Parks[1].ParkName != "Disneyland Park" {
+		t.Errorf("Expected parks sorted by name, got %+v", resp.Parks)
+	}
+	if resp.Parks[1].RideCount != 11 {
+		t.Errorf("Expected 11 rides for Disneyland, got %d", resp.Parks[1].RideCount)
+	}
+}
+
+func TestV1ListParkRides(t *testing.T) {
+	w := serve(t, &fakeStore{}, "GET", "/v1/parks/"+testParkID+"/rides")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp ParkRidesResponse
+	decode(t, w, &resp)
+	if resp.ParkName != "Disneyland Park" || len(resp.Rides) != 11 {
+		t.Errorf("Unexpected park rides response: %+v", resp)
+	}
+	for _, ride := range resp.Rides {
+		if ride.ParkID != testParkID {
+			t.Errorf("Ride %s has park %s", ride.RideID, ride.ParkID)
+		}
+	}
+
+	if w := serve(t, &fakeStore{}, "GET", "/v1/parks/unknown/rides"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown park, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestV1GetRide(t *testing.T) {
+	now := time.Now().UTC()
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{
+		testRecord(1, 30, now.Add(-10*time.Minute)),
+		testRecord(2, 45, now),
+	}}
+
+	w := serve(t, store, "GET", "/v1/rides/"+testRideID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp RideDetailResponse
+	decode(t, w, &resp)
+	if resp.RideName != "Space Mountain" || resp.ParkName != "Disneyland Park" {
+		t.Errorf("Unexpected ride: %+v", resp.RideResource)
+	}
+	if resp.Live == nil || resp.Live.WaitTime == nil || *resp.Live.WaitTime != 45 {
+		t.Errorf("Expected live wait time 45, got %+v", resp.Live)
+	}
+
+	// A tracked ride without collected data still resolves, with live null
+	w = serve(t, &fakeStore{}, "GET", "/v1/rides/"+testRideID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	decode(t, w, &resp)
+	if resp.Live != nil {
+		t.Errorf("Expected null live data, got %+v", resp.Live)
+	}
+
+	if w := serve(t, store, "GET", "/v1/rides/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown ride, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestV1GetRideLive(t *testing.T) {
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 20, time.Now())}}
+
+	w := serve(t, store, "GET", "/v1/rides/"+testRideID+"/live")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp LiveWaitTimeEntry
+	decode(t, w, &resp)
+	if resp.RideID != testRideID || resp.WaitTime == nil || *resp.WaitTime != 20 {
+		t.Errorf("Unexpected live response: %+v", resp)
+	}
+
+	if w := serve(t, &fakeStore{}, "GET", "/v1/rides/"+testRideID+"/live"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without data, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if w := serve(t, &fakeStore{err: errors.New("boom")}, "GET", "/v1/rides/"+testRideID+"/live"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d on store error, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestV1GetRideHistory(t *testing.T) {
+	now := time.Now().UTC()
+	next := &repository.HistoryCursor{LastUpdated: now.Add(-time.Hour), ID: 1}
+	store := &fakeStore{
+		records: []*models.RideDataHistoryRecord{testRecord(2, 45, now), testRecord(1, 30, now.Add(-time.Hour))},
+		next:    next,
+	}
+
+	w := serve(t, store, "GET", "/v1/rides/"+testRideID+"/history?window_hours=2&page_size=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp RideHistoryResponse
+	decode(t, w, &resp)
+	if len(resp.History) != 2 || *resp.History[0].WaitTime != 45 {
+		t.Errorf("Unexpected history: %+v", resp.History)
+	}
+	if resp.NextCursor != next.Encode() {
+		t.Errorf("Expected next cursor %q, got %q", next.Encode(), resp.NextCursor)
+	}
+	if len(store.lastFilter.RideIDs) != 1 || store.lastFilter.RideIDs[0] != testRideID {
+		t.Errorf("Expected history filtered to the ride, got %v", store.lastFilter.RideIDs)
+	}
+	if since := time.Since(store.lastFilter.Since); since < 2*time.Hour || since > 2*time.Hour+time.Minute {
+		t.Errorf("Expected a 2 hour window, got %v", since)
+	}
+
+	// Following the cursor passes it back to the store
+	w = serve(t, store, "GET", "/v1/rides/"+testRideID+"/history?cursor="+resp.NextCursor)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if store.lastAfter == nil || store.lastAfter.ID != next.ID {
+		t.Errorf("Expected cursor to be forwarded, got %+v", store.lastAfter)
+	}
+
+	for _, query := range []string{"?window_hours=abc", "?window_hours=1.5", "?page_size=-1", "?cursor=bogus"} {
+		if w := serve(t, store, "GET", "/v1/rides/"+testRideID+"/history"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("Query %s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestV1GetRideHistory_ClampsWindow(t *testing.T) {
+	store := &fakeStore{}
+	w := serve(t, store, "GET", "/v1/rides/"+testRideID+"/history?window_hours=100000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got, want := w.Header().Get("X-Window-Hours"), strconv.Itoa(MaxWindowHours); got != want {
+		t.Errorf("Expected X-Window-Hours %s, got %q", want, got)
+	}
+	window := time.Duration(MaxWindowHours) * time.Hour
+	if since := time.Since(store.lastFilter.Since); since < window || since > window+time.Minute {
+		t.Errorf("Expected a %v window, got %v", window, since)
+	}
+}
+
+// serveWith sends a request with headers through the router
+func serveWith(t *testing.T, store rideDataStore, target string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	newRouter(store, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+	return w
+}
+
+func TestV1ConditionalRequests(t *testing.T) {
+	now := time.Now().UTC()
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, now)}}
+	target := "/v1/rides/" + testRideID + "/live"
+
+	etag := serve(t, store, "GET", target).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+	w := serveWith(t, store, target, map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected an empty %d for an unchanged ride, got %d %q", http.StatusNotModified, w.Code, w.Body.String())
+	}
+
+	store.records = append(store.records, testRecord(2, 45, now.Add(time.Minute)))
+	if w := serveWith(t, store, target, map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d once the ride changed, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestV1Representations(t *testing.T) {
+	now := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	store := &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(2, 45, now), testRecord(1, 30, now.Add(-time.Hour))}}
+	history := "/v1/rides/" + testRideID + "/history"
+
+	t.Run("csv", func(t *testing.T) {
+		w := serveWith(t, store, history, map[string]string{"Accept": "text/csv"})
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+			t.Fatalf("Expected CSV, got %d %v", w.Code, w.Header())
+		}
+		want := "rideId,snapshotTime,status,waitTime\n" +
+			testRideID + ",2026-10-19T18:00:00Z,OPERATING,45\n" +
+			testRideID + ",2026-10-19T17:00:00Z,OPERATING,30\n"
+		if w.Body.String() != want {
+			t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+		}
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		w := serveWith(t, store, "/v1/rides/"+testRideID+"/live", map[string]string{"Accept": "application/msgpack"})
+		if w.Header().Get("Content-Type") != "application/msgpack" {
+			t.Fatalf("Expected MessagePack, got %v", w.Header())
+		}
+		var entry map[string]any
+		if err := msgpack.Unmarshal(w.Body.Bytes(), &entry); err != nil || entry["rideId"] != testRideID {
+			t.Errorf("Unexpected MessagePack body %v (%v)", entry, err)
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		w := serveWith(t, store, history+"?format=protobuf", nil)
+		if w.Header().Get("Content-Type") != "application/x-protobuf" {
+			t.Fatalf("Expected protobuf, got %v", w.Header())
+		}
+		var msg waittimesv1.GetHistoryResponse
+		if err := proto.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.GetRideId() != testRideID || len(msg.GetHistory()) != 2 || msg.GetHistory()[0].GetWaitTime() != 45 {
+			t.Errorf("Unexpected message %v", &msg)
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		w := serveWith(t, store, "/v1/rides/"+testRideID, map[string]string{"Accept": "application/x-protobuf"})
+		if w.Code != http.StatusNotAcceptable || w.Header().Get("Content-Type") != response.ProblemContentType {
+			t.Errorf("Expected a 406 problem, got %d %v", w.Code, w.Header())
+		}
+	})
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+	for _, target := range []string{"/wait-times", "/v1/parks", history} {
+		for coding, decoder := range decoders {
+			t.Run(coding+" "+target, func(t *testing.T) {
+				w := serveWith(t, store, target, map[string]string{"Accept-Encoding": coding + ", identity;q=0.5"})
+				if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != coding {
+					t.Fatalf("Expected a %s body, got %d %v", coding, w.Code, w.Header())
+				}
+				reader, err := decoder(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err := io.ReadAll(reader)
+				if err != nil || !json.Valid(body) {
+					t.Errorf("Expected JSON after decoding %s, got %q (%v)", coding, body, err)
+				}
+			})
+		}
+	}
+}
+
+func TestV1Routing(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"method not allowed", "POST", "/v1/parks", http.StatusMethodNotAllowed},
+		{"unknown v1 route", "GET", "/v1/unknown", http.StatusNotFound},
+		{"preflight", "OPTIONS", "/v1/rides/" + testRideID, http.StatusOK},
+		{"compatibility shim", "GET", "/wait-times", http.StatusOK},
+		{"health", "GET", "/health", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Origin", "http://localhost:3000")
+			w := httptest.NewRecorder()
+			newRouter(&fakeStore{}, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); tt.target != "/health" && got != "http://localhost:3000" {
+				t.Errorf("Expected CORS origin header, got %q", got)
+			}
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		code   response.Code
+		field  string
+	}{
+		{"invalid window", "GET", "/v1/rides/" + testRideID + "/history?window_hours=abc", http.StatusBadRequest, response.CodeValidation, "window_hours"},
+		{"invalid cursor", "GET", "/wait-times?cursor=bogus", http.StatusBadRequest, response.CodeValidation, "cursor"},
+		{"unknown ride", "GET", "/v1/rides/nope", http.StatusNotFound, response.CodeNotFound, ""},
+		{"mux method not allowed", "POST", "/v1/parks", http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, ""},
+		{"mux not found", "GET", "/v1/unknown", http.StatusNotFound, response.CodeNotFound, ""},
+		{"handler method not allowed", "DELETE", "/wait-times", http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(response.RequestIDHeader, "req-123")
+			w := httptest.NewRecorder()
+			newRouter(&fakeStore{}, closedHub(), nil, nil, nil, nil).ServeHTTP(w, req)
+
+			if w.Code != tt.status || w.Header().Get("Content-Type") != response.ProblemContentType {
+				t.Fatalf("Expected a %d problem, got %d %q: %s", tt.status, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+			}
+			var problem response.Problem
+			decode(t, w, &problem)
+			if problem.Code != tt.code || problem.Status != tt.status || problem.RequestID != "req-123" {
+				t.Errorf("Unexpected problem %+v", problem)
+			}
+			if tt.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field) {
+				t.Errorf("Expected a field error for %s, got %+v", tt.field, problem.Errors)
+			}
+		})
+	}
+}
+
+func TestRouterRateLimits(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{Anonymous: ratelimit.Tier{RequestsPerMinute: 1, Burst: 1}})
+	router := newRouter(&fakeStore{}, closedHub(), limiter, nil, nil, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/v1/parks"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("Expected the first request to pass with RateLimit headers, got %d %v", w.Code, w.Header())
+	}
+	w := get("/v1/parks")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Error("Expected CORS headers on 429 responses so browsers can read them")
+	}
+	doc := loadOpenAPISpec(t)
+	validateAgainstSpec(t, doc, "GET", "/v1/parks", w)
+
+	req := httptest.NewRequest("GET", "/wait-times", nil)
+	req.Header.Set(ratelimit.APIKeyHeader, "wtk_unknown")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for an unknown API key, got %d", w.Code)
+	}
+	validateAgainstSpec(t, doc, "GET", "/wait-times", w)
+
+	for _, target := range []string{"/health", "/openapi.json"} {
+		if w := get(target); w.Code != http.StatusOK {
+			t.Errorf("Expected %s to be exempt from rate limits, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := newRouter(&fakeStore{}, closedHub(), nil, nil, nil, nil)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/rides/"+testRideID, nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	want := `http_request_duration_seconds_count{method="GET",route="GET /v1/rides/{id}",status="200"}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected requests to be counted by route pattern, missing %s", want)
+	}
+}
+
+func TestMetricsEndpoint_RateLimited(t *testing.T) {
+	router := alertRouter(&fakeAlertStore{})
+	serveAlert(router, testAPIKey, "GET", "/v1/alerts", "")
+	serveAlert(router, "", "GET", "/v1/parks", "")
+	serveAlert(router, testAPIKey, "POST", "/v1/parks", "")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`http_request_duration_seconds_count{method="GET",route="GET /v1/alerts",status="200"}`,
+		`http_request_duration_seconds_count{method="GET",route="GET /v1/parks",status="200"}`,
+		`http_request_duration_seconds_count{method="POST",route="/v1/",status="405"}`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected rate limited requests to be counted by route pattern, missing %s", want)
+		}
+	}
+}
+
+func TestRouteSpans_RateLimited(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	router := alertRouter(&fakeAlertStore{})
+	serveAlert(router, testAPIKey, "GET", "/v1/alerts", "")
+	serveAlert(router, testAPIKey, "GET", "/v1/rides/"+testRideID, "")
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	if want := []string{"GET /v1/alerts", "GET /v1/rides/{id}"}; !slices.Equal(names, want) {
+		t.Errorf("Expected spans named after the route patterns %v, got %v", want, names)
+	}
+}
+
+// tracedStore runs its queries through the query tracer, as the database pool does
+type tracedStore struct {
+	*fakeStore
+}
+
+func (s tracedStore) query(ctx context.Context, sql string) {
+	ctx = tracing.QueryTracer{}.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql})
+	tracing.QueryTracer{}.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
+
+func (s tracedStore) GetLatestRideDataForAllRides(ctx context.Context) ([]*models.RideDataHistoryRecord, error) {
+	s.query(ctx, "SELECT DISTINCT ON (ride_id) * FROM ride_data_history")
+	return s.fakeStore.GetLatestRideDataForAllRides(ctx)
+}
+
+func (s tracedStore) GetRideDataHistorySince(ctx context.Context, since time.Time) ([]*models.RideDataHistoryRecord, error) {
+	s.query(ctx, "SELECT * FROM ride_data_history WHERE last_updated >= $1")
+	return s.fakeStore.GetRideDataHistorySince(ctx, since)
+}
+
+func TestWaitTimesSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	w := serve(t, tracedStore{&fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 20, time.Now())}}}, "GET", "/wait-times")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	spans := recorder.Ended()
+	var request sdktrace.ReadOnlySpan
+	var queries []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.Name() == "db SELECT" {
+			queries = append(queries, span)
+		} else {
+			request = span
+		}
+	}
+	if request == nil || len(queries) != 2 {
+		t.Fatalf("Expected a request span and 2 query spans, got %d spans", len(spans))
+	}
+	for _, query := range queries {
+		if query.Parent().SpanID() != request.SpanContext().SpanID() {
+			t.Errorf("Expected the query span to be a child of the request span %s, got parent %s", request.Name(), query.Parent().SpanID())
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	var lastUpdate time.Time
+	ready := health.New(ServiceName, ServiceVersion, time.Second)
+	ready.Add("freshness", health.Freshness(func(context.Context) (map[string]time.Time, error) {
+		return map[string]time.Time{testParkID: lastUpdate}, nil
+	}, map[string]string{testParkID: "Disneyland"}, 30*time.Minute))
+	router := newRouter(&fakeStore{}, closedHub(), nil, ready, nil, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		validateAgainstSpec(t, doc, "GET", target, w)
+		return w
+	}
+
+	lastUpdate = time.Now().Add(-5 * time.Minute)
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Fatalf("Expected fresh data to be ready, got %d: %s", w.Code, w.Body.String())
+	}
+
+	lastUpdate = time.Now().Add(-time.Hour)
+	w := get("/readyz")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected stale data to be unready, got %d", w.Code)
+	}
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checks["freshness"].Status != health.Fail || report.Checks["freshness"].Details["stale_parks"] == nil {
+		t.Errorf("Expected the stale park to be reported, got %+v", report.Checks["freshness"])
+	}
+
+	if w := get("/livez"); w.Code != http.StatusOK {
+		t.Errorf("Expected /livez to ignore dependencies, got %d", w.Code)
+	}
+}
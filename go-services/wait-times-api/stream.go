@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-services/shared/realtime"
+	"go-services/shared/response"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamEventName is the SSE event type of a ride state change
+const streamEventName = "ride-update"
+
+// lastEventID returns the id a reconnecting client last received. Browsers send the Last-Event-ID header
+// on automatic reconnects; the last_event_id query parameter covers clients that open a fresh EventSource.
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, response.InvalidField("Last-Event-ID", "Last-Event-ID must be a non-negative integer")
+	}
+	return id, nil
+}
+
+// streamHandler handles GET /v1/stream, pushing a Server-Sent Event for every ride state change.
+// A comment line is sent every heartbeat so proxies keep the connection open. The stream ends when the
+// client disconnects or falls too far behind; clients then reconnect and resume from Last-Event-ID.
+func streamHandler(hub *realtime.Hub, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		after, err := lastEventID(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		sub, replay := hub.Subscribe(after, StreamBufferSize, nil)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: %d\n\n", StreamRetry.Milliseconds())
+		for _, event := range replay {
+			if err := writeStreamEvent(w, event.ID, event.Change); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			logger.ErrorContext(r.Context(), "Streaming not supported by response writer", "error", err)
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if err := writeStreamEvent(w, event.ID, event.Change); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeStreamEvent writes one SSE event with a JSON data line
+func writeStreamEvent(w http.ResponseWriter, id int64, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, streamEventName, payload)
+	return err
+}
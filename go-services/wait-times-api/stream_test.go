@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// sseEvent is one parsed Server-Sent Event; comment lines are collected in comments
+type sseEvent struct {
+	id       string
+	event    string
+	data     string
+	comments []string
+}
+
+// readEvent reads lines until the blank line that ends an event
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return ev
+		case strings.HasPrefix(line, ":"):
+			ev.comments = append(ev.comments, strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "id: "):
+			ev.id = line[len("id: "):]
+		case strings.HasPrefix(line, "event: "):
+			ev.event = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			ev.data = line[len("data: "):]
+		}
+	}
+}
+
+func openStream(t *testing.T, url string, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// The stream opens with the reconnect delay
+	if first := readEvent(t, reader); first.id != "" {
+		t.Fatalf("Expected retry preamble, got %+v", first)
+	}
+	return reader
+}
+
+// waitForSubscribers blocks until the hub has n subscribers so publishes are not lost to a race
+func waitForSubscribers(t *testing.T, hub *realtime.Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d subscribers, got %d", n, hub.Subscribers())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamHandler_DeliversChanges(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	schema := doc.Components.Schemas["RideDataChange"].Value
+
+	hub := realtime.NewHub(10)
+	server := httptest.NewServer(newRouter(&fakeStore{}, hub, nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+
+	reader := openStream(t, server.URL+"/v1/stream", "")
+	waitForSubscribers(t, hub, 1)
+
+	wait := 40
+	hub.Publish(repository.RideDataChange{
+		ID: 12, RideID: testRideID, ParkID: testParkID, Name: "Space Mountain",
+		Status: "OPERATING", WaitTime: &wait, LastUpdated: time.Now().UTC(),
+	})
+
+	ev := readEvent(t, reader)
+	if ev.id != strconv.FormatInt(hub.LastID(), 10) || ev.event != streamEventName {
+		t.Fatalf("Unexpected event %+v", ev)
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(ev.data), &data); err != nil {
+		t.Fatalf("Event data is not JSON: %v", err)
+	}
+	if err := schema.VisitJSON(data, openapi3.VisitAsResponse()); err != nil {
+		t.Errorf("Event data does not match the RideDataChange schema: %v", err)
+	}
+}
+
+func TestStreamHandler_ResumesFromLastEventID(t *testing.T) {
+	hub := realtime.NewHub(10)
+	var eventIDs []string
+	// Row 3 commits before row 2, so the hub numbers it first
+	for _, id := range []int64{1, 3, 2} {
+		hub.Publish(repository.RideDataChange{ID: id, RideID: testRideID})
+		eventIDs = append(eventIDs, strconv.FormatInt(hub.LastID(), 10))
+	}
+
+	server := httptest.NewServer(newRouter(&fakeStore{}, hub, nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+
+	// Resuming after row 3 still replays row 2, which was published later
+	reader := openStream(t, server.URL+"/v1/stream", eventIDs[1])
+	if ev := readEvent(t, reader); ev.id != eventIDs[2] || !strings.Contains(ev.data, `"id":2`) {
+		t.Fatalf("Expected replayed event %s with row 2, got %+v", eventIDs[2], ev)
+	}
+
+	waitForSubscribers(t, hub, 1)
+	hub.Publish(repository.RideDataChange{ID: 4, RideID: testRideID})
+	if ev := readEvent(t, reader); ev.id != strconv.FormatInt(hub.LastID(), 10) {
+		t.Fatalf("Expected the live event, got %+v", ev)
+	}
+}
+
+func TestStreamHandler_Heartbeat(t *testing.T) {
+	hub := realtime.NewHub(10)
+	server := httptest.NewServer(streamHandler(hub, 20*time.Millisecond))
+	t.Cleanup(server.Close)
+
+	reader := openStream(t, server.URL, "")
+	ev := readEvent(t, reader)
+	if len(ev.comments) != 1 || ev.comments[0] != "heartbeat" {
+		t.Fatalf("Expected heartbeat comment, got %+v", ev)
+	}
+}
+
+func TestStreamHandler_EndsWhenHubCloses(t *testing.T) {
+	hub := realtime.NewHub(10)
+	server := httptest.NewServer(streamHandler(hub, time.Minute))
+	t.Cleanup(server.Close)
+
+	reader := openStream(t, server.URL, "")
+	waitForSubscribers(t, hub, 1)
+	hub.Close()
+
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Fatal("Expected the stream to end after the hub closed")
+	}
+}
@@ -1,11 +1,55 @@
 package main
 
-import "time"
+import (
+	"context"
+	"go-services/shared/middleware"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"net/http"
+	"time"
+)
+
+// rideDataStore is the subset of the ride data history repository the handlers read from
+type rideDataStore interface {
+	GetLatestRideDataForAllRides(ctx context.Context) ([]*models.RideDataHistoryRecord, error)
+	GetLatestRideDataForRides(ctx context.Context, rideIDs []string) ([]*models.RideDataHistoryRecord, error)
+	GetRideDataHistorySince(ctx context.Context, since time.Time) ([]*models.RideDataHistoryRecord, error)
+	GetRideDataHistorySinceForRide(ctx context.Context, since time.Time, rideID string) ([]*models.RideDataHistoryRecord, error)
+	PageRideDataHistory(ctx context.Context, filter repository.HistoryFilter, after *repository.HistoryCursor, pageSize int) (repository.HistoryPage, error)
+	QueryRideDataHistory(ctx context.Context, filter repository.HistoryFilter) ([]*models.RideDataHistoryRecord, error)
+	EachRideDataHistory(ctx context.Context, filter repository.HistoryFilter, fn func(*models.RideDataHistoryRecord) error) error
+}
+
+// alertRuleStore is the alert rule repository the /v1/alerts handlers use
+type alertRuleStore interface {
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) (*models.AlertRule, error)
+	ListAlertRules(ctx context.Context, apiKeyID int64) ([]*models.AlertRule, error)
+	GetAlertRule(ctx context.Context, apiKeyID, id int64) (*models.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, apiKeyID, id int64) error
+	ListAlertDeliveries(ctx context.Context, apiKeyID, ruleID int64, limit int) ([]*models.AlertDelivery, error)
+}
 
-// AllowedOrigins contains the list of allowed origins for CORS
-var AllowedOrigins = map[string]bool{
-	"https://disneyland-line-predictor.vercel.app": true, // Production Vercel URL
-	"http://localhost:3000":                        true, // Local development
+// webhookStore is the webhook repository the /v1/webhooks handlers use
+type webhookStore interface {
+	CreateWebhookSubscription(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, apiKeyID int64) ([]*models.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, apiKeyID, id int64) (*models.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, apiKeyID, id int64) error
+	ListWebhookDeadLetters(ctx context.Context, apiKeyID, subscriptionID int64, limit int) ([]*models.WebhookDeadLetter, error)
+	RedeliverWebhookDeadLetter(ctx context.Context, apiKeyID, subscriptionID, id int64) error
+}
+
+// CORSPolicy is the default cross-origin policy. The CORS_* environment variables override it at
+// startup, e.g. CORS_ALLOWED_ORIGINS to add "https://disneyland-line-predictor-*.vercel.app" previews.
+var CORSPolicy = middleware.CORSConfig{
+	AllowedOrigins: []string{
+		"https://disneyland-line-predictor.vercel.app", // Production Vercel URL
+		"http://localhost:3000",                        // Local development
+	},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions},
+	AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "Last-Event-ID"},
+	ExposedHeaders: []string{"X-Request-ID", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Window-Hours"},
+	MaxAge:         10 * time.Minute,
 }
 
 // HealthResponse represents the health check response
@@ -30,6 +74,93 @@ const (
 	RequestTimeout = 30 * time.Second
 )
 
+// Settings for the /v1/stream Server-Sent Events endpoint
+const (
+	// StreamHeartbeatInterval is how often an idle stream sends a keep-alive comment
+	StreamHeartbeatInterval = 15 * time.Second
+	// StreamRetry is the reconnect delay suggested to clients
+	StreamRetry = 5 * time.Second
+	// StreamBacklogSize is how many recent changes are kept for Last-Event-ID resume
+	StreamBacklogSize = 1000
+	// StreamBufferSize is how many changes a client may lag behind before it is disconnected
+	StreamBufferSize = 64
+	// StreamMaxReconnectBackoff caps the delay between attempts to re-establish LISTEN
+	StreamMaxReconnectBackoff = 30 * time.Second
+	// WebSocketWriteTimeout bounds each message and ping written to a /v1/ws client
+	WebSocketWriteTimeout = 10 * time.Second
+	// WebSocketSnapshotTTL is how long /v1/ws connections share one query for their snapshots
+	WebSocketSnapshotTTL = 2 * time.Second
+)
+
+// Settings for the /readyz checks
+const (
+	// DefaultStaleAfterMinutes is how old the newest ride data of a park may get before /readyz
+	// fails. STALE_AFTER_MINUTES overrides it.
+	DefaultStaleAfterMinutes = 30
+	// PoolSaturationWarning is the share of pool connections in use at which the database check warns
+	PoolSaturationWarning = 0.9
+	// ReadinessTimeout bounds each readiness check
+	ReadinessTimeout = 5 * time.Second
+)
+
+// DefaultMaxWindowHours is the largest history window, in hours, a request may ask for
+const DefaultMaxWindowHours = 7 * 24
+
+// MaxWindowHours caps window_hours on every endpoint, including the GraphQL and gRPC windows.
+// MAX_WINDOW_HOURS overrides it at startup.
+var MaxWindowHours = DefaultMaxWindowHours
+
+// Default limits of clients without an API key, tracked per client IP. RATE_LIMIT_ANON_PER_MINUTE,
+// RATE_LIMIT_ANON_BURST and RATE_LIMIT_ANON_DAILY_QUOTA override them at startup; 0 disables a limit.
+const (
+	DefaultAnonRequestsPerMinute = 60
+	DefaultAnonBurst             = 30
+	DefaultAnonDailyQuota        = 5000
+	// APIKeyCacheTTL is how long key lookups are cached, and so how long a revoked key keeps working
+	APIKeyCacheTTL = time.Minute
+)
+
+// Limits of the /graphql endpoint
+const (
+	// GraphQLMaxDepth rejects deeply nested queries such as ride.park.rides.park...
+	GraphQLMaxDepth = 8
+)
+
+// Limits of the /v1/export endpoint
+const (
+	// ExportMaxWindow caps the from/to range of a single export
+	ExportMaxWindow = 31 * 24 * time.Hour
+	// ExportTimeout bounds how long an export may stream, which is far longer than RequestTimeout
+	ExportTimeout = 10 * time.Minute
+)
+
+// Settings of the /v1/alerts endpoints
+const (
+	// DefaultAlertTimezone applies to alert windows without a time zone
+	DefaultAlertTimezone = "America/Los_Angeles"
+	// DefaultAlertCooldownMinutes is how long a rule stays quiet after it fired, unless set
+	DefaultAlertCooldownMinutes = 60
+	// MinAlertCooldownMinutes and MaxAlertCooldownMinutes bound the cooldown of a rule
+	MinAlertCooldownMinutes = 5
+	MaxAlertCooldownMinutes = 7 * 24 * 60
+	// MaxAlertThreshold bounds thresholds to plausible wait times in minutes
+	MaxAlertThreshold = 600
+	// AlertDeliveriesLimit is how many deliveries GET /v1/alerts/{id}/deliveries returns
+	AlertDeliveriesLimit = 100
+)
+
+// Settings of the /v1/webhooks endpoints
+const (
+	// MaxWebhookRideIDs bounds the rides a subscription may be limited to
+	MaxWebhookRideIDs = 100
+	// WebhookDeadLettersLimit is how many dead letters GET /v1/webhooks/{id}/dead-letters returns
+	WebhookDeadLettersLimit = 100
+)
+
+// VAPIDPublicKey is the Web Push application server key served by GET /v1/alerts/push-key. main
+// reads it from VAPID_PUBLIC_KEY; it must match the collector's VAPID_PRIVATE_KEY.
+var VAPIDPublicKey string
+
 // LiveWaitTimeEntry represents the most recent wait time for a ride
 type LiveWaitTimeEntry struct {
 	RideID      string    `json:"rideId"`
@@ -61,9 +192,171 @@ type ParkAtlasEntry struct {
 	Rides    []AttractionAtlasEntry `json:"rides"`
 }
 
-// WaitTimesResponse represents the response structure with live and historical data
+// WaitTimesResponse represents the response structure with live and historical data.
+// NextCursor is only set on paginated requests when more history is available.
 type WaitTimesResponse struct {
 	LiveWaitTime        []LiveWaitTimeEntry           `json:"liveWaitTime"`
 	AttractionAtlas     []ParkAtlasEntry              `json:"attractionAtlas"`
 	GroupedRidesHistory map[string][]RideHistoryEntry `json:"groupedRidesHistory"`
+	NextCursor          string                        `json:"nextCursor,omitempty"`
+}
+
+// ParkResource represents a park in the /v1 API
+type ParkResource struct {
+	ParkID    string `json:"parkId"`
+	ParkName  string `json:"parkName"`
+	RideCount int    `json:"rideCount"`
+}
+
+// ParksResponse is the response of GET /v1/parks
+type ParksResponse struct {
+	Parks []ParkResource `json:"parks"`
+}
+
+// RideResource represents a tracked ride in the /v1 API
+type RideResource struct {
+	RideID   string `json:"rideId"`
+	RideName string `json:"rideName"`
+	ParkID   string `json:"parkId"`
+	ParkName string `json:"parkName"`
+}
+
+// ParkRidesResponse is the response of GET /v1/parks/{id}/rides
+type ParkRidesResponse struct {
+	ParkID   string         `json:"parkId"`
+	ParkName string         `json:"parkName"`
+	Rides    []RideResource `json:"rides"`
+}
+
+// RideDetailResponse is the response of GET /v1/rides/{id}. Live is null until the ride has been collected.
+type RideDetailResponse struct {
+	RideResource
+	Live *LiveWaitTimeEntry `json:"live"`
+}
+
+// RideHistoryResponse is the response of GET /v1/rides/{id}/history, newest first.
+// NextCursor is set when older history is available.
+type RideHistoryResponse struct {
+	RideID     string             `json:"rideId"`
+	History    []RideHistoryEntry `json:"history"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// AlertWindow limits an alert to a daily time range, "HH:MM" in the rule's time zone
+type AlertWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// PushSubscription is a browser PushSubscription as serialized by its toJSON()
+type PushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256DH string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// AlertRuleRequest is the body of POST /v1/alerts. Exactly the target of Channel is set: WebhookURL,
+// Email or PushSubscription.
+type AlertRuleRequest struct {
+	RideID           string            `json:"rideId"`
+	Comparison       string            `json:"comparison"`
+	Threshold        *int              `json:"threshold"`
+	Window           *AlertWindow      `json:"window,omitempty"`
+	Timezone         string            `json:"timezone,omitempty"`
+	ParkHoursOnly    bool              `json:"parkHoursOnly,omitempty"`
+	CooldownMinutes  *int              `json:"cooldownMinutes,omitempty"`
+	Channel          string            `json:"channel"`
+	WebhookURL       string            `json:"webhookUrl,omitempty"`
+	Email            string            `json:"email,omitempty"`
+	PushSubscription *PushSubscription `json:"pushSubscription,omitempty"`
+}
+
+// AlertRuleResource represents an alert rule in the /v1 API. Target is the webhook URL, email
+// address or push endpoint; push keys are never returned.
+type AlertRuleResource struct {
+	ID              int64        `json:"id"`
+	RideID          string       `json:"rideId"`
+	RideName        string       `json:"rideName"`
+	Comparison      string       `json:"comparison"`
+	Threshold       int          `json:"threshold"`
+	Window          *AlertWindow `json:"window,omitempty"`
+	Timezone        string       `json:"timezone"`
+	ParkHoursOnly   bool         `json:"parkHoursOnly"`
+	CooldownMinutes int          `json:"cooldownMinutes"`
+	Channel         string       `json:"channel"`
+	Target          string       `json:"target"`
+	Enabled         bool         `json:"enabled"`
+	LastTriggeredAt *time.Time   `json:"lastTriggeredAt,omitempty"`
+	CreatedAt       time.Time    `json:"createdAt"`
+}
+
+// AlertRulesResponse is the response of GET /v1/alerts
+type AlertRulesResponse struct {
+	Rules []AlertRuleResource `json:"rules"`
+}
+
+// AlertDeliveriesResponse is the response of GET /v1/alerts/{id}/deliveries, newest first
+type AlertDeliveriesResponse struct {
+	Deliveries []*models.AlertDelivery `json:"deliveries"`
+}
+
+// PushKeyResponse is the response of GET /v1/alerts/push-key
+type PushKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// WebhookSubscriptionRequest is the body of POST /v1/webhooks. An empty RideIDs subscribes to every
+// ride.
+type WebhookSubscriptionRequest struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	RideIDs []string `json:"rideIds,omitempty"`
+}
+
+// WebhookSubscriptionResource represents a webhook subscription in the /v1 API. Secret is only set
+// in the response that created the subscription.
+type WebhookSubscriptionResource struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	RideIDs   []string  `json:"rideIds"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookSubscriptionsResponse is the response of GET /v1/webhooks
+type WebhookSubscriptionsResponse struct {
+	Subscriptions []WebhookSubscriptionResource `json:"subscriptions"`
+}
+
+// WebhookDeadLettersResponse is the response of GET /v1/webhooks/{id}/dead-letters, newest first
+type WebhookDeadLettersResponse struct {
+	DeadLetters []*models.WebhookDeadLetter `json:"deadLetters"`
+}
+
+// wsClientMessage is a message sent by a /v1/ws client
+type wsClientMessage struct {
+	Type    string   `json:"type"`
+	RideIDs []string `json:"rideIds"`
+	ParkIDs []string `json:"parkIds"`
+}
+
+// WSSnapshotMessage carries the latest state of the subscribed rides after each subscription
+type WSSnapshotMessage struct {
+	Type  string              `json:"type"`
+	Rides []LiveWaitTimeEntry `json:"rides"`
+}
+
+// WSUpdateMessage carries one change of a subscribed ride
+type WSUpdateMessage struct {
+	Type   string                    `json:"type"`
+	Change repository.RideDataChange `json:"change"`
+}
+
+// WSErrorMessage reports a rejected client message; the connection stays open
+type WSErrorMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
 }
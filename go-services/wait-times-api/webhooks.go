@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// maxWebhookBody bounds the body of POST /v1/webhooks
+const maxWebhookBody = 16 << 10
+
+// webhookPathID parses the path segment name as the id of what
+func webhookPathID(r *http.Request, name, what string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, response.NotFound(fmt.Sprintf("%s %q does not exist", what, r.PathValue(name)))
+	}
+	return id, nil
+}
+
+// webhookStoreError converts repository errors into API errors
+func webhookStoreError(err error, id int64) error {
+	switch {
+	case errors.Is(err, repository.ErrWebhookSubscriptionNotFound):
+		return response.NotFound(fmt.Sprintf("Webhook %d does not exist", id))
+	case errors.Is(err, repository.ErrWebhookDeadLetterNotFound):
+		return response.NotFound("The dead letter does not exist")
+	case errors.Is(err, repository.ErrWebhookSubscriptionLimit):
+		return response.NewError(http.StatusConflict, fmt.Sprintf("An API key may have at most %d webhooks", repository.MaxWebhookSubscriptionsPerKey))
+	}
+	return response.Internal("Failed to access webhooks", err)
+}
+
+func webhookResource(sub *models.WebhookSubscription) WebhookSubscriptionResource {
+	rideIDs := sub.RideIDs
+	if rideIDs == nil {
+		rideIDs = []string{}
+	}
+	return WebhookSubscriptionResource{
+		ID:        sub.ID,
+		URL:       sub.URL,
+		Events:    sub.Events,
+		RideIDs:   rideIDs,
+		CreatedAt: sub.CreatedAt,
+	}
+}
+
+// newWebhookSubscription validates a request and builds the subscription it describes
+func newWebhookSubscription(req *WebhookSubscriptionRequest, apiKeyID int64) (*models.WebhookSubscription, error) {
+	var fields []response.FieldError
+	invalid := func(field, format string, args ...any) {
+		fields = append(fields, response.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	sub := &models.WebhookSubscription{APIKeyID: apiKeyID, URL: req.URL, RideIDs: []string{}}
+	if !validWebhookURL(req.URL) {
+		invalid("url", "url must be an http or https URL")
+	}
+	if len(req.Events) == 0 {
+		invalid("events", "events must list at least one event type")
+	}
+	for _, event := range req.Events {
+		switch {
+		case !slices.Contains(models.WebhookEventTypes, event):
+			invalid("events", "Unknown event type %q", event)
+		case !slices.Contains(sub.Events, event):
+			sub.Events = append(sub.Events, event)
+		}
+	}
+	if len(req.RideIDs) > MaxWebhookRideIDs {
+		invalid("rideIds", "rideIds may list at most %d rides", MaxWebhookRideIDs)
+	}
+	for _, rideID := range req.RideIDs {
+		switch {
+		case !rideTracked(rideID):
+			invalid("rideIds", "Ride %q is not tracked", rideID)
+		case !slices.Contains(sub.RideIDs, rideID):
+			sub.RideIDs = append(sub.RideIDs, rideID)
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, response.Invalid(fields...)
+	}
+	return sub, nil
+}
+
+// rideTracked reports whether rideID is in the tracked catalog
+func rideTracked(rideID string) bool {
+	_, _, ok := shared.FindFilteredRide(rideID)
+	return ok
+}
+
+// listWebhooksHandler handles GET /v1/webhooks
+func listWebhooksHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		subs, err := store.ListWebhookSubscriptions(r.Context(), key.ID)
+		if err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, 0))
+			return
+		}
+		resources := make([]WebhookSubscriptionResource, 0, len(subs))
+		for _, sub := range subs {
+			resources = append(resources, webhookResource(sub))
+		}
+		writePrivateJSON(w, r, http.StatusOK, WebhookSubscriptionsResponse{Subscriptions: resources})
+	}
+}
+
+// createWebhookHandler handles POST /v1/webhooks. The response is the only one that shows the
+// signing secret.
+func createWebhookHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		var req WebhookSubscriptionRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest("The request body must be a webhook subscription in JSON: "+err.Error()))
+			return
+		}
+		sub, err := newWebhookSubscription(&req, key.ID)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		created, err := store.CreateWebhookSubscription(r.Context(), sub)
+		if err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, 0))
+			return
+		}
+		logger.InfoContext(r.Context(), "Created webhook subscription", "subscription_id", created.ID, "api_key_id", key.ID, "events", created.Events)
+		resource := webhookResource(created)
+		resource.Secret = created.Secret
+		w.Header().Set("Location", fmt.Sprintf("/v1/webhooks/%d", created.ID))
+		writePrivateJSON(w, r, http.StatusCreated, resource)
+	}
+}
+
+// getWebhookHandler handles GET /v1/webhooks/{id}
+func getWebhookHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := webhookPathID(r, "id", "Webhook")
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		sub, err := store.GetWebhookSubscription(r.Context(), key.ID, id)
+		if err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, id))
+			return
+		}
+		writePrivateJSON(w, r, http.StatusOK, webhookResource(sub))
+	}
+}
+
+// deleteWebhookHandler handles DELETE /v1/webhooks/{id}
+func deleteWebhookHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := webhookPathID(r, "id", "Webhook")
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		if err := store.DeleteWebhookSubscription(r.Context(), key.ID, id); err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, id))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listWebhookDeadLettersHandler handles GET /v1/webhooks/{id}/dead-letters
+func listWebhookDeadLettersHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := webhookPathID(r, "id", "Webhook")
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		letters, err := store.ListWebhookDeadLetters(r.Context(), key.ID, id, WebhookDeadLettersLimit)
+		if err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, id))
+			return
+		}
+		writePrivateJSON(w, r, http.StatusOK, WebhookDeadLettersResponse{DeadLetters: letters})
+	}
+}
+
+// redeliverWebhookHandler handles POST /v1/webhooks/{id}/dead-letters/{letterId}/redeliver, which
+// queues a dead letter again
+func redeliverWebhookHandler(store webhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := requireAPIKey(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		id, err := webhookPathID(r, "id", "Webhook")
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		letterID, err := webhookPathID(r, "letterId", "Dead letter")
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		if err := store.RedeliverWebhookDeadLetter(r.Context(), key.ID, id, letterID); err != nil {
+			response.WriteProblem(w, r, webhookStoreError(err, id))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-services/shared"
+	"go-services/shared/models"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"go-services/shared/response"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// rideSubscription selects the tracked rides a WebSocket client receives. A ride matches when it is
+// listed or belongs to a listed park; an empty subscription matches every tracked ride.
+type rideSubscription struct {
+	rides map[string]bool
+	parks map[string]bool
+}
+
+// newRideSubscription validates the requested ride and park IDs against the tracked catalog
+func newRideSubscription(rideIDs, parkIDs []string) (*rideSubscription, error) {
+	sub := &rideSubscription{rides: make(map[string]bool), parks: make(map[string]bool)}
+	for _, id := range rideIDs {
+		if _, _, ok := shared.FindFilteredRide(id); !ok {
+			return nil, fmt.Errorf("unknown ride %q", id)
+		}
+		sub.rides[id] = true
+	}
+	for _, id := range parkIDs {
+		if _, ok := shared.GetParkInfo(id); !ok {
+			return nil, fmt.Errorf("unknown park %q", id)
+		}
+		sub.parks[id] = true
+	}
+	return sub, nil
+}
+
+// matches reports whether a ride is tracked and selected by the subscription
+func (s *rideSubscription) matches(parkID, rideID string) bool {
+	if !shared.IsRideFiltered(parkID, rideID) {
+		return false
+	}
+	if len(s.rides) == 0 && len(s.parks) == 0 {
+		return true
+	}
+	return s.rides[rideID] || s.parks[parkID]
+}
+
+// snapshotCache shares the latest state of every ride between WebSocket connections, so a burst of
+// connections or resubscriptions costs one query per ttl. Each snapshot remembers the last hub event
+// published before its query; subscribing after that event replays the changes the snapshot may miss.
+type snapshotCache struct {
+	repo rideDataStore
+	hub  *realtime.Hub
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	records []*models.RideDataHistoryRecord
+	afterID int64
+	taken   time.Time
+}
+
+func newSnapshotCache(repo rideDataStore, hub *realtime.Hub, ttl time.Duration) *snapshotCache {
+	return &snapshotCache{repo: repo, hub: hub, ttl: ttl}
+}
+
+// get returns the latest record of every ride and the hub event id it is current to. The database is
+// queried once the cached snapshot is older than ttl, and concurrent callers wait for that one query.
+func (s *snapshotCache) get(ctx context.Context) ([]*models.RideDataHistoryRecord, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.taken.IsZero() && time.Since(s.taken) < s.ttl {
+		return s.records, s.afterID, nil
+	}
+
+	taken := time.Now()
+	afterID := s.hub.LastID()
+	// Other connections wait for the query, so it outlives a client that disconnects meanwhile
+	queryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), RequestTimeout)
+	defer cancel()
+	records, err := s.repo.GetLatestRideDataForAllRides(queryCtx)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.records, s.afterID, s.taken = records, afterID, taken
+	return records, afterID, nil
+}
+
+// wsConn serves one WebSocket client. Only the goroutine running serve uses its fields and writes to
+// the connection.
+type wsConn struct {
+	conn      *websocket.Conn
+	hub       *realtime.Hub
+	snapshots *snapshotCache
+	sub       *realtime.Subscription
+	delivered map[string]time.Time
+}
+
+// websocketHandler handles GET /v1/ws. Clients pick rides with repeated ride_id and park_id query
+// parameters or by sending {"type":"subscribe","rideIds":[...],"parkIds":[...]}, which replaces the
+// current selection. Every (re)subscription is answered with a snapshot of the latest state of the
+// selected rides, followed by an update message for each change. Snapshots are shared between
+// connections for WebSocketSnapshotTTL.
+//
+// Changes reach the connection through a bounded hub subscription, so a client that cannot keep up is
+// disconnected with status 1013 (try again later) instead of slowing the notification path.
+//
+// Browsers may connect from the page's own origin or any origin allowOrigin accepts, the same
+// origins CORS allows.
+func websocketHandler(repo rideDataStore, hub *realtime.Hub, heartbeat time.Duration, allowOrigin func(string) bool) http.HandlerFunc {
+	snapshots := newSnapshotCache(repo, hub, WebSocketSnapshotTTL)
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		initial, err := newRideSubscription(query["ride_id"], query["park_id"])
+		if err != nil {
+			response.WriteProblem(w, r, response.NotFound(err.Error()))
+			return
+		}
+
+		// Without the skip, Accept still allows same-origin and non-browser clients
+		opts := &websocket.AcceptOptions{InsecureSkipVerify: allowOrigin(r.Header.Get("Origin"))}
+		conn, err := websocket.Accept(w, r, opts)
+		if err != nil {
+			logger.WarnContext(r.Context(), "Failed to accept WebSocket connection", "error", err)
+			return
+		}
+		defer conn.CloseNow()
+
+		c := &wsConn{conn: conn, hub: hub, snapshots: snapshots}
+		if err := c.serve(r.Context(), initial, heartbeat); err != nil {
+			logger.DebugContext(r.Context(), "WebSocket connection ended", "error", err)
+		}
+	}
+}
+
+func (c *wsConn) serve(ctx context.Context, initial *rideSubscription, heartbeat time.Duration) error {
+	defer func() {
+		if c.sub != nil {
+			c.sub.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	requests := make(chan wsClientMessage)
+	go c.readLoop(ctx, requests)
+
+	if err := c.subscribe(ctx, initial); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-requests:
+			if !ok {
+				return nil
+			}
+			if err := c.handleMessage(ctx, msg); err != nil {
+				return err
+			}
+		case event, ok := <-c.sub.Events():
+			if !ok {
+				c.conn.Close(websocket.StatusTryAgainLater, "subscription ended, reconnect")
+				return fmt.Errorf("subscription dropped")
+			}
+			if err := c.sendUpdate(ctx, event.Change); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			pingCtx, cancelPing := context.WithTimeout(ctx, WebSocketWriteTimeout)
+			err := c.conn.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return fmt.Errorf("ping failed: %w", err)
+			}
+		}
+	}
+}
+
+// readLoop forwards client messages until the connection closes. It also keeps control frames
+// such as pong replies flowing.
+func (c *wsConn) readLoop(ctx context.Context, requests chan<- wsClientMessage) {
+	defer close(requests)
+	for {
+		typ, data, err := c.conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var msg wsClientMessage
+		if typ != websocket.MessageText || json.Unmarshal(data, &msg) != nil {
+			msg = wsClientMessage{Type: "invalid"}
+		}
+		select {
+		case requests <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) handleMessage(ctx context.Context, msg wsClientMessage) error {
+	if msg.Type != "subscribe" {
+		return c.write(ctx, WSErrorMessage{Type: "error", Message: `expected a message of type "subscribe"`})
+	}
+	selection, err := newRideSubscription(msg.RideIDs, msg.ParkIDs)
+	if err != nil {
+		return c.write(ctx, WSErrorMessage{Type: "error", Message: err.Error()})
+	}
+	return c.subscribe(ctx, selection)
+}
+
+// subscribe switches the connection to selection. It sends a snapshot of the selected rides, then
+// replaces the hub subscription with one that replays every change published since the snapshot's
+// query, so no change falls between the two.
+func (c *wsConn) subscribe(ctx context.Context, selection *rideSubscription) error {
+	latest, afterID, err := c.snapshots.get(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get latest ride data for WebSocket snapshot", "error", err)
+		afterID = c.hub.LastID()
+	}
+
+	if c.sub != nil {
+		c.sub.Close()
+	}
+	var replay []realtime.Event
+	c.sub, replay = c.hub.Subscribe(afterID, StreamBufferSize, func(change repository.RideDataChange) bool {
+		return selection.matches(change.ParkID, change.RideID)
+	})
+	if err != nil {
+		return c.write(ctx, WSErrorMessage{Type: "error", Message: "Failed to retrieve ride data"})
+	}
+
+	snapshot := WSSnapshotMessage{Type: "snapshot", Rides: make([]LiveWaitTimeEntry, 0)}
+	c.delivered = make(map[string]time.Time)
+	for _, record := range latest {
+		if selection.matches(record.ParkID, record.RideID) {
+			snapshot.Rides = append(snapshot.Rides, *liveEntry(record))
+			c.delivered[record.RideID] = record.LastUpdated
+		}
+	}
+	if err := c.write(ctx, snapshot); err != nil {
+		return err
+	}
+	for _, event := range replay {
+		if err := c.sendUpdate(ctx, event.Change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendUpdate forwards a change unless the snapshot already covered it
+func (c *wsConn) sendUpdate(ctx context.Context, change repository.RideDataChange) error {
+	if last, ok := c.delivered[change.RideID]; ok && !change.LastUpdated.After(last) {
+		return nil
+	}
+	c.delivered[change.RideID] = change.LastUpdated
+	return c.write(ctx, WSUpdateMessage{Type: "update", Change: change})
+}
+
+func (c *wsConn) write(ctx context.Context, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, WebSocketWriteTimeout)
+	defer cancel()
+	if err := c.conn.Write(writeCtx, websocket.MessageText, data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
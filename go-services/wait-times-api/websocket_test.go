@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go-services/shared/models"
+	"go-services/shared/realtime"
+	"go-services/shared/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const otherParkRideID = "c60c768b-3461-465c-8f4f-b44b087506fc" // Radiator Springs Racers
+
+type wsTestClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialWS(t *testing.T, hub *realtime.Hub, store rideDataStore, query string) *wsTestClient {
+	t.Helper()
+	server := httptest.NewServer(newRouter(store, hub, nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+	return dialWSServer(t, server, query)
+}
+
+func dialWSServer(t *testing.T, server *httptest.Server, query string) *wsTestClient {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/v1/ws"+query, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return &wsTestClient{t: t, conn: conn}
+}
+
+// next reads one message and decodes it into v, returning its type
+func (c *wsTestClient) next(v any) string {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, data, err := c.conn.Read(ctx)
+	if err != nil {
+		c.t.Fatalf("Failed to read message: %v", err)
+	}
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		c.t.Fatalf("Invalid message %s: %v", data, err)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			c.t.Fatalf("Failed to decode %s: %v", data, err)
+		}
+	}
+	return envelope.Type
+}
+
+func (c *wsTestClient) send(msg string) {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.conn.Write(ctx, websocket.MessageText, []byte(msg)); err != nil {
+		c.t.Fatalf("Failed to send: %v", err)
+	}
+}
+
+func snapshotStore(now time.Time) *fakeStore {
+	other := testRecord(5, 60, now)
+	other.RideID = otherParkRideID
+	other.ParkID = "832fcd51-ea19-4e77-85c7-75d5843b127c"
+	other.Name = "Radiator Springs Racers"
+	untracked := testRecord(6, 5, now)
+	untracked.RideID = "not-tracked"
+	return &fakeStore{records: []*models.RideDataHistoryRecord{testRecord(1, 30, now), other, untracked}}
+}
+
+func TestWebSocket_SnapshotAndUpdates(t *testing.T) {
+	now := time.Now().UTC()
+	hub := realtime.NewHub(10)
+	client := dialWS(t, hub, snapshotStore(now), "?ride_id="+testRideID)
+
+	var snapshot WSSnapshotMessage
+	if typ := client.next(&snapshot); typ != "snapshot" {
+		t.Fatalf("Expected snapshot, got %s", typ)
+	}
+	if len(snapshot.Rides) != 1 || snapshot.Rides[0].RideID != testRideID {
+		t.Fatalf("Expected a snapshot of the subscribed ride, got %+v", snapshot.Rides)
+	}
+
+	waitForSubscribers(t, hub, 1)
+	wait := 50
+	// Unsubscribed ride, a change the snapshot already covered, then a genuine update
+	hub.Publish(repository.RideDataChange{ID: 7, RideID: otherParkRideID, ParkID: "832fcd51-ea19-4e77-85c7-75d5843b127c", LastUpdated: now.Add(time.Minute)})
+	hub.Publish(repository.RideDataChange{ID: 1, RideID: testRideID, ParkID: testParkID, LastUpdated: now})
+	hub.Publish(repository.RideDataChange{ID: 8, RideID: testRideID, ParkID: testParkID, WaitTime: &wait, LastUpdated: now.Add(time.Minute)})
+
+	var update WSUpdateMessage
+	if typ := client.next(&update); typ != "update" {
+		t.Fatalf("Expected update, got %s", typ)
+	}
+	if update.Change.ID != 8 || *update.Change.WaitTime != 50 {
+		t.Errorf("Unexpected update %+v", update.Change)
+	}
+}
+
+// countingStore counts the queries for the latest state of every ride
+type countingStore struct {
+	*fakeStore
+	latestQueries atomic.Int32
+}
+
+func (s *countingStore) GetLatestRideDataForAllRides(ctx context.Context) ([]*models.RideDataHistoryRecord, error) {
+	s.latestQueries.Add(1)
+	return s.fakeStore.GetLatestRideDataForAllRides(ctx)
+}
+
+func TestWebSocket_SharedSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+	hub := realtime.NewHub(10)
+	store := &countingStore{fakeStore: snapshotStore(now)}
+	server := httptest.NewServer(newRouter(store, hub, nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+
+	first := dialWSServer(t, server, "?ride_id="+testRideID)
+	first.next(nil)
+
+	// A change after the cached snapshot's query still reaches a client that connects later
+	wait := 45
+	hub.Publish(repository.RideDataChange{ID: 9, RideID: testRideID, ParkID: testParkID, WaitTime: &wait, LastUpdated: now.Add(time.Minute)})
+
+	second := dialWSServer(t, server, "?ride_id="+testRideID)
+	var snapshot WSSnapshotMessage
+	if typ := second.next(&snapshot); typ != "snapshot" || len(snapshot.Rides) != 1 || *snapshot.Rides[0].WaitTime != 30 {
+		t.Fatalf("Expected the cached snapshot, got %s %+v", typ, snapshot.Rides)
+	}
+	var update WSUpdateMessage
+	if typ := second.next(&update); typ != "update" || update.Change.ID != 9 {
+		t.Fatalf("Expected the change published after the snapshot, got %s %+v", typ, update.Change)
+	}
+
+	if n := store.latestQueries.Load(); n != 1 {
+		t.Errorf("Expected the connections to share one snapshot query, got %d", n)
+	}
+}
+
+func TestWebSocket_Resubscribe(t *testing.T) {
+	now := time.Now().UTC()
+	hub := realtime.NewHub(10)
+	client := dialWS(t, hub, snapshotStore(now), "")
+
+	var snapshot WSSnapshotMessage
+	client.next(&snapshot)
+	if len(snapshot.Rides) != 2 {
+		t.Fatalf("Expected every tracked ride in the snapshot, got %+v", snapshot.Rides)
+	}
+
+	client.send(`{"type":"subscribe","parkIds":["832fcd51-ea19-4e77-85c7-75d5843b127c"]}`)
+	if typ := client.next(&snapshot); typ != "snapshot" {
+		t.Fatalf("Expected snapshot, got %s", typ)
+	}
+	if len(snapshot.Rides) != 1 || snapshot.Rides[0].RideID != otherParkRideID {
+		t.Fatalf("Expected a snapshot of the park, got %+v", snapshot.Rides)
+	}
+
+	var errMsg WSErrorMessage
+	client.send(`{"type":"subscribe","rideIds":["nope"]}`)
+	if typ := client.next(&errMsg); typ != "error" || !strings.Contains(errMsg.Message, "nope") {
+		t.Fatalf("Expected error for unknown ride, got %s %+v", typ, errMsg)
+	}
+	client.send(`not json`)
+	if typ := client.next(nil); typ != "error" {
+		t.Fatalf("Expected error for invalid message, got %s", typ)
+	}
+}
+
+func TestWebSocket_ClosesWhenSubscriptionEnds(t *testing.T) {
+	hub := realtime.NewHub(10)
+	client := dialWS(t, hub, &fakeStore{}, "")
+	client.next(nil)
+
+	waitForSubscribers(t, hub, 1)
+	hub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _, err := client.conn.Read(ctx)
+	if status := websocket.CloseStatus(err); status != websocket.StatusTryAgainLater {
+		t.Fatalf("Expected close status %d, got %d (%v)", websocket.StatusTryAgainLater, status, err)
+	}
+}
+
+func TestWebSocket_RejectsUnknownRide(t *testing.T) {
+	w := serve(t, &fakeStore{}, "GET", "/v1/ws?ride_id=nope")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestWebSocket_Origins(t *testing.T) {
+	server := httptest.NewServer(newRouter(&fakeStore{}, closedHub(), nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"http://localhost:3000", true},
+		{server.URL, true},
+		{"https://malicious-site.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, resp, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/v1/ws", &websocket.DialOptions{
+				HTTPHeader: http.Header{"Origin": {tt.origin}},
+			})
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Expected origin %s to connect: %v", tt.origin, err)
+				}
+				conn.CloseNow()
+				return
+			}
+			if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected origin %s to be rejected with 403, got %v", tt.origin, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+  Converts ride_data_history into a table range-partitioned by calendar month of last_updated.
+
+  - The primary key becomes (id, last_updated) because PostgreSQL requires the partition key
+    in every unique constraint. id is still assigned from the existing sequence, so ids stay unique.
+  - The (ride_id, last_updated) unique index is recreated on the partitioned parent and therefore
+    enforced across all partitions.
+  - Partitions are created for every month that has data plus the next three months. After this
+    the live-data-collector creates upcoming partitions itself on every collection run.
+*/
+
+-- Move the existing table out of the way
+ALTER TABLE "public"."ride_data_history" RENAME TO "ride_data_history_legacy";
+ALTER TABLE "public"."ride_data_history_legacy" RENAME CONSTRAINT "ride_data_history_pkey" TO "ride_data_history_legacy_pkey";
+
+-- DropIndex
+DROP INDEX "public"."ride_data_history_park_id_idx";
+DROP INDEX "public"."ride_data_history_entity_type_idx";
+DROP INDEX "public"."ride_data_history_status_idx";
+DROP INDEX "public"."ride_data_history_last_updated_idx";
+DROP INDEX "public"."ride_data_history_ride_id_idx";
+DROP INDEX "public"."ride_data_history_external_id_idx";
+DROP INDEX "public"."ride_data_history_ride_id_last_updated_key";
+
+-- Keep the id sequence alive when the legacy table is dropped
+ALTER SEQUENCE "public"."ride_data_history_id_seq" OWNED BY NONE;
+
+-- CreateTable
+CREATE TABLE "public"."ride_data_history" (
+    "id" BIGINT NOT NULL DEFAULT nextval('"public"."ride_data_history_id_seq"'),
+    "external_id" TEXT NOT NULL,
+    "park_id" TEXT NOT NULL,
+    "entity_type" TEXT NOT NULL,
+    "name" TEXT NOT NULL,
+    "status" TEXT NOT NULL,
+    "last_updated" TIMESTAMP(3) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+    "standby_wait_time" INTEGER,
+    "return_time_state" TEXT,
+    "return_start" TIMESTAMP(3),
+    "return_end" TIMESTAMP(3),
+    "ride_id" TEXT NOT NULL,
+    "operating_hours" JSONB NOT NULL DEFAULT '[]',
+    "forecast" JSONB NOT NULL DEFAULT '[]',
+    CONSTRAINT "ride_data_history_pkey" PRIMARY KEY ("id", "last_updated")
+) PARTITION BY RANGE ("last_updated");
+
+ALTER SEQUENCE "public"."ride_data_history_id_seq" OWNED BY "public"."ride_data_history"."id";
+
+-- CreateIndex
+CREATE INDEX "ride_data_history_park_id_idx" ON "public"."ride_data_history"("park_id");
+CREATE INDEX "ride_data_history_entity_type_idx" ON "public"."ride_data_history"("entity_type");
+CREATE INDEX "ride_data_history_status_idx" ON "public"."ride_data_history"("status");
+CREATE INDEX "ride_data_history_last_updated_idx" ON "public"."ride_data_history"("last_updated");
+CREATE INDEX "ride_data_history_ride_id_idx" ON "public"."ride_data_history"("ride_id");
+CREATE INDEX "ride_data_history_external_id_idx" ON "public"."ride_data_history"("external_id");
+CREATE UNIQUE INDEX "ride_data_history_ride_id_last_updated_key" ON "public"."ride_data_history"("ride_id", "last_updated");
+
+-- Create one partition per month, from the oldest row through three months from now
+DO $$
+DECLARE
+    month_start DATE;
+    last_month DATE;
+BEGIN
+    SELECT COALESCE(date_trunc('month', MIN("last_updated")), date_trunc('month', now() AT TIME ZONE 'UTC'))::DATE,
+           (date_trunc('month', GREATEST(MAX("last_updated"), now() AT TIME ZONE 'UTC')) + INTERVAL '3 months')::DATE
+    INTO month_start, last_month
+    FROM "public"."ride_data_history_legacy";
+
+    IF last_month IS NULL THEN
+        last_month := (date_trunc('month', now() AT TIME ZONE 'UTC') + INTERVAL '3 months')::DATE;
+    END IF;
+
+    WHILE month_start <= last_month LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS "public".%I PARTITION OF "public"."ride_data_history" FOR VALUES FROM (%L) TO (%L)',
+            'ride_data_history_' || to_char(month_start, 'YYYY_MM'),
+            month_start,
+            (month_start + INTERVAL '1 month')::DATE
+        );
+        month_start := (month_start + INTERVAL '1 month')::DATE;
+    END LOOP;
+END $$;
+
+-- Move existing rows, keeping their ids
+INSERT INTO "public"."ride_data_history" (
+    "id", "external_id", "park_id", "entity_type", "name", "status", "last_updated",
+    "created_at", "updated_at", "standby_wait_time", "return_time_state", "return_start",
+    "return_end", "ride_id", "operating_hours", "forecast"
+)
+SELECT
+    "id", "external_id", "park_id", "entity_type", "name", "status", "last_updated",
+    "created_at", "updated_at", "standby_wait_time", "return_time_state", "return_start",
+    "return_end", "ride_id", "operating_hours", "forecast"
+FROM "public"."ride_data_history_legacy";
+
+-- DropTable
+DROP TABLE "public"."ride_data_history_legacy";
@@ -0,0 +1,3 @@
+-- CreateIndex
+-- Supports keyset pagination of history newest first on (last_updated, id)
+CREATE INDEX "ride_data_history_last_updated_id_idx" ON "public"."ride_data_history"("last_updated" DESC, "id" DESC);
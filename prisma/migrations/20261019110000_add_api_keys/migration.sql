@@ -0,0 +1,32 @@
+-- CreateTable
+-- API keys let third-party clients call the wait-times-api directly. Only the SHA-256 hash of a key
+-- is stored; the prefix identifies a key in logs and listings without revealing it.
+CREATE TABLE "public"."api_keys" (
+    "id" BIGSERIAL NOT NULL,
+    "name" TEXT NOT NULL,
+    "prefix" TEXT NOT NULL,
+    "key_hash" TEXT NOT NULL,
+    "requests_per_minute" INTEGER NOT NULL,
+    "burst" INTEGER NOT NULL,
+    "daily_quota" INTEGER NOT NULL DEFAULT 0,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "revoked_at" TIMESTAMP(3),
+
+    CONSTRAINT "api_keys_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+-- Requests per key and UTC day, counted against the key's daily quota
+CREATE TABLE "public"."api_key_usage" (
+    "api_key_id" BIGINT NOT NULL,
+    "day" DATE NOT NULL,
+    "requests" BIGINT NOT NULL DEFAULT 0,
+
+    CONSTRAINT "api_key_usage_pkey" PRIMARY KEY ("api_key_id", "day")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "api_keys_key_hash_key" ON "public"."api_keys"("key_hash");
+
+-- AddForeignKey
+ALTER TABLE "public"."api_key_usage" ADD CONSTRAINT "api_key_usage_api_key_id_fkey" FOREIGN KEY ("api_key_id") REFERENCES "public"."api_keys"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,17 @@
+-- CreateTable
+-- Periods in which a park's ride data went stale during operating hours. The collector's watchdog
+-- opens an incident, alerts once and sends a recovery notice when fresh data arrives again.
+CREATE TABLE "public"."staleness_incidents" (
+    "id" BIGSERIAL NOT NULL,
+    "park_id" TEXT NOT NULL,
+    "last_data_at" TIMESTAMP(3),
+    "opened_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "notified_at" TIMESTAMP(3),
+    "resolved_at" TIMESTAMP(3),
+
+    CONSTRAINT "staleness_incidents_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+-- At most one open incident per park, so concurrent collector instances alert once
+CREATE UNIQUE INDEX "staleness_incidents_open_park_key" ON "public"."staleness_incidents"("park_id") WHERE "resolved_at" IS NULL;
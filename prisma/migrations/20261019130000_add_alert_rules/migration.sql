@@ -0,0 +1,55 @@
+-- CreateTable
+-- Wait time alerts registered by API key holders. The collector evaluates the enabled rules of each
+-- ride it stores data for and delivers matches to the rule's channel: a webhook URL, an email address
+-- or a Web Push subscription. window_start and window_end are minutes after midnight in timezone.
+CREATE TABLE "public"."alert_rules" (
+    "id" BIGSERIAL NOT NULL,
+    "api_key_id" BIGINT NOT NULL,
+    "ride_id" TEXT NOT NULL,
+    "comparison" TEXT NOT NULL,
+    "threshold" INTEGER NOT NULL,
+    "window_start" INTEGER,
+    "window_end" INTEGER,
+    "timezone" TEXT NOT NULL,
+    "park_hours_only" BOOLEAN NOT NULL DEFAULT false,
+    "cooldown_minutes" INTEGER NOT NULL,
+    "channel" TEXT NOT NULL,
+    "target" TEXT NOT NULL,
+    "push_p256dh" TEXT,
+    "push_auth" TEXT,
+    "enabled" BOOLEAN NOT NULL DEFAULT true,
+    "last_triggered_at" TIMESTAMP(3),
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "alert_rules_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+-- Every attempt to deliver a triggered alert, successful or not
+CREATE TABLE "public"."alert_deliveries" (
+    "id" BIGSERIAL NOT NULL,
+    "rule_id" BIGINT NOT NULL,
+    "channel" TEXT NOT NULL,
+    "status" TEXT NOT NULL,
+    "error" TEXT,
+    "ride_id" TEXT NOT NULL,
+    "wait_time" INTEGER,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "alert_deliveries_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "alert_rules_api_key_id_idx" ON "public"."alert_rules"("api_key_id");
+
+-- CreateIndex
+CREATE INDEX "alert_rules_ride_id_idx" ON "public"."alert_rules"("ride_id") WHERE "enabled";
+
+-- CreateIndex
+CREATE INDEX "alert_deliveries_rule_id_created_at_idx" ON "public"."alert_deliveries"("rule_id", "created_at" DESC);
+
+-- AddForeignKey
+ALTER TABLE "public"."alert_rules" ADD CONSTRAINT "alert_rules_api_key_id_fkey" FOREIGN KEY ("api_key_id") REFERENCES "public"."api_keys"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."alert_deliveries" ADD CONSTRAINT "alert_deliveries_rule_id_fkey" FOREIGN KEY ("rule_id") REFERENCES "public"."alert_rules"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,70 @@
+-- CreateTable
+-- Outgoing webhook subscriptions of API key holders. Each subscribes a URL to ride state change
+-- events, optionally only for some rides (empty ride_ids means every ride). secret signs the payloads.
+CREATE TABLE "public"."webhook_subscriptions" (
+    "id" BIGSERIAL NOT NULL,
+    "api_key_id" BIGINT NOT NULL,
+    "url" TEXT NOT NULL,
+    "secret" TEXT NOT NULL,
+    "events" TEXT[] NOT NULL,
+    "ride_ids" TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[],
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "webhook_subscriptions_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+-- Events waiting to be delivered to a subscription. The collector claims due rows, deletes them once
+-- delivered and otherwise schedules the next attempt with exponential backoff.
+CREATE TABLE "public"."webhook_deliveries" (
+    "id" BIGSERIAL NOT NULL,
+    "subscription_id" BIGINT NOT NULL,
+    "event_id" TEXT NOT NULL,
+    "event_type" TEXT NOT NULL,
+    "payload" JSONB NOT NULL,
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "next_attempt_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_error" TEXT,
+    "last_status" INTEGER,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "webhook_deliveries_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+-- Events whose delivery failed on every attempt, kept for inspection and redelivery
+CREATE TABLE "public"."webhook_dead_letters" (
+    "id" BIGSERIAL NOT NULL,
+    "subscription_id" BIGINT NOT NULL,
+    "event_id" TEXT NOT NULL,
+    "event_type" TEXT NOT NULL,
+    "payload" JSONB NOT NULL,
+    "attempts" INTEGER NOT NULL,
+    "last_error" TEXT,
+    "last_status" INTEGER,
+    "created_at" TIMESTAMP(3) NOT NULL,
+    "failed_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "webhook_dead_letters_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "webhook_subscriptions_api_key_id_idx" ON "public"."webhook_subscriptions"("api_key_id");
+
+-- CreateIndex
+CREATE INDEX "webhook_deliveries_next_attempt_at_idx" ON "public"."webhook_deliveries"("next_attempt_at");
+
+-- CreateIndex
+CREATE INDEX "webhook_deliveries_subscription_id_idx" ON "public"."webhook_deliveries"("subscription_id");
+
+-- CreateIndex
+CREATE INDEX "webhook_dead_letters_subscription_id_failed_at_idx" ON "public"."webhook_dead_letters"("subscription_id", "failed_at" DESC);
+
+-- AddForeignKey
+ALTER TABLE "public"."webhook_subscriptions" ADD CONSTRAINT "webhook_subscriptions_api_key_id_fkey" FOREIGN KEY ("api_key_id") REFERENCES "public"."api_keys"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."webhook_deliveries" ADD CONSTRAINT "webhook_deliveries_subscription_id_fkey" FOREIGN KEY ("subscription_id") REFERENCES "public"."webhook_subscriptions"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."webhook_dead_letters" ADD CONSTRAINT "webhook_dead_letters_subscription_id_fkey" FOREIGN KEY ("subscription_id") REFERENCES "public"."webhook_subscriptions"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,26 @@
+-- CreateTable
+-- Triggered alerts waiting to be delivered. The collector queues a row when it claims a rule's
+-- cooldown, and a worker claims due rows, logs each attempt in alert_deliveries and deletes the row
+-- once it is delivered or out of attempts, otherwise scheduling the next attempt with backoff.
+CREATE TABLE "public"."alert_queue" (
+    "id" BIGSERIAL NOT NULL,
+    "rule_id" BIGINT NOT NULL,
+    "ride_id" TEXT NOT NULL,
+    "wait_time" INTEGER,
+    "message" JSONB NOT NULL,
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "next_attempt_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_error" TEXT,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "alert_queue_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "alert_queue_next_attempt_at_idx" ON "public"."alert_queue"("next_attempt_at");
+
+-- CreateIndex
+CREATE INDEX "alert_queue_rule_id_idx" ON "public"."alert_queue"("rule_id");
+
+-- AddForeignKey
+ALTER TABLE "public"."alert_queue" ADD CONSTRAINT "alert_queue_rule_id_fkey" FOREIGN KEY ("rule_id") REFERENCES "public"."alert_rules"("id") ON DELETE CASCADE ON UPDATE CASCADE;
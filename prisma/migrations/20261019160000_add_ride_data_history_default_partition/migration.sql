@@ -0,0 +1,12 @@
+/*
+  Adds a DEFAULT partition to ride_data_history so rows outside every monthly partition are kept
+  instead of failing the insert. This covers restored archives of months whose partition the
+  retention job dropped, and data arriving for a month the collector has not created yet.
+
+  The collector creates each monthly partition detached, moves any rows of that month out of the
+  DEFAULT partition into it and then attaches it, because PostgreSQL refuses to add a partition
+  while the DEFAULT partition holds rows in its range.
+*/
+
+-- CreateTable
+CREATE TABLE IF NOT EXISTS "public"."ride_data_history_default" PARTITION OF "public"."ride_data_history" DEFAULT;
@@ -31,6 +31,13 @@ const RATE_LIMIT_WINDOW_MS = 60 * 1000;
 const RATE_LIMIT_MAX = 60; // requests per IP per window
 const rateBuckets = new Map<string, { count: number; resetAt: number }>();
 
+// Every proxied request comes from this server, so identify it to the API with a key when one is
+// configured; otherwise all visitors would share the API's anonymous per-IP limits.
+function apiKeyHeader(): Record<string, string> {
+    const key = process.env.WAIT_TIMES_API_KEY?.trim();
+    return key ? { 'X-API-Key': key } : {};
+}
+
 function getClientIp(request: NextRequest): string {
     const forwarded = request.headers.get('x-forwarded-for');
     return forwarded?.split(',')[0]?.trim() || 'unknown';
@@ -120,6 +127,7 @@ export async function GET(request: NextRequest) {
             headers: {
                 'Accept-Encoding': 'gzip',
                 'User-Agent': 'Disneyland-Line-Predictor/1.0',
+                ...apiKeyHeader(),
             },
             next: { revalidate: CACHE_MAX_AGE_S },
         }, FETCH_TIMEOUT_MS);
@@ -134,6 +142,7 @@ export async function GET(request: NextRequest) {
                     'Content-Type': 'application/json',
                     'Accept-Encoding': 'gzip',
                     'User-Agent': 'Disneyland-Line-Predictor/1.0',
+                    ...apiKeyHeader(),
                 },
                 // POST requests are not cached by Next.js Data Cache by default
                 cache: 'no-store',
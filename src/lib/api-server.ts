@@ -21,6 +21,9 @@ export async function getWaitTimesData(rideId?: string, windowHours?: number): P
         console.log(`Server-side fetching wait times from: ${url.toString()} (Method: GET)`);
     }
 
+    // All server-side traffic comes from one IP, so identify it with an API key when one is configured
+    const apiKeyHeader: Record<string, string> = config.WAIT_TIMES_API_KEY ? { 'X-API-Key': config.WAIT_TIMES_API_KEY } : {};
+
     try {
         // Try GET first
         let response = await fetch(url.toString(), {
@@ -28,6 +31,7 @@ export async function getWaitTimesData(rideId?: string, windowHours?: number): P
             headers: {
                 'Accept-Encoding': 'gzip',
                 'User-Agent': 'Disneyland-Line-Predictor/Server',
+                ...apiKeyHeader,
             },
             // Revalidate every 30 seconds on the server
             next: { revalidate: 30 }
@@ -42,6 +46,7 @@ export async function getWaitTimesData(rideId?: string, windowHours?: number): P
                     'Content-Type': 'application/json',
                     'Accept-Encoding': 'gzip',
                     'User-Agent': 'Disneyland-Line-Predictor/Server',
+                    ...apiKeyHeader,
                 },
                 cache: 'no-store'
             });
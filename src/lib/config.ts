@@ -22,6 +22,8 @@ const environmentSchema = z.object({
     WAIT_TIMES_API_URL: z.string().url('WAIT_TIMES_API_URL must be a valid URL'),
 
     // Optional variables
+    // Sent as X-API-Key so server-side requests get the key's limits instead of the anonymous per-IP tier
+    WAIT_TIMES_API_KEY: z.string().optional(),
     NEXTAUTH_SECRET: z.string().optional(),
     NEXTAUTH_URL: z.string().optional().or(z.literal('')),
 }).superRefine((data, ctx) => {
@@ -61,6 +63,7 @@ const envData = {
     NODE_ENV: process.env.NODE_ENV,
     DATABASE_URL: cleanEnvVar(process.env.DATABASE_URL),
     WAIT_TIMES_API_URL: cleanEnvVar(process.env.WAIT_TIMES_API_URL || 'https://wait-times-api-602235714983.us-west2.run.app'),
+    WAIT_TIMES_API_KEY: cleanEnvVar(process.env.WAIT_TIMES_API_KEY),
     NEXTAUTH_SECRET: cleanEnvVar(process.env.NEXTAUTH_SECRET),
     NEXTAUTH_URL: cleanEnvVar(process.env.NEXTAUTH_URL),
 };
@@ -90,6 +93,7 @@ try {
                 NODE_ENV: (envData.NODE_ENV as 'development' | 'production' | 'test') || 'development',
                 DATABASE_URL: envData.DATABASE_URL,
                 WAIT_TIMES_API_URL: envData.WAIT_TIMES_API_URL,
+                WAIT_TIMES_API_KEY: envData.WAIT_TIMES_API_KEY,
                 NEXTAUTH_SECRET: envData.NEXTAUTH_SECRET,
                 NEXTAUTH_URL: envData.NEXTAUTH_URL,
             };
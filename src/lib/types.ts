@@ -96,7 +96,8 @@ export interface AttractionDataEntry {
     forecast: string; // Array of JSON objects stringified
 }
 
-// New API response types for the updated wait-times API
+// New API response types for the updated wait-times API.
+// The Go service publishes the authoritative shapes at GET /openapi.json (go-services/wait-times-api/openapi.json).
 export interface LiveWaitTimeEntry {
     rideId: string;
     rideName: string;
@@ -135,4 +136,20 @@ export interface WaitTimesResponse {
     liveWaitTime: LiveWaitTimeEntry[];
     attractionAtlas: ParkAtlasEntry[];
     groupedRidesHistory: GroupedRidesHistory;
+    // Present on paginated requests (page_size / cursor) when more history is available
+    nextCursor?: string;
+}
+
+// Data of a `ride-update` event on the wait-times API's /v1/stream Server-Sent Events endpoint
+export interface RideDataChange {
+    id: number;
+    rideId: string;
+    parkId: string;
+    name: string;
+    status: string;
+    waitTime: number | null;
+    returnTimeState?: string;
+    returnStart?: string; // ISO date string
+    returnEnd?: string; // ISO date string
+    lastUpdated: string; // ISO date string
 }
@@ -119,6 +119,90 @@ resource "google_cloud_run_v2_service" "wait_times_api" {
         }
       }
 
+      # Cloud Run's front end appends the client IP to X-Forwarded-For; without this every anonymous
+      # client would share the front end's rate limit bucket
+      env {
+        name  = "TRUSTED_PROXY_HOPS"
+        value = "1"
+      }
+
+      resources {
+        limits = {
+          cpu    = "1"
+          memory = "256Mi"
+        }
+        cpu_idle = true
+      }
+
+      volume_mounts {
+        name       = "cloudsql"
+        mount_path = "/cloudsql"
+      }
+    }
+
+    volumes {
+      name = "cloudsql"
+      cloud_sql_instance {
+        instances = [google_sql_database_instance.wait_times_db.connection_name]
+      }
+    }
+
+    timeout = "300s"
+
+    scaling {
+      min_instance_count = 0
+      max_instance_count = 2
+    }
+  }
+
+  traffic {
+    percent = 100
+    type    = "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST"
+  }
+
+  depends_on = [
+    google_project_service.cloud_run,
+    google_service_account.cloud_run_sa
+  ]
+}
+
+# Deploy the Wait Times gRPC API as its own Cloud Run service from the same image. Cloud Run forwards
+# one port per service, and gRPC needs it to speak HTTP/2 cleartext (h2c), which would break the
+# WebSocket upgrades of the HTTP service, so the API serves both on PORT and each service receives
+# one protocol.
+resource "google_cloud_run_v2_service" "wait_times_grpc" {
+  name     = var.wait_times_grpc_service_name
+  location = var.region
+  project  = var.project_id
+
+  template {
+    service_account = google_service_account.cloud_run_sa.email
+
+    containers {
+      image = "${var.region}-docker.pkg.dev/${var.project_id}/${google_artifact_registry_repository.wait_times_repo.repository_id}/wait-times-api:${var.wait_times_api_image_tag}"
+
+      ports {
+        name           = "h2c"
+        container_port = 8080
+      }
+
+      env {
+        name = "DATABASE_URL"
+        value_source {
+          secret_key_ref {
+            secret  = data.google_secret_manager_secret.database_connection_string.name
+            version = "latest"
+          }
+        }
+      }
+
+      # Cloud Run's front end appends the client IP to X-Forwarded-For; without this every anonymous
+      # client would share the front end's rate limit bucket
+      env {
+        name  = "TRUSTED_PROXY_HOPS"
+        value = "1"
+      }
+
       resources {
         limits = {
           cpu    = "1"
@@ -159,6 +243,12 @@ resource "google_cloud_run_v2_service" "wait_times_api" {
   ]
 }
 
+locals {
+  # Cloud Run's deterministic URL for the collector. The collector checks that scheduler ID tokens are
+  # issued for this audience, and a service cannot reference its own uri attribute.
+  live_data_collector_uri = "https://${var.live_data_collector_service_name}-${var.project_number}.${var.region}.run.app"
+}
+
 # Deploy Live Data Collector Service Cloud Run service
 resource "google_cloud_run_v2_service" "live_data_collector" {
   name     = var.live_data_collector_service_name
@@ -185,12 +275,24 @@ resource "google_cloud_run_v2_service" "live_data_collector" {
         }
       }
 
+      # /collect only accepts Cloud Scheduler's ID tokens
+      env {
+        name  = "OIDC_AUDIENCE"
+        value = local.live_data_collector_uri
+      }
+
+      env {
+        name  = "OIDC_ALLOWED_EMAILS"
+        value = google_service_account.scheduler_sa.email
+      }
+
       resources {
         limits = {
           cpu    = "1"
           memory = "256Mi"
         }
-        cpu_idle = true
+        # The watchdog and the webhook dispatcher run between requests, so the CPU stays allocated
+        cpu_idle = false
       }
 
       volume_mounts {
@@ -208,8 +310,9 @@ resource "google_cloud_run_v2_service" "live_data_collector" {
 
     timeout = "300s"
 
+    # One instance always runs the background workers
     scaling {
-      min_instance_count = 0
+      min_instance_count = 1
       max_instance_count = 1
     }
   }
@@ -221,7 +324,8 @@ resource "google_cloud_run_v2_service" "live_data_collector" {
 
   depends_on = [
     google_project_service.cloud_run,
-    google_service_account.cloud_run_sa
+    google_service_account.cloud_run_sa,
+    google_service_account.scheduler_sa
   ]
 }
 
@@ -233,6 +337,14 @@ resource "google_cloud_run_service_iam_member" "wait_times_api_public_access" {
   member   = "allUsers"
 }
 
+# Allow public access to the Wait Times gRPC API service
+resource "google_cloud_run_service_iam_member" "wait_times_grpc_public_access" {
+  service  = google_cloud_run_v2_service.wait_times_grpc.name
+  location = google_cloud_run_v2_service.wait_times_grpc.location
+  role     = "roles/run.invoker"
+  member   = "allUsers"
+}
+
 # Create a service account for Cloud Scheduler
 resource "google_service_account" "scheduler_sa" {
   account_id   = "wait-times-scheduler-sa"
@@ -280,7 +392,7 @@ resource "google_cloud_scheduler_job" "data_collection_job" {
 
     oidc_token {
       service_account_email = google_service_account.scheduler_sa.email
-      audience              = google_cloud_run_v2_service.live_data_collector.uri
+      audience              = local.live_data_collector_uri
     }
   }
 
@@ -8,6 +8,11 @@ output "wait_times_api_service_url" {
   value       = google_cloud_run_v2_service.wait_times_api.uri
 }
 
+output "wait_times_grpc_service_url" {
+  description = "URL of the Wait Times gRPC API Cloud Run service"
+  value       = google_cloud_run_v2_service.wait_times_grpc.uri
+}
+
 output "live_data_collector_service_name" {
   description = "Name of the Live Data Collector Cloud Run service"
   value       = google_cloud_run_v2_service.live_data_collector.name
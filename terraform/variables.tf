@@ -22,6 +22,12 @@ variable "wait_times_api_service_name" {
   default     = "wait-times-api"
 }
 
+variable "wait_times_grpc_service_name" {
+  description = "The name of the Wait Times gRPC API Cloud Run service"
+  type        = string
+  default     = "wait-times-grpc"
+}
+
 variable "live_data_collector_service_name" {
   description = "The name of the Live Data Collector Cloud Run service"
   type        = string